@@ -0,0 +1,103 @@
+package expressRouteClient
+
+import (
+	"encoding/xml"
+)
+
+type DedicatedCircuitList struct {
+	XMLName           xml.Name           `xml:"DedicatedCircuits"`
+	Xmlns             string             `xml:"xmlns,attr"`
+	DedicatedCircuits []DedicatedCircuit `xml:"DedicatedCircuit"`
+}
+
+// DedicatedCircuit is an ExpressRoute circuit. Circuits are identified by
+// their service key, which is also handed to the connectivity provider to
+// provision the circuit on their side.
+type DedicatedCircuit struct {
+	Bandwidth                        int
+	BillingType                      string
+	CircuitName                      string
+	Location                         string
+	ServiceKey                       string
+	ServiceProviderName              string
+	ServiceProviderProvisioningState string
+	Sku                              string
+	Status                           string
+}
+
+type NewDedicatedCircuit struct {
+	XMLName             xml.Name `xml:"NewDedicatedCircuit"`
+	Xmlns               string   `xml:"xmlns,attr"`
+	Bandwidth           int
+	BillingType         string `xml:",omitempty"`
+	CircuitName         string
+	Location            string
+	ServiceProviderName string
+	Sku                 string `xml:",omitempty"`
+}
+
+type BgpPeeringAccessType string
+
+const (
+	BgpPeeringAccessTypePrivate   BgpPeeringAccessType = "private"
+	BgpPeeringAccessTypePublic    BgpPeeringAccessType = "public"
+	BgpPeeringAccessTypeMicrosoft BgpPeeringAccessType = "microsoft"
+)
+
+// BgpPeering is a BGP peering of a dedicated circuit. The primary and secondary
+// peer subnets are /30 subnets used for the two BGP sessions of the circuit.
+type BgpPeering struct {
+	XMLName                        xml.Name `xml:"BgpPeering"`
+	AdvertisedPublicPrefixes       string
+	AdvertisedPublicPrefixesState  string
+	AzureAsn                       int
+	CustomerAutonomousSystemNumber int
+	PeerAsn                        int
+	PrimaryAzurePort               string
+	PrimaryPeerSubnet              string
+	RoutingRegistryName            string
+	SecondaryAzurePort             string
+	SecondaryPeerSubnet            string
+	State                          string
+	VlanId                         int
+}
+
+// BgpPeeringOptions are the settings of a new BGP peering. The advertised
+// public prefixes, customer ASN and routing registry are only used for
+// Microsoft peering.
+type BgpPeeringOptions struct {
+	PeerAsn                        int
+	PrimaryPeerSubnet              string
+	SecondaryPeerSubnet            string
+	VlanId                         int
+	SharedKey                      string
+	AdvertisedPublicPrefixes       string
+	CustomerAutonomousSystemNumber int
+	RoutingRegistryName            string
+}
+
+type CreateBgpPeeringInput struct {
+	XMLName                        xml.Name `xml:"CreateBgpPeering"`
+	Xmlns                          string   `xml:"xmlns,attr"`
+	AdvertisedPublicPrefixes       string   `xml:",omitempty"`
+	CustomerAutonomousSystemNumber int      `xml:",omitempty"`
+	PeerAutonomousSystemNumber     int
+	PrimaryPeerSubnet              string
+	RoutingRegistryName            string `xml:",omitempty"`
+	SecondaryPeerSubnet            string
+	SharedKey                      string `xml:",omitempty"`
+	VirtualLanId                   int
+}
+
+type DedicatedCircuitLinkList struct {
+	XMLName               xml.Name               `xml:"DedicatedCircuitLinks"`
+	Xmlns                 string                 `xml:"xmlns,attr"`
+	DedicatedCircuitLinks []DedicatedCircuitLink `xml:"DedicatedCircuitLink"`
+}
+
+// DedicatedCircuitLink is the link between a dedicated circuit and a virtual
+// network.
+type DedicatedCircuitLink struct {
+	State    string
+	VnetName string
+}
@@ -0,0 +1,273 @@
+package expressRouteClient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net"
+
+	azure "github.com/MSOpenTech/azure-sdk-for-go"
+)
+
+const (
+	azureXmlns                    = "http://schemas.microsoft.com/windowsazure"
+	azureDedicatedCircuitListURL  = "services/networking/dedicatedcircuits?api-version=1.0"
+	azureDedicatedCircuitURL      = "services/networking/dedicatedcircuits/%s?api-version=1.0"
+	azureBgpPeeringURL            = "services/networking/dedicatedcircuits/%s/bgp/%s?api-version=1.0"
+	azureDedicatedCircuitLinksURL = "services/networking/dedicatedcircuits/%s/vnets?api-version=1.0"
+	azureDedicatedCircuitLinkURL  = "services/networking/dedicatedcircuits/%s/vnets/%s?api-version=1.0"
+
+	invalidAccessTypeError = "Invalid BGP peering access type: %s. Valid values are 'private', 'public' and 'microsoft'."
+	invalidPeerSubnetError = "Invalid peer subnet %s. Peer subnets have to be /30 subnets."
+	paramNotSpecifiedError = "Parameter %s is not specified."
+
+	peerSubnetPrefixLength = 30
+)
+
+func ListDedicatedCircuits() ([]DedicatedCircuit, error) {
+	response, err := azure.SendAzureGetRequest(azureDedicatedCircuitListURL)
+	if err != nil {
+		return nil, err
+	}
+
+	circuitList := new(DedicatedCircuitList)
+	err = xml.Unmarshal(response, circuitList)
+	if err != nil {
+		return nil, err
+	}
+
+	return circuitList.DedicatedCircuits, nil
+}
+
+func GetDedicatedCircuit(serviceKey string) (*DedicatedCircuit, error) {
+	if len(serviceKey) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "serviceKey")
+	}
+
+	requestURL := fmt.Sprintf(azureDedicatedCircuitURL, serviceKey)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	circuit := new(DedicatedCircuit)
+	err = xml.Unmarshal(response, circuit)
+	if err != nil {
+		return nil, err
+	}
+
+	return circuit, nil
+}
+
+// CreateDedicatedCircuit creates a dedicated circuit with the given bandwidth
+// in Mbps through the connectivity provider at the peering location. Use
+// ListDedicatedCircuits to find the service key of the new circuit.
+func CreateDedicatedCircuit(circuit NewDedicatedCircuit) error {
+	if len(circuit.CircuitName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "circuit.CircuitName")
+	}
+	if len(circuit.Location) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "circuit.Location")
+	}
+	if len(circuit.ServiceProviderName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "circuit.ServiceProviderName")
+	}
+	if circuit.Bandwidth <= 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "circuit.Bandwidth")
+	}
+
+	circuit.Xmlns = azureXmlns
+	circuitBytes, err := xml.Marshal(circuit)
+	if err != nil {
+		return err
+	}
+
+	requestId, err := azure.SendAzurePostRequest(azureDedicatedCircuitListURL, circuitBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// DeleteDedicatedCircuit deletes the dedicated circuit. All virtual network
+// links and BGP peerings have to be removed first.
+func DeleteDedicatedCircuit(serviceKey string) error {
+	if len(serviceKey) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "serviceKey")
+	}
+
+	requestURL := fmt.Sprintf(azureDedicatedCircuitURL, serviceKey)
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func GetBgpPeering(serviceKey string, accessType BgpPeeringAccessType) (*BgpPeering, error) {
+	requestURL, err := bgpPeeringURL(serviceKey, accessType)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	peering := new(BgpPeering)
+	err = xml.Unmarshal(response, peering)
+	if err != nil {
+		return nil, err
+	}
+
+	return peering, nil
+}
+
+// CreateBgpPeering creates the BGP peering of the given access type on the
+// dedicated circuit. The circuit has to be provisioned by the connectivity
+// provider first.
+func CreateBgpPeering(serviceKey string, accessType BgpPeeringAccessType, options BgpPeeringOptions) error {
+	requestURL, err := bgpPeeringURL(serviceKey, accessType)
+	if err != nil {
+		return err
+	}
+	if options.PeerAsn <= 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "options.PeerAsn")
+	}
+	if options.VlanId <= 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "options.VlanId")
+	}
+	for _, subnet := range []string{options.PrimaryPeerSubnet, options.SecondaryPeerSubnet} {
+		err = verifyPeerSubnet(subnet)
+		if err != nil {
+			return err
+		}
+	}
+
+	peering := CreateBgpPeeringInput{
+		Xmlns:                          azureXmlns,
+		AdvertisedPublicPrefixes:       options.AdvertisedPublicPrefixes,
+		CustomerAutonomousSystemNumber: options.CustomerAutonomousSystemNumber,
+		PeerAutonomousSystemNumber:     options.PeerAsn,
+		PrimaryPeerSubnet:              options.PrimaryPeerSubnet,
+		RoutingRegistryName:            options.RoutingRegistryName,
+		SecondaryPeerSubnet:            options.SecondaryPeerSubnet,
+		SharedKey:                      options.SharedKey,
+		VirtualLanId:                   options.VlanId,
+	}
+	peeringBytes, err := xml.Marshal(peering)
+	if err != nil {
+		return err
+	}
+
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", peeringBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func DeleteBgpPeering(serviceKey string, accessType BgpPeeringAccessType) error {
+	requestURL, err := bgpPeeringURL(serviceKey, accessType)
+	if err != nil {
+		return err
+	}
+
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func ListDedicatedCircuitLinks(serviceKey string) ([]DedicatedCircuitLink, error) {
+	if len(serviceKey) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "serviceKey")
+	}
+
+	requestURL := fmt.Sprintf(azureDedicatedCircuitLinksURL, serviceKey)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	linkList := new(DedicatedCircuitLinkList)
+	err = xml.Unmarshal(response, linkList)
+	if err != nil {
+		return nil, err
+	}
+
+	return linkList.DedicatedCircuitLinks, nil
+}
+
+// LinkVirtualNetwork connects the virtual network to the dedicated circuit.
+// The virtual network needs a dynamic routing gateway, see
+// gatewayClient.CreateGateway, and the circuit a private BGP peering.
+func LinkVirtualNetwork(serviceKey, vnetName string) error {
+	if len(serviceKey) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "serviceKey")
+	}
+	if len(vnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+
+	requestURL := fmt.Sprintf(azureDedicatedCircuitLinkURL, serviceKey, vnetName)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", nil)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func UnlinkVirtualNetwork(serviceKey, vnetName string) error {
+	if len(serviceKey) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "serviceKey")
+	}
+	if len(vnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+
+	requestURL := fmt.Sprintf(azureDedicatedCircuitLinkURL, serviceKey, vnetName)
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+//Region private methods starts
+
+func bgpPeeringURL(serviceKey string, accessType BgpPeeringAccessType) (string, error) {
+	if len(serviceKey) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "serviceKey")
+	}
+
+	switch accessType {
+	case BgpPeeringAccessTypePrivate, BgpPeeringAccessTypePublic, BgpPeeringAccessTypeMicrosoft:
+		return fmt.Sprintf(azureBgpPeeringURL, serviceKey, accessType), nil
+	}
+
+	return "", fmt.Errorf(invalidAccessTypeError, accessType)
+}
+
+func verifyPeerSubnet(subnet string) error {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return fmt.Errorf(invalidPeerSubnetError, subnet)
+	}
+
+	ones, _ := ipNet.Mask.Size()
+	if ones != peerSubnetPrefixLength {
+		return fmt.Errorf(invalidPeerSubnetError, subnet)
+	}
+
+	return nil
+}
+
+//Region private methods ends
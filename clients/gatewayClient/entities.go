@@ -0,0 +1,124 @@
+package gatewayClient
+
+import (
+	"encoding/xml"
+)
+
+type GatewayType string
+
+const (
+	GatewayTypeStaticRouting  GatewayType = "StaticRouting"
+	GatewayTypeDynamicRouting GatewayType = "DynamicRouting"
+)
+
+type GatewayState string
+
+const (
+	GatewayStateNotProvisioned GatewayState = "NotProvisioned"
+	GatewayStateDeprovisioning GatewayState = "Deprovisioning"
+	GatewayStateProvisioning   GatewayState = "Provisioning"
+	GatewayStateProvisioned    GatewayState = "Provisioned"
+)
+
+type CreateGatewayParameters struct {
+	XMLName     xml.Name    `xml:"CreateGatewayParameters"`
+	Xmlns       string      `xml:"xmlns,attr"`
+	GatewayType GatewayType `xml:"gatewayType"`
+}
+
+// Gateway is the virtual network gateway of a virtual network site.
+type Gateway struct {
+	XMLName      xml.Name `xml:"Gateway"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	State        GatewayState
+	VIPAddress   string
+	LastEvent    *GatewayEvent
+	GatewayType  GatewayType
+	GatewaySize  string
+	DefaultSites []string `xml:"DefaultSites>string"`
+}
+
+type GatewayEvent struct {
+	Timestamp string
+	Id        string
+	Message   string
+	Data      string
+}
+
+type ConnectivityState string
+
+const (
+	ConnectivityStateConnected    ConnectivityState = "Connected"
+	ConnectivityStateConnecting   ConnectivityState = "Connecting"
+	ConnectivityStateNotConnected ConnectivityState = "NotConnected"
+	ConnectivityStateInitializing ConnectivityState = "Initializing"
+)
+
+type ConnectionList struct {
+	XMLName     xml.Name     `xml:"Connections"`
+	Xmlns       string       `xml:"xmlns,attr"`
+	Connections []Connection `xml:"Connection"`
+}
+
+// Connection is the state of the VPN connection between a gateway and one of
+// the local network sites it is connected to.
+type Connection struct {
+	LocalNetworkSiteName      string
+	ConnectivityState         ConnectivityState
+	LastEvent                 *GatewayEvent
+	IngressBytesTransferred   int64
+	EgressBytesTransferred    int64
+	LastConnectionEstablished string
+	AllocatedIPAddresses      []string `xml:"AllocatedIPAddresses>string"`
+}
+
+type ConnectionOperation string
+
+const (
+	ConnectionOperationConnect    ConnectionOperation = "Connect"
+	ConnectionOperationDisconnect ConnectionOperation = "Disconnect"
+	ConnectionOperationTest       ConnectionOperation = "Test"
+)
+
+type UpdateConnection struct {
+	XMLName   xml.Name `xml:"UpdateConnection"`
+	Xmlns     string   `xml:"xmlns,attr"`
+	Operation ConnectionOperation
+}
+
+type SharedKey struct {
+	XMLName xml.Name `xml:"SharedKey"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Value   string
+}
+
+type ResetSharedKeyParameters struct {
+	XMLName   xml.Name `xml:"ResetSharedKey"`
+	Xmlns     string   `xml:"xmlns,attr"`
+	KeyLength int
+}
+
+type ClientRootCertificateList struct {
+	XMLName                xml.Name                `xml:"ClientRootCertificates"`
+	Xmlns                  string                  `xml:"xmlns,attr"`
+	ClientRootCertificates []ClientRootCertificate `xml:"ClientRootCertificate"`
+}
+
+// ClientRootCertificate is a root certificate the gateway trusts for
+// authenticating point-to-site VPN clients.
+type ClientRootCertificate struct {
+	Expiry     string
+	Subject    string
+	Thumbprint string
+}
+
+type ProcessorArchitecture string
+
+const (
+	ProcessorArchitectureAmd64 ProcessorArchitecture = "Amd64"
+	ProcessorArchitectureX86   ProcessorArchitecture = "X86"
+)
+
+type vpnClientPackageURL struct {
+	URL string `xml:",chardata"`
+}
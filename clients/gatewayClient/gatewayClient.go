@@ -0,0 +1,575 @@
+package gatewayClient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	azure "github.com/MSOpenTech/azure-sdk-for-go"
+	"github.com/MSOpenTech/azure-sdk-for-go/clients/vnetClient"
+)
+
+const (
+	azureXmlns                        = "http://schemas.microsoft.com/windowsazure"
+	azureGatewayURL                   = "services/networking/%s/gateway"
+	azureConnectionListURL            = "services/networking/%s/gateway/connections"
+	azureConnectionURL                = "services/networking/%s/gateway/connection/%s"
+	azureSharedKeyURL                 = "services/networking/%s/gateway/connection/%s/sharedkey"
+	azureClientRootCertificateListURL = "services/networking/%s/gateway/clientrootcertificates"
+	azureClientRootCertificateURL     = "services/networking/%s/gateway/clientrootcertificates/%s"
+	azureVPNClientPackageURL          = "services/networking/%s/gateway/vpnclientpackage?ProcessorArchitecture=%s"
+
+	invalidGatewayTypeError           = "Invalid gateway type: %s. Valid values are 'StaticRouting' and 'DynamicRouting'."
+	connectionNotFoundError           = "Gateway of virtual network %s has no connection to local network site %s."
+	invalidSharedKeyLengthError       = "Invalid shared key length: %d. The length has to be between %d and %d."
+	invalidProcessorArchitectureError = "Invalid processor architecture: %s. Valid values are 'Amd64' and 'X86'."
+	staticRoutingGatewayError         = "Gateway of virtual network %s uses static routing, connecting virtual networks requires dynamic routing."
+	sameVirtualNetworkError           = "Cannot connect virtual network %s to itself."
+	gatewayNotProvisionedError        = "Gateway of virtual network %s is %s, wait until it is provisioned."
+	localNetworkSiteNotFoundError     = "Local network site %s does not exist."
+	paramNotSpecifiedError            = "Parameter %s is not specified."
+
+	gatewayOperationPollInterval = 30 * time.Second
+
+	vnetLocalNetworkSiteNameFormat = "%s-local"
+	// placeholderVPNGatewayAddress is the gateway address of the local network
+	// sites of ConnectVirtualNetworks until the gateways exist. It is from
+	// the TEST-NET-1 range reserved for documentation.
+	placeholderVPNGatewayAddress = "192.0.2.1"
+
+	minSharedKeyLength = 1
+	maxSharedKeyLength = 128
+)
+
+// GatewayOperationTimeout is how long CreateGateway and DeleteGateway wait for
+// Azure to provision or remove a gateway, which usually takes 20 to 30
+// minutes.
+var GatewayOperationTimeout = 60 * time.Minute
+
+// CreateGateway creates the gateway of a virtual network site and waits until
+// it is provisioned. The site needs a subnet named "GatewaySubnet" for the
+// gateway, see vnetClient.AddSubnet. Dynamic routing is required for
+// point-to-site and multi-site VPNs.
+func CreateGateway(vnetName string, gatewayType GatewayType) error {
+	if len(vnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if gatewayType != GatewayTypeStaticRouting && gatewayType != GatewayTypeDynamicRouting {
+		return fmt.Errorf(invalidGatewayTypeError, gatewayType)
+	}
+
+	parameters := CreateGatewayParameters{
+		Xmlns:       azureXmlns,
+		GatewayType: gatewayType,
+	}
+	parametersBytes, err := xml.Marshal(parameters)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureGatewayURL, vnetName)
+	requestId, err := azure.SendAzurePostRequest(requestURL, parametersBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperationWithTimeout(requestId, gatewayOperationPollInterval, GatewayOperationTimeout)
+}
+
+func GetGateway(vnetName string) (*Gateway, error) {
+	if len(vnetName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+
+	requestURL := fmt.Sprintf(azureGatewayURL, vnetName)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	gateway := new(Gateway)
+	err = xml.Unmarshal(response, gateway)
+	if err != nil {
+		return nil, err
+	}
+
+	return gateway, nil
+}
+
+// DeleteGateway deletes the gateway of a virtual network site and waits until
+// it is removed.
+func DeleteGateway(vnetName string) error {
+	if len(vnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+
+	requestURL := fmt.Sprintf(azureGatewayURL, vnetName)
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperationWithTimeout(requestId, gatewayOperationPollInterval, GatewayOperationTimeout)
+}
+
+func ListConnections(vnetName string) ([]Connection, error) {
+	if len(vnetName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+
+	requestURL := fmt.Sprintf(azureConnectionListURL, vnetName)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	connectionList := new(ConnectionList)
+	err = xml.Unmarshal(response, connectionList)
+	if err != nil {
+		return nil, err
+	}
+
+	return connectionList.Connections, nil
+}
+
+// GetConnection returns the connection between the gateway of the virtual
+// network and the given local network site.
+func GetConnection(vnetName, localNetworkSiteName string) (*Connection, error) {
+	if len(vnetName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if len(localNetworkSiteName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "localNetworkSiteName")
+	}
+
+	connections, err := ListConnections(vnetName)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range connections {
+		if connections[i].LocalNetworkSiteName == localNetworkSiteName {
+			return &connections[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf(connectionNotFoundError, vnetName, localNetworkSiteName)
+}
+
+// Connect starts the VPN connection between the gateway of the virtual
+// network and the local network site. The local network site has to be
+// connected to the virtual network site in the network configuration, see
+// vnetClient.ConnectLocalNetworkSite.
+func Connect(vnetName, localNetworkSiteName string) error {
+	return updateConnection(vnetName, localNetworkSiteName, ConnectionOperationConnect)
+}
+
+// Disconnect stops the VPN connection between the gateway of the virtual
+// network and the local network site.
+func Disconnect(vnetName, localNetworkSiteName string) error {
+	return updateConnection(vnetName, localNetworkSiteName, ConnectionOperationDisconnect)
+}
+
+// GetSharedKey returns the IPsec shared key the VPN device of the local
+// network site has to be configured with.
+func GetSharedKey(vnetName, localNetworkSiteName string) (string, error) {
+	if len(vnetName) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if len(localNetworkSiteName) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "localNetworkSiteName")
+	}
+
+	requestURL := fmt.Sprintf(azureSharedKeyURL, vnetName, localNetworkSiteName)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return "", err
+	}
+
+	sharedKey := new(SharedKey)
+	err = xml.Unmarshal(response, sharedKey)
+	if err != nil {
+		return "", err
+	}
+
+	return sharedKey.Value, nil
+}
+
+// SetSharedKey sets the IPsec shared key of the connection to the local
+// network site, e.g. to match the key already configured on the VPN device.
+func SetSharedKey(vnetName, localNetworkSiteName, value string) error {
+	if len(vnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if len(localNetworkSiteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "localNetworkSiteName")
+	}
+	if len(value) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "value")
+	}
+
+	sharedKeyBytes, err := xml.Marshal(SharedKey{Xmlns: azureXmlns, Value: value})
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureSharedKeyURL, vnetName, localNetworkSiteName)
+	requestId, err := azure.SendAzurePostRequest(requestURL, sharedKeyBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// ResetSharedKey replaces the IPsec shared key of the connection to the local
+// network site with a new random key of the given length. Use GetSharedKey to
+// retrieve the new key.
+func ResetSharedKey(vnetName, localNetworkSiteName string, keyLength int) error {
+	if len(vnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if len(localNetworkSiteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "localNetworkSiteName")
+	}
+	if keyLength < minSharedKeyLength || keyLength > maxSharedKeyLength {
+		return fmt.Errorf(invalidSharedKeyLengthError, keyLength, minSharedKeyLength, maxSharedKeyLength)
+	}
+
+	resetSharedKeyBytes, err := xml.Marshal(ResetSharedKeyParameters{Xmlns: azureXmlns, KeyLength: keyLength})
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureSharedKeyURL, vnetName, localNetworkSiteName)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", resetSharedKeyBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// UploadClientRootCertificate adds a root certificate to the gateway of the
+// virtual network. Point-to-site VPN clients authenticate with client
+// certificates issued by one of these roots. certData is the public part of
+// the certificate in base64 encoded .cer format.
+func UploadClientRootCertificate(vnetName string, certData []byte) error {
+	if len(vnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if len(certData) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "certData")
+	}
+
+	requestURL := fmt.Sprintf(azureClientRootCertificateListURL, vnetName)
+	requestId, err := azure.SendAzurePostRequest(requestURL, certData)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func ListClientRootCertificates(vnetName string) ([]ClientRootCertificate, error) {
+	if len(vnetName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+
+	requestURL := fmt.Sprintf(azureClientRootCertificateListURL, vnetName)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	certificateList := new(ClientRootCertificateList)
+	err = xml.Unmarshal(response, certificateList)
+	if err != nil {
+		return nil, err
+	}
+
+	return certificateList.ClientRootCertificates, nil
+}
+
+// GetClientRootCertificate returns the data of the client root certificate
+// with the given thumbprint as it was uploaded.
+func GetClientRootCertificate(vnetName, thumbprint string) ([]byte, error) {
+	if len(vnetName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if len(thumbprint) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "thumbprint")
+	}
+
+	requestURL := fmt.Sprintf(azureClientRootCertificateURL, vnetName, thumbprint)
+	return azure.SendAzureGetRequest(requestURL)
+}
+
+func DeleteClientRootCertificate(vnetName, thumbprint string) error {
+	if len(vnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if len(thumbprint) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "thumbprint")
+	}
+
+	requestURL := fmt.Sprintf(azureClientRootCertificateURL, vnetName, thumbprint)
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// GetVPNClientPackageURL returns a temporary download URL for the point-to-site
+// VPN client package generated by the gateway of the virtual network. The
+// gateway has to use dynamic routing and the virtual network site needs a VPN
+// client address pool, see vnetClient.SetVPNClientAddressPool.
+func GetVPNClientPackageURL(vnetName string, architecture ProcessorArchitecture) (string, error) {
+	if len(vnetName) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if architecture != ProcessorArchitectureAmd64 && architecture != ProcessorArchitectureX86 {
+		return "", fmt.Errorf(invalidProcessorArchitectureError, architecture)
+	}
+
+	requestURL := fmt.Sprintf(azureVPNClientPackageURL, vnetName, architecture)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return "", err
+	}
+
+	packageURL := new(vpnClientPackageURL)
+	err = xml.Unmarshal(response, packageURL)
+	if err != nil {
+		return "", err
+	}
+
+	return packageURL.URL, nil
+}
+
+// ConnectVirtualNetworks connects two virtual networks with a VPN tunnel. It
+// adds a local network site named "<vnet>-local" for each network with its
+// address space and connects each virtual network site to the local network
+// site of the other network, which Azure requires before gateways for
+// site-to-site connections can be created. It then creates dynamic routing
+// gateways for both networks unless they already have one, sets the gateway
+// addresses of the local network sites, which start out with a placeholder,
+// and sets the same shared key on both sides. Every step is skipped if it was
+// already done, so ConnectVirtualNetworks can be run again after a failure.
+// Both virtual network sites need a "GatewaySubnet" subnet and their address
+// spaces must not overlap. Creating the gateways takes up to
+// GatewayOperationTimeout, both are created in parallel.
+func ConnectVirtualNetworks(vnetName1, vnetName2, sharedKey string) error {
+	if len(vnetName1) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName1")
+	}
+	if len(vnetName2) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName2")
+	}
+	if len(sharedKey) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "sharedKey")
+	}
+	if vnetName1 == vnetName2 {
+		return fmt.Errorf(sameVirtualNetworkError, vnetName1)
+	}
+
+	vnetNames := []string{vnetName1, vnetName2}
+	localSiteNames := []string{
+		fmt.Sprintf(vnetLocalNetworkSiteNameFormat, vnetName1),
+		fmt.Sprintf(vnetLocalNetworkSiteNameFormat, vnetName2),
+	}
+
+	for i, vnetName := range vnetNames {
+		site, err := vnetClient.GetVirtualNetworkSite(vnetName)
+		if err != nil {
+			return err
+		}
+
+		err = ensureLocalNetworkSite(localSiteNames[i], site.AddressSpace)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, vnetName := range vnetNames {
+		err := ensureLocalNetworkSiteConnection(vnetName, localSiteNames[1-i])
+		if err != nil {
+			return err
+		}
+	}
+
+	gateways := make([]*Gateway, len(vnetNames))
+	errs := make([]error, len(vnetNames))
+	var wg sync.WaitGroup
+	for i, vnetName := range vnetNames {
+		wg.Add(1)
+		go func(i int, vnetName string) {
+			defer wg.Done()
+			gateways[i], errs[i] = ensureDynamicRoutingGateway(vnetName)
+		}(i, vnetName)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range vnetNames {
+		err := setLocalNetworkSiteGatewayAddress(localSiteNames[i], gateways[i].VIPAddress)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, vnetName := range vnetNames {
+		err := ensureSharedKey(vnetName, localSiteNames[1-i], sharedKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureLocalNetworkSite adds the local network site with the address space
+// and a placeholder gateway address, or updates the address space of an
+// existing site while keeping its gateway address.
+func ensureLocalNetworkSite(name string, addressSpace vnetClient.AddressSpace) error {
+	localSite, err := getLocalNetworkSite(name)
+	if err != nil {
+		return err
+	}
+
+	if localSite == nil {
+		return vnetClient.AddLocalNetworkSite(vnetClient.LocalNetworkSite{
+			Name:              name,
+			AddressSpace:      addressSpace,
+			VPNGatewayAddress: placeholderVPNGatewayAddress,
+		})
+	}
+
+	if reflect.DeepEqual(localSite.AddressSpace.AddressPrefix, addressSpace.AddressPrefix) {
+		return nil
+	}
+
+	localSite.AddressSpace = addressSpace
+	return vnetClient.UpdateLocalNetworkSite(*localSite)
+}
+
+// ensureLocalNetworkSiteConnection connects the virtual network site to the
+// local network site unless it is connected already.
+func ensureLocalNetworkSiteConnection(vnetName, localNetworkSiteName string) error {
+	site, err := vnetClient.GetVirtualNetworkSite(vnetName)
+	if err != nil {
+		return err
+	}
+
+	if site.Gateway != nil {
+		for _, ref := range site.Gateway.ConnectionsToLocalNetwork {
+			if ref.Name == localNetworkSiteName {
+				return nil
+			}
+		}
+	}
+
+	return vnetClient.ConnectLocalNetworkSite(vnetName, localNetworkSiteName)
+}
+
+func setLocalNetworkSiteGatewayAddress(name, address string) error {
+	localSite, err := getLocalNetworkSite(name)
+	if err != nil {
+		return err
+	}
+	if localSite == nil {
+		return fmt.Errorf(localNetworkSiteNotFoundError, name)
+	}
+	if localSite.VPNGatewayAddress == address {
+		return nil
+	}
+
+	localSite.VPNGatewayAddress = address
+	return vnetClient.UpdateLocalNetworkSite(*localSite)
+}
+
+func ensureSharedKey(vnetName, localNetworkSiteName, sharedKey string) error {
+	currentKey, err := GetSharedKey(vnetName, localNetworkSiteName)
+	if err == nil && currentKey == sharedKey {
+		return nil
+	}
+
+	return SetSharedKey(vnetName, localNetworkSiteName, sharedKey)
+}
+
+// getLocalNetworkSite returns the local network site with the given name, or
+// nil if the network configuration has no such site.
+func getLocalNetworkSite(name string) (*vnetClient.LocalNetworkSite, error) {
+	networkConfiguration, err := vnetClient.GetVirtualNetworkConfiguration()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, localSite := range networkConfiguration.Configuration.LocalNetworkSites {
+		if localSite.Name == name {
+			return &localSite, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ensureDynamicRoutingGateway creates a dynamic routing gateway for the virtual
+// network unless it already has one and returns the provisioned gateway.
+func ensureDynamicRoutingGateway(vnetName string) (*Gateway, error) {
+	gateway, err := GetGateway(vnetName)
+	if err != nil {
+		return nil, err
+	}
+
+	if gateway.State == GatewayStateNotProvisioned {
+		err = CreateGateway(vnetName, GatewayTypeDynamicRouting)
+		if err != nil {
+			return nil, err
+		}
+
+		gateway, err = GetGateway(vnetName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if gateway.GatewayType == GatewayTypeStaticRouting {
+		return nil, fmt.Errorf(staticRoutingGatewayError, vnetName)
+	}
+	if gateway.State != GatewayStateProvisioned {
+		return nil, fmt.Errorf(gatewayNotProvisionedError, vnetName, gateway.State)
+	}
+
+	return gateway, nil
+}
+
+func updateConnection(vnetName, localNetworkSiteName string, operation ConnectionOperation) error {
+	if len(vnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if len(localNetworkSiteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "localNetworkSiteName")
+	}
+
+	updateConnectionBytes, err := xml.Marshal(UpdateConnection{Xmlns: azureXmlns, Operation: operation})
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureConnectionURL, vnetName, localNetworkSiteName)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", updateConnectionBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperationWithTimeout(requestId, gatewayOperationPollInterval, GatewayOperationTimeout)
+}
@@ -2,6 +2,7 @@ package hostedServiceClient
 
 import (
 	"encoding/xml"
+	"time"
 )
 
 type HostedServiceDeployment struct {
@@ -11,7 +12,25 @@ type HostedServiceDeployment struct {
 	Label          string
 	Description    string
 	Location       string
-	ReverseDnsFqdn string `xml:"omitempty"`
+	ReverseDnsFqdn string `xml:",omitempty"`
+}
+
+// HostedServiceUpdateOptions holds the properties to change on a hosted
+// service. Empty fields are left unchanged.
+type HostedServiceUpdateOptions struct {
+	Label              string
+	Description        string
+	ExtendedProperties map[string]string
+	ReverseDnsFqdn     string
+}
+
+type UpdateHostedServiceInput struct {
+	XMLName            xml.Name              `xml:"UpdateHostedService"`
+	Xmlns              string                `xml:"xmlns,attr"`
+	Label              string                `xml:",omitempty"`
+	Description        string                `xml:",omitempty"`
+	ExtendedProperties *ExtendedPropertyList `xml:",omitempty"`
+	ReverseDnsFqdn     string                `xml:",omitempty"`
 }
 
 type AvailabilityResponse struct {
@@ -19,3 +38,212 @@ type AvailabilityResponse struct {
 	Result bool
 	Reason string
 }
+
+type HostedService struct {
+	XMLName                           xml.Name `xml:"HostedService"`
+	Xmlns                             string   `xml:"xmlns,attr"`
+	Url                               string
+	ServiceName                       string
+	HostedServiceProperties           HostedServiceProperties
+	Deployments                       []Deployment `xml:"Deployments>Deployment"`
+	DefaultWinRmCertificateThumbprint string
+}
+
+type HostedServiceProperties struct {
+	Description        string
+	AffinityGroup      string
+	Location           string
+	Label              string
+	Status             string
+	DateCreated        string
+	DateLastModified   string
+	ExtendedProperties []ExtendedProperty `xml:"ExtendedProperties>ExtendedProperty"`
+	ReverseDnsFqdn     string
+}
+
+type ExtendedPropertyList struct {
+	ExtendedProperty []ExtendedProperty
+}
+
+type ExtendedProperty struct {
+	Name  string
+	Value string
+}
+
+// Deployment is a deployment of a hosted service as returned when the
+// hosted service is requested with embedded details.
+type Deployment struct {
+	Name                   string
+	DeploymentSlot         string
+	PrivateID              string
+	Status                 string
+	Label                  string
+	Url                    string
+	Configuration          string
+	RoleInstanceList       []RoleInstance `xml:"RoleInstanceList>RoleInstance"`
+	UpgradeDomainCount     int
+	RoleList               []Role `xml:"RoleList>Role"`
+	SdkVersion             string
+	Locked                 bool
+	RollbackAllowed        bool
+	VirtualNetworkName     string
+	CreatedTime            string
+	LastModifiedTime       string
+	VirtualIPs             []VirtualIP `xml:"VirtualIPs>VirtualIP"`
+	ReservedIPName         string
+	ExtensionConfiguration *ExtensionConfiguration
+}
+
+type Role struct {
+	RoleName            string
+	OsVersion           string
+	RoleType            string
+	RoleSize            string
+	AvailabilitySetName string
+}
+
+type RoleInstance struct {
+	RoleName              string
+	InstanceName          string
+	InstanceStatus        string
+	InstanceUpgradeDomain int
+	InstanceFaultDomain   int
+	InstanceSize          string
+	InstanceStateDetails  string
+	InstanceErrorCode     string
+	IpAddress             string
+	PowerState            string
+	HostName              string
+}
+
+type VirtualIP struct {
+	Address         string
+	IsDnsProgrammed bool
+	Name            string
+}
+
+type CertificateList struct {
+	XMLName      xml.Name      `xml:"Certificates"`
+	Xmlns        string        `xml:"xmlns,attr"`
+	Certificates []Certificate `xml:"Certificate"`
+}
+
+// Certificate is a service certificate of a hosted service. Data holds the
+// base64 encoded public part of the certificate.
+type Certificate struct {
+	CertificateUrl      string
+	Thumbprint          string
+	ThumbprintAlgorithm string
+	Data                string
+}
+
+type ExtensionList struct {
+	XMLName    xml.Name    `xml:"Extensions"`
+	Xmlns      string      `xml:"xmlns,attr"`
+	Extensions []Extension `xml:"Extension"`
+}
+
+// Extension is a cloud service extension, e.g. remote desktop or diagnostics
+// for web and worker roles. PublicConfiguration and PrivateConfiguration are
+// base64 encoded; the private configuration is never returned by Azure.
+type Extension struct {
+	XMLName              xml.Name `xml:"Extension"`
+	Xmlns                string   `xml:"xmlns,attr,omitempty"`
+	ProviderNameSpace    string
+	Type                 string
+	Id                   string
+	Thumbprint           string `xml:",omitempty"`
+	ThumbprintAlgorithm  string `xml:",omitempty"`
+	PublicConfiguration  string `xml:",omitempty"`
+	PrivateConfiguration string `xml:",omitempty"`
+	Version              string `xml:",omitempty"`
+	IsJsonExtension      bool   `xml:",omitempty"`
+}
+
+// ExtensionConfiguration selects the extensions applied to all roles or to
+// named roles of a deployment.
+type ExtensionConfiguration struct {
+	AllRoles   *ExtensionReferenceList `xml:",omitempty"`
+	NamedRoles *NamedRoleList          `xml:",omitempty"`
+}
+
+type ExtensionReferenceList struct {
+	Extension []ExtensionReference
+}
+
+type ExtensionReference struct {
+	Id    string
+	State ExtensionState `xml:",omitempty"`
+}
+
+type ExtensionState string
+
+const (
+	ExtensionStateEnable    ExtensionState = "Enable"
+	ExtensionStateDisable   ExtensionState = "Disable"
+	ExtensionStateUninstall ExtensionState = "Uninstall"
+)
+
+type NamedRoleList struct {
+	Role []NamedRole
+}
+
+type NamedRole struct {
+	RoleName   string
+	Extensions ExtensionReferenceList
+}
+
+type ChangeConfiguration struct {
+	XMLName                xml.Name `xml:"ChangeConfiguration"`
+	Xmlns                  string   `xml:"xmlns,attr"`
+	Configuration          string
+	Mode                   string                  `xml:",omitempty"`
+	ExtensionConfiguration *ExtensionConfiguration `xml:",omitempty"`
+}
+
+// DeploymentOptions holds the optional settings of a web or worker role
+// deployment. Name defaults to the label.
+type DeploymentOptions struct {
+	Name                   string
+	StartDeployment        bool
+	TreatWarningsAsError   bool
+	ExtendedProperties     map[string]string
+	ExtensionConfiguration *ExtensionConfiguration
+}
+
+type CreateDeploymentInput struct {
+	XMLName                xml.Name `xml:"CreateDeployment"`
+	Xmlns                  string   `xml:"xmlns,attr"`
+	Name                   string
+	PackageUrl             string
+	Label                  string
+	Configuration          string
+	StartDeployment        bool
+	TreatWarningsAsError   bool
+	ExtendedProperties     *ExtendedPropertyList   `xml:",omitempty"`
+	ExtensionConfiguration *ExtensionConfiguration `xml:",omitempty"`
+}
+
+// RemoteDesktopConfiguration holds the remote desktop credentials of the
+// roles of a deployment. The password is sent in the private configuration
+// of the extension, which Azure encrypts with the service certificate given
+// by Thumbprint, or with a generated certificate if none is given. Roles
+// lists the roles remote desktop is enabled on, all roles if empty.
+type RemoteDesktopConfiguration struct {
+	UserName   string
+	Password   string
+	Expiration time.Time
+	Thumbprint string
+	Roles      []string
+}
+
+type remoteDesktopPublicConfig struct {
+	XMLName    xml.Name `xml:"PublicConfig"`
+	UserName   string
+	Expiration string
+}
+
+type remoteDesktopPrivateConfig struct {
+	XMLName  xml.Name `xml:"PrivateConfig"`
+	Password string
+}
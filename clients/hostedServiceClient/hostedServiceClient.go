@@ -4,6 +4,8 @@ import (
 	"encoding/base64"
 	"encoding/xml"
 	"fmt"
+	"strings"
+	"time"
 
 	azure "github.com/MSOpenTech/azure-sdk-for-go"
 	"github.com/MSOpenTech/azure-sdk-for-go/clients/locationClient"
@@ -13,13 +15,38 @@ const (
 	azureXmlns                        = "http://schemas.microsoft.com/windowsazure"
 	azureDeploymentListURL            = "services/hostedservices/%s/deployments"
 	azureHostedServiceListURL         = "services/hostedservices"
+	azureHostedServiceURL             = "services/hostedservices/%s"
+	embedDetailQuery                  = "?embed-detail=true"
 	deleteAzureHostedServiceURL       = "services/hostedservices/%s?comp=media"
 	azureHostedServiceAvailabilityURL = "services/hostedservices/operations/isavailable/%s"
 	azureDeploymentURL                = "services/hostedservices/%s/deployments/%s"
 	deleteAzureDeploymentURL          = "services/hostedservices/%s/deployments/%s?comp=media"
+	azureCertificateListURL           = "services/hostedservices/%s/certificates"
+	azureExtensionListURL             = "services/hostedservices/%s/extensions"
+	azureExtensionURL                 = "services/hostedservices/%s/extensions/%s"
+	azureDeploymentSlotURL            = "services/hostedservices/%s/deploymentslots/%s"
+	azureDeploymentSlotConfigURL      = "services/hostedservices/%s/deploymentslots/%s/?comp=config"
+	azureCertificateURL               = "services/hostedservices/%s/certificates/%s-%s"
 
-	invalidDnsLengthError  = "The DNS name must be between 3 and 25 characters."
-	paramNotSpecifiedError = "Parameter %s is not specified."
+	invalidDnsLengthError              = "The DNS name must be between 3 and 25 characters."
+	invalidDnsCharacterError           = "The DNS name %s contains invalid character '%s' at position %d. Only lower case letters, numbers and hyphens are allowed."
+	invalidDnsStartError               = "The DNS name %s must start with a lower case letter."
+	invalidDnsEndError                 = "The DNS name %s must not end with a hyphen."
+	invalidReverseDnsError             = "The reverse DNS FQDN %s must end with a period, e.g. 'mail.contoso.com.'."
+	invalidDeploymentSlotError         = "Invalid deployment slot: %s. Valid values are 'Production' and 'Staging'."
+	invalidExpirationError             = "The remote desktop expiration %s is in the past."
+	hostedServiceNameTakenError        = "%s Hosted service name: %s"
+	hostedServiceLocationMismatchError = "Hosted service %s is in location %s, not in %s."
+	extensionRollbackFailedError       = "%s Removing extension %s from hosted service %s failed as well: %s"
+	paramNotSpecifiedError             = "Parameter %s is not specified."
+
+	remoteDesktopExtensionNamespace = "Microsoft.Windows.Azure.Extensions"
+	remoteDesktopExtensionType      = "RDP"
+	remoteDesktopExtensionVersion   = "1.*"
+	remoteDesktopExtensionIdFormat  = "RDP-%s-Ext-%d"
+	remoteDesktopExpirationFormat   = "2006-01-02"
+
+	notFoundStatusCode = 404
 )
 
 func CreateHostedService(dnsName, location string, reverseDnsFqdn string) (string, error) {
@@ -30,17 +57,24 @@ func CreateHostedService(dnsName, location string, reverseDnsFqdn string) (strin
 		return "", fmt.Errorf(paramNotSpecifiedError, "location")
 	}
 
-	err := verifyDNSName(dnsName)
+	err := VerifyDNSName(dnsName)
 	if err != nil {
 		return "", err
 	}
 
+	if len(reverseDnsFqdn) > 0 {
+		err = verifyReverseDnsFqdn(reverseDnsFqdn)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	result, reason, err := CheckHostedServiceNameAvailability(dnsName)
 	if err != nil {
 		return "", err
 	}
 	if !result {
-		return "", fmt.Errorf("%s Hosted service name: %s", reason, dnsName)
+		return "", fmt.Errorf(hostedServiceNameTakenError, reason, dnsName)
 	}
 
 	err = locationClient.ResolveLocation(location)
@@ -68,7 +102,7 @@ func CheckHostedServiceNameAvailability(dnsName string) (bool, string, error) {
 		return false, "", fmt.Errorf(paramNotSpecifiedError, "dnsName")
 	}
 
-	err := verifyDNSName(dnsName)
+	err := VerifyDNSName(dnsName)
 	if err != nil {
 		return false, "", err
 	}
@@ -88,23 +122,505 @@ func CheckHostedServiceNameAvailability(dnsName string) (bool, string, error) {
 	return availabilityResponse.Result, availabilityResponse.Reason, nil
 }
 
+// GetHostedService returns the properties of a hosted service. With
+// embedDetail the deployments of both slots are included along with their
+// roles and role instances.
+func GetHostedService(dnsName string, embedDetail bool) (*HostedService, error) {
+	if len(dnsName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+
+	hostedService := new(HostedService)
+
+	requestURL := fmt.Sprintf(azureHostedServiceURL, dnsName)
+	if embedDetail {
+		requestURL += embedDetailQuery
+	}
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = xml.Unmarshal(response, hostedService)
+	if err != nil {
+		return nil, err
+	}
+
+	return hostedService, nil
+}
+
+// UpdateHostedService changes the label, description, extended properties or
+// reverse DNS FQDN of an existing hosted service.
+func UpdateHostedService(dnsName string, options HostedServiceUpdateOptions) error {
+	if len(dnsName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+
+	if len(options.ReverseDnsFqdn) > 0 {
+		err := verifyReverseDnsFqdn(options.ReverseDnsFqdn)
+		if err != nil {
+			return err
+		}
+	}
+
+	updateConfig := createUpdateHostedServiceConfig(options)
+	updateBytes, err := xml.Marshal(updateConfig)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureHostedServiceURL, dnsName)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", updateBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// EnsureHostedService creates the hosted service in location if it does not
+// exist yet and waits for the operation to complete. An existing service
+// is checked with VerifyHostedServiceName; its reverse DNS FQDN is updated if
+// reverseDnsFqdn is given and differs. The returned bool reports whether the
+// service was created or changed.
+func EnsureHostedService(dnsName, location string, reverseDnsFqdn string) (bool, error) {
+	if len(dnsName) == 0 {
+		return false, fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+	if len(location) == 0 {
+		return false, fmt.Errorf(paramNotSpecifiedError, "location")
+	}
+
+	hostedService, err := VerifyHostedServiceName(dnsName, location)
+	if err != nil {
+		return false, err
+	}
+
+	if hostedService == nil {
+		requestId, err := CreateHostedService(dnsName, location, reverseDnsFqdn)
+		if err != nil {
+			return false, err
+		}
+
+		return true, azure.WaitAsyncOperation(requestId)
+	}
+
+	properties := hostedService.HostedServiceProperties
+	if len(reverseDnsFqdn) == 0 || properties.ReverseDnsFqdn == reverseDnsFqdn {
+		return false, nil
+	}
+
+	err = UpdateHostedService(dnsName, HostedServiceUpdateOptions{ReverseDnsFqdn: reverseDnsFqdn})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// VerifyDNSName checks that dns can be used as the name of a hosted
+// service: 3 to 25 lower case letters, numbers and hyphens, starting with a
+// letter and not ending with a hyphen.
+func VerifyDNSName(dns string) error {
+	if len(dns) < 3 || len(dns) > 25 {
+		return fmt.Errorf(invalidDnsLengthError)
+	}
+
+	for i, r := range dns {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return fmt.Errorf(invalidDnsCharacterError, dns, string(r), i)
+		}
+	}
+
+	if dns[0] < 'a' || dns[0] > 'z' {
+		return fmt.Errorf(invalidDnsStartError, dns)
+	}
+	if dns[len(dns)-1] == '-' {
+		return fmt.Errorf(invalidDnsEndError, dns)
+	}
+
+	return nil
+}
+
+// VerifyHostedServiceName checks whether a hosted service with the given name
+// can be used in location. It returns nil if the name is still available and
+// the hosted service if it already exists in this subscription. An error is
+// returned if the name is taken by another subscription or if the existing
+// service is in another location. Services in an affinity group have no
+// location of their own and are not checked against it.
+func VerifyHostedServiceName(dnsName, location string) (*HostedService, error) {
+	if len(dnsName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+	if len(location) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "location")
+	}
+
+	available, reason, err := CheckHostedServiceNameAvailability(dnsName)
+	if err != nil {
+		return nil, err
+	}
+	if available {
+		return nil, nil
+	}
+
+	// The name is taken, it is only ours if the service belongs to this subscription
+	hostedService, err := GetHostedService(dnsName, false)
+	if err != nil {
+		if azureErr, ok := err.(*azure.AzureError); ok && azureErr.StatusCode == notFoundStatusCode {
+			return nil, fmt.Errorf(hostedServiceNameTakenError, reason, dnsName)
+		}
+		return nil, err
+	}
+
+	serviceLocation := hostedService.HostedServiceProperties.Location
+	if len(serviceLocation) > 0 && !strings.EqualFold(serviceLocation, location) {
+		return nil, fmt.Errorf(hostedServiceLocationMismatchError, dnsName, serviceLocation, location)
+	}
+
+	return hostedService, nil
+}
+
+// DeleteHostedService deletes a hosted service together with all of its
+// deployments, role instances, the OS and data disks attached to them and
+// the VHD blobs of the disks in a single operation.
 func DeleteHostedService(dnsName string) error {
+	return deleteHostedService(dnsName, deleteAzureHostedServiceURL)
+}
+
+// DeleteHostedServiceComplete deletes a hosted service along with its
+// deployments and media, like DeleteHostedService. The name states the
+// scope of the delete for callers tearing down whole services.
+func DeleteHostedServiceComplete(dnsName string) error {
+	return DeleteHostedService(dnsName)
+}
+
+// DeleteEmptyHostedService deletes a hosted service which has no deployments.
+// It fails if the service still has a deployment in either slot, use
+// DeleteHostedService to delete a service along with its deployments.
+func DeleteEmptyHostedService(dnsName string) error {
+	return deleteHostedService(dnsName, azureHostedServiceURL)
+}
+
+func ListServiceCertificates(dnsName string) ([]Certificate, error) {
+	if len(dnsName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+
+	requestURL := fmt.Sprintf(azureCertificateListURL, dnsName)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	certificateList := new(CertificateList)
+	err = xml.Unmarshal(response, certificateList)
+	if err != nil {
+		return nil, err
+	}
+
+	return certificateList.Certificates, nil
+}
+
+// GetServiceCertificate returns the public part of a service certificate
+// identified by its thumbprint, e.g. thumbprintAlgorithm "sha1".
+func GetServiceCertificate(dnsName, thumbprintAlgorithm, thumbprint string) (*Certificate, error) {
+	if len(dnsName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+	if len(thumbprintAlgorithm) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "thumbprintAlgorithm")
+	}
+	if len(thumbprint) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "thumbprint")
+	}
+
+	requestURL := fmt.Sprintf(azureCertificateURL, dnsName, thumbprintAlgorithm, thumbprint)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate := new(Certificate)
+	err = xml.Unmarshal(response, certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate.Thumbprint = thumbprint
+	certificate.ThumbprintAlgorithm = thumbprintAlgorithm
+	return certificate, nil
+}
+
+func DeleteServiceCertificate(dnsName, thumbprintAlgorithm, thumbprint string) error {
+	if len(dnsName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+	if len(thumbprintAlgorithm) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "thumbprintAlgorithm")
+	}
+	if len(thumbprint) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "thumbprint")
+	}
+
+	requestURL := fmt.Sprintf(azureCertificateURL, dnsName, thumbprintAlgorithm, thumbprint)
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func deleteHostedService(dnsName, urlFormat string) error {
+	if len(dnsName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+
+	err := VerifyDNSName(dnsName)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(urlFormat, dnsName)
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// AddExtension adds an extension to the hosted service. The extension takes
+// effect on the roles of a deployment once it is applied with
+// SetDeploymentExtensions.
+func AddExtension(dnsName string, extension Extension) error {
 	if len(dnsName) == 0 {
 		return fmt.Errorf(paramNotSpecifiedError, "dnsName")
 	}
+	if len(extension.ProviderNameSpace) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "extension.ProviderNameSpace")
+	}
+	if len(extension.Type) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "extension.Type")
+	}
+	if len(extension.Id) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "extension.Id")
+	}
+
+	extension.Xmlns = azureXmlns
+	extensionBytes, err := xml.Marshal(extension)
+	if err != nil {
+		return err
+	}
 
-	err := verifyDNSName(dnsName)
+	requestURL := fmt.Sprintf(azureExtensionListURL, dnsName)
+	requestId, err := azure.SendAzurePostRequest(requestURL, extensionBytes)
 	if err != nil {
 		return err
 	}
 
-	requestURL := fmt.Sprintf(deleteAzureHostedServiceURL, dnsName)
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func ListExtensions(dnsName string) ([]Extension, error) {
+	if len(dnsName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+
+	requestURL := fmt.Sprintf(azureExtensionListURL, dnsName)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	extensionList := new(ExtensionList)
+	err = xml.Unmarshal(response, extensionList)
+	if err != nil {
+		return nil, err
+	}
+
+	return extensionList.Extensions, nil
+}
+
+// DeleteExtension removes an extension from the hosted service. The extension
+// has to be uninstalled from all deployments first.
+func DeleteExtension(dnsName, extensionId string) error {
+	if len(dnsName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+	if len(extensionId) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "extensionId")
+	}
+
+	requestURL := fmt.Sprintf(azureExtensionURL, dnsName, extensionId)
 	requestId, err := azure.SendAzureDeleteRequest(requestURL)
 	if err != nil {
 		return err
 	}
 
-	azure.WaitAsyncOperation(requestId)
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// SetDeploymentExtensions applies the extensions of the hosted service to the
+// roles of the deployment in the given slot ("Production" or "Staging"). The
+// service configuration of the deployment is kept as is.
+func SetDeploymentExtensions(dnsName, deploymentSlot string, extensionConfiguration ExtensionConfiguration) error {
+	if len(dnsName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+	if len(deploymentSlot) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentSlot")
+	}
+
+	deployment, err := getDeploymentInSlot(dnsName, deploymentSlot)
+	if err != nil {
+		return err
+	}
+
+	changeConfiguration := ChangeConfiguration{
+		Xmlns:                  azureXmlns,
+		Configuration:          deployment.Configuration,
+		Mode:                   "Auto",
+		ExtensionConfiguration: &extensionConfiguration,
+	}
+	changeConfigurationBytes, err := xml.Marshal(changeConfiguration)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureDeploymentSlotConfigURL, dnsName, deploymentSlot)
+	requestId, err := azure.SendAzurePostRequest(requestURL, changeConfigurationBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// CreateDeployment deploys a web and worker role package to the given slot of
+// the hosted service. packageBlobURL is the URL of the .cspkg file in a
+// storage account of the subscription and configuration the contents of the
+// .cscfg file. Unless options.StartDeployment is set the deployment is created
+// in the suspended state.
+func CreateDeployment(dnsName, deploymentSlot, packageBlobURL string, configuration []byte, label string, options DeploymentOptions) error {
+	if len(dnsName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+	if deploymentSlot != azure.SlotProduction && deploymentSlot != azure.SlotStaging {
+		return fmt.Errorf(invalidDeploymentSlotError, deploymentSlot)
+	}
+	if len(packageBlobURL) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "packageBlobURL")
+	}
+	if len(configuration) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "configuration")
+	}
+	if len(label) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "label")
+	}
+
+	deployment := CreateDeploymentInput{
+		Xmlns:                  azureXmlns,
+		Name:                   options.Name,
+		PackageUrl:             packageBlobURL,
+		Label:                  base64.StdEncoding.EncodeToString([]byte(label)),
+		Configuration:          base64.StdEncoding.EncodeToString(configuration),
+		StartDeployment:        options.StartDeployment,
+		TreatWarningsAsError:   options.TreatWarningsAsError,
+		ExtendedProperties:     createExtendedPropertyList(options.ExtendedProperties),
+		ExtensionConfiguration: options.ExtensionConfiguration,
+	}
+	if len(deployment.Name) == 0 {
+		deployment.Name = label
+	}
+
+	deploymentBytes, err := xml.Marshal(deployment)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureDeploymentSlotURL, dnsName, deploymentSlot)
+	requestId, err := azure.SendAzurePostRequest(requestURL, deploymentBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// SetRemoteDesktopExtension enables remote desktop on the roles of the
+// deployment in the given slot with the RDP extension. Any remote desktop
+// extension the roles had before is replaced and removed from the hosted
+// service, while other extensions of the deployment are kept. If the
+// deployment cannot be updated, the new extension is removed again.
+func SetRemoteDesktopExtension(dnsName, deploymentSlot string, config RemoteDesktopConfiguration) error {
+	if len(dnsName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+	if deploymentSlot != azure.SlotProduction && deploymentSlot != azure.SlotStaging {
+		return fmt.Errorf(invalidDeploymentSlotError, deploymentSlot)
+	}
+	if len(config.UserName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "config.UserName")
+	}
+	if len(config.Password) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "config.Password")
+	}
+	if config.Expiration.Before(time.Now()) {
+		return fmt.Errorf(invalidExpirationError, config.Expiration.Format(remoteDesktopExpirationFormat))
+	}
+
+	deployment, err := getDeploymentInSlot(dnsName, deploymentSlot)
+	if err != nil {
+		return err
+	}
+
+	extensions, err := ListExtensions(dnsName)
+	if err != nil {
+		return err
+	}
+
+	extension, err := createRemoteDesktopExtension(deploymentSlot, config)
+	if err != nil {
+		return err
+	}
+
+	err = AddExtension(dnsName, extension)
+	if err != nil {
+		return err
+	}
+
+	oldExtensionIds := map[string]bool{}
+	for _, existing := range extensions {
+		if existing.ProviderNameSpace == remoteDesktopExtensionNamespace && existing.Type == remoteDesktopExtensionType {
+			oldExtensionIds[existing.Id] = true
+		}
+	}
+
+	extensionConfiguration := replaceExtensionReferences(deployment.ExtensionConfiguration, oldExtensionIds, extension.Id, config.Roles)
+	err = SetDeploymentExtensions(dnsName, deploymentSlot, extensionConfiguration)
+	if err != nil {
+		// The new extension is not referenced yet, remove it so that a retry
+		// does not leave it behind
+		deleteErr := DeleteExtension(dnsName, extension.Id)
+		if deleteErr != nil {
+			return fmt.Errorf(extensionRollbackFailedError, err, extension.Id, dnsName, deleteErr)
+		}
+		return err
+	}
+
+	// The old extensions can only be deleted once no deployment references
+	// them, and they may still be in use by the deployment in the other slot.
+	for id := range oldExtensionIds {
+		if !isExtensionReferenced(dnsName, id) {
+			err = DeleteExtension(dnsName, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -120,9 +636,180 @@ func createHostedServiceDeploymentConfig(dnsName, location string, reverseDnsFqd
 	return deployment
 }
 
-func verifyDNSName(dns string) error {
-	if len(dns) < 3 || len(dns) > 25 {
-		return fmt.Errorf(invalidDnsLengthError)
+func createUpdateHostedServiceConfig(options HostedServiceUpdateOptions) UpdateHostedServiceInput {
+	updateConfig := UpdateHostedServiceInput{}
+	updateConfig.Xmlns = azureXmlns
+	if len(options.Label) > 0 {
+		updateConfig.Label = base64.StdEncoding.EncodeToString([]byte(options.Label))
+	}
+	updateConfig.Description = options.Description
+	updateConfig.ReverseDnsFqdn = options.ReverseDnsFqdn
+
+	updateConfig.ExtendedProperties = createExtendedPropertyList(options.ExtendedProperties)
+
+	return updateConfig
+}
+
+func createExtendedPropertyList(extendedProperties map[string]string) *ExtendedPropertyList {
+	if len(extendedProperties) == 0 {
+		return nil
+	}
+
+	names := azure.SortedKeys(extendedProperties)
+
+	list := &ExtendedPropertyList{}
+	for _, name := range names {
+		list.ExtendedProperty = append(list.ExtendedProperty, ExtendedProperty{Name: name, Value: extendedProperties[name]})
+	}
+
+	return list
+}
+
+func getDeploymentInSlot(dnsName, deploymentSlot string) (*Deployment, error) {
+	requestURL := fmt.Sprintf(azureDeploymentSlotURL, dnsName, deploymentSlot)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment := new(Deployment)
+	err = xml.Unmarshal(response, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
+
+func createRemoteDesktopExtension(deploymentSlot string, config RemoteDesktopConfiguration) (Extension, error) {
+	publicConfigBytes, err := xml.Marshal(remoteDesktopPublicConfig{
+		UserName:   config.UserName,
+		Expiration: config.Expiration.Format(remoteDesktopExpirationFormat),
+	})
+	if err != nil {
+		return Extension{}, err
+	}
+
+	privateConfigBytes, err := xml.Marshal(remoteDesktopPrivateConfig{Password: config.Password})
+	if err != nil {
+		return Extension{}, err
+	}
+
+	extension := Extension{
+		ProviderNameSpace:    remoteDesktopExtensionNamespace,
+		Type:                 remoteDesktopExtensionType,
+		Id:                   fmt.Sprintf(remoteDesktopExtensionIdFormat, deploymentSlot, time.Now().Unix()),
+		PublicConfiguration:  base64.StdEncoding.EncodeToString(publicConfigBytes),
+		PrivateConfiguration: base64.StdEncoding.EncodeToString(privateConfigBytes),
+		Version:              remoteDesktopExtensionVersion,
+	}
+	if len(config.Thumbprint) > 0 {
+		extension.Thumbprint = config.Thumbprint
+		extension.ThumbprintAlgorithm = "sha1"
+	}
+
+	return extension, nil
+}
+
+// replaceExtensionReferences returns a copy of the extension configuration
+// without the references to the old extensions and with a reference to the
+// new extension for the given roles, or for all roles if roles is empty.
+func replaceExtensionReferences(current *ExtensionConfiguration, oldExtensionIds map[string]bool, newExtensionId string, roles []string) ExtensionConfiguration {
+	withoutOld := func(list ExtensionReferenceList) ExtensionReferenceList {
+		kept := ExtensionReferenceList{}
+		for _, reference := range list.Extension {
+			if !oldExtensionIds[reference.Id] {
+				kept.Extension = append(kept.Extension, reference)
+			}
+		}
+		return kept
+	}
+	newReference := ExtensionReference{Id: newExtensionId}
+
+	config := ExtensionConfiguration{}
+	if current != nil && current.AllRoles != nil {
+		allRoles := withoutOld(*current.AllRoles)
+		config.AllRoles = &allRoles
+	}
+	if current != nil && current.NamedRoles != nil {
+		namedRoles := &NamedRoleList{}
+		for _, role := range current.NamedRoles.Role {
+			namedRoles.Role = append(namedRoles.Role, NamedRole{RoleName: role.RoleName, Extensions: withoutOld(role.Extensions)})
+		}
+		config.NamedRoles = namedRoles
+	}
+
+	if len(roles) == 0 {
+		if config.AllRoles == nil {
+			config.AllRoles = &ExtensionReferenceList{}
+		}
+		config.AllRoles.Extension = append(config.AllRoles.Extension, newReference)
+		return config
+	}
+
+	if config.NamedRoles == nil {
+		config.NamedRoles = &NamedRoleList{}
+	}
+	for _, roleName := range roles {
+		found := false
+		for i := range config.NamedRoles.Role {
+			if config.NamedRoles.Role[i].RoleName == roleName {
+				config.NamedRoles.Role[i].Extensions.Extension = append(config.NamedRoles.Role[i].Extensions.Extension, newReference)
+				found = true
+				break
+			}
+		}
+		if !found {
+			config.NamedRoles.Role = append(config.NamedRoles.Role, NamedRole{
+				RoleName:   roleName,
+				Extensions: ExtensionReferenceList{Extension: []ExtensionReference{newReference}},
+			})
+		}
+	}
+
+	return config
+}
+
+// isExtensionReferenced reports whether a deployment of the hosted service
+// uses the extension. Errors are reported as referenced so that the extension
+// is kept.
+func isExtensionReferenced(dnsName, extensionId string) bool {
+	for _, slot := range []string{azure.SlotProduction, azure.SlotStaging} {
+		deployment, err := getDeploymentInSlot(dnsName, slot)
+		if err != nil {
+			if azureErr, ok := err.(*azure.AzureError); ok && azureErr.StatusCode == notFoundStatusCode {
+				continue
+			}
+			return true
+		}
+		if deployment.ExtensionConfiguration == nil {
+			continue
+		}
+
+		references := []ExtensionReference{}
+		if deployment.ExtensionConfiguration.AllRoles != nil {
+			references = append(references, deployment.ExtensionConfiguration.AllRoles.Extension...)
+		}
+		if deployment.ExtensionConfiguration.NamedRoles != nil {
+			for _, role := range deployment.ExtensionConfiguration.NamedRoles.Role {
+				references = append(references, role.Extensions.Extension...)
+			}
+		}
+		for _, reference := range references {
+			if reference.Id == extensionId {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// verifyReverseDnsFqdn checks that the FQDN is fully qualified. Azure also
+// requires the FQDN to resolve to the cloud service, which it verifies itself.
+func verifyReverseDnsFqdn(fqdn string) error {
+	if !strings.HasSuffix(fqdn, ".") {
+		return fmt.Errorf(invalidReverseDnsError, fqdn)
 	}
 
 	return nil
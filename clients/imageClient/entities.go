@@ -2,6 +2,7 @@ package imageClient
 
 import (
 	"encoding/xml"
+	"strings"
 )
 
 type ImageList struct {
@@ -11,12 +12,158 @@ type ImageList struct {
 }
 
 type OSImage struct {
-	Category        string
-	Label           string
-	LogicalSizeInGB string
-	Name            string
-	OS              string
-	Eula            string
-	Description     string
-	Location        string
+	AffinityGroup     string
+	Category          string
+	Label             string
+	Location          string
+	LogicalSizeInGB   float64
+	MediaLink         string
+	Name              string
+	OS                string
+	Eula              string
+	Description       string
+	ImageFamily       string
+	ShowInGui         bool
+	PublishedDate     string
+	IsPremium         bool
+	IconUri           string
+	PrivacyUri        string
+	RecommendedVMSize string
+	PublisherName     string
+	SmallIconUri      string
+	Language          string
+	IOType            string
+}
+
+type OSImageDetails struct {
+	XMLName xml.Name `xml:"OSImageDetails"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	OSImage
+	IsCorrupted         bool
+	ReplicationProgress ReplicationProgress
+}
+
+type ReplicationProgress struct {
+	ReplicationProgressElement []ReplicationProgressElement
+}
+
+type ReplicationProgressElement struct {
+	Location string
+	Progress string
+}
+
+// IsAvailableIn reports whether the image is listed for the location and,
+// when replication progress is reported, has finished replicating there.
+func (details *OSImageDetails) IsAvailableIn(location string) bool {
+	if details.IsCorrupted {
+		return false
+	}
+
+	listed := false
+	for _, imageLocation := range details.Locations() {
+		if strings.EqualFold(imageLocation, location) {
+			listed = true
+			break
+		}
+	}
+
+	if !listed {
+		return false
+	}
+
+	for _, replication := range details.ReplicationProgress.ReplicationProgressElement {
+		if strings.EqualFold(replication.Location, location) {
+			return replication.Progress == replicationCompleteProgress
+		}
+	}
+
+	return true
+}
+
+type OSImageOptions struct {
+	Eula              string
+	Description       string
+	ImageFamily       string
+	PublishedDate     string
+	IsPremium         bool
+	ShowInGui         bool
+	PrivacyUri        string
+	IconUri           string
+	RecommendedVMSize string
+	SmallIconUri      string
+	Language          string
+}
+
+type OSImageConfig struct {
+	XMLName           xml.Name `xml:"OSImage"`
+	Xmlns             string   `xml:"xmlns,attr"`
+	Label             string
+	MediaLink         string `xml:",omitempty"`
+	Name              string `xml:",omitempty"`
+	OS                string `xml:",omitempty"`
+	Eula              string `xml:",omitempty"`
+	Description       string `xml:",omitempty"`
+	ImageFamily       string `xml:",omitempty"`
+	PublishedDate     string `xml:",omitempty"`
+	IsPremium         bool
+	ShowInGui         bool
+	PrivacyUri        string `xml:",omitempty"`
+	IconUri           string `xml:",omitempty"`
+	RecommendedVMSize string `xml:",omitempty"`
+	SmallIconUri      string `xml:",omitempty"`
+	Language          string `xml:",omitempty"`
+}
+
+type ImageType string
+
+const (
+	ImageTypeOSImage   ImageType = "OSImage"
+	ImageTypeVMImage   ImageType = "VMImage"
+	ImageTypeUserImage ImageType = "UserImage"
+)
+
+// ResolvedImage describes the image found by ResolveImage. Images created in
+// the subscription are reported as ImageTypeUserImage whether they are OS or
+// VM images, IsVMImage distinguishes the two.
+type ResolvedImage struct {
+	Name      string
+	Label     string
+	Type      ImageType
+	IsVMImage bool
+	OS        string
+	Locations []string
+	MediaLink string
+}
+
+// IsAvailableIn reports whether the image can be used in the location. Images
+// that do not report locations are assumed to be available everywhere.
+func (image *ResolvedImage) IsAvailableIn(location string) bool {
+	if len(image.Locations) == 0 {
+		return true
+	}
+
+	for _, imageLocation := range image.Locations {
+		if strings.EqualFold(imageLocation, location) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type OSImageFilter struct {
+	OS            string
+	Category      string
+	PublisherName string
+	ImageFamily   string
+	Location      string
+}
+
+// Locations returns the locations the image is available in.
+func (image *OSImage) Locations() []string {
+	if len(image.Location) == 0 {
+		return nil
+	}
+
+	return strings.Split(image.Location, ";")
 }
@@ -4,13 +4,34 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
 	azure "github.com/MSOpenTech/azure-sdk-for-go"
+	"github.com/MSOpenTech/azure-sdk-for-go/clients/storage"
+	"github.com/MSOpenTech/azure-sdk-for-go/clients/vmImageClient"
 )
 
 const (
-	azureImageListURL      = "services/images"
-	invalidImageError      = "Can not find image %s in specified subscription, please specify another image name."
-	paramNotSpecifiedError = "Parameter %s is not specified."
+	azureXmlns           = "http://schemas.microsoft.com/windowsazure"
+	azureImageListURL    = "services/images"
+	azureImageURL        = "services/images/%s"
+	azureImageDetailsURL = "services/images/%s/details"
+	deleteMediaQuery     = "?comp=media"
+
+	osLinux                     = "Linux"
+	osWindows                   = "Windows"
+	replicationCompleteProgress = "100"
+	userImageCategory           = "User"
+	imageCopySASValidity        = 24 * time.Hour
+
+	invalidImageError        = "Can not find image %s in specified subscription, please specify another image name."
+	invalidOSError           = "Invalid OS: %s. Valid values are 'Linux' and 'Windows'."
+	imageHasNoMediaLinkError = "Image %s has no media link. Only user images can be copied."
+	invalidMediaLinkError    = "Media link %s is not a valid blob URL."
+	imageFamilyNotFoundError = "No image in family %s is available in location %s."
+	paramNotSpecifiedError   = "Parameter %s is not specified."
 )
 
 func GetImageList() (ImageList, error) {
@@ -29,14 +50,251 @@ func GetImageList() (ImageList, error) {
 	return imageList, err
 }
 
+// ListOSImages returns the OS images matching every non-empty field of the
+// filter. Fields are compared case-insensitively and Location matches images
+// available in that location.
+func ListOSImages(filter OSImageFilter) ([]OSImage, error) {
+	imageList, err := GetImageList()
+	if err != nil {
+		return nil, err
+	}
+
+	var images []OSImage
+	for _, image := range imageList.OSImages {
+		if !matchesFilter(image, filter) {
+			continue
+		}
+
+		images = append(images, image)
+	}
+
+	return images, nil
+}
+
+// GetLatestImageInFamily returns the most recently published OS image of the
+// family that is available in the location.
+func GetLatestImageInFamily(imageFamily, location string) (*OSImage, error) {
+	if len(imageFamily) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "imageFamily")
+	}
+	if len(location) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "location")
+	}
+
+	images, err := ListOSImages(OSImageFilter{ImageFamily: imageFamily, Location: location})
+	if err != nil {
+		return nil, err
+	}
+
+	var latestImage *OSImage
+	for i := range images {
+		if latestImage == nil || isPublishedAfter(images[i], *latestImage) {
+			latestImage = &images[i]
+		}
+	}
+
+	if latestImage == nil {
+		return nil, fmt.Errorf(imageFamilyNotFoundError, imageFamily, location)
+	}
+
+	return latestImage, nil
+}
+
+// AddOSImage registers a generalized VHD from blob storage as an OS image
+// which can then be used to create VMs in the subscription.
+func AddOSImage(name, label, mediaLink, os string, options OSImageOptions) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if len(label) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "label")
+	}
+	if len(mediaLink) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "mediaLink")
+	}
+	if os != osLinux && os != osWindows {
+		return fmt.Errorf(invalidOSError, os)
+	}
+
+	imageConfig := createOSImageConfig(name, label, mediaLink, os, options)
+	imageBytes, err := xml.Marshal(imageConfig)
+	if err != nil {
+		return err
+	}
+
+	requestId, err := azure.SendAzurePostRequest(azureImageListURL, imageBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func GetOSImageDetails(name string) (*OSImageDetails, error) {
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	imageDetails := new(OSImageDetails)
+
+	requestURL := fmt.Sprintf(azureImageDetailsURL, name)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = xml.Unmarshal(response, imageDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	return imageDetails, nil
+}
+
+// UpdateOSImage replaces the label and descriptive metadata of an OS image.
+// The media link, name and OS of an image cannot be changed.
+func UpdateOSImage(name, label string, options OSImageOptions) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if len(label) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "label")
+	}
+
+	imageConfig := createOSImageConfig("", label, "", "", options)
+	imageBytes, err := xml.Marshal(imageConfig)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureImageURL, name)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", imageBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// DeleteOSImage removes an OS image from the image repository, and when
+// deleteBlob is set also deletes the VHD backing it.
+func DeleteOSImage(name string, deleteBlob bool) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	requestURL := fmt.Sprintf(azureImageURL, name)
+	if deleteBlob {
+		requestURL += deleteMediaQuery
+	}
+
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// CopyOSImage copies the VHD backing a user OS image to a container in
+// another storage account, typically in another region, and registers the
+// copy as a new OS image named targetImageName. The source blob is shared
+// with the copy operation through a short lived read-only SAS.
+func CopyOSImage(imageName, targetImageName, sourceAccountKey, targetAccountName, targetAccountKey, targetContainer string) error {
+	if len(imageName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "imageName")
+	}
+	if len(targetImageName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "targetImageName")
+	}
+	if len(sourceAccountKey) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "sourceAccountKey")
+	}
+	if len(targetAccountName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "targetAccountName")
+	}
+	if len(targetAccountKey) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "targetAccountKey")
+	}
+	if len(targetContainer) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "targetContainer")
+	}
+
+	image, err := GetOSImageDetails(imageName)
+	if err != nil {
+		return err
+	}
+	if len(image.MediaLink) == 0 {
+		return fmt.Errorf(imageHasNoMediaLinkError, imageName)
+	}
+
+	sourceAccountName, sourceContainer, blobName, err := parseMediaLink(image.MediaLink)
+	if err != nil {
+		return err
+	}
+
+	sourceClient, err := storage.NewBasicClient(sourceAccountName, sourceAccountKey)
+	if err != nil {
+		return err
+	}
+
+	sourceBlobURI, err := sourceClient.GetBlobService().GetBlobSASURI(sourceContainer, blobName, time.Now().Add(imageCopySASValidity), "r")
+	if err != nil {
+		return err
+	}
+
+	targetClient, err := storage.NewBasicClient(targetAccountName, targetAccountKey)
+	if err != nil {
+		return err
+	}
+
+	targetBlobService := targetClient.GetBlobService()
+	_, err = targetBlobService.CreateContainerIfNotExists(targetContainer, storage.ContainerAccessTypePrivate)
+	if err != nil {
+		return err
+	}
+
+	err = targetBlobService.CopyBlob(targetContainer, blobName, sourceBlobURI)
+	if err != nil {
+		return err
+	}
+
+	options := OSImageOptions{
+		Eula:              image.Eula,
+		Description:       image.Description,
+		ImageFamily:       image.ImageFamily,
+		PublishedDate:     image.PublishedDate,
+		IsPremium:         image.IsPremium,
+		ShowInGui:         image.ShowInGui,
+		PrivacyUri:        image.PrivacyUri,
+		IconUri:           image.IconUri,
+		RecommendedVMSize: image.RecommendedVMSize,
+		SmallIconUri:      image.SmallIconUri,
+		Language:          image.Language,
+	}
+
+	return AddOSImage(targetImageName, image.Label, targetBlobService.GetBlobUrl(targetContainer, blobName), image.OS, options)
+}
+
 func ResolveImageName(imageName string) error {
 	if len(imageName) == 0 {
 		return fmt.Errorf(paramNotSpecifiedError, "imageName")
 	}
 
+	_, err := ResolveImage(imageName)
+	return err
+}
+
+// ResolveImage looks up an OS image or VM image by name or label. OS images
+// take precedence when both kinds match.
+func ResolveImage(imageName string) (*ResolvedImage, error) {
+	if len(imageName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "imageName")
+	}
+
 	imageList, err := GetImageList()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for _, image := range imageList.OSImages {
@@ -44,8 +302,126 @@ func ResolveImageName(imageName string) error {
 			continue
 		}
 
-		return nil
+		resolvedImage := &ResolvedImage{
+			Name:      image.Name,
+			Label:     image.Label,
+			Type:      ImageTypeOSImage,
+			OS:        image.OS,
+			Locations: image.Locations(),
+			MediaLink: image.MediaLink,
+		}
+		if image.Category == userImageCategory {
+			resolvedImage.Type = ImageTypeUserImage
+		}
+
+		return resolvedImage, nil
+	}
+
+	vmImageList, err := vmImageClient.ListVMImages(vmImageClient.VMImageFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range vmImageList.VMImages {
+		if image.Name != imageName && image.Label != imageName {
+			continue
+		}
+
+		resolvedImage := &ResolvedImage{
+			Name:      image.Name,
+			Label:     image.Label,
+			Type:      ImageTypeVMImage,
+			IsVMImage: true,
+			OS:        image.OSDiskConfiguration.OS,
+			MediaLink: image.OSDiskConfiguration.MediaLink,
+		}
+		if len(image.Location) > 0 {
+			resolvedImage.Locations = strings.Split(image.Location, ";")
+		}
+		if image.Category == userImageCategory {
+			resolvedImage.Type = ImageTypeUserImage
+		}
+
+		return resolvedImage, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf(invalidImageError, imageName))
+}
+
+func createOSImageConfig(name, label, mediaLink, os string, options OSImageOptions) OSImageConfig {
+	imageConfig := OSImageConfig{}
+	imageConfig.Xmlns = azureXmlns
+	imageConfig.Name = name
+	imageConfig.Label = label
+	imageConfig.MediaLink = mediaLink
+	imageConfig.OS = os
+	imageConfig.Eula = options.Eula
+	imageConfig.Description = options.Description
+	imageConfig.ImageFamily = options.ImageFamily
+	imageConfig.PublishedDate = options.PublishedDate
+	imageConfig.IsPremium = options.IsPremium
+	imageConfig.ShowInGui = options.ShowInGui
+	imageConfig.PrivacyUri = options.PrivacyUri
+	imageConfig.IconUri = options.IconUri
+	imageConfig.RecommendedVMSize = options.RecommendedVMSize
+	imageConfig.SmallIconUri = options.SmallIconUri
+	imageConfig.Language = options.Language
+
+	return imageConfig
+}
+
+func parseMediaLink(mediaLink string) (string, string, string, error) {
+	mediaURL, err := url.Parse(mediaLink)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	accountName := strings.SplitN(mediaURL.Host, ".", 2)[0]
+	pathParts := strings.SplitN(strings.TrimPrefix(mediaURL.Path, "/"), "/", 2)
+	if len(accountName) == 0 || len(pathParts) != 2 || len(pathParts[0]) == 0 || len(pathParts[1]) == 0 {
+		return "", "", "", fmt.Errorf(invalidMediaLinkError, mediaLink)
+	}
+
+	return accountName, pathParts[0], pathParts[1], nil
+}
+
+func isPublishedAfter(image, otherImage OSImage) bool {
+	publishedDate, err := time.Parse(time.RFC3339, image.PublishedDate)
+	if err != nil {
+		return false
+	}
+
+	otherPublishedDate, err := time.Parse(time.RFC3339, otherImage.PublishedDate)
+	if err != nil {
+		return true
+	}
+
+	return publishedDate.After(otherPublishedDate)
+}
+
+func matchesFilter(image OSImage, filter OSImageFilter) bool {
+	if len(filter.OS) > 0 && !strings.EqualFold(image.OS, filter.OS) {
+		return false
+	}
+	if len(filter.Category) > 0 && !strings.EqualFold(image.Category, filter.Category) {
+		return false
+	}
+	if len(filter.PublisherName) > 0 && !strings.EqualFold(image.PublisherName, filter.PublisherName) {
+		return false
+	}
+	if len(filter.ImageFamily) > 0 && !strings.EqualFold(image.ImageFamily, filter.ImageFamily) {
+		return false
+	}
+
+	if len(filter.Location) > 0 {
+		for _, location := range image.Locations() {
+			if strings.EqualFold(location, filter.Location) {
+				return true
+			}
+		}
+
+		return false
 	}
 
-	return errors.New(fmt.Sprintf(invalidImageError, imageName))
+	return true
 }
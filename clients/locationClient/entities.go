@@ -13,12 +13,128 @@ type LocationList struct {
 	Locations []Location `xml:"Location"`
 }
 
+type AvailableService string
+
+const (
+	AvailableServiceCompute          AvailableService = "Compute"
+	AvailableServiceStorage          AvailableService = "Storage"
+	AvailableServicePersistentVMRole AvailableService = "PersistentVMRole"
+	AvailableServiceHighMemory       AvailableService = "HighMemory"
+)
+
+// Location is an Azure region along with the services, role sizes and
+// storage account types offered in it. Which of these are available can
+// differ between subscriptions.
+//
+// Services holds the same values as AvailableServices, typed. It is filled in
+// when the location is read from XML.
 type Location struct {
 	Name                    string
 	DisplayName             string
-	AvailableServices       []string `xml:"AvailableServices>AvailableService"`
-	WebWorkerRoleSizes      []string `xml:"ComputeCapabilities>WebWorkerRoleSizes>RoleSize"`
-	VirtualMachineRoleSizes []string `xml:"ComputeCapabilities>VirtualMachinesRoleSizes>RoleSize"`
+	AvailableServices       []string           `xml:"AvailableServices>AvailableService"`
+	Services                []AvailableService `xml:"-"`
+	WebWorkerRoleSizes      []string           `xml:"ComputeCapabilities>WebWorkerRoleSizes>RoleSize"`
+	VirtualMachineRoleSizes []string           `xml:"ComputeCapabilities>VirtualMachinesRoleSizes>RoleSize"`
+	StorageAccountTypes     []string           `xml:"StorageCapabilities>StorageAccountTypes>StorageAccountType"`
+}
+
+// UnmarshalXML reads the location and fills in Services.
+func (location *Location) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	type locationElement Location
+	err := decoder.DecodeElement((*locationElement)(location), &start)
+	if err != nil {
+		return err
+	}
+
+	location.Services = make([]AvailableService, len(location.AvailableServices))
+	for i, service := range location.AvailableServices {
+		location.Services[i] = AvailableService(service)
+	}
+
+	return nil
+}
+
+// SupportsService reports whether the service is available in the location.
+func (location Location) SupportsService(service AvailableService) bool {
+	for _, availableService := range location.AvailableServices {
+		if availableService == string(service) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (location Location) SupportsVirtualMachineRoleSize(roleSize string) bool {
+	return containsString(location.VirtualMachineRoleSizes, roleSize)
+}
+
+func (location Location) SupportsWebWorkerRoleSize(roleSize string) bool {
+	return containsString(location.WebWorkerRoleSizes, roleSize)
+}
+
+func (location Location) SupportsStorageAccountType(storageAccountType string) bool {
+	return containsString(location.StorageAccountTypes, storageAccountType)
+}
+
+// LocationRequirements are the capabilities a location has to offer to be
+// returned by FindLocations. Empty fields are not checked.
+type LocationRequirements struct {
+	Services               []AvailableService
+	VirtualMachineRoleSize string
+	WebWorkerRoleSize      string
+	StorageAccountType     string
+}
+
+// Satisfies reports whether the location offers everything the requirements
+// ask for.
+func (location Location) Satisfies(requirements LocationRequirements) bool {
+	for _, service := range requirements.Services {
+		if !location.SupportsService(service) {
+			return false
+		}
+	}
+
+	if len(requirements.VirtualMachineRoleSize) > 0 && !location.SupportsVirtualMachineRoleSize(requirements.VirtualMachineRoleSize) {
+		return false
+	}
+	if len(requirements.WebWorkerRoleSize) > 0 && !location.SupportsWebWorkerRoleSize(requirements.WebWorkerRoleSize) {
+		return false
+	}
+	if len(requirements.StorageAccountType) > 0 && !location.SupportsStorageAccountType(requirements.StorageAccountType) {
+		return false
+	}
+
+	return true
+}
+
+// clone returns a deep copy of the location list, so that lists handed out
+// from the cache do not share their slices.
+func (locationList LocationList) clone() LocationList {
+	clone := locationList
+	if locationList.Locations != nil {
+		clone.Locations = make([]Location, len(locationList.Locations))
+		for i, location := range locationList.Locations {
+			location.AvailableServices = append([]string(nil), location.AvailableServices...)
+			location.Services = append([]AvailableService(nil), location.Services...)
+			location.WebWorkerRoleSizes = append([]string(nil), location.WebWorkerRoleSizes...)
+			location.VirtualMachineRoleSizes = append([]string(nil), location.VirtualMachineRoleSizes...)
+			location.StorageAccountTypes = append([]string(nil), location.StorageAccountTypes...)
+			clone.Locations[i] = location
+		}
+	}
+
+	return clone
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (locationList LocationList) String() string {
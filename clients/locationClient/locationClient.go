@@ -4,6 +4,9 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
+
 	azure "github.com/MSOpenTech/azure-sdk-for-go"
 )
 
@@ -11,8 +14,26 @@ const (
 	azureLocationListURL   = "locations"
 	invalidLocationError   = "Invalid location: %s. Available locations: %s"
 	paramNotSpecifiedError = "Parameter %s is not specified."
+
+	defaultLocationCacheTTL = 10 * time.Minute
 )
 
+var locationCache = struct {
+	sync.Mutex
+	ttl       time.Duration
+	fetchedAt time.Time
+	locations *LocationList
+	fetch     *locationListFetch
+}{ttl: defaultLocationCacheTTL}
+
+// locationListFetch is a request for the location list in progress, which
+// concurrent callers wait for instead of sending their own.
+type locationListFetch struct {
+	done      chan struct{}
+	locations LocationList
+	err       error
+}
+
 func ResolveLocation(location string) error {
 	if len(location) == 0 {
 		return fmt.Errorf(paramNotSpecifiedError, "location")
@@ -34,20 +55,85 @@ func ResolveLocation(location string) error {
 	return errors.New(fmt.Sprintf(invalidLocationError, location, locations))
 }
 
+// GetLocationList returns the locations available to the subscription. The
+// list is cached for the duration set by SetLocationCacheTTL, use
+// RefreshLocationList to bypass the cache. The returned list is a copy which
+// the caller may change.
 func GetLocationList() (LocationList, error) {
-	locationList := LocationList{}
+	locationCache.Lock()
+	if locationCache.locations != nil && time.Since(locationCache.fetchedAt) < locationCache.ttl {
+		locations := locationCache.locations.clone()
+		locationCache.Unlock()
+		return locations, nil
+	}
+	locationCache.Unlock()
 
-	response, err := azure.SendAzureGetRequest(azureLocationListURL)
+	return refreshLocationList()
+}
+
+func RefreshLocationList() (LocationList, error) {
+	return refreshLocationList()
+}
+
+// SetLocationCacheTTL changes how long the location list is cached. A ttl of
+// zero disables caching.
+func SetLocationCacheTTL(ttl time.Duration) {
+	locationCache.Lock()
+	defer locationCache.Unlock()
+
+	locationCache.ttl = ttl
+}
+
+// ListLocations returns the locations available to the subscription with
+// their capabilities. See GetLocationList for how the list is cached, the
+// returned slice is a copy which the caller may change.
+func ListLocations() ([]Location, error) {
+	locations, err := GetLocationList()
 	if err != nil {
-		return locationList, err
+		return nil, err
 	}
 
-	err = xml.Unmarshal(response, &locationList)
+	return locations.Locations, nil
+}
+
+// LocationsSupporting returns the locations in which the service, e.g.
+// AvailableServicePersistentVMRole for virtual machines, is available.
+func LocationsSupporting(service AvailableService) ([]Location, error) {
+	if len(service) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "service")
+	}
+
+	locations, err := ListLocations()
 	if err != nil {
-		return locationList, err
+		return nil, err
 	}
 
-	return locationList, nil
+	supporting := []Location{}
+	for _, location := range locations {
+		if location.SupportsService(service) {
+			supporting = append(supporting, location)
+		}
+	}
+
+	return supporting, nil
+}
+
+// FindLocations returns the locations which satisfy all of the requirements,
+// in the order Azure lists them. The result is empty if no location does.
+func FindLocations(requirements LocationRequirements) ([]Location, error) {
+	locations, err := ListLocations()
+	if err != nil {
+		return nil, err
+	}
+
+	found := []Location{}
+	for _, location := range locations {
+		if location.Satisfies(requirements) {
+			found = append(found, location)
+		}
+	}
+
+	return found, nil
 }
 
 func GetLocation(location string) (*Location, error) {
@@ -70,3 +156,51 @@ func GetLocation(location string) (*Location, error) {
 
 	return nil, errors.New(fmt.Sprintf(invalidLocationError, location, locations))
 }
+
+// refreshLocationList fetches the location list and caches it. The cache is
+// not locked while the request is in progress, callers arriving in the
+// meantime wait for its result.
+func refreshLocationList() (LocationList, error) {
+	locationCache.Lock()
+	fetch := locationCache.fetch
+	if fetch == nil {
+		fetch = &locationListFetch{done: make(chan struct{})}
+		locationCache.fetch = fetch
+		locationCache.Unlock()
+
+		fetch.locations, fetch.err = fetchLocationList()
+
+		locationCache.Lock()
+		if fetch.err == nil {
+			locations := fetch.locations.clone()
+			locationCache.locations = &locations
+			locationCache.fetchedAt = time.Now()
+		}
+		locationCache.fetch = nil
+		close(fetch.done)
+	}
+	locationCache.Unlock()
+
+	<-fetch.done
+	if fetch.err != nil {
+		return LocationList{}, fetch.err
+	}
+
+	return fetch.locations.clone(), nil
+}
+
+func fetchLocationList() (LocationList, error) {
+	locationList := LocationList{}
+
+	response, err := azure.SendAzureGetRequest(azureLocationListURL)
+	if err != nil {
+		return locationList, err
+	}
+
+	err = xml.Unmarshal(response, &locationList)
+	if err != nil {
+		return locationList, err
+	}
+
+	return locationList, nil
+}
@@ -0,0 +1,62 @@
+package mobileServiceClient
+
+import (
+	"encoding/xml"
+)
+
+// MobileService is a classic Mobile Service as returned by the mobile
+// services management endpoints, which respond with JSON.
+type MobileService struct {
+	Name           string `json:"name"`
+	State          string `json:"state"`
+	Location       string `json:"location"`
+	Region         string `json:"region"`
+	Platform       string `json:"platform"`
+	ApplicationURL string `json:"applicationUrl"`
+}
+
+// MobileServiceKeys are the keys clients of a mobile service authenticate
+// with. The master key grants administrative access and must not be shipped
+// with client applications.
+type MobileServiceKeys struct {
+	ApplicationKey string `json:"applicationKey"`
+	MasterKey      string `json:"masterKey"`
+}
+
+// CreateMobileServiceParameters describes a new mobile service. The service
+// stores its data in an existing SQL Database, which has to be on a server
+// in the same location.
+type CreateMobileServiceParameters struct {
+	Name                     string
+	Location                 string
+	Description              string
+	SqlServerName            string
+	SqlDatabaseName          string
+	SqlAdministratorLogin    string
+	SqlAdministratorPassword string
+}
+
+type Application struct {
+	XMLName       xml.Name `xml:"Application"`
+	Xmlns         string   `xml:"xmlns,attr"`
+	Name          string
+	Label         string
+	Description   string
+	Configuration string
+}
+
+type applicationSpec struct {
+	SchemaVersion     string
+	Location          string
+	ExternalResources map[string]applicationResource
+	InternalResources map[string]applicationResource
+}
+
+type applicationResource struct {
+	Name                         string
+	Type                         string
+	URI                          string                 `json:",omitempty"`
+	Version                      string                 `json:",omitempty"`
+	ProvisioningParameters       map[string]string      `json:",omitempty"`
+	ProvisioningConfigParameters map[string]interface{} `json:",omitempty"`
+}
@@ -0,0 +1,210 @@
+package mobileServiceClient
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+
+	azure "github.com/MSOpenTech/azure-sdk-for-go"
+)
+
+const (
+	azureXmlns                = "http://schemas.microsoft.com/windowsazure"
+	azureApplicationListURL   = "applications"
+	azureApplicationURL       = "applications/%s"
+	azureMobileServiceListURL = "services/mobileservices/mobileservices"
+	azureMobileServiceURL     = "services/mobileservices/mobileservices/%s"
+	azureMobileServiceKeysURL = "services/mobileservices/mobileservices/%s/keys"
+	azureDeleteDataParameter  = "?deletedata=true"
+	azureSqlServerURL         = "https://management.core.windows.net:8443/%s/services/sqlservers/servers/%s"
+	azureSqlDatabaseURL       = "https://management.core.windows.net:8443/%s/services/sqlservers/servers/%s/databases/%s"
+
+	applicationNameFormat     = "%smobileservice"
+	applicationSchemaVersion  = "2012-05.1.0"
+	mobileServiceVersion      = "2012-05-21.1.0"
+	mobileServiceResourceName = "ZumoMobileService"
+	mobileServiceResourceType = "Microsoft.WindowsAzure.MobileServices.MobileService"
+	sqlServerResourceName     = "ZumoSqlServer"
+	sqlServerResourceType     = "Microsoft.WindowsAzure.SQLAzure.Server"
+	sqlDatabaseResourceName   = "ZumoSqlDatabase"
+	sqlDatabaseResourceType   = "Microsoft.WindowsAzure.SQLAzure.DataBase"
+
+	paramNotSpecifiedError = "Parameter %s is not specified."
+)
+
+// CreateMobileService creates a mobile service backed by an existing SQL
+// Database.
+func CreateMobileService(params CreateMobileServiceParameters) error {
+	if len(params.Name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "params.Name")
+	}
+	if len(params.Location) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "params.Location")
+	}
+	if len(params.SqlServerName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "params.SqlServerName")
+	}
+	if len(params.SqlDatabaseName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "params.SqlDatabaseName")
+	}
+	if len(params.SqlAdministratorLogin) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "params.SqlAdministratorLogin")
+	}
+	if len(params.SqlAdministratorPassword) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "params.SqlAdministratorPassword")
+	}
+
+	specBytes, err := json.Marshal(createApplicationSpec(params))
+	if err != nil {
+		return err
+	}
+
+	application := Application{
+		Xmlns:         azureXmlns,
+		Name:          fmt.Sprintf(applicationNameFormat, params.Name),
+		Label:         params.Name,
+		Description:   params.Description,
+		Configuration: base64.StdEncoding.EncodeToString(specBytes),
+	}
+	applicationBytes, err := xml.Marshal(application)
+	if err != nil {
+		return err
+	}
+
+	requestId, err := azure.SendAzurePostRequest(azureApplicationListURL, applicationBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func ListMobileServices() ([]MobileService, error) {
+	response, err := azure.SendAzureGetRequest(azureMobileServiceListURL)
+	if err != nil {
+		return nil, err
+	}
+
+	mobileServices := []MobileService{}
+	err = json.Unmarshal(response, &mobileServices)
+	if err != nil {
+		return nil, err
+	}
+
+	return mobileServices, nil
+}
+
+func GetMobileService(name string) (*MobileService, error) {
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	response, err := azure.SendAzureGetRequest(fmt.Sprintf(azureMobileServiceURL, name))
+	if err != nil {
+		return nil, err
+	}
+
+	mobileService := new(MobileService)
+	err = json.Unmarshal(response, mobileService)
+	if err != nil {
+		return nil, err
+	}
+
+	return mobileService, nil
+}
+
+// DeleteMobileService deletes the mobile service. If deleteData is set the
+// tables of the service are dropped from its SQL Database as well, otherwise
+// the database is left untouched.
+func DeleteMobileService(name string, deleteData bool) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	requestURL := fmt.Sprintf(azureMobileServiceURL, name)
+	if deleteData {
+		requestURL += azureDeleteDataParameter
+	}
+
+	_, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	requestId, err := azure.SendAzureDeleteRequest(fmt.Sprintf(azureApplicationURL, fmt.Sprintf(applicationNameFormat, name)))
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func GetMobileServiceKeys(name string) (*MobileServiceKeys, error) {
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	response, err := azure.SendAzureGetRequest(fmt.Sprintf(azureMobileServiceKeysURL, name))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := new(MobileServiceKeys)
+	err = json.Unmarshal(response, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+//Region private methods starts
+
+// createApplicationSpec returns the application specification which
+// provisions the mobile service against the existing SQL server and
+// database.
+func createApplicationSpec(params CreateMobileServiceParameters) applicationSpec {
+	subscriptionID := azure.GetPublishSettings().SubscriptionID
+
+	return applicationSpec{
+		SchemaVersion: applicationSchemaVersion,
+		Location:      params.Location,
+		ExternalResources: map[string]applicationResource{
+			sqlServerResourceName: {
+				Name: sqlServerResourceName,
+				Type: sqlServerResourceType,
+				URI:  fmt.Sprintf(azureSqlServerURL, subscriptionID, params.SqlServerName),
+			},
+			sqlDatabaseResourceName: {
+				Name: sqlDatabaseResourceName,
+				Type: sqlDatabaseResourceType,
+				URI:  fmt.Sprintf(azureSqlDatabaseURL, subscriptionID, params.SqlServerName, params.SqlDatabaseName),
+			},
+		},
+		InternalResources: map[string]applicationResource{
+			mobileServiceResourceName: {
+				Name:    mobileServiceResourceName,
+				Type:    mobileServiceResourceType,
+				Version: mobileServiceVersion,
+				ProvisioningParameters: map[string]string{
+					"Name":     params.Name,
+					"Location": params.Location,
+				},
+				ProvisioningConfigParameters: map[string]interface{}{
+					"Server": map[string]interface{}{
+						"StringConcat": []interface{}{
+							map[string]string{"ResourceReference": sqlServerResourceName + ".Name"},
+							".database.windows.net",
+						},
+					},
+					"Database":                   map[string]string{"ResourceReference": sqlDatabaseResourceName + ".Name"},
+					"AdministratorLogin":         params.SqlAdministratorLogin,
+					"AdministratorLoginPassword": params.SqlAdministratorPassword,
+				},
+			},
+		},
+	}
+}
+
+//Region private methods ends
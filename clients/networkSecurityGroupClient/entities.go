@@ -0,0 +1,55 @@
+package networkSecurityGroupClient
+
+import (
+	"encoding/xml"
+)
+
+type RuleType string
+
+const (
+	RuleTypeInbound  RuleType = "Inbound"
+	RuleTypeOutbound RuleType = "Outbound"
+)
+
+type RuleAction string
+
+const (
+	RuleActionAllow RuleAction = "Allow"
+	RuleActionDeny  RuleAction = "Deny"
+)
+
+type RuleProtocol string
+
+const (
+	RuleProtocolTCP RuleProtocol = "TCP"
+	RuleProtocolUDP RuleProtocol = "UDP"
+	RuleProtocolAll RuleProtocol = "*"
+)
+
+// Rule is a rule of a network security group. Address prefixes are CIDRs,
+// single addresses, "*" or the default tags like "INTERNET" and
+// "VIRTUAL_NETWORK". Port ranges are a single port, a range like "1000-2000"
+// or "*". Rules are evaluated by ascending priority.
+type Rule struct {
+	XMLName                  xml.Name `xml:"Rule"`
+	Xmlns                    string   `xml:"xmlns,attr,omitempty"`
+	Name                     string   `xml:",omitempty"`
+	Type                     RuleType
+	Priority                 int
+	Action                   RuleAction
+	SourceAddressPrefix      string
+	SourcePortRange          string
+	DestinationAddressPrefix string
+	DestinationPortRange     string
+	Protocol                 RuleProtocol
+	State                    string `xml:",omitempty"`
+	IsDefault                bool   `xml:",omitempty"`
+}
+
+// SecurityGroupReference names the network security group associated with a
+// subnet, role or network interface.
+type SecurityGroupReference struct {
+	XMLName xml.Name `xml:"NetworkSecurityGroup"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Name    string
+}
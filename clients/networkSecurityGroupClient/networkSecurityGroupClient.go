@@ -0,0 +1,309 @@
+package networkSecurityGroupClient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	azure "github.com/MSOpenTech/azure-sdk-for-go"
+)
+
+const (
+	azureXmlns                             = "http://schemas.microsoft.com/windowsazure"
+	azureRuleURL                           = "services/networking/networksecuritygroups/%s/rules/%s"
+	azureSubnetSecurityGroupsURL           = "services/networking/virtualnetwork/%s/subnets/%s/networksecuritygroups"
+	azureRoleSecurityGroupsURL             = "services/hostedservices/%s/deployments/%s/roles/%s/networksecuritygroups"
+	azureNetworkInterfaceSecurityGroupsURL = "services/hostedservices/%s/deployments/%s/roles/%s/networkinterfaces/%s/networksecuritygroups"
+
+	minRulePriority = 100
+	maxRulePriority = 4096
+
+	invalidRulePriorityError = "Invalid priority %d of rule %s. The priority has to be between %d and %d."
+	invalidRuleTypeError     = "Invalid type %s of rule %s. Valid values are 'Inbound' and 'Outbound'."
+	invalidRuleActionError   = "Invalid action %s of rule %s. Valid values are 'Allow' and 'Deny'."
+	invalidRuleProtocolError = "Invalid protocol %s of rule %s. Valid values are 'TCP', 'UDP' and '*'."
+	invalidPortRangeError    = "Invalid port range %s of rule %s."
+	paramNotSpecifiedError   = "Parameter %s is not specified."
+)
+
+// SetRule creates the rule in the network security group, or replaces the
+// rule with the same name.
+func SetRule(securityGroupName string, rule Rule) error {
+	if len(securityGroupName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "securityGroupName")
+	}
+
+	err := verifyRule(rule)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureRuleURL, securityGroupName, rule.Name)
+
+	// The rule name is part of the URL and must not be in the body.
+	rule.Xmlns = azureXmlns
+	rule.Name = ""
+	rule.State = ""
+	rule.IsDefault = false
+	ruleBytes, err := xml.Marshal(rule)
+	if err != nil {
+		return err
+	}
+
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", ruleBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// DeleteRule deletes a rule from the network security group. Default rules
+// cannot be deleted.
+func DeleteRule(securityGroupName, ruleName string) error {
+	if len(securityGroupName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "securityGroupName")
+	}
+	if len(ruleName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "ruleName")
+	}
+
+	requestURL := fmt.Sprintf(azureRuleURL, securityGroupName, ruleName)
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// AddToSubnet applies the network security group to all virtual machines in
+// the subnet of the virtual network site. A subnet has at most one network
+// security group.
+func AddToSubnet(securityGroupName, vnetName, subnetName string) error {
+	if len(vnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if len(subnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "subnetName")
+	}
+
+	return addAssociation(securityGroupName, fmt.Sprintf(azureSubnetSecurityGroupsURL, vnetName, subnetName))
+}
+
+// GetForSubnet returns the name of the network security group associated with
+// the subnet.
+func GetForSubnet(vnetName, subnetName string) (string, error) {
+	if len(vnetName) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if len(subnetName) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "subnetName")
+	}
+
+	return getAssociation(fmt.Sprintf(azureSubnetSecurityGroupsURL, vnetName, subnetName))
+}
+
+func RemoveFromSubnet(securityGroupName, vnetName, subnetName string) error {
+	if len(vnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "vnetName")
+	}
+	if len(subnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "subnetName")
+	}
+
+	return removeAssociation(securityGroupName, fmt.Sprintf(azureSubnetSecurityGroupsURL, vnetName, subnetName))
+}
+
+// AddToRole applies the network security group to the primary network
+// interface of the role. The role has to be in a virtual network.
+func AddToRole(securityGroupName, cloudserviceName, deploymentName, roleName string) error {
+	url, err := roleSecurityGroupsURL(cloudserviceName, deploymentName, roleName)
+	if err != nil {
+		return err
+	}
+
+	return addAssociation(securityGroupName, url)
+}
+
+// GetForRole returns the name of the network security group associated with
+// the primary network interface of the role.
+func GetForRole(cloudserviceName, deploymentName, roleName string) (string, error) {
+	url, err := roleSecurityGroupsURL(cloudserviceName, deploymentName, roleName)
+	if err != nil {
+		return "", err
+	}
+
+	return getAssociation(url)
+}
+
+func RemoveFromRole(securityGroupName, cloudserviceName, deploymentName, roleName string) error {
+	url, err := roleSecurityGroupsURL(cloudserviceName, deploymentName, roleName)
+	if err != nil {
+		return err
+	}
+
+	return removeAssociation(securityGroupName, url)
+}
+
+// AddToNetworkInterface applies the network security group to a secondary
+// network interface of the role.
+func AddToNetworkInterface(securityGroupName, cloudserviceName, deploymentName, roleName, networkInterfaceName string) error {
+	url, err := networkInterfaceSecurityGroupsURL(cloudserviceName, deploymentName, roleName, networkInterfaceName)
+	if err != nil {
+		return err
+	}
+
+	return addAssociation(securityGroupName, url)
+}
+
+// GetForNetworkInterface returns the name of the network security group
+// associated with a secondary network interface of the role.
+func GetForNetworkInterface(cloudserviceName, deploymentName, roleName, networkInterfaceName string) (string, error) {
+	url, err := networkInterfaceSecurityGroupsURL(cloudserviceName, deploymentName, roleName, networkInterfaceName)
+	if err != nil {
+		return "", err
+	}
+
+	return getAssociation(url)
+}
+
+func RemoveFromNetworkInterface(securityGroupName, cloudserviceName, deploymentName, roleName, networkInterfaceName string) error {
+	url, err := networkInterfaceSecurityGroupsURL(cloudserviceName, deploymentName, roleName, networkInterfaceName)
+	if err != nil {
+		return err
+	}
+
+	return removeAssociation(securityGroupName, url)
+}
+
+//Region private methods starts
+
+func roleSecurityGroupsURL(cloudserviceName, deploymentName, roleName string) (string, error) {
+	if len(cloudserviceName) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+
+	return fmt.Sprintf(azureRoleSecurityGroupsURL, cloudserviceName, deploymentName, roleName), nil
+}
+
+func networkInterfaceSecurityGroupsURL(cloudserviceName, deploymentName, roleName, networkInterfaceName string) (string, error) {
+	if len(networkInterfaceName) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "networkInterfaceName")
+	}
+
+	_, err := roleSecurityGroupsURL(cloudserviceName, deploymentName, roleName)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(azureNetworkInterfaceSecurityGroupsURL, cloudserviceName, deploymentName, roleName, networkInterfaceName), nil
+}
+
+func addAssociation(securityGroupName, url string) error {
+	if len(securityGroupName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "securityGroupName")
+	}
+
+	reference := SecurityGroupReference{Xmlns: azureXmlns, Name: securityGroupName}
+	referenceBytes, err := xml.Marshal(reference)
+	if err != nil {
+		return err
+	}
+
+	requestId, err := azure.SendAzurePostRequest(url, referenceBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func getAssociation(url string) (string, error) {
+	response, err := azure.SendAzureGetRequest(url)
+	if err != nil {
+		return "", err
+	}
+
+	reference := new(SecurityGroupReference)
+	err = xml.Unmarshal(response, reference)
+	if err != nil {
+		return "", err
+	}
+
+	return reference.Name, nil
+}
+
+func removeAssociation(securityGroupName, url string) error {
+	if len(securityGroupName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "securityGroupName")
+	}
+
+	requestId, err := azure.SendAzureDeleteRequest(url + "/" + securityGroupName)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func verifyRule(rule Rule) error {
+	if len(rule.Name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "rule.Name")
+	}
+	if rule.Priority < minRulePriority || rule.Priority > maxRulePriority {
+		return fmt.Errorf(invalidRulePriorityError, rule.Priority, rule.Name, minRulePriority, maxRulePriority)
+	}
+	if rule.Type != RuleTypeInbound && rule.Type != RuleTypeOutbound {
+		return fmt.Errorf(invalidRuleTypeError, rule.Type, rule.Name)
+	}
+	if rule.Action != RuleActionAllow && rule.Action != RuleActionDeny {
+		return fmt.Errorf(invalidRuleActionError, rule.Action, rule.Name)
+	}
+	if rule.Protocol != RuleProtocolTCP && rule.Protocol != RuleProtocolUDP && rule.Protocol != RuleProtocolAll {
+		return fmt.Errorf(invalidRuleProtocolError, rule.Protocol, rule.Name)
+	}
+	if len(rule.SourceAddressPrefix) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "rule.SourceAddressPrefix")
+	}
+	if len(rule.DestinationAddressPrefix) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "rule.DestinationAddressPrefix")
+	}
+
+	for _, portRange := range []string{rule.SourcePortRange, rule.DestinationPortRange} {
+		if !isValidPortRange(portRange) {
+			return fmt.Errorf(invalidPortRangeError, portRange, rule.Name)
+		}
+	}
+
+	return nil
+}
+
+// isValidPortRange accepts "*", a single port or a range of ports like
+// "1000-2000".
+func isValidPortRange(portRange string) bool {
+	if portRange == "*" {
+		return true
+	}
+
+	bounds := strings.SplitN(portRange, "-", 2)
+	previous := 0
+	for _, bound := range bounds {
+		port, err := strconv.Atoi(bound)
+		if err != nil || port < 1 || port > 65535 || port < previous {
+			return false
+		}
+		previous = port
+	}
+
+	return true
+}
+
+//Region private methods ends
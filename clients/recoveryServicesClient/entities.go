@@ -0,0 +1,91 @@
+package recoveryServicesClient
+
+import (
+	"encoding/xml"
+)
+
+type VaultType string
+
+const (
+	VaultTypeBackup       VaultType = "BackupVault"
+	VaultTypeSiteRecovery VaultType = "HyperVRecoveryManagerVault"
+)
+
+// Vault is a Backup or Hyper-V Recovery Manager vault. Vaults are resources
+// of a per-region cloud service which is created along with the first vault
+// in that region.
+type Vault struct {
+	Name         string
+	Type         VaultType
+	Location     string
+	State        string
+	CloudService string
+}
+
+type CloudServiceList struct {
+	XMLName       xml.Name       `xml:"CloudServices"`
+	Xmlns         string         `xml:"xmlns,attr"`
+	CloudServices []CloudService `xml:"CloudService"`
+}
+
+type CloudService struct {
+	XMLName     xml.Name `xml:"CloudService"`
+	Xmlns       string   `xml:"xmlns,attr"`
+	Name        string   `xml:",omitempty"`
+	Label       string
+	Description string
+	GeoRegion   string
+	Resources   []Resource `xml:"Resources>Resource"`
+}
+
+type Resource struct {
+	XMLName                   xml.Name `xml:"Resource"`
+	Xmlns                     string   `xml:"xmlns,attr,omitempty"`
+	ResourceProviderNamespace string   `xml:",omitempty"`
+	Type                      string
+	Name                      string `xml:",omitempty"`
+	Plan                      string
+	SchemaVersion             string
+	ETag                      string
+	State                     string `xml:",omitempty"`
+}
+
+type CertificateArgs struct {
+	XMLName    xml.Name              `xml:"CertificateArgs"`
+	Xmlns      string                `xml:"xmlns,attr"`
+	Properties []CertificateProperty `xml:"Properties>KeyValueOfstringstring"`
+}
+
+type CertificateProperty struct {
+	Key   string
+	Value string
+}
+
+// VaultCertificate is returned when a certificate is registered with a vault
+// and holds the Access Control Service details the vault agents authenticate
+// against.
+type VaultCertificate struct {
+	XMLName            xml.Name `xml:"UploadCertificateResponse"`
+	ResourceId         int64
+	GlobalAcsNamespace string
+	GlobalAcsHostName  string
+	GlobalAcsRPRealm   string
+}
+
+// VaultCredentials is the content of a vault credentials file, which is
+// imported by the Backup agent or the Site Recovery provider to register a
+// server with the vault.
+type VaultCredentials struct {
+	XMLName        xml.Name
+	SubscriptionId string
+	ResourceType   VaultType
+	ResourceName   string
+	ManagementCert string
+	AcsNamespace   AcsNamespace
+}
+
+type AcsNamespace struct {
+	HostName              string
+	Namespace             string
+	ResourceProviderRealm string
+}
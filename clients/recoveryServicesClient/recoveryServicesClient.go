@@ -0,0 +1,265 @@
+package recoveryServicesClient
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	azure "github.com/MSOpenTech/azure-sdk-for-go"
+)
+
+const (
+	azureXmlns                  = "http://schemas.microsoft.com/windowsazure"
+	azureCloudServiceListURL    = "cloudservices"
+	azureCloudServiceURL        = "cloudservices/%s"
+	azureVaultURL               = "cloudservices/%s/resources/%s/%s/%s"
+	azureVaultCertificateURL    = "cloudservices/%s/resources/%s/%s/%s/certificates/%s"
+	backupNamespace             = "WABackup"
+	siteRecoveryNamespace       = "WAHyperVRecoveryManager"
+	cloudServiceNameFormat      = "CS-%s-RecoveryServices"
+	vaultCertificateName        = "IdMgmtInternalCert"
+	vaultSchemaVersion          = "1.1"
+	backupCredentialsName       = "BackupVaultCreds"
+	siteRecoveryCredentialsName = "ASRVaultCreds"
+
+	invalidVaultTypeError  = "Invalid vault type: %s. Valid values are 'BackupVault' and 'HyperVRecoveryManagerVault'."
+	vaultNotFoundError     = "Vault %s was not found."
+	paramNotSpecifiedError = "Parameter %s is not specified."
+)
+
+// CreateVault creates a vault in the given location, creating the cloud
+// service which holds the vaults of that location if it does not exist yet.
+func CreateVault(name, location string, vaultType VaultType) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if len(location) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "location")
+	}
+
+	namespace, err := getVaultNamespace(vaultType)
+	if err != nil {
+		return err
+	}
+
+	cloudServiceName := getCloudServiceName(location)
+	err = ensureCloudService(cloudServiceName, location)
+	if err != nil {
+		return err
+	}
+
+	etag, err := azure.NewUUID()
+	if err != nil {
+		return err
+	}
+
+	resource := Resource{
+		Xmlns:         azureXmlns,
+		Type:          string(vaultType),
+		SchemaVersion: vaultSchemaVersion,
+		ETag:          etag,
+	}
+	resourceBytes, err := xml.Marshal(resource)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureVaultURL, cloudServiceName, namespace, vaultType, name)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", resourceBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// ListVaults returns the vaults of the given type in all locations.
+func ListVaults(vaultType VaultType) ([]Vault, error) {
+	namespace, err := getVaultNamespace(vaultType)
+	if err != nil {
+		return nil, err
+	}
+
+	cloudServices, err := listCloudServices()
+	if err != nil {
+		return nil, err
+	}
+
+	vaults := []Vault{}
+	for _, cloudService := range cloudServices {
+		for _, resource := range cloudService.Resources {
+			if resource.ResourceProviderNamespace != namespace || resource.Type != string(vaultType) {
+				continue
+			}
+
+			vaults = append(vaults, Vault{
+				Name:         resource.Name,
+				Type:         vaultType,
+				Location:     cloudService.GeoRegion,
+				State:        resource.State,
+				CloudService: cloudService.Name,
+			})
+		}
+	}
+
+	return vaults, nil
+}
+
+func GetVault(name string, vaultType VaultType) (*Vault, error) {
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	vaults, err := ListVaults(vaultType)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vault := range vaults {
+		if strings.EqualFold(vault.Name, name) {
+			return &vault, nil
+		}
+	}
+
+	return nil, fmt.Errorf(vaultNotFoundError, name)
+}
+
+func DeleteVault(name string, vaultType VaultType) error {
+	vault, err := GetVault(name, vaultType)
+	if err != nil {
+		return err
+	}
+
+	namespace, _ := getVaultNamespace(vaultType)
+	requestURL := fmt.Sprintf(azureVaultURL, vault.CloudService, namespace, vaultType, vault.Name)
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// GetVaultCredentials registers the DER encoded certificate with the vault
+// and returns the credentials the Backup agent or the Site Recovery provider
+// use to register servers with the vault. The private key of the certificate
+// is never sent to Azure, so ManagementCert is left empty for the caller to
+// fill with the base64 encoded PKCS #12 certificate before the credentials
+// are written to a file.
+func GetVaultCredentials(name string, vaultType VaultType, certificate []byte) (*VaultCredentials, error) {
+	if len(certificate) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "certificate")
+	}
+
+	vault, err := GetVault(name, vaultType)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace, _ := getVaultNamespace(vaultType)
+	certificateArgs := CertificateArgs{
+		Xmlns: azureXmlns,
+		Properties: []CertificateProperty{
+			{Key: "certificate", Value: base64.StdEncoding.EncodeToString(certificate)},
+		},
+	}
+	certificateBytes, err := xml.Marshal(certificateArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	requestURL := fmt.Sprintf(azureVaultCertificateURL, vault.CloudService, namespace, vaultType, vault.Name, vaultCertificateName)
+	response, err := azure.SendAzureRequest(requestURL, "PUT", "", certificateBytes)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	vaultCertificate := new(VaultCertificate)
+	err = xml.NewDecoder(response.Body).Decode(vaultCertificate)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialsName := backupCredentialsName
+	if vaultType == VaultTypeSiteRecovery {
+		credentialsName = siteRecoveryCredentialsName
+	}
+
+	return &VaultCredentials{
+		XMLName:        xml.Name{Local: credentialsName},
+		SubscriptionId: azure.GetPublishSettings().SubscriptionID,
+		ResourceType:   vaultType,
+		ResourceName:   vault.Name,
+		AcsNamespace: AcsNamespace{
+			HostName:              vaultCertificate.GlobalAcsHostName,
+			Namespace:             vaultCertificate.GlobalAcsNamespace,
+			ResourceProviderRealm: vaultCertificate.GlobalAcsRPRealm,
+		},
+	}, nil
+}
+
+//Region private methods starts
+
+func listCloudServices() ([]CloudService, error) {
+	response, err := azure.SendAzureGetRequest(azureCloudServiceListURL)
+	if err != nil {
+		return nil, err
+	}
+
+	cloudServiceList := new(CloudServiceList)
+	err = xml.Unmarshal(response, cloudServiceList)
+	if err != nil {
+		return nil, err
+	}
+
+	return cloudServiceList.CloudServices, nil
+}
+
+func ensureCloudService(name, location string) error {
+	cloudServices, err := listCloudServices()
+	if err != nil {
+		return err
+	}
+	for _, cloudService := range cloudServices {
+		if strings.EqualFold(cloudService.Name, name) {
+			return nil
+		}
+	}
+
+	cloudService := CloudService{
+		Xmlns:       azureXmlns,
+		Label:       name,
+		Description: name,
+		GeoRegion:   location,
+	}
+	cloudServiceBytes, err := xml.Marshal(cloudService)
+	if err != nil {
+		return err
+	}
+
+	requestId, err := azure.SendAzurePutRequest(fmt.Sprintf(azureCloudServiceURL, name), "", cloudServiceBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func getVaultNamespace(vaultType VaultType) (string, error) {
+	switch vaultType {
+	case VaultTypeBackup:
+		return backupNamespace, nil
+	case VaultTypeSiteRecovery:
+		return siteRecoveryNamespace, nil
+	}
+
+	return "", fmt.Errorf(invalidVaultTypeError, vaultType)
+}
+
+func getCloudServiceName(location string) string {
+	return fmt.Sprintf(cloudServiceNameFormat, strings.Replace(location, " ", "", -1))
+}
+
+//Region private methods ends
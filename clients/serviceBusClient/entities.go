@@ -0,0 +1,97 @@
+package serviceBusClient
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// QueueOptions are the settings of a new queue. Zero values keep the Service
+// Bus defaults.
+type QueueOptions struct {
+	MaxSizeInMegabytes                  int
+	DefaultMessageTimeToLive            time.Duration
+	LockDuration                        time.Duration
+	RequiresDuplicateDetection          bool
+	DuplicateDetectionHistoryTimeWindow time.Duration
+	RequiresSession                     bool
+	DeadLetteringOnMessageExpiration    bool
+	MaxDeliveryCount                    int
+}
+
+// TopicOptions are the settings of a new topic. Zero values keep the Service
+// Bus defaults.
+type TopicOptions struct {
+	MaxSizeInMegabytes                  int
+	DefaultMessageTimeToLive            time.Duration
+	RequiresDuplicateDetection          bool
+	DuplicateDetectionHistoryTimeWindow time.Duration
+}
+
+// SubscriptionOptions are the settings of a new topic subscription. Zero
+// values keep the Service Bus defaults.
+type SubscriptionOptions struct {
+	LockDuration                     time.Duration
+	RequiresSession                  bool
+	DefaultMessageTimeToLive         time.Duration
+	DeadLetteringOnMessageExpiration bool
+	MaxDeliveryCount                 int
+}
+
+// Queue is the description of a queue. Durations are in ISO 8601 format, e.g.
+// "PT1M" or "P14D".
+type Queue struct {
+	XMLName                             xml.Name `xml:"http://schemas.microsoft.com/netservices/2010/10/servicebus/connect QueueDescription"`
+	Name                                string   `xml:"-"`
+	LockDuration                        string   `xml:",omitempty"`
+	MaxSizeInMegabytes                  int      `xml:",omitempty"`
+	RequiresDuplicateDetection          bool
+	RequiresSession                     bool
+	DefaultMessageTimeToLive            string `xml:",omitempty"`
+	DeadLetteringOnMessageExpiration    bool
+	DuplicateDetectionHistoryTimeWindow string `xml:",omitempty"`
+	MaxDeliveryCount                    int    `xml:",omitempty"`
+	SizeInBytes                         int64  `xml:",omitempty"`
+	MessageCount                        int64  `xml:",omitempty"`
+}
+
+// Topic is the description of a topic.
+type Topic struct {
+	XMLName                             xml.Name `xml:"http://schemas.microsoft.com/netservices/2010/10/servicebus/connect TopicDescription"`
+	Name                                string   `xml:"-"`
+	DefaultMessageTimeToLive            string   `xml:",omitempty"`
+	MaxSizeInMegabytes                  int      `xml:",omitempty"`
+	RequiresDuplicateDetection          bool
+	DuplicateDetectionHistoryTimeWindow string `xml:",omitempty"`
+	SizeInBytes                         int64  `xml:",omitempty"`
+}
+
+// Subscription is the description of a subscription to a topic.
+type Subscription struct {
+	XMLName                          xml.Name `xml:"http://schemas.microsoft.com/netservices/2010/10/servicebus/connect SubscriptionDescription"`
+	Name                             string   `xml:"-"`
+	LockDuration                     string   `xml:",omitempty"`
+	RequiresSession                  bool
+	DefaultMessageTimeToLive         string `xml:",omitempty"`
+	DeadLetteringOnMessageExpiration bool
+	MessageCount                     int64 `xml:",omitempty"`
+	MaxDeliveryCount                 int   `xml:",omitempty"`
+}
+
+// entry is the Atom entry Service Bus wraps entity descriptions in.
+type entry struct {
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom entry"`
+	Title   string       `xml:"title,omitempty"`
+	Content entryContent `xml:"content"`
+}
+
+type entryContent struct {
+	Type         string        `xml:"type,attr"`
+	Queue        *Queue        `xml:",omitempty"`
+	Topic        *Topic        `xml:",omitempty"`
+	Subscription *Subscription `xml:",omitempty"`
+}
+
+type feed struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	Entries []entry  `xml:"entry"`
+}
@@ -0,0 +1,234 @@
+package serviceBusClient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	azure "github.com/MSOpenTech/azure-sdk-for-go"
+)
+
+const (
+	azureQueueListURL        = "services/servicebus/namespaces/%s/queues"
+	azureQueueURL            = "services/servicebus/namespaces/%s/queues/%s"
+	azureTopicListURL        = "services/servicebus/namespaces/%s/topics"
+	azureTopicURL            = "services/servicebus/namespaces/%s/topics/%s"
+	azureSubscriptionListURL = "services/servicebus/namespaces/%s/topics/%s/subscriptions"
+	azureSubscriptionURL     = "services/servicebus/namespaces/%s/topics/%s/subscriptions/%s"
+
+	atomContentType  = "application/atom+xml"
+	entryContentType = "application/xml"
+
+	paramNotSpecifiedError = "Parameter %s is not specified."
+)
+
+// CreateQueue creates a queue in the Service Bus namespace.
+func CreateQueue(namespace, name string, options QueueOptions) error {
+	if len(namespace) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "namespace")
+	}
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	queue := &Queue{
+		LockDuration:                        formatDuration(options.LockDuration),
+		MaxSizeInMegabytes:                  options.MaxSizeInMegabytes,
+		RequiresDuplicateDetection:          options.RequiresDuplicateDetection,
+		RequiresSession:                     options.RequiresSession,
+		DefaultMessageTimeToLive:            formatDuration(options.DefaultMessageTimeToLive),
+		DeadLetteringOnMessageExpiration:    options.DeadLetteringOnMessageExpiration,
+		DuplicateDetectionHistoryTimeWindow: formatDuration(options.DuplicateDetectionHistoryTimeWindow),
+		MaxDeliveryCount:                    options.MaxDeliveryCount,
+	}
+
+	return putEntry(fmt.Sprintf(azureQueueURL, namespace, name), entryContent{Queue: queue})
+}
+
+func ListQueues(namespace string) ([]Queue, error) {
+	if len(namespace) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "namespace")
+	}
+
+	entries, err := getFeed(fmt.Sprintf(azureQueueListURL, namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	queues := []Queue{}
+	for _, entry := range entries {
+		if entry.Content.Queue != nil {
+			entry.Content.Queue.Name = entry.Title
+			queues = append(queues, *entry.Content.Queue)
+		}
+	}
+
+	return queues, nil
+}
+
+func DeleteQueue(namespace, name string) error {
+	if len(namespace) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "namespace")
+	}
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	_, err := azure.SendAzureDeleteRequest(fmt.Sprintf(azureQueueURL, namespace, name))
+	return err
+}
+
+// CreateTopic creates a topic in the Service Bus namespace.
+func CreateTopic(namespace, name string, options TopicOptions) error {
+	if len(namespace) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "namespace")
+	}
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	topic := &Topic{
+		DefaultMessageTimeToLive:            formatDuration(options.DefaultMessageTimeToLive),
+		MaxSizeInMegabytes:                  options.MaxSizeInMegabytes,
+		RequiresDuplicateDetection:          options.RequiresDuplicateDetection,
+		DuplicateDetectionHistoryTimeWindow: formatDuration(options.DuplicateDetectionHistoryTimeWindow),
+	}
+
+	return putEntry(fmt.Sprintf(azureTopicURL, namespace, name), entryContent{Topic: topic})
+}
+
+func ListTopics(namespace string) ([]Topic, error) {
+	if len(namespace) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "namespace")
+	}
+
+	entries, err := getFeed(fmt.Sprintf(azureTopicListURL, namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	topics := []Topic{}
+	for _, entry := range entries {
+		if entry.Content.Topic != nil {
+			entry.Content.Topic.Name = entry.Title
+			topics = append(topics, *entry.Content.Topic)
+		}
+	}
+
+	return topics, nil
+}
+
+// DeleteTopic deletes the topic along with all of its subscriptions.
+func DeleteTopic(namespace, name string) error {
+	if len(namespace) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "namespace")
+	}
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	_, err := azure.SendAzureDeleteRequest(fmt.Sprintf(azureTopicURL, namespace, name))
+	return err
+}
+
+// CreateSubscription creates a subscription to the topic.
+func CreateSubscription(namespace, topicName, name string, options SubscriptionOptions) error {
+	if len(namespace) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "namespace")
+	}
+	if len(topicName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "topicName")
+	}
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	subscription := &Subscription{
+		LockDuration:                     formatDuration(options.LockDuration),
+		RequiresSession:                  options.RequiresSession,
+		DefaultMessageTimeToLive:         formatDuration(options.DefaultMessageTimeToLive),
+		DeadLetteringOnMessageExpiration: options.DeadLetteringOnMessageExpiration,
+		MaxDeliveryCount:                 options.MaxDeliveryCount,
+	}
+
+	return putEntry(fmt.Sprintf(azureSubscriptionURL, namespace, topicName, name), entryContent{Subscription: subscription})
+}
+
+func ListSubscriptions(namespace, topicName string) ([]Subscription, error) {
+	if len(namespace) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "namespace")
+	}
+	if len(topicName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "topicName")
+	}
+
+	entries, err := getFeed(fmt.Sprintf(azureSubscriptionListURL, namespace, topicName))
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := []Subscription{}
+	for _, entry := range entries {
+		if entry.Content.Subscription != nil {
+			entry.Content.Subscription.Name = entry.Title
+			subscriptions = append(subscriptions, *entry.Content.Subscription)
+		}
+	}
+
+	return subscriptions, nil
+}
+
+func DeleteSubscription(namespace, topicName, name string) error {
+	if len(namespace) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "namespace")
+	}
+	if len(topicName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "topicName")
+	}
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	_, err := azure.SendAzureDeleteRequest(fmt.Sprintf(azureSubscriptionURL, namespace, topicName, name))
+	return err
+}
+
+//Region private methods starts
+
+func putEntry(url string, content entryContent) error {
+	content.Type = entryContentType
+	entryBytes, err := xml.Marshal(entry{Content: content})
+	if err != nil {
+		return err
+	}
+
+	_, err = azure.SendAzurePutRequest(url, atomContentType, entryBytes)
+	return err
+}
+
+func getFeed(url string) ([]entry, error) {
+	response, err := azure.SendAzureGetRequest(url)
+	if err != nil {
+		return nil, err
+	}
+
+	entryFeed := new(feed)
+	err = xml.Unmarshal(response, entryFeed)
+	if err != nil {
+		return nil, err
+	}
+
+	return entryFeed.Entries, nil
+}
+
+// formatDuration formats d as an ISO 8601 duration with a precision of one
+// second. Zero durations are left out of requests.
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("PT%dS", int64(d/time.Second))
+}
+
+//Region private methods ends
@@ -0,0 +1,83 @@
+package sqlDatabaseClient
+
+import (
+	"encoding/xml"
+)
+
+type Edition string
+
+const (
+	EditionBasic    Edition = "Basic"
+	EditionStandard Edition = "Standard"
+	EditionPremium  Edition = "Premium"
+)
+
+type DatabaseList struct {
+	XMLName   xml.Name   `xml:"ServiceResources"`
+	Xmlns     string     `xml:"xmlns,attr"`
+	Databases []Database `xml:"ServiceResource"`
+}
+
+// Database is a SQL database on a server. ServiceObjectiveId is the requested
+// performance level, AssignedServiceObjectiveId the one currently in effect;
+// they differ while a change of the performance level is in progress.
+type Database struct {
+	Name                                       string
+	Type                                       string
+	State                                      string
+	Id                                         int
+	Edition                                    Edition
+	MaxSizeGB                                  int
+	MaxSizeBytes                               int64
+	CollationName                              string
+	CreationDate                               string
+	IsFederationRoot                           bool
+	IsSystemObject                             bool
+	SizeMB                                     string
+	ServiceObjectiveId                         string
+	AssignedServiceObjectiveId                 string
+	ServiceObjectiveAssignmentState            string
+	ServiceObjectiveAssignmentStateDescription string
+	ServiceObjectiveAssignmentErrorCode        string
+	ServiceObjectiveAssignmentErrorDescription string
+	ServiceObjectiveAssignmentSuccessDate      string
+	RecoveryPeriodStartDate                    string
+}
+
+// DatabaseOptions are the settings of a new or updated database. Empty or zero
+// fields keep the defaults of the edition when creating and the current value
+// when updating. Use GetServiceObjectiveId to look up the ID of a performance
+// level like "S1".
+type DatabaseOptions struct {
+	Edition            Edition
+	MaxSizeGB          int
+	CollationName      string
+	ServiceObjectiveId string
+}
+
+type DatabaseInput struct {
+	XMLName            xml.Name `xml:"ServiceResource"`
+	Xmlns              string   `xml:"xmlns,attr"`
+	Name               string
+	Edition            Edition `xml:",omitempty"`
+	MaxSizeGB          int     `xml:",omitempty"`
+	CollationName      string  `xml:",omitempty"`
+	ServiceObjectiveId string  `xml:",omitempty"`
+}
+
+type ServiceObjectiveList struct {
+	XMLName           xml.Name           `xml:"ServiceResources"`
+	Xmlns             string             `xml:"xmlns,attr"`
+	ServiceObjectives []ServiceObjective `xml:"ServiceResource"`
+}
+
+// ServiceObjective is a performance level, like "Basic", "S0" or "P1", a
+// database can be assigned.
+type ServiceObjective struct {
+	Name        string
+	Id          string
+	IsDefault   bool
+	IsSystem    bool
+	Enabled     bool
+	Description string
+}
@@ -0,0 +1,228 @@
+package sqlDatabaseClient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	azure "github.com/MSOpenTech/azure-sdk-for-go"
+)
+
+const (
+	azureXmlns                   = "http://schemas.microsoft.com/windowsazure"
+	azureDatabaseListURL         = "services/sqlservers/servers/%s/databases"
+	azureDatabaseURL             = "services/sqlservers/servers/%s/databases/%s"
+	azureServiceObjectiveListURL = "services/sqlservers/servers/%s/serviceobjectives"
+
+	serviceObjectiveAssignmentPending = "Pending"
+	serviceObjectiveAssignmentFailed  = "Failed"
+	databaseOperationPollInterval     = 10 * time.Second
+
+	invalidEditionError                    = "Invalid edition: %s. Valid values are 'Basic', 'Standard' and 'Premium'."
+	serviceObjectiveNotFoundError          = "Service objective %s was not found on server %s."
+	serviceObjectiveAssignmentError        = "Assigning the service objective of database %s failed: %s"
+	serviceObjectiveAssignmentTimeoutError = "Assigning the service objective of database %s did not complete within %s."
+	paramNotSpecifiedError                 = "Parameter %s is not specified."
+)
+
+// DatabaseOperationTimeout is how long CreateDatabase and UpdateDatabase wait
+// for the performance level of a database to be assigned. Moving between
+// editions can take hours for large databases.
+var DatabaseOperationTimeout = 60 * time.Minute
+
+func ListDatabases(serverName string) ([]Database, error) {
+	if len(serverName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "serverName")
+	}
+
+	requestURL := fmt.Sprintf(azureDatabaseListURL, serverName) + "?contentview=generic"
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	databaseList := new(DatabaseList)
+	err = xml.Unmarshal(response, databaseList)
+	if err != nil {
+		return nil, err
+	}
+
+	return databaseList.Databases, nil
+}
+
+func GetDatabase(serverName, databaseName string) (*Database, error) {
+	if len(serverName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "serverName")
+	}
+	if len(databaseName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "databaseName")
+	}
+
+	requestURL := fmt.Sprintf(azureDatabaseURL, serverName, databaseName)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	database := new(Database)
+	err = xml.Unmarshal(response, database)
+	if err != nil {
+		return nil, err
+	}
+
+	return database, nil
+}
+
+// CreateDatabase creates a database on the server and waits until its
+// performance level is assigned.
+func CreateDatabase(serverName, databaseName string, options DatabaseOptions) error {
+	if len(serverName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "serverName")
+	}
+	if len(databaseName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "databaseName")
+	}
+
+	databaseBytes, err := createDatabaseInput(databaseName, options)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureDatabaseListURL, serverName)
+	_, err = azure.SendAzurePostRequest(requestURL, databaseBytes)
+	if err != nil {
+		return err
+	}
+
+	return waitForServiceObjectiveAssignment(serverName, databaseName)
+}
+
+// UpdateDatabase changes the edition, maximum size or performance level of
+// the database and waits until the new performance level is assigned. The
+// database stays online during the change.
+func UpdateDatabase(serverName, databaseName string, options DatabaseOptions) error {
+	if len(serverName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "serverName")
+	}
+	if len(databaseName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "databaseName")
+	}
+
+	databaseBytes, err := createDatabaseInput(databaseName, options)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureDatabaseURL, serverName, databaseName)
+	_, err = azure.SendAzurePutRequest(requestURL, "", databaseBytes)
+	if err != nil {
+		return err
+	}
+
+	return waitForServiceObjectiveAssignment(serverName, databaseName)
+}
+
+func DeleteDatabase(serverName, databaseName string) error {
+	if len(serverName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "serverName")
+	}
+	if len(databaseName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "databaseName")
+	}
+
+	requestURL := fmt.Sprintf(azureDatabaseURL, serverName, databaseName)
+	_, err := azure.SendAzureDeleteRequest(requestURL)
+	return err
+}
+
+func ListServiceObjectives(serverName string) ([]ServiceObjective, error) {
+	if len(serverName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "serverName")
+	}
+
+	requestURL := fmt.Sprintf(azureServiceObjectiveListURL, serverName)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceObjectiveList := new(ServiceObjectiveList)
+	err = xml.Unmarshal(response, serviceObjectiveList)
+	if err != nil {
+		return nil, err
+	}
+
+	return serviceObjectiveList.ServiceObjectives, nil
+}
+
+// GetServiceObjectiveId returns the ID of the performance level with the given
+// name, e.g. "S1", for use in DatabaseOptions.
+func GetServiceObjectiveId(serverName, serviceObjectiveName string) (string, error) {
+	if len(serviceObjectiveName) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "serviceObjectiveName")
+	}
+
+	serviceObjectives, err := ListServiceObjectives(serverName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, serviceObjective := range serviceObjectives {
+		if strings.EqualFold(serviceObjective.Name, serviceObjectiveName) {
+			return serviceObjective.Id, nil
+		}
+	}
+
+	return "", fmt.Errorf(serviceObjectiveNotFoundError, serviceObjectiveName, serverName)
+}
+
+//Region private methods starts
+
+func createDatabaseInput(databaseName string, options DatabaseOptions) ([]byte, error) {
+	switch options.Edition {
+	case "", EditionBasic, EditionStandard, EditionPremium:
+	default:
+		return nil, fmt.Errorf(invalidEditionError, options.Edition)
+	}
+
+	input := DatabaseInput{
+		Xmlns:              azureXmlns,
+		Name:               databaseName,
+		Edition:            options.Edition,
+		MaxSizeGB:          options.MaxSizeGB,
+		CollationName:      options.CollationName,
+		ServiceObjectiveId: options.ServiceObjectiveId,
+	}
+
+	return xml.Marshal(input)
+}
+
+// waitForServiceObjectiveAssignment polls the database until its requested
+// performance level is the assigned one or the assignment failed.
+func waitForServiceObjectiveAssignment(serverName, databaseName string) error {
+	deadline := time.Now().Add(DatabaseOperationTimeout)
+	for {
+		database, err := GetDatabase(serverName, databaseName)
+		if err != nil {
+			return err
+		}
+
+		// The state may still describe the previous assignment right after a
+		// request, the assignment is only done once the IDs match
+		state := database.ServiceObjectiveAssignmentStateDescription
+		if state == serviceObjectiveAssignmentFailed {
+			return fmt.Errorf(serviceObjectiveAssignmentError, databaseName, database.ServiceObjectiveAssignmentErrorDescription)
+		}
+		if state != serviceObjectiveAssignmentPending && strings.EqualFold(database.ServiceObjectiveId, database.AssignedServiceObjectiveId) {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf(serviceObjectiveAssignmentTimeoutError, databaseName, DatabaseOperationTimeout)
+		}
+		time.Sleep(databaseOperationPollInterval)
+	}
+}
+
+//Region private methods ends
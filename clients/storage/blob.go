@@ -2,6 +2,7 @@ package storage
 
 import (
 	"bytes"
+	"crypto/md5"
 	"encoding/base64"
 	"encoding/xml"
 	"errors"
@@ -64,6 +65,8 @@ type BlobProperties struct {
 	ContentLength         int64    `xml:"Content-Length"`
 	ContentType           string   `xml:"Content-Type"`
 	ContentEncoding       string   `xml:"Content-Encoding"`
+	ContentLanguage       string   `xml:"Content-Language"`
+	CacheControl          string   `xml:"Cache-Control"`
 	BlobType              BlobType `xml:"x-ms-blob-blob-type"`
 	SequenceNumber        int64    `xml:"x-ms-blob-sequence-number"`
 	CopyId                string   `xml:"CopyId"`
@@ -72,6 +75,9 @@ type BlobProperties struct {
 	CopyProgress          string   `xml:"CopyProgress"`
 	CopyCompletionTime    string   `xml:"CopyCompletionTime"`
 	CopyStatusDescription string   `xml:"CopyStatusDescription"`
+	LeaseStatus           string   `xml:"LeaseStatus"`
+	LeaseState            string   `xml:"LeaseState"`
+	LeaseDuration         string   `xml:"LeaseDuration"`
 }
 
 // BlobListResponse contains the response fields from
@@ -118,6 +124,16 @@ func (p ListContainersParameters) getParameters() url.Values {
 	return out
 }
 
+// BlobHeaders contains the system properties of a blob that can be set with
+// SetBlobProperties. See https://msdn.microsoft.com/en-us/library/azure/ee691966.aspx
+type BlobHeaders struct {
+	ContentType     string
+	ContentMD5      string
+	ContentEncoding string
+	ContentLanguage string
+	CacheControl    string
+}
+
 // ListBlobsParameters defines the set of customizable
 // parameters to make a List Blobs call. https://msdn.microsoft.com/en-us/library/azure/dd135734.aspx
 type ListBlobsParameters struct {
@@ -201,6 +217,56 @@ const (
 	ContainerAccessTypeContainer ContainerAccessType = "container"
 )
 
+// ContainerAccessPolicy is a stored access policy of a container that shared
+// access signatures can refer to by Id. See
+// https://msdn.microsoft.com/en-us/library/azure/ee393341.aspx
+type ContainerAccessPolicy struct {
+	Id         string
+	Start      time.Time
+	Expiry     time.Time
+	Permission string
+}
+
+// ContainerPermissions contains the public access level and the stored
+// access policies of a container. See https://msdn.microsoft.com/en-us/library/azure/dd179391.aspx
+type ContainerPermissions struct {
+	AccessType     ContainerAccessType
+	AccessPolicies []ContainerAccessPolicy
+}
+
+// signedIdentifiers is the wire format of the container ACL used by
+// Get Container ACL and Set Container ACL calls.
+type signedIdentifiers struct {
+	XMLName           xml.Name           `xml:"SignedIdentifiers"`
+	SignedIdentifiers []signedIdentifier `xml:"SignedIdentifier"`
+}
+
+type signedIdentifier struct {
+	Id           string       `xml:"Id"`
+	AccessPolicy accessPolicy `xml:"AccessPolicy"`
+}
+
+type accessPolicy struct {
+	Start      string `xml:"Start"`
+	Expiry     string `xml:"Expiry"`
+	Permission string `xml:"Permission"`
+}
+
+// LeaseDurationInfinite acquires a lease that does not expire until it is
+// released or broken. Finite leases last between 15 and 60 seconds.
+const LeaseDurationInfinite = -1
+
+const (
+	leaseActionAcquire = "acquire"
+	leaseActionRenew   = "renew"
+	leaseActionRelease = "release"
+	leaseActionBreak   = "break"
+)
+
+// BlobCopyPollInterval is the interval at which WaitForBlobCopy checks the
+// status of a pending copy.
+const BlobCopyPollInterval = 5 * time.Second
+
 const (
 	MaxBlobBlockSize = 4 * 1024 * 1024
 	MaxBlobPageSize  = 4 * 1024 * 1024
@@ -255,6 +321,7 @@ type PageRange struct {
 var (
 	ErrNotCreated  = errors.New("storage: operation has returned a successful error code other than 201 Created.")
 	ErrNotAccepted = errors.New("storage: operation has returned a successful error code other than 202 Accepted.")
+	ErrMD5Mismatch = errors.New("storage: MD5 hash of the downloaded content does not match its Content-MD5.")
 
 	errBlobCopyAborted    = errors.New("storage: blob copy is aborted")
 	errBlobCopyIdMismatch = errors.New("storage: blob copy id is a mismatch")
@@ -262,6 +329,8 @@ var (
 
 const errUnexpectedStatus = "storage: was expecting status code: %d, got: %d"
 
+const metadataHeaderPrefix = "x-ms-meta-"
+
 // ListContainers returns the list of containers in a storage account along with
 // pagination token and other response details. See https://msdn.microsoft.com/en-us/library/azure/dd179352.aspx
 func (b BlobStorageClient) ListContainers(params ListContainersParameters) (ContainerListResponse, error) {
@@ -363,6 +432,136 @@ func (b BlobStorageClient) deleteContainer(name string) (*storageResponse, error
 	return b.client.exec(verb, uri, headers, nil)
 }
 
+// SetContainerMetadata replaces the user-defined metadata of the container
+// with the given name-value pairs. See https://msdn.microsoft.com/en-us/library/azure/dd179362.aspx
+func (b BlobStorageClient) SetContainerMetadata(name string, metadata map[string]string) error {
+	params := url.Values{"restype": {"container"}, "comp": {"metadata"}}
+	uri := b.client.getEndpoint(blobServiceName, pathForContainer(name), params)
+
+	headers := b.client.getStandardHeaders()
+	headers["Content-Length"] = "0"
+	for k, v := range metadata {
+		headers[metadataHeaderPrefix+strings.ToLower(k)] = v
+	}
+
+	resp, err := b.client.exec("PUT", uri, headers, nil)
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusOK {
+		return fmt.Errorf(errUnexpectedStatus, http.StatusOK, resp.statusCode)
+	}
+	return nil
+}
+
+// GetContainerMetadata returns the user-defined metadata of the container.
+// Metadata names are returned in lower case. See https://msdn.microsoft.com/en-us/library/azure/ee691976.aspx
+func (b BlobStorageClient) GetContainerMetadata(name string) (map[string]string, error) {
+	params := url.Values{"restype": {"container"}, "comp": {"metadata"}}
+	uri := b.client.getEndpoint(blobServiceName, pathForContainer(name), params)
+	headers := b.client.getStandardHeaders()
+
+	resp, err := b.client.exec("GET", uri, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.body.Close()
+
+	if resp.statusCode != http.StatusOK {
+		return nil, fmt.Errorf(errUnexpectedStatus, http.StatusOK, resp.statusCode)
+	}
+	return metadataFromHeaders(resp.headers), nil
+}
+
+// GetContainerProperties returns the system properties of the container.
+// See https://msdn.microsoft.com/en-us/library/azure/dd179370.aspx
+func (b BlobStorageClient) GetContainerProperties(name string) (*ContainerProperties, error) {
+	uri := b.client.getEndpoint(blobServiceName, pathForContainer(name), url.Values{"restype": {"container"}})
+	headers := b.client.getStandardHeaders()
+
+	resp, err := b.client.exec("HEAD", uri, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.statusCode != http.StatusOK {
+		return nil, fmt.Errorf(errUnexpectedStatus, http.StatusOK, resp.statusCode)
+	}
+
+	return &ContainerProperties{
+		LastModified:  resp.headers.Get("Last-Modified"),
+		Etag:          resp.headers.Get("Etag"),
+		LeaseStatus:   resp.headers.Get("x-ms-lease-status"),
+		LeaseState:    resp.headers.Get("x-ms-lease-state"),
+		LeaseDuration: resp.headers.Get("x-ms-lease-duration"),
+	}, nil
+}
+
+// SetContainerPermissions sets the public access level of the container and
+// replaces its stored access policies. See https://msdn.microsoft.com/en-us/library/azure/dd179391.aspx
+func (b BlobStorageClient) SetContainerPermissions(name string, permissions ContainerPermissions) error {
+	body, err := prepareContainerACLRequest(permissions.AccessPolicies)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{"restype": {"container"}, "comp": {"acl"}}
+	uri := b.client.getEndpoint(blobServiceName, pathForContainer(name), params)
+
+	headers := b.client.getStandardHeaders()
+	headers["Content-Length"] = fmt.Sprintf("%v", len(body))
+	if permissions.AccessType != "" {
+		headers["x-ms-blob-public-access"] = string(permissions.AccessType)
+	}
+
+	resp, err := b.client.exec("PUT", uri, headers, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusOK {
+		return fmt.Errorf(errUnexpectedStatus, http.StatusOK, resp.statusCode)
+	}
+	return nil
+}
+
+// GetContainerPermissions returns the public access level and the stored
+// access policies of the container. See https://msdn.microsoft.com/en-us/library/azure/dd179469.aspx
+func (b BlobStorageClient) GetContainerPermissions(name string) (ContainerPermissions, error) {
+	params := url.Values{"restype": {"container"}, "comp": {"acl"}}
+	uri := b.client.getEndpoint(blobServiceName, pathForContainer(name), params)
+	headers := b.client.getStandardHeaders()
+
+	var out ContainerPermissions
+	resp, err := b.client.exec("GET", uri, headers, nil)
+	if err != nil {
+		return out, err
+	}
+
+	var acl signedIdentifiers
+	err = xmlUnmarshal(resp.body, &acl)
+	if err != nil {
+		return out, err
+	}
+
+	out.AccessType = ContainerAccessType(resp.headers.Get("x-ms-blob-public-access"))
+	for _, v := range acl.SignedIdentifiers {
+		policy := ContainerAccessPolicy{Id: v.Id, Permission: v.AccessPolicy.Permission}
+		if v.AccessPolicy.Start != "" {
+			policy.Start, err = time.Parse(time.RFC3339, v.AccessPolicy.Start)
+			if err != nil {
+				return out, err
+			}
+		}
+		if v.AccessPolicy.Expiry != "" {
+			policy.Expiry, err = time.Parse(time.RFC3339, v.AccessPolicy.Expiry)
+			if err != nil {
+				return out, err
+			}
+		}
+		out.AccessPolicies = append(out.AccessPolicies, policy)
+	}
+	return out, nil
+}
+
 // ListBlobs returns an object that contains list of blobs in the container,
 // pagination token and other information in the response of List Blobs call.
 // See https://msdn.microsoft.com/en-us/library/azure/dd135734.aspx
@@ -418,6 +617,9 @@ func (b BlobStorageClient) GetBlob(container, name string) (io.ReadCloser, error
 	if resp.statusCode != http.StatusOK {
 		return nil, fmt.Errorf(errUnexpectedStatus, http.StatusOK, resp.statusCode)
 	}
+	if expectedMD5 := resp.headers.Get("Content-MD5"); expectedMD5 != "" {
+		return newMD5VerifyingReader(resp.body, expectedMD5)
+	}
 	return resp.body, nil
 }
 
@@ -490,7 +692,10 @@ func (b BlobStorageClient) GetBlobProperties(container, name string) (*BlobPrope
 		Etag:                  resp.headers.Get("Etag"),
 		ContentMD5:            resp.headers.Get("Content-MD5"),
 		ContentLength:         contentLength,
+		ContentType:           resp.headers.Get("Content-Type"),
 		ContentEncoding:       resp.headers.Get("Content-Encoding"),
+		ContentLanguage:       resp.headers.Get("Content-Language"),
+		CacheControl:          resp.headers.Get("Cache-Control"),
 		SequenceNumber:        sequenceNum,
 		CopyCompletionTime:    resp.headers.Get("x-ms-copy-completion-time"),
 		CopyStatusDescription: resp.headers.Get("x-ms-copy-status-description"),
@@ -499,9 +704,95 @@ func (b BlobStorageClient) GetBlobProperties(container, name string) (*BlobPrope
 		CopySource:            resp.headers.Get("x-ms-copy-source"),
 		CopyStatus:            resp.headers.Get("x-ms-copy-status"),
 		BlobType:              BlobType(resp.headers.Get("x-ms-blob-type")),
+		LeaseStatus:           resp.headers.Get("x-ms-lease-status"),
+		LeaseState:            resp.headers.Get("x-ms-lease-state"),
+		LeaseDuration:         resp.headers.Get("x-ms-lease-duration"),
 	}, nil
 }
 
+// SetBlobProperties sets the system properties of the blob. Properties which
+// are left empty are cleared on the blob.
+// See https://msdn.microsoft.com/en-us/library/azure/ee691966.aspx
+func (b BlobStorageClient) SetBlobProperties(container, name string, properties BlobHeaders) error {
+	return b.SetBlobPropertiesWithLease(container, name, properties, "")
+}
+
+// SetBlobPropertiesWithLease sets the system properties of a blob with an
+// active lease, leaseId has to be the id of the lease. See SetBlobProperties.
+func (b BlobStorageClient) SetBlobPropertiesWithLease(container, name string, properties BlobHeaders, leaseId string) error {
+	uri := b.client.getEndpoint(blobServiceName, pathForBlob(container, name), url.Values{"comp": {"properties"}})
+
+	headers := b.client.getStandardHeaders()
+	headers["Content-Length"] = "0"
+	addLeaseIdHeader(headers, leaseId)
+	for k, v := range map[string]string{
+		"x-ms-blob-content-type":     properties.ContentType,
+		"x-ms-blob-content-md5":      properties.ContentMD5,
+		"x-ms-blob-content-encoding": properties.ContentEncoding,
+		"x-ms-blob-content-language": properties.ContentLanguage,
+		"x-ms-blob-cache-control":    properties.CacheControl,
+	} {
+		if v != "" {
+			headers[k] = v
+		}
+	}
+
+	resp, err := b.client.exec("PUT", uri, headers, nil)
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusOK {
+		return fmt.Errorf(errUnexpectedStatus, http.StatusOK, resp.statusCode)
+	}
+	return nil
+}
+
+// SetBlobMetadata replaces the user-defined metadata of the blob with the
+// given name-value pairs. See https://msdn.microsoft.com/en-us/library/azure/dd179414.aspx
+func (b BlobStorageClient) SetBlobMetadata(container, name string, metadata map[string]string) error {
+	return b.SetBlobMetadataWithLease(container, name, metadata, "")
+}
+
+// SetBlobMetadataWithLease replaces the user-defined metadata of a blob with
+// an active lease, leaseId has to be the id of the lease. See SetBlobMetadata.
+func (b BlobStorageClient) SetBlobMetadataWithLease(container, name string, metadata map[string]string, leaseId string) error {
+	uri := b.client.getEndpoint(blobServiceName, pathForBlob(container, name), url.Values{"comp": {"metadata"}})
+
+	headers := b.client.getStandardHeaders()
+	headers["Content-Length"] = "0"
+	addLeaseIdHeader(headers, leaseId)
+	for k, v := range metadata {
+		headers[metadataHeaderPrefix+strings.ToLower(k)] = v
+	}
+
+	resp, err := b.client.exec("PUT", uri, headers, nil)
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusOK {
+		return fmt.Errorf(errUnexpectedStatus, http.StatusOK, resp.statusCode)
+	}
+	return nil
+}
+
+// GetBlobMetadata returns the user-defined metadata of the blob. Metadata
+// names are returned in lower case. See https://msdn.microsoft.com/en-us/library/azure/dd179350.aspx
+func (b BlobStorageClient) GetBlobMetadata(container, name string) (map[string]string, error) {
+	uri := b.client.getEndpoint(blobServiceName, pathForBlob(container, name), url.Values{"comp": {"metadata"}})
+	headers := b.client.getStandardHeaders()
+
+	resp, err := b.client.exec("GET", uri, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.body.Close()
+
+	if resp.statusCode != http.StatusOK {
+		return nil, fmt.Errorf(errUnexpectedStatus, http.StatusOK, resp.statusCode)
+	}
+	return metadataFromHeaders(resp.headers), nil
+}
+
 // CreateBlockBlob initializes an empty block blob with no blocks.
 // See https://msdn.microsoft.com/en-us/library/azure/dd179451.aspx
 func (b BlobStorageClient) CreateBlockBlob(container, name string) error {
@@ -526,14 +817,25 @@ func (b BlobStorageClient) CreateBlockBlob(container, name string) error {
 // list at the end. This is a helper method built on top of PutBlock
 // and PutBlockList methods with sequential block ID counting logic.
 func (b BlobStorageClient) PutBlockBlob(container, name string, blob io.Reader) error { // TODO (ahmetalpbalkan) consider ReadCloser and closing
-	return b.putBlockBlob(container, name, blob, MaxBlobBlockSize)
+	return b.putBlockBlob(container, name, blob, MaxBlobBlockSize, "")
+}
+
+// PutBlockBlobWithLease uploads the stream into a block blob with an active
+// lease, leaseId has to be the id of the lease. See PutBlockBlob.
+func (b BlobStorageClient) PutBlockBlobWithLease(container, name string, blob io.Reader, leaseId string) error {
+	return b.putBlockBlob(container, name, blob, MaxBlobBlockSize, leaseId)
 }
 
-func (b BlobStorageClient) putBlockBlob(container, name string, blob io.Reader, chunkSize int) error {
+func (b BlobStorageClient) putBlockBlob(container, name string, blob io.Reader, chunkSize int, leaseId string) error {
 	if chunkSize <= 0 || chunkSize > MaxBlobBlockSize {
 		chunkSize = MaxBlobBlockSize
 	}
 
+	// Hash the whole blob while reading so that its MD5 is stored with the
+	// block list and verified on download.
+	blobHash := md5.New()
+	blob = io.TeeReader(blob, blobHash)
+
 	chunk := make([]byte, chunkSize)
 	n, err := blob.Read(chunk)
 	if err != nil && err != io.EOF {
@@ -542,7 +844,7 @@ func (b BlobStorageClient) putBlockBlob(container, name string, blob io.Reader,
 
 	if err == io.EOF {
 		// Fits into one block
-		return b.putSingleBlockBlob(container, name, chunk[:n])
+		return b.putSingleBlockBlob(container, name, chunk[:n], leaseId)
 	} else {
 		// Does not fit into one block. Upload block by block then commit the block list
 		blockList := []Block{}
@@ -551,7 +853,7 @@ func (b BlobStorageClient) putBlockBlob(container, name string, blob io.Reader,
 		for blockNum := 0; ; blockNum++ {
 			id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%011d", blockNum)))
 			data := chunk[:n]
-			err = b.PutBlock(container, name, id, data)
+			err = b.putBlock(container, name, id, uint64(len(data)), bytes.NewReader(data), contentMD5(data), leaseId)
 			if err != nil {
 				return err
 			}
@@ -568,11 +870,12 @@ func (b BlobStorageClient) putBlockBlob(container, name string, blob io.Reader,
 		}
 
 		// Commit block list
-		return b.PutBlockList(container, name, blockList)
+		blobMD5 := base64.StdEncoding.EncodeToString(blobHash.Sum(nil))
+		return b.putBlockList(container, name, blockList, blobMD5, leaseId)
 	}
 }
 
-func (b BlobStorageClient) putSingleBlockBlob(container, name string, chunk []byte) error {
+func (b BlobStorageClient) putSingleBlockBlob(container, name string, chunk []byte, leaseId string) error {
 	if len(chunk) > MaxBlobBlockSize {
 		return fmt.Errorf("storage: provided chunk (%d bytes) cannot fit into single-block blob (max %d bytes)", len(chunk), MaxBlobBlockSize)
 	}
@@ -581,6 +884,8 @@ func (b BlobStorageClient) putSingleBlockBlob(container, name string, chunk []by
 	headers := b.client.getStandardHeaders()
 	headers["x-ms-blob-type"] = string(BlobTypeBlock)
 	headers["Content-Length"] = fmt.Sprintf("%v", len(chunk))
+	headers["Content-MD5"] = contentMD5(chunk)
+	addLeaseIdHeader(headers, leaseId)
 
 	resp, err := b.client.exec("PUT", uri, headers, bytes.NewReader(chunk))
 	if err != nil {
@@ -595,8 +900,10 @@ func (b BlobStorageClient) putSingleBlockBlob(container, name string, chunk []by
 
 // PutBlock saves the given data chunk to the specified block blob with
 // given ID. See https://msdn.microsoft.com/en-us/library/azure/dd135726.aspx
+// The MD5 hash of the chunk is sent along so the service rejects corrupted
+// blocks.
 func (b BlobStorageClient) PutBlock(container, name, blockId string, chunk []byte) error {
-	return b.PutBlockWithLength(container, name, blockId, uint64(len(chunk)), bytes.NewReader(chunk))
+	return b.putBlock(container, name, blockId, uint64(len(chunk)), bytes.NewReader(chunk), contentMD5(chunk), "")
 }
 
 // PutBlockWithLength saves the given data stream of exactly specified size to the block blob
@@ -604,10 +911,18 @@ func (b BlobStorageClient) PutBlock(container, name, blockId string, chunk []byt
 // It is an alternative to PutBlocks where data comes as stream but the length is
 // known in advance.
 func (b BlobStorageClient) PutBlockWithLength(container, name, blockId string, size uint64, blob io.Reader) error {
+	return b.putBlock(container, name, blockId, size, blob, "", "")
+}
+
+func (b BlobStorageClient) putBlock(container, name, blockId string, size uint64, blob io.Reader, blockMD5, leaseId string) error {
 	uri := b.client.getEndpoint(blobServiceName, pathForBlob(container, name), url.Values{"comp": {"block"}, "blockid": {blockId}})
 	headers := b.client.getStandardHeaders()
 	headers["x-ms-blob-type"] = string(BlobTypeBlock)
 	headers["Content-Length"] = fmt.Sprintf("%v", size)
+	if blockMD5 != "" {
+		headers["Content-MD5"] = blockMD5
+	}
+	addLeaseIdHeader(headers, leaseId)
 
 	resp, err := b.client.exec("PUT", uri, headers, blob)
 	if err != nil {
@@ -623,11 +938,21 @@ func (b BlobStorageClient) PutBlockWithLength(container, name, blockId string, s
 // PutBlockList saves list of blocks to the specified block blob. See
 // https://msdn.microsoft.com/en-us/library/azure/dd179467.aspx
 func (b BlobStorageClient) PutBlockList(container, name string, blocks []Block) error {
+	return b.putBlockList(container, name, blocks, "", "")
+}
+
+// putBlockList commits the block list and, if blobMD5 is set, stores it as
+// the MD5 hash of the whole blob.
+func (b BlobStorageClient) putBlockList(container, name string, blocks []Block, blobMD5, leaseId string) error {
 	blockListXml := prepareBlockListRequest(blocks)
 
 	uri := b.client.getEndpoint(blobServiceName, pathForBlob(container, name), url.Values{"comp": {"blocklist"}})
 	headers := b.client.getStandardHeaders()
 	headers["Content-Length"] = fmt.Sprintf("%v", len(blockListXml))
+	if blobMD5 != "" {
+		headers["x-ms-blob-content-md5"] = blobMD5
+	}
+	addLeaseIdHeader(headers, leaseId)
 
 	resp, err := b.client.exec("PUT", uri, headers, strings.NewReader(blockListXml))
 	if err != nil {
@@ -661,12 +986,19 @@ func (b BlobStorageClient) GetBlockList(container, name string, blockType BlockL
 // be created using this method before writing pages.
 // See https://msdn.microsoft.com/en-us/library/azure/dd179451.aspx
 func (b BlobStorageClient) PutPageBlob(container, name string, size int64) error {
+	return b.PutPageBlobWithLease(container, name, size, "")
+}
+
+// PutPageBlobWithLease replaces a blob with an active lease with an empty
+// page blob, leaseId has to be the id of the lease. See PutPageBlob.
+func (b BlobStorageClient) PutPageBlobWithLease(container, name string, size int64, leaseId string) error {
 	path := fmt.Sprintf("%s/%s", container, name)
 	uri := b.client.getEndpoint(blobServiceName, path, url.Values{})
 	headers := b.client.getStandardHeaders()
 	headers["x-ms-blob-type"] = string(BlobTypePage)
 	headers["x-ms-blob-content-length"] = fmt.Sprintf("%v", size)
 	headers["Content-Length"] = fmt.Sprintf("%v", 0)
+	addLeaseIdHeader(headers, leaseId)
 
 	resp, err := b.client.exec("PUT", uri, headers, nil)
 	if err != nil {
@@ -683,12 +1015,19 @@ func (b BlobStorageClient) PutPageBlob(container, name string, size int64) error
 // with 512-byte boundaries and chunk must be of size multiplies by 512.
 // See https://msdn.microsoft.com/en-us/library/ee691975.aspx
 func (b BlobStorageClient) PutPage(container, name string, startByte, endByte int64, writeType PageWriteType, chunk []byte) error {
+	return b.PutPageWithLease(container, name, startByte, endByte, writeType, chunk, "")
+}
+
+// PutPageWithLease writes or clears a range of pages of a page blob with an
+// active lease, leaseId has to be the id of the lease. See PutPage.
+func (b BlobStorageClient) PutPageWithLease(container, name string, startByte, endByte int64, writeType PageWriteType, chunk []byte, leaseId string) error {
 	path := fmt.Sprintf("%s/%s", container, name)
 	uri := b.client.getEndpoint(blobServiceName, path, url.Values{"comp": {"page"}})
 	headers := b.client.getStandardHeaders()
 	headers["x-ms-blob-type"] = string(BlobTypePage)
 	headers["x-ms-page-write"] = string(writeType)
 	headers["x-ms-range"] = fmt.Sprintf("bytes=%v-%v", startByte, endByte)
+	addLeaseIdHeader(headers, leaseId)
 
 	var contentLength int64
 	var data io.Reader
@@ -698,6 +1037,7 @@ func (b BlobStorageClient) PutPage(container, name string, startByte, endByte in
 	} else {
 		contentLength = int64(len(chunk))
 		data = bytes.NewReader(chunk)
+		headers["Content-MD5"] = contentMD5(chunk)
 	}
 	headers["Content-Length"] = fmt.Sprintf("%v", contentLength)
 
@@ -737,15 +1077,19 @@ func (b BlobStorageClient) GetPageRanges(container, name string) (GetPageRangesR
 // GetBlobURL method.) There is no SLA on blob copy and therefore this helper
 // method works faster on smaller files. See https://msdn.microsoft.com/en-us/library/azure/dd894037.aspx
 func (b BlobStorageClient) CopyBlob(container, name, sourceBlob string) error {
-	copyId, err := b.startBlobCopy(container, name, sourceBlob)
+	copyId, err := b.StartBlobCopy(container, name, sourceBlob)
 	if err != nil {
 		return err
 	}
 
-	return b.waitForBlobCopy(container, name, copyId)
+	return b.WaitForBlobCopy(container, name, copyId, nil)
 }
 
-func (b BlobStorageClient) startBlobCopy(container, name, sourceBlob string) (string, error) {
+// StartBlobCopy starts an asynchronous copy of the blob at sourceBlob URL into
+// the specified blob and returns the copy id. The source can be in another
+// storage account or region as long as the URL is readable, e.g. a URL
+// with a Shared Access Signature. See https://msdn.microsoft.com/en-us/library/azure/dd894037.aspx
+func (b BlobStorageClient) StartBlobCopy(container, name, sourceBlob string) (string, error) {
 	uri := b.client.getEndpoint(blobServiceName, pathForBlob(container, name), url.Values{})
 
 	headers := b.client.getStandardHeaders()
@@ -767,7 +1111,10 @@ func (b BlobStorageClient) startBlobCopy(container, name, sourceBlob string) (st
 	return copyId, nil
 }
 
-func (b BlobStorageClient) waitForBlobCopy(container, name, copyId string) error {
+// WaitForBlobCopy polls the properties of the destination blob until the copy
+// with the given id completes. If progress is not nil it is called with the
+// number of bytes copied so far and the total number of bytes after each poll.
+func (b BlobStorageClient) WaitForBlobCopy(container, name, copyId string, progress func(copied, total int64)) error {
 	for {
 		props, err := b.GetBlobProperties(container, name)
 		if err != nil {
@@ -778,10 +1125,19 @@ func (b BlobStorageClient) waitForBlobCopy(container, name, copyId string) error
 			return errBlobCopyIdMismatch
 		}
 
+		if progress != nil && props.CopyProgress != "" {
+			copied, total, err := parseBlobCopyProgress(props.CopyProgress)
+			if err != nil {
+				return err
+			}
+			progress(copied, total)
+		}
+
 		switch props.CopyStatus {
 		case blobCopyStatusSuccess:
 			return nil
 		case blobCopyStatusPending:
+			time.Sleep(BlobCopyPollInterval)
 			continue
 		case blobCopyStatusAborted:
 			return errBlobCopyAborted
@@ -793,11 +1149,148 @@ func (b BlobStorageClient) waitForBlobCopy(container, name, copyId string) error
 	}
 }
 
+// SnapshotBlob creates a read-only snapshot of the blob and returns the
+// snapshot timestamp which identifies it. See https://msdn.microsoft.com/en-us/library/azure/ee691971.aspx
+func (b BlobStorageClient) SnapshotBlob(container, name string) (string, error) {
+	uri := b.client.getEndpoint(blobServiceName, pathForBlob(container, name), url.Values{"comp": {"snapshot"}})
+
+	headers := b.client.getStandardHeaders()
+	headers["Content-Length"] = "0"
+
+	resp, err := b.client.exec("PUT", uri, headers, nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.statusCode != http.StatusCreated {
+		return "", ErrNotCreated
+	}
+
+	snapshot := resp.headers.Get("x-ms-snapshot")
+	if snapshot == "" {
+		return "", errors.New("storage: got empty snapshot header")
+	}
+	return snapshot, nil
+}
+
+// AcquireLease acquires a lease on the blob for the given duration in seconds,
+// or LeaseDurationInfinite, and returns the lease id. proposedLeaseId is
+// optional. See https://msdn.microsoft.com/en-us/library/azure/ee691972.aspx
+func (b BlobStorageClient) AcquireLease(container, name string, duration int, proposedLeaseId string) (string, error) {
+	if duration != LeaseDurationInfinite && (duration < 15 || duration > 60) {
+		return "", fmt.Errorf("storage: invalid lease duration %d, must be between 15 and 60 seconds or infinite", duration)
+	}
+
+	headers := map[string]string{"x-ms-lease-duration": strconv.Itoa(duration)}
+	if proposedLeaseId != "" {
+		headers["x-ms-proposed-lease-id"] = proposedLeaseId
+	}
+
+	resp, err := b.leaseBlob(container, name, leaseActionAcquire, headers)
+	if err != nil {
+		return "", err
+	}
+	if resp.statusCode != http.StatusCreated {
+		return "", ErrNotCreated
+	}
+	return resp.headers.Get("x-ms-lease-id"), nil
+}
+
+// RenewLease renews the lease with the given id on the blob.
+// See https://msdn.microsoft.com/en-us/library/azure/ee691972.aspx
+func (b BlobStorageClient) RenewLease(container, name, leaseId string) error {
+	resp, err := b.leaseBlob(container, name, leaseActionRenew, map[string]string{"x-ms-lease-id": leaseId})
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusOK {
+		return fmt.Errorf(errUnexpectedStatus, http.StatusOK, resp.statusCode)
+	}
+	return nil
+}
+
+// ReleaseLease releases the lease with the given id so that another client
+// can acquire a lease on the blob immediately.
+// See https://msdn.microsoft.com/en-us/library/azure/ee691972.aspx
+func (b BlobStorageClient) ReleaseLease(container, name, leaseId string) error {
+	resp, err := b.leaseBlob(container, name, leaseActionRelease, map[string]string{"x-ms-lease-id": leaseId})
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusOK {
+		return fmt.Errorf(errUnexpectedStatus, http.StatusOK, resp.statusCode)
+	}
+	return nil
+}
+
+// BreakLease breaks the current lease on the blob without knowing its id, e.g.
+// a lease left behind on a VHD. breakPeriod is the number of seconds (0-60)
+// the lease is kept before it breaks, a negative value lets the service use
+// the remaining lease time. Returns the seconds until the lease is broken.
+// See https://msdn.microsoft.com/en-us/library/azure/ee691972.aspx
+func (b BlobStorageClient) BreakLease(container, name string, breakPeriod int) (int, error) {
+	if breakPeriod > 60 {
+		return 0, fmt.Errorf("storage: invalid lease break period %d, must be between 0 and 60 seconds", breakPeriod)
+	}
+
+	headers := map[string]string{}
+	if breakPeriod >= 0 {
+		headers["x-ms-lease-break-period"] = strconv.Itoa(breakPeriod)
+	}
+
+	resp, err := b.leaseBlob(container, name, leaseActionBreak, headers)
+	if err != nil {
+		return 0, err
+	}
+	if resp.statusCode != http.StatusAccepted {
+		return 0, ErrNotAccepted
+	}
+	return strconv.Atoi(resp.headers.Get("x-ms-lease-time"))
+}
+
+func (b BlobStorageClient) leaseBlob(container, name, action string, extraHeaders map[string]string) (*storageResponse, error) {
+	uri := b.client.getEndpoint(blobServiceName, pathForBlob(container, name), url.Values{"comp": {"lease"}})
+
+	headers := b.client.getStandardHeaders()
+	headers["Content-Length"] = "0"
+	headers["x-ms-lease-action"] = action
+	for k, v := range extraHeaders {
+		headers[k] = v
+	}
+
+	return b.client.exec("PUT", uri, headers, nil)
+}
+
+// AbortBlobCopy aborts a pending copy operation and leaves the destination
+// blob with zero length. See https://msdn.microsoft.com/en-us/library/azure/jj159098.aspx
+func (b BlobStorageClient) AbortBlobCopy(container, name, copyId string) error {
+	params := url.Values{"comp": {"copy"}, "copyid": {copyId}}
+	uri := b.client.getEndpoint(blobServiceName, pathForBlob(container, name), params)
+
+	headers := b.client.getStandardHeaders()
+	headers["Content-Length"] = "0"
+	headers["x-ms-copy-action"] = "abort"
+
+	resp, err := b.client.exec("PUT", uri, headers, nil)
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusNoContent {
+		return fmt.Errorf(errUnexpectedStatus, http.StatusNoContent, resp.statusCode)
+	}
+	return nil
+}
+
 // DeleteBlob deletes the given blob from the specified container.
 // If the blob does not exists at the time of the Delete Blob operation, it
 // returns error. See https://msdn.microsoft.com/en-us/library/azure/dd179413.aspx
 func (b BlobStorageClient) DeleteBlob(container, name string) error {
-	resp, err := b.deleteBlob(container, name)
+	return b.DeleteBlobWithLease(container, name, "")
+}
+
+// DeleteBlobWithLease deletes a blob with an active lease, leaseId has to be
+// the id of the lease. See DeleteBlob.
+func (b BlobStorageClient) DeleteBlobWithLease(container, name, leaseId string) error {
+	resp, err := b.deleteBlob(container, name, leaseId)
 	if err != nil {
 		return err
 	}
@@ -811,21 +1304,83 @@ func (b BlobStorageClient) DeleteBlob(container, name string) error {
 // If the blob is deleted with this call, returns true. Otherwise returns
 // false. See https://msdn.microsoft.com/en-us/library/azure/dd179413.aspx
 func (b BlobStorageClient) DeleteBlobIfExists(container, name string) (bool, error) {
-	resp, err := b.deleteBlob(container, name)
+	resp, err := b.deleteBlob(container, name, "")
 	if resp != nil && (resp.statusCode == http.StatusAccepted || resp.statusCode == http.StatusNotFound) {
 		return resp.statusCode == http.StatusAccepted, nil
 	}
 	return false, err
 }
 
-func (b BlobStorageClient) deleteBlob(container, name string) (*storageResponse, error) {
+func (b BlobStorageClient) deleteBlob(container, name, leaseId string) (*storageResponse, error) {
 	verb := "DELETE"
 	uri := b.client.getEndpoint(blobServiceName, pathForBlob(container, name), url.Values{})
 	headers := b.client.getStandardHeaders()
+	addLeaseIdHeader(headers, leaseId)
 
 	return b.client.exec(verb, uri, headers, nil)
 }
 
+// addLeaseIdHeader adds the lease id of a write to a leased blob to the
+// request headers, if there is one.
+func addLeaseIdHeader(headers map[string]string, leaseId string) {
+	if leaseId != "" {
+		headers["x-ms-lease-id"] = leaseId
+	}
+}
+
+// helper method to collect the user-defined metadata from response headers
+func metadataFromHeaders(h http.Header) map[string]string {
+	metadata := make(map[string]string)
+	for k, v := range h {
+		k = strings.ToLower(k)
+		if !strings.HasPrefix(k, metadataHeaderPrefix) || len(v) == 0 {
+			continue
+		}
+		metadata[strings.TrimPrefix(k, metadataHeaderPrefix)] = v[0]
+	}
+	return metadata
+}
+
+// helper method to construct the request body of a Set Container ACL call
+func prepareContainerACLRequest(policies []ContainerAccessPolicy) ([]byte, error) {
+	acl := signedIdentifiers{}
+	for _, p := range policies {
+		acl.SignedIdentifiers = append(acl.SignedIdentifiers, signedIdentifier{
+			Id: p.Id,
+			AccessPolicy: accessPolicy{
+				Start:      p.Start.UTC().Format(time.RFC3339),
+				Expiry:     p.Expiry.UTC().Format(time.RFC3339),
+				Permission: p.Permission,
+			},
+		})
+	}
+
+	body, err := xml.Marshal(acl)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// helper method to parse the x-ms-copy-progress value in the form of
+// <bytes copied>/<bytes total>
+func parseBlobCopyProgress(progress string) (int64, int64, error) {
+	parts := strings.Split(progress, "/")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("storage: invalid blob copy progress: '%s'", progress)
+	}
+
+	copied, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	total, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return copied, total, nil
+}
+
 // helper method to construct the path to a container given its name
 func pathForContainer(name string) string {
 	return fmt.Sprintf("/%s", name)
@@ -49,6 +49,62 @@ func Test_blobSASStringToSign(t *testing.T) {
 	}
 }
 
+func Test_metadataFromHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("x-ms-meta-Owner", "packer")
+	h.Set("x-ms-request-id", "foo")
+
+	out := metadataFromHeaders(h)
+	if expected := map[string]string{"owner": "packer"}; !reflect.DeepEqual(out, expected) {
+		t.Errorf("Wrong metadata. Expected: '%v', got: '%v'", expected, out)
+	}
+}
+
+func Test_addLeaseIdHeader(t *testing.T) {
+	headers := map[string]string{}
+	addLeaseIdHeader(headers, "")
+	if _, ok := headers["x-ms-lease-id"]; ok {
+		t.Errorf("Wrong headers. Expected no lease id, got: '%v'", headers)
+	}
+
+	addLeaseIdHeader(headers, "lease")
+	if expected := "lease"; headers["x-ms-lease-id"] != expected {
+		t.Errorf("Wrong lease id. Expected: '%s', got: '%s'", expected, headers["x-ms-lease-id"])
+	}
+}
+
+func Test_prepareContainerACLRequest(t *testing.T) {
+	start := time.Date(2015, 4, 1, 10, 0, 0, 0, time.UTC)
+	out, err := prepareContainerACLRequest([]ContainerAccessPolicy{{
+		Id:         "policy",
+		Start:      start,
+		Expiry:     start.Add(time.Hour),
+		Permission: "r",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<SignedIdentifiers><SignedIdentifier><Id>policy</Id><AccessPolicy><Start>2015-04-01T10:00:00Z</Start><Expiry>2015-04-01T11:00:00Z</Expiry><Permission>r</Permission></AccessPolicy></SignedIdentifier></SignedIdentifiers>`
+	if string(out) != expected {
+		t.Errorf("Wrong ACL request. Expected: '%s', got: '%s'", expected, out)
+	}
+}
+
+func Test_parseBlobCopyProgress(t *testing.T) {
+	copied, total, err := parseBlobCopyProgress("512/2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied != 512 || total != 2048 {
+		t.Errorf("Wrong copy progress. Expected: 512/2048, got: %d/%d", copied, total)
+	}
+
+	if _, _, err := parseBlobCopyProgress("512"); err == nil {
+		t.Error("Expected error for malformed copy progress, got nil")
+	}
+}
+
 func TestGetBlobSASURI(t *testing.T) {
 	api, err := NewClient("foo", "YmFy", DefaultBaseUrl, "2013-08-15", true)
 	if err != nil {
@@ -744,7 +800,7 @@ func TestPutMultiBlockBlob(t *testing.T) {
 	}
 	defer cli.DeleteContainer(cnt)
 
-	err = cli.putBlockBlob(cnt, blob, bytes.NewReader(body), blockSize)
+	err = cli.putBlockBlob(cnt, blob, bytes.NewReader(body), blockSize, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -797,7 +853,7 @@ func TestGetBlockList_PutBlockList(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer cli.deleteBlob(cnt, blob)
+	defer cli.deleteBlob(cnt, blob, "")
 
 	// Get committed blocks
 	committed, err := cli.GetBlockList(cnt, blob, BlockListTypeCommitted)
@@ -1070,6 +1126,255 @@ func TestGetPageRanges(t *testing.T) {
 	}
 }
 
+func TestContainerMetadata(t *testing.T) {
+	cli, err := getBlobClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt := randContainer()
+
+	err = cli.CreateContainer(cnt, ContainerAccessTypePrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.DeleteContainer(cnt)
+
+	metadata := map[string]string{"owner": "packer", "purpose": "vhds"}
+	err = cli.SetContainerMetadata(cnt, metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := cli.GetContainerMetadata(cnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, metadata) {
+		t.Fatalf("Wrong metadata. Expected: '%v', got: '%v'", metadata, out)
+	}
+}
+
+func TestContainerPermissions(t *testing.T) {
+	cli, err := getBlobClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt := randContainer()
+
+	err = cli.CreateContainer(cnt, ContainerAccessTypePrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.DeleteContainer(cnt)
+
+	start := time.Now().UTC().Truncate(time.Second)
+	permissions := ContainerPermissions{
+		AccessType: ContainerAccessTypeBlob,
+		AccessPolicies: []ContainerAccessPolicy{{
+			Id:         randString(10),
+			Start:      start,
+			Expiry:     start.Add(time.Hour),
+			Permission: "r",
+		}},
+	}
+	err = cli.SetContainerPermissions(cnt, permissions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := cli.GetContainerPermissions(cnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, permissions) {
+		t.Fatalf("Wrong permissions. Expected: '%v', got: '%v'", permissions, out)
+	}
+}
+
+func TestBlobPropertiesAndMetadata(t *testing.T) {
+	cli, err := getBlobClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt := randContainer()
+	blob := randString(20)
+
+	err = cli.CreateContainer(cnt, ContainerAccessTypePrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.DeleteContainer(cnt)
+
+	err = cli.PutBlockBlob(cnt, blob, bytes.NewReader([]byte(randString(100))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cli.SetBlobProperties(cnt, blob, BlobHeaders{ContentType: "application/octet-stream", CacheControl: "no-cache"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	props, err := cli.GetBlobProperties(cnt, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "application/octet-stream"; props.ContentType != expected {
+		t.Fatalf("Wrong content type. Expected: '%s', got: '%s'", expected, props.ContentType)
+	}
+	if expected := "no-cache"; props.CacheControl != expected {
+		t.Fatalf("Wrong cache control. Expected: '%s', got: '%s'", expected, props.CacheControl)
+	}
+
+	metadata := map[string]string{"os": "linux"}
+	err = cli.SetBlobMetadata(cnt, blob, metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := cli.GetBlobMetadata(cnt, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, metadata) {
+		t.Fatalf("Wrong metadata. Expected: '%v', got: '%v'", metadata, out)
+	}
+}
+
+func TestSnapshotBlob(t *testing.T) {
+	cli, err := getBlobClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt := randContainer()
+	blob := randString(20)
+
+	err = cli.CreateContainer(cnt, ContainerAccessTypePrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.DeleteContainer(cnt)
+
+	err = cli.PutBlockBlob(cnt, blob, bytes.NewReader([]byte(randString(100))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := cli.SnapshotBlob(cnt, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot == "" {
+		t.Fatal("Expected snapshot timestamp, got empty string")
+	}
+}
+
+func TestBlobLease(t *testing.T) {
+	cli, err := getBlobClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt := randContainer()
+	blob := randString(20)
+
+	err = cli.CreateContainer(cnt, ContainerAccessTypePrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.DeleteContainer(cnt)
+
+	err = cli.PutBlockBlob(cnt, blob, bytes.NewReader([]byte(randString(100))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaseId, err := cli.AcquireLease(cnt, blob, 30, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	props, err := cli.GetBlobProperties(cnt, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "locked"; props.LeaseStatus != expected {
+		t.Fatalf("Wrong lease status. Expected: '%s', got: '%s'", expected, props.LeaseStatus)
+	}
+
+	err = cli.RenewLease(cnt, blob, leaseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cli.ReleaseLease(cnt, blob, leaseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cli.AcquireLease(cnt, blob, LeaseDurationInfinite, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, err := cli.BreakLease(cnt, blob, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 0 {
+		t.Fatalf("Expected lease to break immediately, got: %d seconds", remaining)
+	}
+}
+
+func TestBlobLeaseWrites(t *testing.T) {
+	cli, err := getBlobClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt := randContainer()
+	blob := randString(20)
+
+	err = cli.CreateContainer(cnt, ContainerAccessTypePrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.DeleteContainer(cnt)
+
+	err = cli.PutBlockBlob(cnt, blob, bytes.NewReader([]byte(randString(100))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaseId, err := cli.AcquireLease(cnt, blob, LeaseDurationInfinite, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes without the lease id are rejected
+	if err = cli.SetBlobMetadata(cnt, blob, map[string]string{"owner": "packer"}); err == nil {
+		t.Fatal("Expected SetBlobMetadata without the lease id to fail")
+	}
+
+	err = cli.PutBlockBlobWithLease(cnt, blob, bytes.NewReader([]byte(randString(100))), leaseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cli.SetBlobMetadataWithLease(cnt, blob, map[string]string{"owner": "packer"}, leaseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cli.SetBlobPropertiesWithLease(cnt, blob, BlobHeaders{ContentType: "text/plain"}, leaseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cli.DeleteBlobWithLease(cnt, blob, leaseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func deleteTestContainers(cli *BlobStorageClient) error {
 	for {
 		resp, err := cli.ListContainers(ListContainersParameters{Prefix: testContainerPrefix})
@@ -1089,7 +1394,11 @@ func deleteTestContainers(cli *BlobStorageClient) error {
 	return nil
 }
 
-func getBlobClient() (*BlobStorageClient, error) {
+func getStorageClient() (*StorageClient, error) {
+	if os.Getenv("STORAGE_EMULATOR") != "" {
+		return NewEmulatorClient()
+	}
+
 	name := os.Getenv("ACCOUNT_NAME")
 	if name == "" {
 		return nil, errors.New("ACCOUNT_NAME not set, need an empty storage account to test")
@@ -1098,7 +1407,11 @@ func getBlobClient() (*BlobStorageClient, error) {
 	if key == "" {
 		return nil, errors.New("ACCOUNT_KEY not set")
 	}
-	cli, err := NewBasicClient(name, key)
+	return NewBasicClient(name, key)
+}
+
+func getBlobClient() (*BlobStorageClient, error) {
+	cli, err := getStorageClient()
 	if err != nil {
 		return nil, err
 	}
@@ -22,8 +22,30 @@ const (
 	blobServiceName  = "blob"
 	tableServiceName = "table"
 	queueServiceName = "queue"
+	fileServiceName  = "file"
+
+	// StorageEmulatorAccountName and StorageEmulatorAccountKey are the well
+	// known credentials of the development account of the local storage
+	// emulator and Azurite.
+	StorageEmulatorAccountName = "devstoreaccount1"
+	StorageEmulatorAccountKey  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+	storageEmulatorHost = "127.0.0.1"
+
+	// emptyContentLengthApiVersion is the first version of the storage
+	// services which expects an empty Content-Length in the string to sign
+	// of requests without a body, instead of "0".
+	emptyContentLengthApiVersion = "2015-02-21"
 )
 
+// storageEmulatorPorts are the ports the storage emulator listens on for
+// each service. There is no file service in the emulator.
+var storageEmulatorPorts = map[string]int{
+	blobServiceName:  10000,
+	queueServiceName: 10001,
+	tableServiceName: 10002,
+}
+
 // StorageClient is the object that needs to be constructed
 // to perform operations on the storage account.
 type StorageClient struct {
@@ -32,6 +54,7 @@ type StorageClient struct {
 	useHttps    bool
 	baseUrl     string
 	apiVersion  string
+	emulator    bool
 }
 
 type storageResponse struct {
@@ -85,6 +108,18 @@ func NewClient(accountName, accountKey, blobServiceBaseUrl, apiVersion string, u
 		apiVersion:  apiVersion}, nil
 }
 
+// NewEmulatorClient constructs a StorageClient for the development account
+// of the local storage emulator. The emulator is reached over HTTP on the
+// local host and addresses the account in the path instead of the host name.
+func NewEmulatorClient() (*StorageClient, error) {
+	cli, err := NewClient(StorageEmulatorAccountName, StorageEmulatorAccountKey, storageEmulatorHost, DefaultApiVersion, false)
+	if err != nil {
+		return nil, err
+	}
+	cli.emulator = true
+	return cli, nil
+}
+
 func (c StorageClient) getBaseUrl(service string) string {
 	scheme := "http"
 	if c.useHttps {
@@ -92,6 +127,9 @@ func (c StorageClient) getBaseUrl(service string) string {
 	}
 
 	host := fmt.Sprintf("%s.%s.%s", c.accountName, service, c.baseUrl)
+	if c.emulator {
+		host = fmt.Sprintf("%s:%d", c.baseUrl, storageEmulatorPorts[service])
+	}
 
 	u := &url.URL{
 		Scheme: scheme,
@@ -109,6 +147,10 @@ func (c StorageClient) getEndpoint(service, path string, params url.Values) stri
 	if path == "" {
 		path = "/" // API doesn't accept path segments not starting with '/'
 	}
+	if c.emulator {
+		// emulator expects the account name as the first path segment
+		path = "/" + c.accountName + path
+	}
 
 	u.Path = path
 	u.RawQuery = params.Encode()
@@ -121,6 +163,14 @@ func (c StorageClient) GetBlobService() *BlobStorageClient {
 	return &BlobStorageClient{c}
 }
 
+// GetFileService returns a FileServiceClient which can operate on the
+// file service of the storage account. The storage emulator has no file
+// service, the operations of a FileServiceClient of an emulator client
+// return ErrFileServiceNotInEmulator.
+func (c StorageClient) GetFileService() *FileServiceClient {
+	return &FileServiceClient{c}
+}
+
 func (c StorageClient) createAuthorizationHeader(canonicalizedString string) string {
 	signature := c.computeHmac256(canonicalizedString)
 	return fmt.Sprintf("%s %s:%s", "SharedKey", c.accountName, signature)
@@ -219,11 +269,16 @@ func (c StorageClient) buildCanonicalizedResource(uri string) (string, error) {
 }
 
 func (c StorageClient) buildCanonicalizedString(verb string, headers map[string]string, canonicalizedResource string) string {
+	contentLength := headers["Content-Length"]
+	if contentLength == "0" && headers["x-ms-version"] >= emptyContentLengthApiVersion {
+		contentLength = ""
+	}
+
 	canonicalizedString := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s",
 		verb,
 		headers["Content-Encoding"],
 		headers["Content-Language"],
-		headers["Content-Length"],
+		contentLength,
 		headers["Content-MD5"],
 		headers["Content-Type"],
 		headers["Date"],
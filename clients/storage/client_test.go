@@ -44,6 +44,47 @@ func TestGetBaseUrl_Custom_NoHttps(t *testing.T) {
 	}
 }
 
+func TestGetBaseUrl_Emulator(t *testing.T) {
+	cli, err := NewEmulatorClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := cli.getBaseUrl("queue")
+
+	if expected := "http://127.0.0.1:10001"; output != expected {
+		t.Fatalf("Wrong base url. Expected: '%s', got: '%s'", expected, output)
+	}
+}
+
+func TestGetEndpoint_Emulator(t *testing.T) {
+	cli, err := NewEmulatorClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := cli.getEndpoint(blobServiceName, "/container/blob", url.Values{"comp": {"list"}})
+
+	if expected := "http://127.0.0.1:10000/devstoreaccount1/container/blob?comp=list"; output != expected {
+		t.Fatalf("Wrong endpoint url. Expected: '%s', got: '%s'", expected, output)
+	}
+}
+
+func TestFileService_Emulator(t *testing.T) {
+	cli, err := NewEmulatorClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileCli := cli.GetFileService()
+	if err := fileCli.CreateShare("share"); err != ErrFileServiceNotInEmulator {
+		t.Fatalf("Wrong error. Expected: '%v', got: '%v'", ErrFileServiceNotInEmulator, err)
+	}
+	if _, err := fileCli.ShareExists("share"); err != ErrFileServiceNotInEmulator {
+		t.Fatalf("Wrong error. Expected: '%v', got: '%v'", ErrFileServiceNotInEmulator, err)
+	}
+}
+
 func TestGetEndpoint_None(t *testing.T) {
 	cli, err := NewBasicClient("foo", "YmFy")
 	if err != nil {
@@ -164,6 +205,32 @@ func Test_buildCanonicalizedHeader(t *testing.T) {
 	}
 }
 
+func Test_buildCanonicalizedString(t *testing.T) {
+	cli, err := NewBasicClient("foo", "YmFy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type test struct {
+		headers  map[string]string
+		expected string
+	}
+	tests := []test{
+		{map[string]string{"Content-Length": "0", "x-ms-version": "2014-02-14"},
+			"PUT\n\n\n0\n\n\n\n\n\n\n\n\nx-ms-version:2014-02-14\n/foo/share"},
+		{map[string]string{"Content-Length": "0", "x-ms-version": "2015-02-21"},
+			"PUT\n\n\n\n\n\n\n\n\n\n\n\nx-ms-version:2015-02-21\n/foo/share"},
+		{map[string]string{"Content-Length": "12", "x-ms-version": "2015-02-21"},
+			"PUT\n\n\n12\n\n\n\n\n\n\n\n\nx-ms-version:2015-02-21\n/foo/share"},
+	}
+
+	for _, i := range tests {
+		if out := cli.buildCanonicalizedString("PUT", i.headers, "/foo/share"); out != i.expected {
+			t.Fatalf("Wrong canonicalized string. Expected:\n'%s', Got:\n'%s'", i.expected, out)
+		}
+	}
+}
+
 func TestReturnsStorageServiceError(t *testing.T) {
 	cli, err := getBlobClient()
 	if err != nil {
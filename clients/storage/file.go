@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// FileServiceClient contains operations for the Azure Files service, which
+// exposes SMB file shares backed by the storage account.
+type FileServiceClient struct {
+	client StorageClient
+}
+
+const (
+	// MaxFileRangeSize is the largest range that can be written to a file
+	// with a single Put Range call.
+	MaxFileRangeSize = 4 * 1024 * 1024
+
+	// MaxShareQuotaInGB is the largest quota a share can be given.
+	MaxShareQuotaInGB = 5120
+
+	// shareQuotaApiVersion is the first service version which supports share
+	// quotas. It is used only for SetShareQuota so the rest of the client
+	// keeps the configured version.
+	shareQuotaApiVersion = "2015-02-21"
+)
+
+// ErrFileServiceNotInEmulator is returned by the operations of a
+// FileServiceClient of an emulator client, as the storage emulator has no
+// file service.
+var ErrFileServiceNotInEmulator = errors.New("storage: the storage emulator does not support the file service")
+
+// CreateShare creates a file share within the storage account with the given
+// name. Returns error if the share already exists.
+// See https://msdn.microsoft.com/en-us/library/azure/dn167008.aspx
+func (f FileServiceClient) CreateShare(name string) error {
+	resp, err := f.createShare(name)
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusCreated {
+		return ErrNotCreated
+	}
+	return nil
+}
+
+// CreateShareIfNotExists creates a file share if it does not exist. Returns
+// true if the share is newly created or false if the share already exists.
+func (f FileServiceClient) CreateShareIfNotExists(name string) (bool, error) {
+	resp, err := f.createShare(name)
+	if resp != nil && (resp.statusCode == http.StatusCreated || resp.statusCode == http.StatusConflict) {
+		return resp.statusCode == http.StatusCreated, nil
+	}
+	return false, err
+}
+
+func (f FileServiceClient) createShare(name string) (*storageResponse, error) {
+	uri := f.client.getEndpoint(fileServiceName, pathForShare(name), url.Values{"restype": {"share"}})
+
+	headers := f.client.getStandardHeaders()
+	headers["Content-Length"] = "0"
+	return f.exec("PUT", uri, headers, nil)
+}
+
+// ShareExists returns true if a share with the given name exists on the
+// storage account, otherwise returns false.
+func (f FileServiceClient) ShareExists(name string) (bool, error) {
+	uri := f.client.getEndpoint(fileServiceName, pathForShare(name), url.Values{"restype": {"share"}})
+	headers := f.client.getStandardHeaders()
+
+	resp, err := f.exec("HEAD", uri, headers, nil)
+	if resp != nil && (resp.statusCode == http.StatusOK || resp.statusCode == http.StatusNotFound) {
+		return resp.statusCode == http.StatusOK, nil
+	}
+	return false, err
+}
+
+// DeleteShare deletes the share with the given name and all directories and
+// files in it. See https://msdn.microsoft.com/en-us/library/azure/dn689090.aspx
+func (f FileServiceClient) DeleteShare(name string) error {
+	uri := f.client.getEndpoint(fileServiceName, pathForShare(name), url.Values{"restype": {"share"}})
+	headers := f.client.getStandardHeaders()
+
+	resp, err := f.exec("DELETE", uri, headers, nil)
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusAccepted {
+		return ErrNotAccepted
+	}
+	return nil
+}
+
+// SetShareQuota sets the maximum size of the share in gigabytes.
+// See https://msdn.microsoft.com/en-us/library/azure/mt427368.aspx
+func (f FileServiceClient) SetShareQuota(name string, quotaInGB int) error {
+	if quotaInGB <= 0 || quotaInGB > MaxShareQuotaInGB {
+		return fmt.Errorf("storage: invalid share quota %d GB, must be between 1 and %d GB", quotaInGB, MaxShareQuotaInGB)
+	}
+
+	params := url.Values{"restype": {"share"}, "comp": {"properties"}}
+	uri := f.client.getEndpoint(fileServiceName, pathForShare(name), params)
+
+	headers := f.client.getStandardHeaders()
+	headers["x-ms-version"] = shareQuotaApiVersion
+	headers["Content-Length"] = "0"
+	headers["x-ms-share-quota"] = strconv.Itoa(quotaInGB)
+
+	resp, err := f.exec("PUT", uri, headers, nil)
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusOK {
+		return fmt.Errorf(errUnexpectedStatus, http.StatusOK, resp.statusCode)
+	}
+	return nil
+}
+
+// CreateDirectory creates a directory in the share. path is relative to the
+// share root and its parent directory has to exist.
+// See https://msdn.microsoft.com/en-us/library/azure/dn166993.aspx
+func (f FileServiceClient) CreateDirectory(share, path string) error {
+	uri := f.client.getEndpoint(fileServiceName, pathForFile(share, path), url.Values{"restype": {"directory"}})
+
+	headers := f.client.getStandardHeaders()
+	headers["Content-Length"] = "0"
+
+	resp, err := f.exec("PUT", uri, headers, nil)
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusCreated {
+		return ErrNotCreated
+	}
+	return nil
+}
+
+// DeleteDirectory deletes an empty directory from the share.
+// See https://msdn.microsoft.com/en-us/library/azure/dn166969.aspx
+func (f FileServiceClient) DeleteDirectory(share, path string) error {
+	uri := f.client.getEndpoint(fileServiceName, pathForFile(share, path), url.Values{"restype": {"directory"}})
+	headers := f.client.getStandardHeaders()
+
+	resp, err := f.exec("DELETE", uri, headers, nil)
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusAccepted {
+		return ErrNotAccepted
+	}
+	return nil
+}
+
+// CreateFile initializes an empty file of the given size in bytes. The
+// content is written with PutRange. See https://msdn.microsoft.com/en-us/library/azure/dn194271.aspx
+func (f FileServiceClient) CreateFile(share, path string, size int64) error {
+	uri := f.client.getEndpoint(fileServiceName, pathForFile(share, path), url.Values{})
+
+	headers := f.client.getStandardHeaders()
+	headers["Content-Length"] = "0"
+	headers["x-ms-type"] = "file"
+	headers["x-ms-content-length"] = strconv.FormatInt(size, 10)
+
+	resp, err := f.exec("PUT", uri, headers, nil)
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusCreated {
+		return ErrNotCreated
+	}
+	return nil
+}
+
+// PutRange writes chunk into the file at the given byte range. endByte is
+// inclusive and the range cannot be larger than MaxFileRangeSize.
+// See https://msdn.microsoft.com/en-us/library/azure/dn194276.aspx
+func (f FileServiceClient) PutRange(share, path string, startByte, endByte int64, chunk []byte) error {
+	if size := endByte - startByte + 1; size != int64(len(chunk)) || size > MaxFileRangeSize {
+		return fmt.Errorf("storage: invalid file range %d-%d for a chunk of %d bytes (max %d bytes)", startByte, endByte, len(chunk), MaxFileRangeSize)
+	}
+
+	uri := f.client.getEndpoint(fileServiceName, pathForFile(share, path), url.Values{"comp": {"range"}})
+
+	headers := f.client.getStandardHeaders()
+	headers["Content-Length"] = fmt.Sprintf("%v", len(chunk))
+	headers["x-ms-range"] = fmt.Sprintf("bytes=%d-%d", startByte, endByte)
+	headers["x-ms-write"] = "update"
+	headers["Content-MD5"] = contentMD5(chunk)
+
+	resp, err := f.exec("PUT", uri, headers, bytes.NewReader(chunk))
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusCreated {
+		return ErrNotCreated
+	}
+	return nil
+}
+
+// UploadFile creates a file of the given size and writes the content read
+// from file into it in ranges of MaxFileRangeSize. This is a helper method
+// built on top of CreateFile and PutRange.
+func (f FileServiceClient) UploadFile(share, path string, file io.Reader, size int64) error {
+	err := f.CreateFile(share, path, size)
+	if err != nil {
+		return err
+	}
+
+	chunk := make([]byte, MaxFileRangeSize)
+	for offset := int64(0); offset < size; {
+		n, err := io.ReadFull(file, chunk[:min64(MaxFileRangeSize, size-offset)])
+		if err != nil {
+			return err
+		}
+
+		err = f.PutRange(share, path, offset, offset+int64(n)-1, chunk[:n])
+		if err != nil {
+			return err
+		}
+		offset += int64(n)
+	}
+	return nil
+}
+
+// GetFile returns a stream to read the file. Caller must call Close() on the
+// reader to close the underlying connection.
+// See https://msdn.microsoft.com/en-us/library/azure/dn194274.aspx
+func (f FileServiceClient) GetFile(share, path string) (io.ReadCloser, error) {
+	uri := f.client.getEndpoint(fileServiceName, pathForFile(share, path), url.Values{})
+	headers := f.client.getStandardHeaders()
+
+	resp, err := f.exec("GET", uri, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.statusCode != http.StatusOK {
+		resp.body.Close()
+		return nil, fmt.Errorf(errUnexpectedStatus, http.StatusOK, resp.statusCode)
+	}
+	if expectedMD5 := resp.headers.Get("Content-MD5"); expectedMD5 != "" {
+		return newMD5VerifyingReader(resp.body, expectedMD5)
+	}
+	return resp.body, nil
+}
+
+// DeleteFile deletes the file from the share.
+// See https://msdn.microsoft.com/en-us/library/azure/dn689085.aspx
+func (f FileServiceClient) DeleteFile(share, path string) error {
+	uri := f.client.getEndpoint(fileServiceName, pathForFile(share, path), url.Values{})
+	headers := f.client.getStandardHeaders()
+
+	resp, err := f.exec("DELETE", uri, headers, nil)
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusAccepted {
+		return ErrNotAccepted
+	}
+	return nil
+}
+
+// exec sends the request to the file service of the account, failing
+// without a request for emulator clients.
+func (f FileServiceClient) exec(verb, url string, headers map[string]string, body io.Reader) (*storageResponse, error) {
+	if f.client.emulator {
+		return nil, ErrFileServiceNotInEmulator
+	}
+	return f.client.exec(verb, url, headers, body)
+}
+
+// helper method to construct the path to a share given its name
+func pathForShare(name string) string {
+	return fmt.Sprintf("/%s", name)
+}
+
+// helper method to construct the path to a file or directory given its share
+// and its path relative to the share root
+func pathForFile(share, path string) string {
+	return fmt.Sprintf("/%s/%s", share, path)
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+const testSharePrefix = "zzzzsharetest-"
+
+func Test_pathForShare(t *testing.T) {
+	out := pathForShare("foo")
+	if expected := "/foo"; out != expected {
+		t.Errorf("Wrong pathForShare. Expected: '%s', got: '%s'", expected, out)
+	}
+}
+
+func Test_pathForFile(t *testing.T) {
+	out := pathForFile("foo", "dir/file")
+	if expected := "/foo/dir/file"; out != expected {
+		t.Errorf("Wrong pathForFile. Expected: '%s', got: '%s'", expected, out)
+	}
+}
+
+func TestCreateDeleteShare(t *testing.T) {
+	cli, err := getFileClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := randShare()
+
+	err = cli.CreateShare(share)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := cli.ShareExists(share)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatalf("Existing share returned as non-existing: %s", share)
+	}
+
+	err = cli.SetShareQuota(share, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cli.DeleteShare(share)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUploadGetFile(t *testing.T) {
+	cli, err := getFileClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := randShare()
+	dir := randString(10)
+	file := dir + "/" + randString(10)
+	body := []byte(randString(1024))
+
+	err = cli.CreateShare(share)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.DeleteShare(share)
+
+	err = cli.CreateDirectory(share, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cli.UploadFile(share, file, bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := cli.GetFile(share, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Close()
+
+	out, err := ioutil.ReadAll(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(body, out) {
+		t.Fatalf("Wrong file content. Expected: %d bytes, got: %d bytes", len(body), len(out))
+	}
+
+	err = cli.DeleteFile(share, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cli.DeleteDirectory(share, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func getFileClient() (*FileServiceClient, error) {
+	cli, err := getStorageClient()
+	if err != nil {
+		return nil, err
+	}
+	return cli.GetFileService(), nil
+}
+
+func randShare() string {
+	return testSharePrefix + randString(32-len(testSharePrefix))
+}
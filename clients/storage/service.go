@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ServiceType selects the service of the storage account the service
+// properties are read from or written to.
+type ServiceType string
+
+const (
+	ServiceTypeBlob  ServiceType = blobServiceName
+	ServiceTypeQueue ServiceType = queueServiceName
+	ServiceTypeTable ServiceType = tableServiceName
+)
+
+// ServiceProperties contains the analytics logging, metrics and CORS settings
+// of a storage service. Sections left nil in a SetServiceProperties call are
+// not changed on the service.
+// See https://msdn.microsoft.com/en-us/library/azure/hh452235.aspx
+type ServiceProperties struct {
+	XMLName               xml.Name  `xml:"StorageServiceProperties"`
+	Logging               *Logging  `xml:"Logging,omitempty"`
+	HourMetrics           *Metrics  `xml:"HourMetrics,omitempty"`
+	MinuteMetrics         *Metrics  `xml:"MinuteMetrics,omitempty"`
+	Cors                  *CorsList `xml:"Cors,omitempty"`
+	DefaultServiceVersion string    `xml:"DefaultServiceVersion,omitempty"`
+}
+
+// Logging contains the analytics logging settings of a storage service.
+type Logging struct {
+	Version         string          `xml:"Version"`
+	Delete          bool            `xml:"Delete"`
+	Read            bool            `xml:"Read"`
+	Write           bool            `xml:"Write"`
+	RetentionPolicy RetentionPolicy `xml:"RetentionPolicy"`
+}
+
+// Metrics contains the hour or minute metrics settings of a storage service.
+// IncludeAPIs can only be set when metrics are enabled.
+type Metrics struct {
+	Version         string          `xml:"Version"`
+	Enabled         bool            `xml:"Enabled"`
+	IncludeAPIs     *bool           `xml:"IncludeAPIs,omitempty"`
+	RetentionPolicy RetentionPolicy `xml:"RetentionPolicy"`
+}
+
+// RetentionPolicy defines how many days logs and metrics are kept. Days is
+// only sent when the policy is enabled.
+type RetentionPolicy struct {
+	Enabled bool `xml:"Enabled"`
+	Days    int  `xml:"Days,omitempty"`
+}
+
+// CorsList contains the CORS rules of a storage service. An empty list removes
+// all rules.
+type CorsList struct {
+	CorsRules []CorsRule `xml:"CorsRule"`
+}
+
+// CorsRule is a CORS rule of a storage service. Origins, methods and headers
+// are comma separated lists. See https://msdn.microsoft.com/en-us/library/azure/dn535601.aspx
+type CorsRule struct {
+	AllowedOrigins  string `xml:"AllowedOrigins"`
+	AllowedMethods  string `xml:"AllowedMethods"`
+	MaxAgeInSeconds int    `xml:"MaxAgeInSeconds"`
+	ExposedHeaders  string `xml:"ExposedHeaders"`
+	AllowedHeaders  string `xml:"AllowedHeaders"`
+}
+
+// GetServiceProperties returns the analytics logging, metrics and CORS
+// settings of the given service. See https://msdn.microsoft.com/en-us/library/azure/hh452239.aspx
+func (c StorageClient) GetServiceProperties(service ServiceType) (ServiceProperties, error) {
+	uri := c.getEndpoint(string(service), "", url.Values{"restype": {"service"}, "comp": {"properties"}})
+	headers := c.getStandardHeaders()
+
+	var out ServiceProperties
+	resp, err := c.exec("GET", uri, headers, nil)
+	if err != nil {
+		return out, err
+	}
+
+	err = xmlUnmarshal(resp.body, &out)
+	return out, err
+}
+
+// SetServiceProperties updates the analytics logging, metrics and CORS
+// settings of the given service. See https://msdn.microsoft.com/en-us/library/azure/hh452235.aspx
+func (c StorageClient) SetServiceProperties(service ServiceType, properties ServiceProperties) error {
+	body, err := xml.Marshal(properties)
+	if err != nil {
+		return err
+	}
+	body = append([]byte(xml.Header), body...)
+
+	uri := c.getEndpoint(string(service), "", url.Values{"restype": {"service"}, "comp": {"properties"}})
+	headers := c.getStandardHeaders()
+	headers["Content-Length"] = fmt.Sprintf("%v", len(body))
+
+	resp, err := c.exec("PUT", uri, headers, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusAccepted {
+		return ErrNotAccepted
+	}
+	return nil
+}
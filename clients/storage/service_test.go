@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestServicePropertiesMarshal(t *testing.T) {
+	properties := ServiceProperties{
+		HourMetrics: &Metrics{
+			Version:         "1.0",
+			Enabled:         false,
+			RetentionPolicy: RetentionPolicy{Enabled: false},
+		},
+		Cors: &CorsList{},
+	}
+
+	out, err := xml.Marshal(properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<StorageServiceProperties><HourMetrics><Version>1.0</Version><Enabled>false</Enabled><RetentionPolicy><Enabled>false</Enabled></RetentionPolicy></HourMetrics><Cors></Cors></StorageServiceProperties>`
+	if string(out) != expected {
+		t.Errorf("Wrong service properties. Expected: '%s', got: '%s'", expected, out)
+	}
+}
+
+func TestSetGetServiceProperties(t *testing.T) {
+	cli, err := getStorageClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	includeAPIs := true
+	properties := ServiceProperties{
+		HourMetrics: &Metrics{
+			Version:         "1.0",
+			Enabled:         true,
+			IncludeAPIs:     &includeAPIs,
+			RetentionPolicy: RetentionPolicy{Enabled: true, Days: 7},
+		},
+		Cors: &CorsList{CorsRules: []CorsRule{{
+			AllowedOrigins:  "*",
+			AllowedMethods:  "GET",
+			MaxAgeInSeconds: 500,
+			ExposedHeaders:  "x-ms-meta-*",
+			AllowedHeaders:  "x-ms-meta-*",
+		}}},
+	}
+
+	err = cli.SetServiceProperties(ServiceTypeBlob, properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := cli.GetServiceProperties(ServiceTypeBlob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out.HourMetrics, properties.HourMetrics) {
+		t.Fatalf("Wrong hour metrics. Expected: '%v', got: '%v'", properties.HourMetrics, out.HourMetrics)
+	}
+	if !reflect.DeepEqual(out.Cors, properties.Cors) {
+		t.Fatalf("Wrong CORS rules. Expected: '%v', got: '%v'", properties.Cors, out.Cors)
+	}
+}
@@ -1,11 +1,14 @@
 package storage
 
 import (
+	"bytes"
 	"crypto/hmac"
+	"crypto/md5"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/xml"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -19,6 +22,46 @@ func (c StorageClient) computeHmac256(message string) string {
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
 
+// contentMD5 returns the base64 encoded MD5 hash of data as expected in the
+// Content-MD5 header.
+func contentMD5(data []byte) string {
+	h := md5.Sum(data)
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// md5VerifyingReader computes the MD5 hash of the content read through it and
+// returns ErrMD5Mismatch instead of io.EOF if it does not match the expected
+// Content-MD5 value.
+type md5VerifyingReader struct {
+	body     io.ReadCloser
+	hash     hash.Hash
+	expected []byte
+}
+
+// newMD5VerifyingReader wraps body to verify it against expectedMD5. body is
+// closed if expectedMD5 is not a valid Content-MD5 value.
+func newMD5VerifyingReader(body io.ReadCloser, expectedMD5 string) (io.ReadCloser, error) {
+	expected, err := base64.StdEncoding.DecodeString(expectedMD5)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return &md5VerifyingReader{body: body, hash: md5.New(), expected: expected}, nil
+}
+
+func (r *md5VerifyingReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF && !bytes.Equal(r.hash.Sum(nil), r.expected) {
+		return n, ErrMD5Mismatch
+	}
+	return n, err
+}
+
+func (r *md5VerifyingReader) Close() error {
+	return r.body.Close()
+}
+
 func currentTimeRfc1123Formatted() string {
 	return timeRfc1123Formatted(time.Now().UTC())
 }
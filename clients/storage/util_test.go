@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"io"
 	"io/ioutil"
 	"net/url"
 	"reflect"
@@ -78,3 +79,48 @@ func Test_xmlUnmarshal(t *testing.T) {
 		t.Fatal("Got wrong value")
 	}
 }
+
+func Test_contentMD5(t *testing.T) {
+	out := contentMD5([]byte("foo"))
+	if expected := "rL0Y20zC+Fzt72VPzMSk2A=="; out != expected {
+		t.Errorf("Wrong contentMD5. Expected: '%s', got: '%s'", expected, out)
+	}
+}
+
+func Test_md5VerifyingReader(t *testing.T) {
+	r, err := newMD5VerifyingReader(ioutil.NopCloser(strings.NewReader("foo")), contentMD5([]byte("foo")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = newMD5VerifyingReader(ioutil.NopCloser(strings.NewReader("bar")), contentMD5([]byte("foo")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(r); err != ErrMD5Mismatch {
+		t.Errorf("Expected ErrMD5Mismatch, got: %v", err)
+	}
+}
+
+func Test_md5VerifyingReader_InvalidHeader(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader("foo")}
+	if _, err := newMD5VerifyingReader(body, "not base64!"); err == nil {
+		t.Fatal("Expected an error for an invalid Content-MD5")
+	}
+	if !body.closed {
+		t.Errorf("Expected the body to be closed")
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
@@ -0,0 +1,448 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	vhdFooterSize        = 512
+	vhdFooterCookie      = "conectix"
+	vhdDiskTypeFixed     = 2
+	vhdSizeAlignment     = 1024 * 1024
+	vhdPageSize          = 512
+	vhdTransferChunkSize = MaxBlobPageSize
+
+	// VHDTransferParallelism is the number of page ranges UploadVHD and
+	// DownloadVHD transfer concurrently.
+	VHDTransferParallelism = 8
+	// VHDTransferMaxRetries is the number of times UploadVHD and DownloadVHD
+	// retry a page range after a transient failure.
+	VHDTransferMaxRetries = 3
+)
+
+var (
+	errVHDFooterNotFound = errors.New("storage: VHD footer not found, the file is not a VHD")
+	errVHDNotFixed       = errors.New("storage: only fixed size VHDs can be uploaded, convert dynamic VHDs first")
+	errVHDFooterChecksum = errors.New("storage: VHD footer checksum does not match, the footer is corrupt")
+	errVHDNotPageBlob    = errors.New("storage: VHDs can only be downloaded from page blobs")
+)
+
+const (
+	errBlobURLNotBlob = "storage: %s is not a blob URL"
+	errBlobURLAccount = "storage: %s is not a blob URL of this storage account"
+)
+
+// UploadVHDFile uploads the fixed size VHD at the given path into a page
+// blob. See UploadVHD.
+func (b BlobStorageClient) UploadVHDFile(container, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return b.UploadVHD(container, name, f, fi.Size())
+}
+
+// UploadVHD uploads a fixed size VHD of the given size into a page blob, with
+// the VHD footer in the last page of the blob as Azure requires for disks and
+// OS images. Azure also requires the virtual size of the disk to be a
+// multiple of 1 MB; a VHD of another size is padded with zeros up to the
+// next MB and its footer is updated to the new size. Pages that contain only
+// zeros are skipped, since a new page blob already reads as zeros, and the
+// remaining page ranges are uploaded in parallel with retries.
+func (b BlobStorageClient) UploadVHD(container, name string, vhd io.ReaderAt, size int64) error {
+	footer, err := verifyVHD(vhd, size)
+	if err != nil {
+		return err
+	}
+
+	blobSize := int64(binary.BigEndian.Uint64(footer[48:56])) + vhdFooterSize
+	err = b.PutPageBlob(container, name, blobSize)
+	if err != nil {
+		return err
+	}
+
+	return runVHDTransfer(func(jobs chan<- func() error, abort <-chan struct{}) error {
+		err := b.readVHDRanges(container, name, vhd, size-vhdFooterSize, jobs, abort)
+		if err != nil {
+			return err
+		}
+
+		job := func() error {
+			return b.PutPage(container, name, blobSize-vhdFooterSize, blobSize-1, PageWriteTypeUpdate, footer)
+		}
+
+		select {
+		case jobs <- job:
+		case <-abort:
+		}
+		return nil
+	})
+}
+
+// DownloadVHD downloads the page blob at blobURL, such as a captured disk or
+// image VHD, into a local file at localPath. The blob has to belong to the
+// storage account of the client. Only the page ranges which were written to
+// the blob are downloaded, in parallel with retries, and pages which contain
+// only zeros are not written to the file, so the file stays sparse on file
+// systems which support it. The local file is removed if the download fails.
+func (b BlobStorageClient) DownloadVHD(blobURL, localPath string) error {
+	container, name, err := b.parseBlobURL(blobURL)
+	if err != nil {
+		return err
+	}
+
+	props, err := b.GetBlobProperties(container, name)
+	if err != nil {
+		return err
+	}
+	if props.BlobType != BlobTypePage {
+		return errVHDNotPageBlob
+	}
+
+	ranges, err := b.GetPageRanges(container, name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+
+	err = f.Truncate(props.ContentLength)
+	if err == nil {
+		err = runVHDTransfer(func(jobs chan<- func() error, abort <-chan struct{}) error {
+			return b.sendVHDDownloadJobs(container, name, f, ranges.PageList, jobs, abort)
+		})
+	}
+
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(localPath)
+	}
+	return err
+}
+
+// parseBlobURL returns the container and the name of the blob at blobURL, as
+// returned by GetBlobUrl. Blobs in the root container have no container in
+// the URL.
+func (b BlobStorageClient) parseBlobURL(blobURL string) (container, name string, err error) {
+	u, err := url.Parse(blobURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	base, err := url.Parse(b.client.getBaseUrl(blobServiceName))
+	if err != nil {
+		return "", "", err
+	}
+	if !strings.EqualFold(u.Host, base.Host) {
+		return "", "", fmt.Errorf(errBlobURLAccount, blobURL)
+	}
+
+	path := strings.TrimPrefix(u.Path, "/")
+	if b.client.emulator {
+		// emulator URLs have the account name as the first path segment
+		path = strings.TrimPrefix(path, b.client.accountName+"/")
+	}
+
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) == 1 {
+		parts = []string{"$root", parts[0]}
+	}
+	if len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", fmt.Errorf(errBlobURLNotBlob, blobURL)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// runVHDTransfer runs the jobs sent by produce on VHDTransferParallelism
+// workers, retrying each job on transient failures. The first failure aborts
+// the transfer and is returned.
+func runVHDTransfer(produce func(jobs chan<- func() error, abort <-chan struct{}) error) error {
+	jobs := make(chan func() error, VHDTransferParallelism)
+	abort := make(chan struct{})
+	var (
+		wg          sync.WaitGroup
+		once        sync.Once
+		transferErr error
+	)
+
+	for i := 0; i < VHDTransferParallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				select {
+				case <-abort:
+					return
+				default:
+				}
+
+				err := withRetry(job)
+				if err != nil {
+					// Keep the first error and make the producer and the
+					// other workers stop.
+					once.Do(func() {
+						transferErr = err
+						close(abort)
+					})
+					return
+				}
+			}
+		}()
+	}
+
+	err := produce(jobs, abort)
+	close(jobs)
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	return transferErr
+}
+
+func withRetry(job func() error) error {
+	var err error
+	for attempt := 0; attempt <= VHDTransferMaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+
+		err = job()
+		if err == nil || !isRetriableError(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// readVHDRanges reads the first size bytes of the VHD sequentially and sends
+// a job to upload each of their non-empty page ranges until they are read,
+// reading fails or the transfer is aborted.
+func (b BlobStorageClient) readVHDRanges(container, name string, vhd io.ReaderAt, size int64, jobs chan<- func() error, abort <-chan struct{}) error {
+	for offset := int64(0); offset < size; offset += vhdTransferChunkSize {
+		chunk := make([]byte, min64(vhdTransferChunkSize, size-offset))
+		_, err := vhd.ReadAt(chunk, offset)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		for _, p := range findNonEmptyPageRanges(chunk) {
+			start, data := offset+p.Start, chunk[p.Start:p.End+1]
+			job := func() error {
+				return b.PutPage(container, name, start, start+int64(len(data))-1, PageWriteTypeUpdate, data)
+			}
+
+			select {
+			case jobs <- job:
+			case <-abort:
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// sendVHDDownloadJobs sends a job to download each of the page ranges in
+// pieces of at most vhdTransferChunkSize and write their non-empty pages to
+// the file until all ranges are sent or the transfer is aborted.
+func (b BlobStorageClient) sendVHDDownloadJobs(container, name string, f io.WriterAt, ranges []PageRange, jobs chan<- func() error, abort <-chan struct{}) error {
+	for _, r := range ranges {
+		for start := r.Start; start <= r.End; start += vhdTransferChunkSize {
+			start, end := start, min64(start+vhdTransferChunkSize-1, r.End)
+			job := func() error {
+				return b.downloadVHDRange(container, name, f, start, end)
+			}
+
+			select {
+			case jobs <- job:
+			case <-abort:
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b BlobStorageClient) downloadVHDRange(container, name string, f io.WriterAt, start, end int64) error {
+	body, err := b.GetBlobRange(container, name, fmt.Sprintf("%d-%d", start, end))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	chunk := make([]byte, end-start+1)
+	_, err = io.ReadFull(body, chunk)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range findNonEmptyPageRanges(chunk) {
+		_, err = f.WriteAt(chunk[p.Start:p.End+1], start+p.Start)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findNonEmptyPageRanges returns the ranges of consecutive pages in chunk that
+// contain at least one non-zero byte. Range ends are inclusive, as in
+// PageRange responses.
+func findNonEmptyPageRanges(chunk []byte) []PageRange {
+	var out []PageRange
+	emptyPage := make([]byte, vhdPageSize)
+
+	start := int64(-1)
+	for i := int64(0); i < int64(len(chunk)); i += vhdPageSize {
+		end := min64(i+vhdPageSize, int64(len(chunk)))
+		if bytes.Equal(chunk[i:end], emptyPage[:end-i]) {
+			if start >= 0 {
+				out = append(out, PageRange{Start: start, End: i - 1})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		out = append(out, PageRange{Start: start, End: int64(len(chunk)) - 1})
+	}
+
+	return out
+}
+
+// verifyVHD checks that the VHD ends with the footer of a fixed size disk
+// and returns the footer to upload with it. If the virtual size of the disk
+// is not a multiple of 1 MB, the returned footer has the size rounded up and
+// the disk geometry and checksum updated to match.
+func verifyVHD(vhd io.ReaderAt, size int64) ([]byte, error) {
+	if size < vhdFooterSize || size%vhdPageSize != 0 {
+		return nil, fmt.Errorf("storage: VHD size (%d bytes) must be a multiple of %d bytes", size, vhdPageSize)
+	}
+
+	footer := make([]byte, vhdFooterSize)
+	_, err := vhd.ReadAt(footer, size-vhdFooterSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if string(footer[0:8]) != vhdFooterCookie {
+		return nil, errVHDFooterNotFound
+	}
+	if binary.BigEndian.Uint32(footer[64:68]) != vhdFooterChecksum(footer) {
+		return nil, errVHDFooterChecksum
+	}
+	if binary.BigEndian.Uint32(footer[60:64]) != vhdDiskTypeFixed {
+		return nil, errVHDNotFixed
+	}
+
+	virtualSize := int64(binary.BigEndian.Uint64(footer[48:56]))
+	if virtualSize != size-vhdFooterSize {
+		return nil, fmt.Errorf("storage: VHD virtual size (%d bytes) does not match the file size (%d bytes)", virtualSize, size)
+	}
+
+	if virtualSize%vhdSizeAlignment != 0 {
+		virtualSize += vhdSizeAlignment - virtualSize%vhdSizeAlignment
+		binary.BigEndian.PutUint64(footer[48:56], uint64(virtualSize))
+		binary.BigEndian.PutUint32(footer[56:60], vhdDiskGeometry(virtualSize))
+		binary.BigEndian.PutUint32(footer[64:68], vhdFooterChecksum(footer))
+	}
+
+	return footer, nil
+}
+
+// vhdFooterChecksum returns the one's complement of the sum of the bytes of
+// the footer, leaving out the checksum field itself.
+func vhdFooterChecksum(footer []byte) uint32 {
+	var sum uint32
+	for i, c := range footer {
+		if i >= 64 && i < 68 {
+			continue
+		}
+		sum += uint32(c)
+	}
+
+	return ^sum
+}
+
+// vhdDiskGeometry returns the cylinders, heads and sectors per track of a
+// disk of the given size, packed as in the VHD footer, using the algorithm of
+// the VHD specification.
+func vhdDiskGeometry(size int64) uint32 {
+	totalSectors := size / vhdPageSize
+	if totalSectors > 65535*16*255 {
+		totalSectors = 65535 * 16 * 255
+	}
+
+	var sectorsPerTrack, heads, cylinderTimesHeads int64
+	if totalSectors >= 65535*16*63 {
+		sectorsPerTrack = 255
+		heads = 16
+		cylinderTimesHeads = totalSectors / sectorsPerTrack
+	} else {
+		sectorsPerTrack = 17
+		cylinderTimesHeads = totalSectors / sectorsPerTrack
+		heads = (cylinderTimesHeads + 1023) / 1024
+		if heads < 4 {
+			heads = 4
+		}
+		if cylinderTimesHeads >= heads*1024 || heads > 16 {
+			sectorsPerTrack = 31
+			heads = 16
+			cylinderTimesHeads = totalSectors / sectorsPerTrack
+		}
+		if cylinderTimesHeads >= heads*1024 {
+			sectorsPerTrack = 63
+			heads = 16
+			cylinderTimesHeads = totalSectors / sectorsPerTrack
+		}
+	}
+	cylinders := cylinderTimesHeads / heads
+
+	return uint32(cylinders)<<16 | uint32(heads)<<8 | uint32(sectorsPerTrack)
+}
+
+// isRetriableError reports whether a failed request may succeed when sent
+// again. Client errors returned by the storage service are not retried,
+// except for content corrupted in transit.
+func isRetriableError(err error) bool {
+	if storageErr, ok := err.(StorageServiceError); ok {
+		return storageErr.StatusCode >= 500 || storageErr.Code == "Md5Mismatch"
+	}
+	return true
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_findNonEmptyPageRanges(t *testing.T) {
+	chunk := make([]byte, 5*vhdPageSize)
+	chunk[10] = 1                // page 0
+	chunk[vhdPageSize+511] = 1   // page 1
+	chunk[4*vhdPageSize+100] = 1 // page 4
+
+	out := findNonEmptyPageRanges(chunk)
+	expected := []PageRange{
+		{Start: 0, End: 2*vhdPageSize - 1},
+		{Start: 4 * vhdPageSize, End: 5*vhdPageSize - 1},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("Wrong page ranges. Expected: '%v', got: '%v'", expected, out)
+	}
+
+	if out := findNonEmptyPageRanges(make([]byte, vhdPageSize)); len(out) != 0 {
+		t.Errorf("Expected no page ranges for an empty chunk, got: '%v'", out)
+	}
+}
+
+func Test_verifyVHD(t *testing.T) {
+	size := int64(vhdSizeAlignment + vhdFooterSize)
+
+	vhd := testVHD(size, vhdDiskTypeFixed)
+	footer, err := verifyVHD(bytes.NewReader(vhd), size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(footer, vhd[size-vhdFooterSize:]) {
+		t.Error("Expected the footer of an aligned VHD to be unchanged")
+	}
+
+	if _, err := verifyVHD(bytes.NewReader(testVHD(size, 3)), size); err != errVHDNotFixed {
+		t.Errorf("Expected errVHDNotFixed for a dynamic VHD, got: %v", err)
+	}
+
+	if _, err := verifyVHD(bytes.NewReader(make([]byte, size)), size); err != errVHDFooterNotFound {
+		t.Errorf("Expected errVHDFooterNotFound for a file without footer, got: %v", err)
+	}
+
+	corrupt := testVHD(size, vhdDiskTypeFixed)
+	corrupt[size-vhdFooterSize+100] = 1
+	if _, err := verifyVHD(bytes.NewReader(corrupt), size); err != errVHDFooterChecksum {
+		t.Errorf("Expected errVHDFooterChecksum for a corrupt footer, got: %v", err)
+	}
+
+	if _, err := verifyVHD(bytes.NewReader(testVHD(size, vhdDiskTypeFixed)), size-1); err == nil {
+		t.Error("Expected error for a size which is not page aligned, got nil")
+	}
+}
+
+func Test_verifyVHD_UnalignedSize(t *testing.T) {
+	size := int64(vhdSizeAlignment + 4*vhdPageSize + vhdFooterSize)
+
+	footer, err := verifyVHD(bytes.NewReader(testVHD(size, vhdDiskTypeFixed)), size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out, expected := binary.BigEndian.Uint64(footer[48:56]), uint64(2*vhdSizeAlignment); out != expected {
+		t.Errorf("Wrong current size. Expected: '%d', got: '%d'", expected, out)
+	}
+	if out, expected := binary.BigEndian.Uint32(footer[56:60]), vhdDiskGeometry(2*vhdSizeAlignment); out != expected {
+		t.Errorf("Wrong disk geometry. Expected: '%x', got: '%x'", expected, out)
+	}
+	if out, expected := binary.BigEndian.Uint32(footer[64:68]), vhdFooterChecksum(footer); out != expected {
+		t.Errorf("Wrong checksum. Expected: '%x', got: '%x'", expected, out)
+	}
+}
+
+func Test_vhdDiskGeometry(t *testing.T) {
+	type test struct {
+		size                              int64
+		cylinders, heads, sectorsPerTrack uint32
+	}
+
+	tests := []test{
+		{vhdSizeAlignment, 30, 4, 17},
+		{30 * 1024 * vhdSizeAlignment, 62415, 16, 63},
+		{2048 * 1024 * vhdSizeAlignment, 65535, 16, 255},
+	}
+
+	for _, i := range tests {
+		expected := i.cylinders<<16 | i.heads<<8 | i.sectorsPerTrack
+		if out := vhdDiskGeometry(i.size); out != expected {
+			t.Errorf("Wrong disk geometry for %d bytes. Expected: '%x', got: '%x'", i.size, expected, out)
+		}
+	}
+}
+
+func TestUploadVHD(t *testing.T) {
+	cli, err := getBlobClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt := randContainer()
+	blob := randString(20) + ".vhd"
+
+	err = cli.CreateContainer(cnt, ContainerAccessTypePrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.DeleteContainer(cnt)
+
+	size := int64(vhdSizeAlignment + vhdFooterSize)
+	vhd := testVHD(size, vhdDiskTypeFixed)
+	copy(vhd[vhdPageSize:], randString(vhdPageSize))
+
+	err = cli.UploadVHD(cnt, blob, bytes.NewReader(vhd), size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the data page and the footer are expected to be written.
+	out, err := cli.GetPageRanges(cnt, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PageRange{
+		{Start: vhdPageSize, End: 2*vhdPageSize - 1},
+		{Start: size - vhdFooterSize, End: size - 1},
+	}
+	if !reflect.DeepEqual(out.PageList, expected) {
+		t.Fatalf("Wrong page ranges. Expected: '%v', got: '%v'", expected, out.PageList)
+	}
+}
+
+func TestDownloadVHD(t *testing.T) {
+	cli, err := getBlobClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt := randContainer()
+	blob := randString(20) + ".vhd"
+
+	err = cli.CreateContainer(cnt, ContainerAccessTypePrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.DeleteContainer(cnt)
+
+	size := int64(vhdSizeAlignment + vhdFooterSize)
+	vhd := testVHD(size, vhdDiskTypeFixed)
+	copy(vhd[3*vhdPageSize:], randString(2*vhdPageSize))
+
+	err = cli.UploadVHD(cnt, blob, bytes.NewReader(vhd), size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ioutil.TempFile("", "vhd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	err = cli.DownloadVHD(cli.GetBlobUrl(cnt, blob), f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, vhd) {
+		t.Fatalf("Downloaded VHD is wrong. Expected: %d bytes, got: %d bytes", len(vhd), len(out))
+	}
+}
+
+func Test_parseBlobURL(t *testing.T) {
+	cli, err := NewBasicClient("foo", "YmFy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobCli := cli.GetBlobService()
+
+	type test struct {
+		url               string
+		expectedContainer string
+		expectedName      string
+		expectedError     string
+	}
+
+	tests := []test{
+		{"https://foo.blob.core.windows.net/vhds/disk.vhd", "vhds", "disk.vhd", ""},
+		{"https://FOO.blob.core.windows.net/vhds/images/disk.vhd", "vhds", "images/disk.vhd", ""},
+		{"https://foo.blob.core.windows.net/disk.vhd", "$root", "disk.vhd", ""},
+		{"https://foo.blob.core.windows.net/vhds/", "", "", fmt.Sprintf(errBlobURLNotBlob, "https://foo.blob.core.windows.net/vhds/")},
+		{"https://bar.blob.core.windows.net/vhds/disk.vhd", "", "", fmt.Sprintf(errBlobURLAccount, "https://bar.blob.core.windows.net/vhds/disk.vhd")},
+	}
+
+	for _, i := range tests {
+		container, name, err := blobCli.parseBlobURL(i.url)
+		if len(i.expectedError) > 0 {
+			if err == nil || err.Error() != i.expectedError {
+				t.Errorf("Wrong error for %s. Expected: '%s', got: '%v'", i.url, i.expectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Wrong result for %s. Expected no error, got: '%s'", i.url, err)
+			continue
+		}
+		if container != i.expectedContainer || name != i.expectedName {
+			t.Errorf("Wrong blob for %s. Expected: '%s/%s', got: '%s/%s'", i.url, i.expectedContainer, i.expectedName, container, name)
+		}
+	}
+}
+
+func testVHD(size int64, diskType uint32) []byte {
+	vhd := make([]byte, size)
+	footer := vhd[size-vhdFooterSize:]
+	copy(footer, vhdFooterCookie)
+	binary.BigEndian.PutUint64(footer[48:56], uint64(size-vhdFooterSize))
+	binary.BigEndian.PutUint32(footer[60:64], diskType)
+	binary.BigEndian.PutUint32(footer[64:68], vhdFooterChecksum(footer))
+	return vhd
+}
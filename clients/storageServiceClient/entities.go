@@ -14,29 +14,112 @@ type StorageService struct {
 	Url                      string
 	ServiceName              string
 	StorageServiceProperties StorageServiceProperties
+	ExtendedProperties       ExtendedPropertyList
+}
+
+// IsCreated reports whether the storage account has finished provisioning.
+func (storageService StorageService) IsCreated() bool {
+	return storageService.StorageServiceProperties.Status == StorageServiceStatusCreated
 }
 
 type StorageServiceProperties struct {
 	Description           string
+	AffinityGroup         string
 	Location              string
 	Label                 string
-	Status                string
+	Status                StorageServiceStatus
 	Endpoints             []string `xml:"Endpoints>Endpoint"`
-	GeoReplicationEnabled string
+	GeoReplicationEnabled bool
 	GeoPrimaryRegion      string
+	StatusOfPrimary       GeoRegionStatus
+	LastGeoFailoverTime   string
+	GeoSecondaryRegion    string
+	StatusOfSecondary     GeoRegionStatus
+	CreationTime          string
+	CustomDomains         []CustomDomain `xml:"CustomDomains>CustomDomain"`
+	SecondaryReadEnabled  bool
+	AccountType           AccountType
+}
+
+type GeoRegionStatus string
+
+const (
+	GeoRegionStatusAvailable   GeoRegionStatus = "Available"
+	GeoRegionStatusUnavailable GeoRegionStatus = "Unavailable"
+)
+
+type StorageServiceStatus string
+
+const (
+	StorageServiceStatusCreating     StorageServiceStatus = "Creating"
+	StorageServiceStatusCreated      StorageServiceStatus = "Created"
+	StorageServiceStatusDeleting     StorageServiceStatus = "Deleting"
+	StorageServiceStatusDeleted      StorageServiceStatus = "Deleted"
+	StorageServiceStatusChanging     StorageServiceStatus = "Changing"
+	StorageServiceStatusResolvingDns StorageServiceStatus = "ResolvingDns"
+)
+
+type AccountType string
+
+const (
+	AccountTypeStandardLRS   AccountType = "Standard_LRS"
+	AccountTypeStandardGRS   AccountType = "Standard_GRS"
+	AccountTypeStandardZRS   AccountType = "Standard_ZRS"
+	AccountTypeStandardRAGRS AccountType = "Standard_RAGRS"
+	AccountTypePremiumLRS    AccountType = "Premium_LRS"
+)
+
+// StorageServiceOptions holds the optional settings of a new storage account.
+// Exactly one of Location and AffinityGroup has to be set. Label defaults to
+// the service name and AccountType to Standard_LRS.
+type StorageServiceOptions struct {
+	Location           string
+	AffinityGroup      string
+	AccountType        AccountType
+	Label              string
+	Description        string
+	ExtendedProperties map[string]string
 }
 
 type StorageServiceDeployment struct {
-	XMLName               xml.Name `xml:"CreateStorageServiceInput"`
-	Xmlns                 string   `xml:"xmlns,attr"`
-	ServiceName           string
-	Description           string
-	Label                 string
-	AffinityGroup         string `xml:",omitempty"`
-	Location              string `xml:",omitempty"`
-	GeoReplicationEnabled bool
-	ExtendedProperties    ExtendedPropertyList
-	SecondaryReadEnabled  bool
+	XMLName            xml.Name `xml:"CreateStorageServiceInput"`
+	Xmlns              string   `xml:"xmlns,attr"`
+	ServiceName        string
+	Description        string `xml:",omitempty"`
+	Label              string
+	AffinityGroup      string                `xml:",omitempty"`
+	Location           string                `xml:",omitempty"`
+	ExtendedProperties *ExtendedPropertyList `xml:",omitempty"`
+	AccountType        AccountType
+}
+
+// StorageServiceUpdateOptions holds the settings to change on an existing
+// storage account. Empty fields are left unchanged.
+type StorageServiceUpdateOptions struct {
+	AccountType        AccountType
+	Label              string
+	Description        string
+	CustomDomain       *CustomDomain
+	ExtendedProperties map[string]string
+}
+
+type UpdateStorageServiceInput struct {
+	XMLName            xml.Name              `xml:"UpdateStorageServiceInput"`
+	Xmlns              string                `xml:"xmlns,attr"`
+	Description        string                `xml:",omitempty"`
+	Label              string                `xml:",omitempty"`
+	ExtendedProperties *ExtendedPropertyList `xml:",omitempty"`
+	CustomDomains      *CustomDomainList     `xml:",omitempty"`
+	AccountType        AccountType           `xml:",omitempty"`
+}
+
+type CustomDomainList struct {
+	CustomDomain []CustomDomain
+}
+
+type CustomDomain struct {
+	Name             string
+	UseSubDomainName bool
 }
 
 type ExtendedPropertyList struct {
@@ -5,8 +5,9 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
-	azure "github.com/MSOpenTech/azure-sdk-for-go"
 	"strings"
+
+	azure "github.com/MSOpenTech/azure-sdk-for-go"
 )
 
 const (
@@ -14,8 +15,20 @@ const (
 	azureStorageServiceListURL = "services/storageservices"
 	azureStorageServiceURL     = "services/storageservices/%s"
 
-	blobEndpointNotFoundError = "Blob endpoint was not found in storage serice %s"
-	paramNotSpecifiedError    = "Parameter %s is not specified."
+	blobEndpointHost  = ".blob.core"
+	queueEndpointHost = ".queue.core"
+	tableEndpointHost = ".table.core"
+	fileEndpointHost  = ".file.core"
+
+	blobEndpointNotFoundError                = "Blob endpoint was not found in storage serice %s"
+	queueEndpointNotFoundError               = "Queue endpoint was not found in storage service %s"
+	tableEndpointNotFoundError               = "Table endpoint was not found in storage service %s"
+	fileEndpointNotFoundError                = "File endpoint was not found in storage service %s"
+	invalidAccountTypeError                  = "Invalid account type: %s. Valid values are 'Standard_LRS', 'Standard_GRS', 'Standard_ZRS', 'Standard_RAGRS' and 'Premium_LRS'."
+	locationAffinityGroupError               = "Exactly one of location and affinity group has to be specified."
+	storageServiceLocationMismatchError      = "Storage service %s is in location %s, not in %s."
+	storageServiceAffinityGroupMismatchError = "Storage service %s is in affinity group %s, not in %s."
+	paramNotSpecifiedError                   = "Parameter %s is not specified."
 )
 
 func GetStorageServiceList() (*StorageServiceList, error) {
@@ -34,6 +47,18 @@ func GetStorageServiceList() (*StorageServiceList, error) {
 	return storageServiceList, nil
 }
 
+// ListStorageServices returns all storage accounts of the subscription with
+// their full properties, including endpoints, geo-replication state and
+// account type, so callers can select accounts by any of them.
+func ListStorageServices() ([]StorageService, error) {
+	storageServiceList, err := GetStorageServiceList()
+	if err != nil {
+		return nil, err
+	}
+
+	return storageServiceList.StorageServices, nil
+}
+
 func GetStorageServiceByName(serviceName string) (*StorageService, error) {
 	if len(serviceName) == 0 {
 		return nil, fmt.Errorf(paramNotSpecifiedError, "serviceName")
@@ -59,13 +84,12 @@ func GetStorageServiceByLocation(location string) (*StorageService, error) {
 		return nil, fmt.Errorf(paramNotSpecifiedError, "location")
 	}
 
-	storageService := new(StorageService)
-	storageServiceList, err := GetStorageServiceList()
+	storageServices, err := ListStorageServices()
 	if err != nil {
-		return storageService, err
+		return nil, err
 	}
 
-	for _, storageService := range storageServiceList.StorageServices {
+	for _, storageService := range storageServices {
 		if storageService.StorageServiceProperties.Location != location {
 			continue
 		}
@@ -77,14 +101,32 @@ func GetStorageServiceByLocation(location string) (*StorageService, error) {
 }
 
 func CreateStorageService(name, location string) (*StorageService, error) {
+	if len(location) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "location")
+	}
+
+	return CreateStorageServiceWithOptions(name, StorageServiceOptions{Location: location})
+}
+
+// CreateStorageServiceWithOptions creates a storage account and waits for the
+// create operation to complete. The returned service reports the provisioning
+// state in StorageServiceProperties.Status; DNS resolution of the endpoints
+// can still be in progress when the operation completes.
+func CreateStorageServiceWithOptions(name string, options StorageServiceOptions) (*StorageService, error) {
 	if len(name) == 0 {
 		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
 	}
-	if len(location) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "location")
+	if (len(options.Location) == 0) == (len(options.AffinityGroup) == 0) {
+		return nil, errors.New(locationAffinityGroupError)
+	}
+	if len(options.AccountType) > 0 {
+		err := verifyAccountType(options.AccountType)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	storageDeploymentConfig := createStorageServiceDeploymentConf(name, location)
+	storageDeploymentConfig := createStorageServiceDeploymentConf(name, options)
 	deploymentBytes, err := xml.Marshal(storageDeploymentConfig)
 	if err != nil {
 		return nil, err
@@ -95,7 +137,11 @@ func CreateStorageService(name, location string) (*StorageService, error) {
 		return nil, err
 	}
 
-	azure.WaitAsyncOperation(requestId)
+	err = azure.WaitAsyncOperation(requestId)
+	if err != nil {
+		return nil, err
+	}
+
 	storageService, err := GetStorageServiceByName(storageDeploymentConfig.ServiceName)
 	if err != nil {
 		return nil, err
@@ -104,26 +150,244 @@ func CreateStorageService(name, location string) (*StorageService, error) {
 	return storageService, nil
 }
 
+// EnsureStorageService creates the storage account with the given options if
+// it does not exist yet. An existing account must be in the same location or
+// affinity group; its account type, label, description and extended
+// properties are updated where the options set them to a different value.
+// The returned bool reports whether the account was created or changed.
+func EnsureStorageService(name string, options StorageServiceOptions) (*StorageService, bool, error) {
+	if len(name) == 0 {
+		return nil, false, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if (len(options.Location) == 0) == (len(options.AffinityGroup) == 0) {
+		return nil, false, errors.New(locationAffinityGroupError)
+	}
+
+	// Look the account up in the list, as getting a missing account by name
+	// is retried as a failed request.
+	storageServices, err := ListStorageServices()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var existing *StorageService
+	for i := range storageServices {
+		if strings.EqualFold(storageServices[i].ServiceName, name) {
+			existing = &storageServices[i]
+			break
+		}
+	}
+
+	if existing == nil {
+		storageService, err := CreateStorageServiceWithOptions(name, options)
+		if err != nil {
+			return nil, false, err
+		}
+		return storageService, true, nil
+	}
+
+	properties := existing.StorageServiceProperties
+	if len(options.Location) > 0 && !strings.EqualFold(properties.Location, options.Location) {
+		return nil, false, fmt.Errorf(storageServiceLocationMismatchError, name, properties.Location, options.Location)
+	}
+	if len(options.AffinityGroup) > 0 && !strings.EqualFold(properties.AffinityGroup, options.AffinityGroup) {
+		return nil, false, fmt.Errorf(storageServiceAffinityGroupMismatchError, name, properties.AffinityGroup, options.AffinityGroup)
+	}
+
+	updateOptions, changed := createStorageServiceConvergeOptions(existing, options)
+	if !changed {
+		return existing, false, nil
+	}
+
+	err = UpdateStorageService(existing.ServiceName, updateOptions)
+	if err != nil {
+		return nil, false, err
+	}
+
+	storageService, err := GetStorageServiceByName(existing.ServiceName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return storageService, true, nil
+}
+
+// UpdateStorageService changes the account type, label, description, custom
+// domain or extended properties of an existing storage account.
+func UpdateStorageService(name string, options StorageServiceUpdateOptions) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if len(options.AccountType) > 0 {
+		err := verifyAccountType(options.AccountType)
+		if err != nil {
+			return err
+		}
+	}
+	if options.CustomDomain != nil && len(options.CustomDomain.Name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "options.CustomDomain.Name")
+	}
+
+	updateConfig := createStorageServiceUpdateConf(options)
+	updateBytes, err := xml.Marshal(updateConfig)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureStorageServiceURL, name)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", updateBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func DeleteStorageService(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	requestURL := fmt.Sprintf(azureStorageServiceURL, name)
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
 func GetBlobEndpoint(storageService *StorageService) (string, error) {
+	return getEndpoint(storageService, blobEndpointHost, blobEndpointNotFoundError)
+}
+
+func GetQueueEndpoint(storageService *StorageService) (string, error) {
+	return getEndpoint(storageService, queueEndpointHost, queueEndpointNotFoundError)
+}
+
+func GetTableEndpoint(storageService *StorageService) (string, error) {
+	return getEndpoint(storageService, tableEndpointHost, tableEndpointNotFoundError)
+}
+
+func GetFileEndpoint(storageService *StorageService) (string, error) {
+	return getEndpoint(storageService, fileEndpointHost, fileEndpointNotFoundError)
+}
+
+func getEndpoint(storageService *StorageService, host, notFoundError string) (string, error) {
 	for _, endpoint := range storageService.StorageServiceProperties.Endpoints {
-		if !strings.Contains(endpoint, ".blob.core") {
+		if !strings.Contains(endpoint, host) {
 			continue
 		}
 
 		return endpoint, nil
 	}
 
-	return "", errors.New(fmt.Sprintf(blobEndpointNotFoundError, storageService.ServiceName))
+	return "", errors.New(fmt.Sprintf(notFoundError, storageService.ServiceName))
 }
 
-func createStorageServiceDeploymentConf(name, location string) StorageServiceDeployment {
+func createStorageServiceDeploymentConf(name string, options StorageServiceOptions) StorageServiceDeployment {
 	storageServiceDeployment := StorageServiceDeployment{}
 
 	storageServiceDeployment.ServiceName = name
-	label := base64.StdEncoding.EncodeToString([]byte(name))
-	storageServiceDeployment.Label = label
-	storageServiceDeployment.Location = location
+	label := options.Label
+	if len(label) == 0 {
+		label = name
+	}
+	storageServiceDeployment.Label = base64.StdEncoding.EncodeToString([]byte(label))
+	storageServiceDeployment.Description = options.Description
+	storageServiceDeployment.Location = options.Location
+	storageServiceDeployment.AffinityGroup = options.AffinityGroup
+	storageServiceDeployment.AccountType = options.AccountType
+	if len(storageServiceDeployment.AccountType) == 0 {
+		storageServiceDeployment.AccountType = AccountTypeStandardLRS
+	}
+	storageServiceDeployment.ExtendedProperties = createExtendedPropertyList(options.ExtendedProperties)
 	storageServiceDeployment.Xmlns = azureXmlns
 
 	return storageServiceDeployment
 }
+
+func createStorageServiceUpdateConf(options StorageServiceUpdateOptions) UpdateStorageServiceInput {
+	updateConfig := UpdateStorageServiceInput{}
+	updateConfig.Xmlns = azureXmlns
+	updateConfig.Description = options.Description
+	if len(options.Label) > 0 {
+		updateConfig.Label = base64.StdEncoding.EncodeToString([]byte(options.Label))
+	}
+	updateConfig.ExtendedProperties = createExtendedPropertyList(options.ExtendedProperties)
+	if options.CustomDomain != nil {
+		updateConfig.CustomDomains = &CustomDomainList{CustomDomain: []CustomDomain{*options.CustomDomain}}
+	}
+	updateConfig.AccountType = options.AccountType
+
+	return updateConfig
+}
+
+// createStorageServiceConvergeOptions returns the update of the settings in
+// options which differ from those of the existing account and whether there
+// are any.
+func createStorageServiceConvergeOptions(existing *StorageService, options StorageServiceOptions) (StorageServiceUpdateOptions, bool) {
+	updateOptions := StorageServiceUpdateOptions{}
+	changed := false
+	properties := existing.StorageServiceProperties
+
+	if len(options.AccountType) > 0 && properties.AccountType != options.AccountType {
+		updateOptions.AccountType = options.AccountType
+		changed = true
+	}
+
+	if len(options.Label) > 0 {
+		// Labels are returned base64 encoded
+		label, err := base64.StdEncoding.DecodeString(properties.Label)
+		if err != nil || string(label) != options.Label {
+			updateOptions.Label = options.Label
+			changed = true
+		}
+	}
+
+	if len(options.Description) > 0 && properties.Description != options.Description {
+		updateOptions.Description = options.Description
+		changed = true
+	}
+
+	existingProperties := map[string]string{}
+	for _, property := range existing.ExtendedProperties.ExtendedProperty {
+		existingProperties[property.Name] = property.Value
+	}
+	for name, value := range options.ExtendedProperties {
+		if existingValue, ok := existingProperties[name]; ok && existingValue == value {
+			continue
+		}
+		if updateOptions.ExtendedProperties == nil {
+			updateOptions.ExtendedProperties = map[string]string{}
+		}
+		updateOptions.ExtendedProperties[name] = value
+		changed = true
+	}
+
+	return updateOptions, changed
+}
+
+func createExtendedPropertyList(properties map[string]string) *ExtendedPropertyList {
+	if len(properties) == 0 {
+		return nil
+	}
+
+	names := azure.SortedKeys(properties)
+
+	extendedProperties := &ExtendedPropertyList{}
+	for _, name := range names {
+		extendedProperties.ExtendedProperty = append(extendedProperties.ExtendedProperty, ExtendedProperty{Name: name, Value: properties[name]})
+	}
+
+	return extendedProperties
+}
+
+func verifyAccountType(accountType AccountType) error {
+	switch accountType {
+	case AccountTypeStandardLRS, AccountTypeStandardGRS, AccountTypeStandardZRS, AccountTypeStandardRAGRS, AccountTypePremiumLRS:
+		return nil
+	}
+
+	return fmt.Errorf(invalidAccountTypeError, accountType)
+}
@@ -0,0 +1,48 @@
+package subscriptionClient
+
+import (
+	"encoding/xml"
+)
+
+// Subscription holds the properties of the subscription along with its
+// quotas and how much of each quota is in use.
+type Subscription struct {
+	XMLName                    xml.Name `xml:"Subscription"`
+	Xmlns                      string   `xml:"xmlns,attr"`
+	SubscriptionID             string
+	SubscriptionName           string
+	SubscriptionStatus         string
+	AccountAdminLiveEmailId    string
+	ServiceAdminLiveEmailId    string
+	MaxCoreCount               int
+	MaxStorageAccounts         int
+	MaxHostedServices          int
+	CurrentCoreCount           int
+	CurrentHostedServices      int
+	CurrentStorageAccounts     int
+	MaxVirtualNetworkSites     int
+	CurrentVirtualNetworkSites int
+	MaxLocalNetworkSites       int
+	MaxDnsServers              int
+	MaxExtraVIPCount           int
+	OfferCategories            string
+	CreatedTime                string
+}
+
+// AvailableCores returns the number of cores which can still be used by new
+// role instances.
+func (subscription *Subscription) AvailableCores() int {
+	return subscription.MaxCoreCount - subscription.CurrentCoreCount
+}
+
+// AvailableHostedServices returns the number of hosted services which can
+// still be created.
+func (subscription *Subscription) AvailableHostedServices() int {
+	return subscription.MaxHostedServices - subscription.CurrentHostedServices
+}
+
+// AvailableStorageAccounts returns the number of storage accounts which can
+// still be created.
+func (subscription *Subscription) AvailableStorageAccounts() int {
+	return subscription.MaxStorageAccounts - subscription.CurrentStorageAccounts
+}
@@ -0,0 +1,24 @@
+package subscriptionClient
+
+import (
+	"encoding/xml"
+
+	azure "github.com/MSOpenTech/azure-sdk-for-go"
+)
+
+// GetSubscription returns the properties, quotas and current usage of the
+// currently active subscription.
+func GetSubscription() (*Subscription, error) {
+	response, err := azure.SendAzureGetSubscriptionRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := new(Subscription)
+	err = xml.Unmarshal(response, subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
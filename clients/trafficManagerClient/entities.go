@@ -0,0 +1,132 @@
+package trafficManagerClient
+
+import (
+	"encoding/xml"
+)
+
+type ProfileStatus string
+
+const (
+	ProfileStatusEnabled  ProfileStatus = "Enabled"
+	ProfileStatusDisabled ProfileStatus = "Disabled"
+)
+
+type ProfileList struct {
+	XMLName  xml.Name  `xml:"Profiles"`
+	Xmlns    string    `xml:"xmlns,attr"`
+	Profiles []Profile `xml:"Profile"`
+}
+
+// Profile is a Traffic Manager profile. A profile owns a domain name under
+// trafficmanager.net and the definitions that describe how requests for that
+// name are load balanced. Only one definition of a profile is enabled at a time.
+type Profile struct {
+	XMLName       xml.Name `xml:"Profile"`
+	Xmlns         string   `xml:"xmlns,attr"`
+	DomainName    string
+	Name          string
+	Status        ProfileStatus
+	StatusDetails ProfileStatusDetails
+	Definitions   []DefinitionStatusAndVersion `xml:"Definitions>DefinitionStatusAndVersion"`
+}
+
+type ProfileStatusDetails struct {
+	EnabledVersion int
+}
+
+type DefinitionStatusAndVersion struct {
+	Status  ProfileStatus
+	Version int
+}
+
+type CreateProfileInput struct {
+	XMLName    xml.Name `xml:"Profile"`
+	Xmlns      string   `xml:"xmlns,attr"`
+	DomainName string
+	Name       string
+}
+
+type UpdateProfileInput struct {
+	XMLName       xml.Name `xml:"Profile"`
+	Xmlns         string   `xml:"xmlns,attr"`
+	Status        ProfileStatus
+	StatusDetails *ProfileStatusDetails `xml:",omitempty"`
+}
+
+type LoadBalancingMethod string
+
+const (
+	LoadBalancingMethodPerformance LoadBalancingMethod = "Performance"
+	LoadBalancingMethodFailover    LoadBalancingMethod = "Failover"
+	LoadBalancingMethodRoundRobin  LoadBalancingMethod = "RoundRobin"
+)
+
+type EndpointType string
+
+const (
+	EndpointTypeCloudService   EndpointType = "CloudService"
+	EndpointTypeAzureWebsite   EndpointType = "AzureWebsite"
+	EndpointTypeAny            EndpointType = "Any"
+	EndpointTypeTrafficManager EndpointType = "TrafficManager"
+)
+
+type EndpointStatus string
+
+const (
+	EndpointStatusEnabled  EndpointStatus = "Enabled"
+	EndpointStatusDisabled EndpointStatus = "Disabled"
+)
+
+// Definition is a version of the load balancing configuration of a profile.
+// Definitions cannot be changed once created; changing the configuration
+// creates a new definition which becomes the enabled one.
+type Definition struct {
+	XMLName    xml.Name `xml:"Definition"`
+	Xmlns      string   `xml:"xmlns,attr"`
+	DnsOptions DnsOptions
+	Status     ProfileStatus `xml:",omitempty"`
+	Version    int           `xml:",omitempty"`
+	Monitors   []Monitor     `xml:"Monitors>Monitor"`
+	Policy     Policy
+}
+
+type DnsOptions struct {
+	TimeToLiveInSeconds int
+}
+
+// Monitor describes how Traffic Manager probes the endpoints of a definition.
+// Only HTTP and HTTPS monitors with an interval of 30 seconds, a timeout of 10
+// seconds and 3 tolerated failures are supported by the service.
+type Monitor struct {
+	IntervalInSeconds         int
+	TimeoutInSeconds          int
+	ToleratedNumberOfFailures int
+	Protocol                  string
+	Port                      int
+	HttpOptions               HttpOptions
+}
+
+type HttpOptions struct {
+	Verb               string
+	RelativePath       string
+	ExpectedStatusCode int
+}
+
+// Policy holds the endpoints of a definition. With the Failover method the
+// order of the endpoints is their priority, with the RoundRobin method the
+// weights of the endpoints set the share of the traffic they receive.
+type Policy struct {
+	LoadBalancingMethod LoadBalancingMethod
+	Endpoints           []Endpoint `xml:"Endpoints>Endpoint"`
+	MonitorStatus       string     `xml:",omitempty"`
+}
+
+type Endpoint struct {
+	DomainName        string
+	Status            EndpointStatus
+	Type              EndpointType `xml:",omitempty"`
+	Location          string       `xml:",omitempty"`
+	MinChildEndpoints int          `xml:",omitempty"`
+	Weight            int          `xml:",omitempty"`
+	MonitorStatus     string       `xml:",omitempty"`
+}
@@ -0,0 +1,371 @@
+package trafficManagerClient
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+
+	azure "github.com/MSOpenTech/azure-sdk-for-go"
+)
+
+const (
+	azureXmlns          = "http://schemas.microsoft.com/windowsazure"
+	azureProfileListURL = "services/WATM/profiles"
+	azureProfileURL     = "services/WATM/profiles/%s"
+	azureDefinitionsURL = "services/WATM/profiles/%s/definitions"
+	azureDefinitionURL  = "services/WATM/profiles/%s/definitions/%d"
+
+	invalidLoadBalancingMethodError = "Invalid load balancing method: %s. Valid values are 'Performance', 'Failover' and 'RoundRobin'."
+	invalidEndpointLocationError    = "Endpoint %s has no location. Endpoints of type 'Any' need a location with the Performance method."
+	invalidEndpointPriorityError    = "Invalid priority %d for endpoint %s. Priorities range from 1 to %d."
+	invalidEndpointWeightError      = "Invalid weight %d for endpoint %s. Weights range from 1 to 1000."
+	endpointExistsError             = "Endpoint %s already exists in profile %s."
+	endpointNotFoundError           = "Endpoint %s was not found in profile %s."
+	noEndpointsError                = "Definition has no endpoints."
+	noEnabledDefinitionError        = "Profile %s has no enabled definition."
+	paramNotSpecifiedError          = "Parameter %s is not specified."
+
+	maxEndpointWeight = 1000
+)
+
+// CreateProfile creates a Traffic Manager profile for the given domain name,
+// which has to be a subdomain of trafficmanager.net. The profile does not
+// route any traffic until a definition is created for it.
+func CreateProfile(name, domainName string) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if len(domainName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "domainName")
+	}
+
+	profile := CreateProfileInput{
+		Xmlns:      azureXmlns,
+		DomainName: domainName,
+		Name:       name,
+	}
+	profileBytes, err := xml.Marshal(profile)
+	if err != nil {
+		return err
+	}
+
+	_, err = azure.SendAzurePostRequest(azureProfileListURL, profileBytes)
+	return err
+}
+
+func ListProfiles() ([]Profile, error) {
+	response, err := azure.SendAzureGetRequest(azureProfileListURL)
+	if err != nil {
+		return nil, err
+	}
+
+	profileList := new(ProfileList)
+	err = xml.Unmarshal(response, profileList)
+	if err != nil {
+		return nil, err
+	}
+
+	return profileList.Profiles, nil
+}
+
+func GetProfile(name string) (*Profile, error) {
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	response, err := azure.SendAzureGetRequest(fmt.Sprintf(azureProfileURL, name))
+	if err != nil {
+		return nil, err
+	}
+
+	profile := new(Profile)
+	err = xml.Unmarshal(response, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+// DeleteProfile deletes the profile along with all of its definitions.
+func DeleteProfile(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	_, err := azure.SendAzureDeleteRequest(fmt.Sprintf(azureProfileURL, name))
+	return err
+}
+
+// EnableProfile enables the profile with its currently enabled definition.
+func EnableProfile(name string) error {
+	return updateProfileStatus(name, ProfileStatusEnabled)
+}
+
+// DisableProfile disables the profile. DNS queries for its domain name are
+// no longer answered until the profile is enabled again.
+func DisableProfile(name string) error {
+	return updateProfileStatus(name, ProfileStatusDisabled)
+}
+
+// CreateDefinition creates a new definition for the profile, which replaces
+// the currently enabled definition. The Status, Version and MonitorStatus
+// fields are set by the service and are ignored.
+func CreateDefinition(profileName string, definition Definition) error {
+	if len(profileName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "profileName")
+	}
+
+	err := verifyDefinition(definition)
+	if err != nil {
+		return err
+	}
+
+	definition.Xmlns = azureXmlns
+	definition.Status = ""
+	definition.Version = 0
+	definition.Policy.MonitorStatus = ""
+	endpoints := make([]Endpoint, len(definition.Policy.Endpoints))
+	for i, endpoint := range definition.Policy.Endpoints {
+		endpoint.MonitorStatus = ""
+		endpoints[i] = endpoint
+	}
+	definition.Policy.Endpoints = endpoints
+
+	definitionBytes, err := xml.Marshal(definition)
+	if err != nil {
+		return err
+	}
+
+	_, err = azure.SendAzurePostRequest(fmt.Sprintf(azureDefinitionsURL, profileName), definitionBytes)
+	return err
+}
+
+// GetDefinition returns the definition of the profile with the given
+// version. Use GetProfile to find the version of the enabled definition.
+func GetDefinition(profileName string, version int) (*Definition, error) {
+	if len(profileName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "profileName")
+	}
+	if version <= 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "version")
+	}
+
+	response, err := azure.SendAzureGetRequest(fmt.Sprintf(azureDefinitionURL, profileName, version))
+	if err != nil {
+		return nil, err
+	}
+
+	definition := new(Definition)
+	err = xml.Unmarshal(response, definition)
+	if err != nil {
+		return nil, err
+	}
+
+	return definition, nil
+}
+
+// AddEndpoint adds the endpoint to the enabled definition of the profile. With
+// the Failover method the endpoint is added with the lowest priority.
+func AddEndpoint(profileName string, endpoint Endpoint) error {
+	if len(endpoint.DomainName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "endpoint.DomainName")
+	}
+
+	return updateDefinition(profileName, func(definition *Definition) error {
+		if findEndpoint(definition, endpoint.DomainName) >= 0 {
+			return fmt.Errorf(endpointExistsError, endpoint.DomainName, profileName)
+		}
+
+		definition.Policy.Endpoints = append(definition.Policy.Endpoints, endpoint)
+		return nil
+	})
+}
+
+// RemoveEndpoint removes the endpoint with the given domain name from the
+// enabled definition of the profile.
+func RemoveEndpoint(profileName, domainName string) error {
+	if len(domainName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "domainName")
+	}
+
+	return updateDefinition(profileName, func(definition *Definition) error {
+		i := findEndpoint(definition, domainName)
+		if i < 0 {
+			return fmt.Errorf(endpointNotFoundError, domainName, profileName)
+		}
+
+		endpoints := definition.Policy.Endpoints
+		definition.Policy.Endpoints = append(endpoints[:i:i], endpoints[i+1:]...)
+		return nil
+	})
+}
+
+// SetEndpointStatus enables or disables the endpoint with the given domain
+// name without removing it from the profile, for example to drain one side
+// of a blue/green deployment.
+func SetEndpointStatus(profileName, domainName string, status EndpointStatus) error {
+	if len(domainName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "domainName")
+	}
+
+	return updateEndpoint(profileName, domainName, func(endpoint *Endpoint) error {
+		endpoint.Status = status
+		return nil
+	})
+}
+
+// SetEndpointWeight sets the weight of the endpoint with the given domain
+// name. Weights are only used by the RoundRobin method.
+func SetEndpointWeight(profileName, domainName string, weight int) error {
+	if len(domainName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "domainName")
+	}
+	if weight < 1 || weight > maxEndpointWeight {
+		return fmt.Errorf(invalidEndpointWeightError, weight, domainName)
+	}
+
+	return updateEndpoint(profileName, domainName, func(endpoint *Endpoint) error {
+		endpoint.Weight = weight
+		return nil
+	})
+}
+
+// SetEndpointPriority moves the endpoint with the given domain name to the
+// given position in the failover order of the profile, where 1 is the
+// endpoint that receives all traffic while it is healthy. The order of the
+// other endpoints is kept.
+func SetEndpointPriority(profileName, domainName string, priority int) error {
+	if len(domainName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "domainName")
+	}
+
+	return updateDefinition(profileName, func(definition *Definition) error {
+		i := findEndpoint(definition, domainName)
+		if i < 0 {
+			return fmt.Errorf(endpointNotFoundError, domainName, profileName)
+		}
+
+		endpoints := definition.Policy.Endpoints
+		if priority < 1 || priority > len(endpoints) {
+			return fmt.Errorf(invalidEndpointPriorityError, priority, domainName, len(endpoints))
+		}
+
+		definition.Policy.Endpoints = moveEndpoint(endpoints, i, priority)
+		return nil
+	})
+}
+
+//Region private methods starts
+
+// updateDefinition applies change to a copy of the enabled definition of the
+// profile and creates the result as the new definition. Definitions are
+// immutable, so this is the only way to change the endpoints of a profile.
+func updateDefinition(profileName string, change func(*Definition) error) error {
+	profile, err := GetProfile(profileName)
+	if err != nil {
+		return err
+	}
+	if profile.StatusDetails.EnabledVersion <= 0 {
+		return fmt.Errorf(noEnabledDefinitionError, profileName)
+	}
+
+	definition, err := GetDefinition(profileName, profile.StatusDetails.EnabledVersion)
+	if err != nil {
+		return err
+	}
+
+	err = change(definition)
+	if err != nil {
+		return err
+	}
+
+	return CreateDefinition(profileName, *definition)
+}
+
+func updateEndpoint(profileName, domainName string, change func(*Endpoint) error) error {
+	return updateDefinition(profileName, func(definition *Definition) error {
+		i := findEndpoint(definition, domainName)
+		if i < 0 {
+			return fmt.Errorf(endpointNotFoundError, domainName, profileName)
+		}
+
+		return change(&definition.Policy.Endpoints[i])
+	})
+}
+
+func findEndpoint(definition *Definition, domainName string) int {
+	for i, endpoint := range definition.Policy.Endpoints {
+		if strings.EqualFold(endpoint.DomainName, domainName) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// moveEndpoint returns the endpoints with the endpoint at index i moved to
+// the given 1-based priority, keeping the order of the other endpoints.
+func moveEndpoint(endpoints []Endpoint, i, priority int) []Endpoint {
+	endpoint := endpoints[i]
+	reordered := append(endpoints[:i:i], endpoints[i+1:]...)
+	return append(reordered[:priority-1:priority-1], append([]Endpoint{endpoint}, reordered[priority-1:]...)...)
+}
+
+func updateProfileStatus(name string, status ProfileStatus) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	// The status applies to a definition, Azure rejects updates without one
+	profile, err := GetProfile(name)
+	if err != nil {
+		return err
+	}
+	if profile.StatusDetails.EnabledVersion <= 0 {
+		return fmt.Errorf(noEnabledDefinitionError, name)
+	}
+
+	profileInput := UpdateProfileInput{
+		Xmlns:         azureXmlns,
+		Status:        status,
+		StatusDetails: &ProfileStatusDetails{EnabledVersion: profile.StatusDetails.EnabledVersion},
+	}
+	profileBytes, err := xml.Marshal(profileInput)
+	if err != nil {
+		return err
+	}
+
+	_, err = azure.SendAzurePutRequest(fmt.Sprintf(azureProfileURL, name), "", profileBytes)
+	return err
+}
+
+func verifyDefinition(definition Definition) error {
+	switch definition.Policy.LoadBalancingMethod {
+	case LoadBalancingMethodPerformance, LoadBalancingMethodFailover, LoadBalancingMethodRoundRobin:
+	default:
+		return fmt.Errorf(invalidLoadBalancingMethodError, definition.Policy.LoadBalancingMethod)
+	}
+
+	if len(definition.Monitors) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "definition.Monitors")
+	}
+	if len(definition.Policy.Endpoints) == 0 {
+		return errors.New(noEndpointsError)
+	}
+
+	for _, endpoint := range definition.Policy.Endpoints {
+		if len(endpoint.DomainName) == 0 {
+			return fmt.Errorf(paramNotSpecifiedError, "endpoint.DomainName")
+		}
+		if definition.Policy.LoadBalancingMethod == LoadBalancingMethodPerformance &&
+			endpoint.Type == EndpointTypeAny && len(endpoint.Location) == 0 {
+			return fmt.Errorf(invalidEndpointLocationError, endpoint.DomainName)
+		}
+	}
+
+	return nil
+}
+
+//Region private methods ends
@@ -0,0 +1,69 @@
+package trafficManagerClient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_moveEndpoint(t *testing.T) {
+	type test struct {
+		name     string
+		i        int
+		priority int
+		expected []string
+	}
+
+	tests := []test{
+		{"to the top", 2, 1, []string{"c", "a", "b", "d"}},
+		{"to the bottom", 0, 4, []string{"b", "c", "d", "a"}},
+		{"up", 3, 2, []string{"a", "d", "b", "c"}},
+		{"down", 1, 3, []string{"a", "c", "b", "d"}},
+		{"same position", 1, 2, []string{"a", "b", "c", "d"}},
+	}
+
+	for _, i := range tests {
+		endpoints := testEndpoints("a", "b", "c", "d")
+		out := moveEndpoint(endpoints, i.i, i.priority)
+
+		var domainNames []string
+		for _, endpoint := range out {
+			domainNames = append(domainNames, endpoint.DomainName)
+		}
+		if !reflect.DeepEqual(domainNames, i.expected) {
+			t.Fatalf("Wrong order for %s. Expected: '%v', got: '%v'", i.name, i.expected, domainNames)
+		}
+
+		if original := testEndpoints("a", "b", "c", "d"); !reflect.DeepEqual(endpoints, original) {
+			t.Fatalf("Wrong endpoints for %s. Expected the input to be unchanged: '%v', got: '%v'", i.name, original, endpoints)
+		}
+	}
+}
+
+func Test_findEndpoint(t *testing.T) {
+	definition := &Definition{Policy: Policy{Endpoints: testEndpoints("a.cloudapp.net", "b.cloudapp.net")}}
+
+	type test struct {
+		domainName string
+		expected   int
+	}
+
+	tests := []test{
+		{"a.cloudapp.net", 0},
+		{"B.CloudApp.net", 1},
+		{"c.cloudapp.net", -1},
+	}
+
+	for _, i := range tests {
+		if out := findEndpoint(definition, i.domainName); out != i.expected {
+			t.Fatalf("Wrong index for %s. Expected: '%d', got: '%d'", i.domainName, i.expected, out)
+		}
+	}
+}
+
+func testEndpoints(domainNames ...string) []Endpoint {
+	var endpoints []Endpoint
+	for _, domainName := range domainNames {
+		endpoints = append(endpoints, Endpoint{DomainName: domainName, Status: EndpointStatusEnabled})
+	}
+	return endpoints
+}
@@ -0,0 +1,102 @@
+package vmClient
+
+//Region public methods starts
+
+// Clone returns a deep copy of the role. The Add* and Set* functions of this
+// package work on a copy of the role they are given, so a role can be used
+// as the base of several variants.
+func (role *Role) Clone() *Role {
+	if role == nil {
+		return nil
+	}
+
+	clone := *role
+
+	if role.ConfigurationSets.ConfigurationSet != nil {
+		clone.ConfigurationSets.ConfigurationSet = make([]ConfigurationSet, len(role.ConfigurationSets.ConfigurationSet))
+		for i, configurationSet := range role.ConfigurationSets.ConfigurationSet {
+			clone.ConfigurationSets.ConfigurationSet[i] = configurationSet.clone()
+		}
+	}
+
+	if role.ResourceExtensionReferences.ResourceExtensionReference != nil {
+		clone.ResourceExtensionReferences.ResourceExtensionReference = make([]ResourceExtensionReference, len(role.ResourceExtensionReferences.ResourceExtensionReference))
+		for i, reference := range role.ResourceExtensionReferences.ResourceExtensionReference {
+			reference.ResourceExtensionParameterValues.ResourceExtensionParameterValue = append([]ResourceExtensionParameter(nil), reference.ResourceExtensionParameterValues.ResourceExtensionParameterValue...)
+			clone.ResourceExtensionReferences.ResourceExtensionReference[i] = reference
+		}
+	}
+
+	clone.DataVirtualHardDisks.DataVirtualHardDisk = append([]DataVirtualHardDisk(nil), role.DataVirtualHardDisks.DataVirtualHardDisk...)
+
+	if role.DebugSettings != nil {
+		debugSettings := *role.DebugSettings
+		clone.DebugSettings = &debugSettings
+	}
+
+	if role.Certificates != nil {
+		clone.Certificates = make([]RoleCertificate, len(role.Certificates))
+		for i, certificate := range role.Certificates {
+			certificate.Data = append([]byte(nil), certificate.Data...)
+			clone.Certificates[i] = certificate
+		}
+	}
+
+	clone.DnsServers = append([]DnsServer(nil), role.DnsServers...)
+
+	return &clone
+}
+
+//Region public methods ends
+
+//Region private methods starts
+
+func (configurationSet ConfigurationSet) clone() ConfigurationSet {
+	clone := configurationSet
+
+	if configurationSet.EnableAutomaticUpdates != nil {
+		enableAutomaticUpdates := *configurationSet.EnableAutomaticUpdates
+		clone.EnableAutomaticUpdates = &enableAutomaticUpdates
+	}
+
+	if configurationSet.WinRM != nil {
+		winRM := *configurationSet.WinRM
+		winRM.Listeners.Listener = append([]WinRMListener(nil), winRM.Listeners.Listener...)
+		clone.WinRM = &winRM
+	}
+
+	if configurationSet.AdditionalUnattendContent != nil {
+		unattendContent := *configurationSet.AdditionalUnattendContent
+		passes := make([]UnattendPass, len(unattendContent.Passes.UnattendPass))
+		for i, pass := range unattendContent.Passes.UnattendPass {
+			components := make([]UnattendComponent, len(pass.Components.UnattendComponent))
+			for j, component := range pass.Components.UnattendComponent {
+				component.ComponentSettings.ComponentSetting = append([]ComponentSetting(nil), component.ComponentSettings.ComponentSetting...)
+				components[j] = component
+			}
+			pass.Components.UnattendComponent = components
+			passes[i] = pass
+		}
+		unattendContent.Passes.UnattendPass = passes
+		clone.AdditionalUnattendContent = &unattendContent
+	}
+
+	clone.InputEndpoints.InputEndpoint = append([]InputEndpoint(nil), configurationSet.InputEndpoints.InputEndpoint...)
+
+	if configurationSet.SubnetNames != nil {
+		subnetNames := SubnetNames{SubnetName: append([]string(nil), configurationSet.SubnetNames.SubnetName...)}
+		clone.SubnetNames = &subnetNames
+	}
+
+	if configurationSet.PublicIPs != nil {
+		publicIPs := PublicIPs{PublicIP: append([]PublicIP(nil), configurationSet.PublicIPs.PublicIP...)}
+		clone.PublicIPs = &publicIPs
+	}
+
+	clone.SSH.PublicKeys.PublicKey = append([]PublicKey(nil), configurationSet.SSH.PublicKeys.PublicKey...)
+	clone.SSH.KeyPairs.KeyPair = append([]KeyPair(nil), configurationSet.SSH.KeyPairs.KeyPair...)
+
+	return clone
+}
+
+//Region private methods ends
@@ -0,0 +1,109 @@
+package vmClient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRole_Clone(t *testing.T) {
+	type test struct {
+		name   string
+		modify func(role *Role)
+	}
+
+	tests := []test{
+		{"configuration set", func(role *Role) { role.ConfigurationSets.ConfigurationSet[0].ComputerName = "other" }},
+		{"automatic updates", func(role *Role) { *role.ConfigurationSets.ConfigurationSet[0].EnableAutomaticUpdates = false }},
+		{"winrm listener", func(role *Role) {
+			role.ConfigurationSets.ConfigurationSet[0].WinRM.Listeners.Listener[0].Protocol = "Https"
+		}},
+		{"unattend pass", func(role *Role) {
+			role.ConfigurationSets.ConfigurationSet[0].AdditionalUnattendContent.Passes.UnattendPass[0].PassName = "other"
+		}},
+		{"unattend component", func(role *Role) {
+			role.ConfigurationSets.ConfigurationSet[0].AdditionalUnattendContent.Passes.UnattendPass[0].Components.UnattendComponent[0].ComponentName = "other"
+		}},
+		{"unattend setting", func(role *Role) {
+			role.ConfigurationSets.ConfigurationSet[0].AdditionalUnattendContent.Passes.UnattendPass[0].Components.UnattendComponent[0].ComponentSettings.ComponentSetting[0].Content = "other"
+		}},
+		{"input endpoint", func(role *Role) {
+			role.ConfigurationSets.ConfigurationSet[1].InputEndpoints.InputEndpoint[0].Port = 443
+		}},
+		{"subnet name", func(role *Role) { role.ConfigurationSets.ConfigurationSet[1].SubnetNames.SubnetName[0] = "other" }},
+		{"public ip", func(role *Role) { role.ConfigurationSets.ConfigurationSet[1].PublicIPs.PublicIP[0].Name = "other" }},
+		{"ssh public key", func(role *Role) {
+			role.ConfigurationSets.ConfigurationSet[0].SSH.PublicKeys.PublicKey[0].Path = "other"
+		}},
+		{"ssh key pair", func(role *Role) { role.ConfigurationSets.ConfigurationSet[0].SSH.KeyPairs.KeyPair[0].Path = "other" }},
+		{"extension parameter", func(role *Role) {
+			role.ResourceExtensionReferences.ResourceExtensionReference[0].ResourceExtensionParameterValues.ResourceExtensionParameterValue[0].Value = "other"
+		}},
+		{"data disk", func(role *Role) { role.DataVirtualHardDisks.DataVirtualHardDisk[0].Lun = 1 }},
+		{"debug settings", func(role *Role) { role.DebugSettings.BootDiagnosticsEnabled = false }},
+		{"certificate", func(role *Role) { role.Certificates[0].Data[0] = 'x' }},
+		{"dns server", func(role *Role) { role.DnsServers[0].Address = "10.0.0.5" }},
+	}
+
+	for _, i := range tests {
+		role := testCloneRole()
+		clone := role.Clone()
+		if !reflect.DeepEqual(clone, role) {
+			t.Fatalf("Wrong clone for %s. Expected: '%v', got: '%v'", i.name, role, clone)
+		}
+
+		i.modify(clone)
+		if !reflect.DeepEqual(role, testCloneRole()) {
+			t.Fatalf("Changing the %s of the clone changed the role: '%v'", i.name, role)
+		}
+	}
+}
+
+func TestRole_CloneNil(t *testing.T) {
+	var role *Role
+	if clone := role.Clone(); clone != nil {
+		t.Fatalf("Wrong clone. Expected: 'nil', got: '%v'", clone)
+	}
+}
+
+func testCloneRole() *Role {
+	enableAutomaticUpdates := true
+
+	return &Role{
+		RoleName: "myvm",
+		ConfigurationSets: ConfigurationSets{ConfigurationSet: []ConfigurationSet{
+			{
+				ConfigurationSetType:   "WindowsProvisioningConfiguration",
+				ComputerName:           "myvm",
+				EnableAutomaticUpdates: &enableAutomaticUpdates,
+				WinRM:                  &WinRM{Listeners: WinRMListenerList{Listener: []WinRMListener{{Protocol: "Http"}}}},
+				AdditionalUnattendContent: &AdditionalUnattendContent{Passes: UnattendPassList{UnattendPass: []UnattendPass{{
+					PassName: "oobeSystem",
+					Components: UnattendComponentList{UnattendComponent: []UnattendComponent{{
+						ComponentName:     "Microsoft-Windows-Shell-Setup",
+						ComponentSettings: ComponentSettingList{ComponentSetting: []ComponentSetting{{SettingName: "AutoLogon", Content: "content"}}},
+					}}},
+				}}}},
+				SSH: SSH{
+					PublicKeys: PublicKeyList{PublicKey: []PublicKey{{Fingerprint: "fingerprint", Path: "/home/azureuser/.ssh/authorized_keys"}}},
+					KeyPairs:   KeyPairList{KeyPair: []KeyPair{{Fingerprint: "fingerprint", Path: "/home/azureuser/.ssh/id_rsa"}}},
+				},
+			},
+			{
+				ConfigurationSetType: "NetworkConfiguration",
+				InputEndpoints:       InputEndpoints{InputEndpoint: []InputEndpoint{{Name: "web", Protocol: "tcp", Port: 80, LocalPort: 80}}},
+				SubnetNames:          &SubnetNames{SubnetName: []string{"subnet"}},
+				PublicIPs:            &PublicIPs{PublicIP: []PublicIP{{Name: "ip"}}},
+			},
+		}},
+		ResourceExtensionReferences: ResourceExtensionReferences{ResourceExtensionReference: []ResourceExtensionReference{{
+			ReferenceName: "docker",
+			ResourceExtensionParameterValues: ResourceExtensionParameterValues{ResourceExtensionParameterValue: []ResourceExtensionParameter{
+				{Key: "key", Value: "value", Type: "Public"},
+			}},
+		}}},
+		DataVirtualHardDisks: DataVirtualHardDisks{DataVirtualHardDisk: []DataVirtualHardDisk{{DiskLabel: "data", Lun: 0}}},
+		DebugSettings:        &DebugSettings{BootDiagnosticsEnabled: true},
+		Certificates:         []RoleCertificate{{Data: []byte("certificate")}},
+		DnsServers:           []DnsServer{{Name: "dns", Address: "10.0.0.4"}},
+	}
+}
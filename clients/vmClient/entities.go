@@ -2,184 +2,756 @@ package vmClient
 
 import (
 	"encoding/xml"
+	"fmt"
+
+	"github.com/MSOpenTech/azure-sdk-for-go/clients/storageServiceClient"
 )
 
 type VMDeployment struct {
-	XMLName          xml.Name `xml:"Deployment"`
-	Xmlns            string   `xml:"xmlns,attr"`
-	Name             string
-	DeploymentSlot   string
-	Status           string `xml:",omitempty"`
-	Label            string
-	Url              string `xml:",omitempty"`
-	RoleList         RoleList
-	RoleInstanceList RoleInstanceList `xml:",omitempty"`
-	VirtualIPs       VirtualIPs       `xml:",omitempty"`
+	XMLName            xml.Name          `xml:"Deployment" json:"-"`
+	Xmlns              string            `xml:"xmlns,attr" json:"-"`
+	Name               string            `json:"name,omitempty"`
+	DeploymentSlot     string            `json:"deploymentSlot,omitempty"`
+	Status             string            `xml:",omitempty" json:"status,omitempty"`
+	Label              string            `json:"label,omitempty"`
+	Url                string            `xml:",omitempty" json:"url,omitempty"`
+	RoleList           RoleList          `json:"roleList,omitempty"`
+	VirtualNetworkName string            `xml:",omitempty" json:"virtualNetworkName,omitempty"`
+	Dns                *Dns              `xml:",omitempty" json:"dns,omitempty"`
+	LoadBalancers      *LoadBalancerList `xml:",omitempty" json:"loadBalancers,omitempty"`
+	RoleInstanceList   RoleInstanceList  `xml:",omitempty" json:"roleInstanceList,omitempty"`
+	VirtualIPs         VirtualIPs        `xml:",omitempty" json:"virtualIPs,omitempty"`
+}
+
+type VMDeploymentOptions struct {
+	DeploymentName string         `json:"deploymentName,omitempty"`
+	Label          string         `json:"label,omitempty"`
+	LoadBalancers  []LoadBalancer `json:"loadBalancers,omitempty"`
 }
 
 type RoleList struct {
-	Role []*Role
+	Role []*Role `json:"role,omitempty"`
 }
 
 type RoleInstanceList struct {
-	RoleInstance []*RoleInstance
+	RoleInstance []*RoleInstance `json:"roleInstance,omitempty"`
 }
 
 type RoleInstance struct {
-	RoleName          string
-	InstanceName      string
-	InstanceStatus    string
-	InstanceSize      string
-	PowerState        string
-	IpAddress         string
-	InstanceEndpoints InstanceEndpoints `xml:",omitempty"`
+	RoleName                          string                      `json:"roleName,omitempty"`
+	InstanceName                      string                      `json:"instanceName,omitempty"`
+	InstanceStatus                    RoleInstanceStatus          `json:"instanceStatus,omitempty"`
+	InstanceUpgradeDomain             int                         `json:"instanceUpgradeDomain,omitempty"`
+	InstanceFaultDomain               int                         `json:"instanceFaultDomain,omitempty"`
+	InstanceSize                      string                      `json:"instanceSize,omitempty"`
+	InstanceStateDetails              string                      `json:"instanceStateDetails,omitempty"`
+	InstanceErrorCode                 string                      `json:"instanceErrorCode,omitempty"`
+	PowerState                        PowerState                  `json:"powerState,omitempty"`
+	IpAddress                         string                      `json:"ipAddress,omitempty"`
+	InstanceEndpoints                 InstanceEndpoints           `xml:",omitempty" json:"instanceEndpoints,omitempty"`
+	HostName                          string                      `json:"hostName,omitempty"`
+	RemoteAccessCertificateThumbprint string                      `json:"remoteAccessCertificateThumbprint,omitempty"`
+	GuestAgentStatus                  *GuestAgentStatus           `json:"guestAgentStatus,omitempty"`
+	ResourceExtensionStatusList       ResourceExtensionStatusList `json:"resourceExtensionStatusList,omitempty"`
+}
+
+type GuestAgentStatus struct {
+	ProtocolVersion   string            `json:"protocolVersion,omitempty"`
+	Timestamp         string            `json:"timestamp,omitempty"`
+	GuestAgentVersion string            `json:"guestAgentVersion,omitempty"`
+	Status            string            `json:"status,omitempty"`
+	Code              int               `json:"code,omitempty"`
+	FormattedMessage  *FormattedMessage `json:"formattedMessage,omitempty"`
+}
+
+type FormattedMessage struct {
+	Language string `json:"language,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+type ResourceExtensionStatusList struct {
+	ResourceExtensionStatus []ResourceExtensionStatus `json:"resourceExtensionStatus,omitempty"`
+}
+
+type ResourceExtensionStatus struct {
+	HandlerName            string                  `json:"handlerName,omitempty"`
+	Version                string                  `json:"version,omitempty"`
+	Status                 string                  `json:"status,omitempty"`
+	Code                   int                     `json:"code,omitempty"`
+	FormattedMessage       *FormattedMessage       `json:"formattedMessage,omitempty"`
+	ExtensionSettingStatus *ExtensionSettingStatus `json:"extensionSettingStatus,omitempty"`
+}
+
+// IsFailed reports whether the extension handler is not running or the last
+// run of the extension reported an error.
+func (extensionStatus *ResourceExtensionStatus) IsFailed() bool {
+	if extensionStatus.Status == extensionHandlerStatusNotReady || extensionStatus.Status == extensionHandlerStatusUnresponsive {
+		return true
+	}
+
+	return extensionStatus.ExtensionSettingStatus != nil && extensionStatus.ExtensionSettingStatus.Status == extensionSettingStatusError
+}
+
+type ExtensionSettingStatus struct {
+	Timestamp        string            `json:"timestamp,omitempty"`
+	Name             string            `json:"name,omitempty"`
+	Operation        string            `json:"operation,omitempty"`
+	Status           string            `json:"status,omitempty"`
+	Code             int               `json:"code,omitempty"`
+	FormattedMessage *FormattedMessage `json:"formattedMessage,omitempty"`
+	SubStatusList    SubStatusList     `json:"subStatusList,omitempty"`
+}
+
+type SubStatusList struct {
+	SubStatus []SubStatus `json:"subStatus,omitempty"`
+}
+
+type SubStatus struct {
+	Name             string            `json:"name,omitempty"`
+	Status           string            `json:"status,omitempty"`
+	FormattedMessage *FormattedMessage `json:"formattedMessage,omitempty"`
+}
+
+type RoleInstanceStatus string
+
+const (
+	RoleInstanceStatusRoleStateUnknown    RoleInstanceStatus = "RoleStateUnknown"
+	RoleInstanceStatusCreatingVM          RoleInstanceStatus = "CreatingVM"
+	RoleInstanceStatusStartingVM          RoleInstanceStatus = "StartingVM"
+	RoleInstanceStatusCreatingRole        RoleInstanceStatus = "CreatingRole"
+	RoleInstanceStatusStartingRole        RoleInstanceStatus = "StartingRole"
+	RoleInstanceStatusReadyRole           RoleInstanceStatus = "ReadyRole"
+	RoleInstanceStatusBusyRole            RoleInstanceStatus = "BusyRole"
+	RoleInstanceStatusStoppingRole        RoleInstanceStatus = "StoppingRole"
+	RoleInstanceStatusStoppingVM          RoleInstanceStatus = "StoppingVM"
+	RoleInstanceStatusDeletingVM          RoleInstanceStatus = "DeletingVM"
+	RoleInstanceStatusStoppedVM           RoleInstanceStatus = "StoppedVM"
+	RoleInstanceStatusRestartingRole      RoleInstanceStatus = "RestartingRole"
+	RoleInstanceStatusCyclingRole         RoleInstanceStatus = "CyclingRole"
+	RoleInstanceStatusFailedStartingRole  RoleInstanceStatus = "FailedStartingRole"
+	RoleInstanceStatusFailedStartingVM    RoleInstanceStatus = "FailedStartingVM"
+	RoleInstanceStatusUnresponsiveRole    RoleInstanceStatus = "UnresponsiveRole"
+	RoleInstanceStatusStoppedDeallocated  RoleInstanceStatus = "StoppedDeallocated"
+	RoleInstanceStatusPreparing           RoleInstanceStatus = "Preparing"
+	RoleInstanceStatusProvisioningFailed  RoleInstanceStatus = "ProvisioningFailed"
+	RoleInstanceStatusProvisioningTimeout RoleInstanceStatus = "ProvisioningTimeout"
+)
+
+type PowerState string
+
+const (
+	PowerStateStarting PowerState = "Starting"
+	PowerStateStarted  PowerState = "Started"
+	PowerStateStopping PowerState = "Stopping"
+	PowerStateStopped  PowerState = "Stopped"
+	PowerStateUnknown  PowerState = "Unknown"
+)
+
+// IsReady reports whether the role instance has finished provisioning and
+// its guest agent reports the role as ready.
+func (roleInstance *RoleInstance) IsReady() bool {
+	return roleInstance.InstanceStatus == RoleInstanceStatusReadyRole
+}
+
+// IsRunning reports whether the virtual machine behind the role instance is
+// powered on, regardless of the role status reported by the guest agent.
+func (roleInstance *RoleInstance) IsRunning() bool {
+	return roleInstance.PowerState == PowerStateStarted
+}
+
+// IsStopped reports whether the role instance is stopped, either still
+// allocated or deallocated.
+func (roleInstance *RoleInstance) IsStopped() bool {
+	return roleInstance.InstanceStatus == RoleInstanceStatusStoppedVM || roleInstance.IsDeallocated()
+}
+
+// IsDeallocated reports whether the role instance is stopped and its compute
+// resources have been released.
+func (roleInstance *RoleInstance) IsDeallocated() bool {
+	return roleInstance.InstanceStatus == RoleInstanceStatusStoppedDeallocated
+}
+
+// IsFailed reports whether the role instance reached a status it will not
+// recover from without intervention.
+func (roleInstance *RoleInstance) IsFailed() bool {
+	switch roleInstance.InstanceStatus {
+	case RoleInstanceStatusFailedStartingRole,
+		RoleInstanceStatusFailedStartingVM,
+		RoleInstanceStatusProvisioningFailed,
+		RoleInstanceStatusProvisioningTimeout:
+		return true
+	}
+
+	return false
+}
+
+// IsReady reports whether every role instance in the deployment is ready.
+func (deployment *VMDeployment) IsReady() bool {
+	for _, roleInstance := range deployment.RoleInstanceList.RoleInstance {
+		if !roleInstance.IsReady() {
+			return false
+		}
+	}
+
+	return len(deployment.RoleInstanceList.RoleInstance) > 0
+}
+
+// IsRunning reports whether every role instance in the deployment is
+// powered on.
+func (deployment *VMDeployment) IsRunning() bool {
+	for _, roleInstance := range deployment.RoleInstanceList.RoleInstance {
+		if !roleInstance.IsRunning() {
+			return false
+		}
+	}
+
+	return len(deployment.RoleInstanceList.RoleInstance) > 0
+}
+
+// IsFailed reports whether any role instance in the deployment has failed.
+func (deployment *VMDeployment) IsFailed() bool {
+	for _, roleInstance := range deployment.RoleInstanceList.RoleInstance {
+		if roleInstance.IsFailed() {
+			return true
+		}
+	}
+
+	return false
 }
 
 type InstanceEndpoints struct {
-	InstanceEndpoint []InstanceEndpoint
+	InstanceEndpoint []InstanceEndpoint `json:"instanceEndpoint,omitempty"`
 }
 
 type InstanceEndpoint struct {
-	Name       string
-	Vip        string
-	PublicPort int
-	LocalPort  int
-	Protocol   string
+	Name       string `json:"name,omitempty"`
+	Vip        string `json:"vip,omitempty"`
+	PublicPort int    `json:"publicPort,omitempty"`
+	LocalPort  int    `json:"localPort,omitempty"`
+	Protocol   string `json:"protocol,omitempty"`
+}
+
+// DeploymentEventType is the kind of change a DeploymentEvent reports.
+type DeploymentEventType string
+
+const (
+	DeploymentEventInstanceAdded          DeploymentEventType = "InstanceAdded"
+	DeploymentEventInstanceRemoved        DeploymentEventType = "InstanceRemoved"
+	DeploymentEventInstanceStatusChanged  DeploymentEventType = "InstanceStatusChanged"
+	DeploymentEventEndpointAdded          DeploymentEventType = "EndpointAdded"
+	DeploymentEventEndpointRemoved        DeploymentEventType = "EndpointRemoved"
+	DeploymentEventEndpointChanged        DeploymentEventType = "EndpointChanged"
+	DeploymentEventExtensionStatusChanged DeploymentEventType = "ExtensionStatusChanged"
+	DeploymentEventError                  DeploymentEventType = "Error"
+)
+
+// DeploymentEvent is a change of a deployment found by WatchDeployment.
+// Previous* hold the state before the change and are nil for additions, the
+// other pointers hold the state after it and are nil for removals. Only the
+// fields that belong to the event type are set; Err is set for
+// DeploymentEventError, when the deployment could not be read.
+type DeploymentEvent struct {
+	Type                    DeploymentEventType      `json:"type,omitempty"`
+	RoleName                string                   `json:"roleName,omitempty"`
+	InstanceName            string                   `json:"instanceName,omitempty"`
+	PreviousInstance        *RoleInstance            `json:"previousInstance,omitempty"`
+	Instance                *RoleInstance            `json:"instance,omitempty"`
+	PreviousEndpoint        *InstanceEndpoint        `json:"previousEndpoint,omitempty"`
+	Endpoint                *InstanceEndpoint        `json:"endpoint,omitempty"`
+	PreviousExtensionStatus *ResourceExtensionStatus `json:"previousExtensionStatus,omitempty"`
+	ExtensionStatus         *ResourceExtensionStatus `json:"extensionStatus,omitempty"`
+	Err                     error                    `json:"-"`
+}
+
+type RoleInstanceNetworkInfo struct {
+	RoleName         string             `json:"roleName,omitempty"`
+	InstanceName     string             `json:"instanceName,omitempty"`
+	VirtualIPAddress string             `json:"virtualIPAddress,omitempty"`
+	IPAddress        string             `json:"ipAddress,omitempty"`
+	Endpoints        []InstanceEndpoint `json:"endpoints,omitempty"`
+}
+
+type SSHConnectionInfo struct {
+	DnsName   string `json:"dnsName,omitempty"`
+	IPAddress string `json:"ipAddress,omitempty"`
+	Port      int    `json:"port,omitempty"`
 }
 
 type Role struct {
-	RoleName                    string
-	RoleType                    string
-	ConfigurationSets           ConfigurationSets
-	ResourceExtensionReferences ResourceExtensionReferences `xml:",omitempty"`
-	OSVirtualHardDisk           OSVirtualHardDisk
-	RoleSize                    string
-	ProvisionGuestAgent         bool
-	UseCertAuth                 bool   `xml:"-"`
-	CertPath                    string `xml:"-"`
+	RoleName                    string                      `json:"roleName,omitempty"`
+	RoleType                    string                      `json:"roleType,omitempty"`
+	ConfigurationSets           ConfigurationSets           `json:"configurationSets,omitempty"`
+	ResourceExtensionReferences ResourceExtensionReferences `xml:",omitempty" json:"resourceExtensionReferences,omitempty"`
+	AvailabilitySetName         string                      `xml:",omitempty" json:"availabilitySetName,omitempty"`
+	DataVirtualHardDisks        DataVirtualHardDisks        `xml:",omitempty" json:"dataVirtualHardDisks,omitempty"`
+	OSVirtualHardDisk           OSVirtualHardDisk           `json:"osVirtualHardDisk,omitempty"`
+	RoleSize                    string                      `json:"roleSize,omitempty"`
+	ProvisionGuestAgent         bool                        `json:"provisionGuestAgent,omitempty"`
+	DebugSettings               *DebugSettings              `xml:",omitempty" json:"debugSettings,omitempty"`
+	UseCertAuth                 bool                        `xml:"-" json:"useCertAuth,omitempty"`
+	CertPath                    string                      `xml:"-" json:"certPath,omitempty"`
+	Certificates                []RoleCertificate           `xml:"-" json:"certificates,omitempty"`
+	VirtualNetworkName          string                      `xml:"-" json:"virtualNetworkName,omitempty"`
+	DnsServers                  []DnsServer                 `xml:"-" json:"dnsServers,omitempty"`
+	ETag                        string                      `xml:"-" json:"eTag,omitempty"`
+}
+
+type DebugSettings struct {
+	BootDiagnosticsEnabled   bool   `json:"bootDiagnosticsEnabled,omitempty"`
+	ConsoleScreenshotBlobUri string `xml:",omitempty" json:"consoleScreenshotBlobUri,omitempty"`
+	SerialOutputBlobUri      string `xml:",omitempty" json:"serialOutputBlobUri,omitempty"`
+}
+
+// PreconditionFailedError is returned by UpdateRole when the role was changed
+// by someone else after it was retrieved with GetRole.
+type PreconditionFailedError struct {
+	RoleName string `json:"roleName,omitempty"`
+	ETag     string `json:"eTag,omitempty"`
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return fmt.Sprintf(rolePreconditionFailedError, e.RoleName, e.ETag)
+}
+
+type Dns struct {
+	DnsServers DnsServerList `json:"dnsServers,omitempty"`
+}
+
+type DnsServerList struct {
+	DnsServer []DnsServer `json:"dnsServer,omitempty"`
+}
+
+type DnsServer struct {
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+type PersistentVMRole struct {
+	XMLName xml.Name `xml:"PersistentVMRole" json:"-"`
+	Xmlns   string   `xml:"xmlns,attr" json:"-"`
+	Role
+}
+
+// RoleCertificate is a certificate to install on a role. Its password is not
+// written to JSON.
+type RoleCertificate struct {
+	Data     []byte `json:"data,omitempty"`
+	Password string `json:"-"`
 }
 
 type ConfigurationSets struct {
-	ConfigurationSet []ConfigurationSet
+	ConfigurationSet []ConfigurationSet `json:"configurationSet,omitempty"`
 }
 
 type ResourceExtensionReferences struct {
-	ResourceExtensionReference []ResourceExtensionReference
+	ResourceExtensionReference []ResourceExtensionReference `json:"resourceExtensionReference,omitempty"`
 }
 
 type InputEndpoints struct {
-	InputEndpoint []InputEndpoint
+	InputEndpoint []InputEndpoint `json:"inputEndpoint,omitempty"`
 }
 
 type ResourceExtensionReference struct {
-	ReferenceName                    string
-	Publisher                        string
-	Name                             string
-	Version                          string
-	ResourceExtensionParameterValues ResourceExtensionParameterValues `xml:",omitempty"`
-	State                            string
+	ReferenceName                    string                           `json:"referenceName,omitempty"`
+	Publisher                        string                           `json:"publisher,omitempty"`
+	Name                             string                           `json:"name,omitempty"`
+	Version                          string                           `json:"version,omitempty"`
+	ResourceExtensionParameterValues ResourceExtensionParameterValues `xml:",omitempty" json:"resourceExtensionParameterValues,omitempty"`
+	State                            string                           `json:"state,omitempty"`
 }
 
 type ResourceExtensionParameterValues struct {
-	ResourceExtensionParameterValue []ResourceExtensionParameter
+	ResourceExtensionParameterValue []ResourceExtensionParameter `json:"resourceExtensionParameterValue,omitempty"`
 }
 
 type ResourceExtensionParameter struct {
-	Key   string
-	Value string
-	Type  string
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+type DataVirtualHardDisks struct {
+	DataVirtualHardDisk []DataVirtualHardDisk `json:"dataVirtualHardDisk,omitempty"`
+}
+
+type DataVirtualHardDisk struct {
+	HostCaching         HostCachingType `xml:",omitempty" json:"hostCaching,omitempty"`
+	DiskLabel           string          `xml:",omitempty" json:"diskLabel,omitempty"`
+	DiskName            string          `xml:",omitempty" json:"diskName,omitempty"`
+	Lun                 int             `json:"lun,omitempty"`
+	LogicalDiskSizeInGB int             `xml:",omitempty" json:"logicalDiskSizeInGB,omitempty"`
+	MediaLink           string          `xml:",omitempty" json:"mediaLink,omitempty"`
+}
+
+type HostCachingType string
+
+const (
+	HostCachingTypeNone      HostCachingType = "None"
+	HostCachingTypeReadOnly  HostCachingType = "ReadOnly"
+	HostCachingTypeReadWrite HostCachingType = "ReadWrite"
+)
+
+type OSDiskMediaOptions struct {
+	StorageAccount       string `json:"storageAccount,omitempty"`
+	Container            string `json:"container,omitempty"`
+	BlobName             string `json:"blobName,omitempty"`
+	CreateStorageAccount bool   `json:"createStorageAccount,omitempty"`
 }
 
 type OSVirtualHardDisk struct {
-	MediaLink       string
-	SourceImageName string
-	HostCaching     string `xml:",omitempty"`
-	DiskName        string `xml:",omitempty"`
-	OS              string `xml:",omitempty"`
+	HostCaching     HostCachingType `xml:",omitempty" json:"hostCaching,omitempty"`
+	DiskName        string          `xml:",omitempty" json:"diskName,omitempty"`
+	MediaLink       string          `xml:",omitempty" json:"mediaLink,omitempty"`
+	SourceImageName string          `xml:",omitempty" json:"sourceImageName,omitempty"`
+	OS              string          `xml:",omitempty" json:"os,omitempty"`
 }
 
+// ConfigurationSet holds the provisioning and network settings of a role. The
+// passwords are not written to JSON.
 type ConfigurationSet struct {
-	ConfigurationSetType             string
-	HostName                         string `xml:",omitempty"`
-	UserName                         string `xml:",omitempty"`
-	UserPassword                     string `xml:",omitempty"`
-	DisableSshPasswordAuthentication bool
-	InputEndpoints                   InputEndpoints `xml:",omitempty"`
-	SSH                              SSH            `xml:",omitempty"`
-	CustomData                       string         `xml:",omitempty"`
+	ConfigurationSetType             string                     `json:"configurationSetType,omitempty"`
+	ComputerName                     string                     `xml:",omitempty" json:"computerName,omitempty"`
+	AdminPassword                    string                     `xml:",omitempty" json:"-"`
+	EnableAutomaticUpdates           *bool                      `xml:",omitempty" json:"enableAutomaticUpdates,omitempty"`
+	TimeZone                         string                     `xml:",omitempty" json:"timeZone,omitempty"`
+	WinRM                            *WinRM                     `xml:",omitempty" json:"winRM,omitempty"`
+	AdminUsername                    string                     `xml:",omitempty" json:"adminUsername,omitempty"`
+	AdditionalUnattendContent        *AdditionalUnattendContent `xml:",omitempty" json:"additionalUnattendContent,omitempty"`
+	HostName                         string                     `xml:",omitempty" json:"hostName,omitempty"`
+	UserName                         string                     `xml:",omitempty" json:"userName,omitempty"`
+	UserPassword                     string                     `xml:",omitempty" json:"-"`
+	DisableSshPasswordAuthentication bool                       `json:"disableSshPasswordAuthentication,omitempty"`
+	InputEndpoints                   InputEndpoints             `xml:",omitempty" json:"inputEndpoints,omitempty"`
+	SubnetNames                      *SubnetNames               `xml:",omitempty" json:"subnetNames,omitempty"`
+	PublicIPs                        *PublicIPs                 `xml:",omitempty" json:"publicIPs,omitempty"`
+	SSH                              SSH                        `xml:",omitempty" json:"ssh,omitempty"`
+	CustomData                       string                     `xml:",omitempty" json:"customData,omitempty"`
+}
+
+type SubnetNames struct {
+	SubnetName []string `json:"subnetName,omitempty"`
+}
+
+type PublicIPs struct {
+	PublicIP []PublicIP `json:"publicIP,omitempty"`
+}
+
+type PublicIP struct {
+	Name                 string `json:"name,omitempty"`
+	IdleTimeoutInMinutes int    `xml:",omitempty" json:"idleTimeoutInMinutes,omitempty"`
+}
+
+type WinRM struct {
+	Listeners WinRMListenerList `json:"listeners,omitempty"`
+}
+
+type WinRMListenerList struct {
+	Listener []WinRMListener `json:"listener,omitempty"`
+}
+
+type WinRMListener struct {
+	CertificateThumbprint string `xml:",omitempty" json:"certificateThumbprint,omitempty"`
+	Protocol              string `json:"protocol,omitempty"`
+}
+
+type AdditionalUnattendContent struct {
+	Passes UnattendPassList `json:"passes,omitempty"`
+}
+
+type UnattendPassList struct {
+	UnattendPass []UnattendPass `json:"unattendPass,omitempty"`
+}
+
+type UnattendPass struct {
+	PassName   string                `json:"passName,omitempty"`
+	Components UnattendComponentList `json:"components,omitempty"`
+}
+
+type UnattendComponentList struct {
+	UnattendComponent []UnattendComponent `json:"unattendComponent,omitempty"`
+}
+
+type UnattendComponent struct {
+	ComponentName     string               `json:"componentName,omitempty"`
+	ComponentSettings ComponentSettingList `json:"componentSettings,omitempty"`
+}
+
+type ComponentSettingList struct {
+	ComponentSetting []ComponentSetting `json:"componentSetting,omitempty"`
+}
+
+type ComponentSetting struct {
+	SettingName string `json:"settingName,omitempty"`
+	Content     string `json:"content,omitempty"`
 }
 
 type SSH struct {
-	PublicKeys PublicKeyList
+	PublicKeys PublicKeyList `json:"publicKeys,omitempty"`
+	KeyPairs   KeyPairList   `xml:",omitempty" json:"keyPairs,omitempty"`
 }
 
 type PublicKeyList struct {
-	PublicKey []PublicKey
+	PublicKey []PublicKey `json:"publicKey,omitempty"`
 }
 
 type PublicKey struct {
-	Fingerprint string
-	Path        string
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Path        string `json:"path,omitempty"`
+}
+
+type KeyPairList struct {
+	KeyPair []KeyPair `json:"keyPair,omitempty"`
+}
+
+type KeyPair struct {
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Path        string `json:"path,omitempty"`
 }
 
 type InputEndpoint struct {
-	LocalPort int
-	Name      string
-	Port      int
-	Protocol  string
-	Vip       string
+	LocalPort            int    `json:"localPort,omitempty"`
+	Name                 string `json:"name,omitempty"`
+	Port                 int    `json:"port,omitempty"`
+	Protocol             string `json:"protocol,omitempty"`
+	Vip                  string `json:"vip,omitempty"`
+	LoadBalancerName     string `xml:",omitempty" json:"loadBalancerName,omitempty"`
+	IdleTimeoutInMinutes int    `xml:",omitempty" json:"idleTimeoutInMinutes,omitempty"`
 }
 
 type ServiceCertificate struct {
-	XMLName           xml.Name `xml:"CertificateFile"`
-	Xmlns             string   `xml:"xmlns,attr"`
-	Data              string
-	CertificateFormat string
-	Password          string `xml:",omitempty"`
+	XMLName           xml.Name `xml:"CertificateFile" json:"-"`
+	Xmlns             string   `xml:"xmlns,attr" json:"-"`
+	Data              string   `json:"data,omitempty"`
+	CertificateFormat string   `json:"certificateFormat,omitempty"`
+	Password          string   `xml:",omitempty" json:"-"`
 }
 
 type StartRoleOperation struct {
-	Xmlns         string `xml:"xmlns,attr"`
-	OperationType string
+	Xmlns         string `xml:"xmlns,attr" json:"-"`
+	OperationType string `json:"operationType,omitempty"`
 }
 
 type ShutdownRoleOperation struct {
-	Xmlns         string `xml:"xmlns,attr"`
-	OperationType string
+	Xmlns              string             `xml:"xmlns,attr" json:"-"`
+	OperationType      string             `json:"operationType,omitempty"`
+	PostShutdownAction PostShutdownAction `xml:",omitempty" json:"postShutdownAction,omitempty"`
+}
+
+type PostShutdownAction string
+
+const (
+	PostShutdownActionStopped            PostShutdownAction = "Stopped"
+	PostShutdownActionStoppedDeallocated PostShutdownAction = "StoppedDeallocated"
+)
+
+type StartRolesOperation struct {
+	Xmlns string   `xml:"xmlns,attr" json:"-"`
+	Roles []string `xml:"Roles>Name" json:"roles,omitempty"`
+}
+
+type ShutdownRolesOperation struct {
+	Xmlns              string             `xml:"xmlns,attr" json:"-"`
+	Roles              []string           `xml:"Roles>Name" json:"roles,omitempty"`
+	PostShutdownAction PostShutdownAction `json:"postShutdownAction,omitempty"`
 }
 
 type RestartRoleOperation struct {
-	Xmlns         string `xml:"xmlns,attr"`
-	OperationType string
+	Xmlns         string `xml:"xmlns,attr" json:"-"`
+	OperationType string `json:"operationType,omitempty"`
 }
 
 type RoleSizeList struct {
-	XMLName   xml.Name   `xml:"RoleSizes"`
-	Xmlns     string     `xml:"xmlns,attr"`
-	RoleSizes []RoleSize `xml:"RoleSize"`
+	XMLName   xml.Name   `xml:"RoleSizes" json:"-"`
+	Xmlns     string     `xml:"xmlns,attr" json:"-"`
+	RoleSizes []RoleSize `xml:"RoleSize" json:"roleSizes,omitempty"`
 }
 
 type RoleSize struct {
-	Name                               string
-	Label                              string
-	Cores                              int
-	MemoryInMb                         int
-	SupportedByWebWorkerRoles          bool
-	SupportedByVirtualMachines         bool
-	MaxDataDiskCount                   int
-	WebWorkerResourceDiskSizeInMb      int
-	VirtualMachineResourceDiskSizeInMb int
+	Name                               string `json:"name,omitempty"`
+	Label                              string `json:"label,omitempty"`
+	Cores                              int    `json:"cores,omitempty"`
+	MemoryInMb                         int    `json:"memoryInMb,omitempty"`
+	SupportedByWebWorkerRoles          bool   `json:"supportedByWebWorkerRoles,omitempty"`
+	SupportedByVirtualMachines         bool   `json:"supportedByVirtualMachines,omitempty"`
+	MaxDataDiskCount                   int    `json:"maxDataDiskCount,omitempty"`
+	WebWorkerResourceDiskSizeInMb      int    `json:"webWorkerResourceDiskSizeInMb,omitempty"`
+	VirtualMachineResourceDiskSizeInMb int    `json:"virtualMachineResourceDiskSizeInMb,omitempty"`
+}
+
+// clone returns a copy of the role size list which does not share its slice.
+func (roleSizeList RoleSizeList) clone() RoleSizeList {
+	clone := roleSizeList
+	if roleSizeList.RoleSizes != nil {
+		clone.RoleSizes = append([]RoleSize(nil), roleSizeList.RoleSizes...)
+	}
+
+	return clone
+}
+
+// CoreQuota compares the cores requested by a batch of roles with the core
+// quota of the subscription. It is returned by CheckCoreQuota.
+type CoreQuota struct {
+	MaxCores                 int            `json:"maxCores"`
+	CurrentCores             int            `json:"currentCores"`
+	RequestedCores           int            `json:"requestedCores"`
+	RequestedCoresByRoleSize map[string]int `json:"requestedCoresByRoleSize,omitempty"`
+}
+
+// AvailableCores returns the number of cores the subscription has left.
+func (quota *CoreQuota) AvailableCores() int {
+	return quota.MaxCores - quota.CurrentCores
+}
+
+// Fits reports whether the requested cores are within the cores the
+// subscription has left.
+func (quota *CoreQuota) Fits() bool {
+	return quota.RequestedCores <= quota.AvailableCores()
+}
+
+// ProvisioningPlan describes the storage accounts, cloud services and
+// virtual machines ProvisionVMs creates.
+type ProvisioningPlan struct {
+	StorageServices []StorageServicePlan `json:"storageServices,omitempty"`
+	CloudServices   []CloudServicePlan   `json:"cloudServices,omitempty"`
+	// MaxParallelism limits the number of requests sent at the same time,
+	// it defaults to 4.
+	MaxParallelism int `json:"maxParallelism,omitempty"`
+	// RollbackOnFailure makes ProvisionVMs delete everything it created when
+	// any step fails.
+	RollbackOnFailure bool `json:"rollbackOnFailure,omitempty"`
+}
+
+// StorageServicePlan is a storage account of a ProvisioningPlan. Accounts
+// which already exist are used as they are.
+type StorageServicePlan struct {
+	Name    string                                     `json:"name,omitempty"`
+	Options storageServiceClient.StorageServiceOptions `json:"options,omitempty"`
+}
+
+// CloudServicePlan is a cloud service of a ProvisioningPlan along with the
+// roles of the deployment created in it. A cloud service which already exists
+// in the subscription is used if it is in Location, and if it already has a
+// deployment the roles are added to it. Options.DeploymentName is required.
+//
+// Roles created from an image without a media link, such as those built by
+// RoleBuilder.Build, get their OS disk in StorageAccount once the storage
+// accounts of the plan exist. StorageAccount can be an account of the plan or
+// an existing one; if it is empty an existing account in Location is used.
+type CloudServicePlan struct {
+	Name           string              `json:"name,omitempty"`
+	Location       string              `json:"location,omitempty"`
+	ReverseDnsFqdn string              `json:"reverseDnsFqdn,omitempty"`
+	StorageAccount string              `json:"storageAccount,omitempty"`
+	Roles          []*Role             `json:"roles,omitempty"`
+	Options        VMDeploymentOptions `json:"options,omitempty"`
+}
+
+type LoadBalancerList struct {
+	LoadBalancer []LoadBalancer `json:"loadBalancer,omitempty"`
+}
+
+// LoadBalancer is an internal load balancer of a deployment. Input endpoints
+// which reference it by name are only reachable from within the virtual
+// network, on the frontend address in the given subnet.
+type LoadBalancer struct {
+	XMLName                 xml.Name                `xml:"LoadBalancer" json:"-"`
+	Xmlns                   string                  `xml:"xmlns,attr,omitempty" json:"-"`
+	Name                    string                  `json:"name,omitempty"`
+	FrontendIpConfiguration FrontendIpConfiguration `json:"frontendIpConfiguration,omitempty"`
+}
+
+type FrontendIpConfiguration struct {
+	Type                          string `json:"type,omitempty"`
+	SubnetName                    string `xml:",omitempty" json:"subnetName,omitempty"`
+	StaticVirtualNetworkIPAddress string `xml:",omitempty" json:"staticVirtualNetworkIPAddress,omitempty"`
 }
 
 type VirtualIPs struct {
-	VirtualIP []VirtualIP
+	VirtualIP []VirtualIP `json:"virtualIP,omitempty"`
 }
 
 type VirtualIP struct {
-	Address string
+	Address string `json:"address,omitempty"`
+}
+
+// SSHCertificate is a key pair created by GenerateSSHCertificate. PublicKey
+// is in OpenSSH format and Fingerprint is the SHA1 thumbprint of Certificate.
+type SSHCertificate struct {
+	Certificate []byte `json:"certificate,omitempty"`
+	PrivateKey  []byte `json:"privateKey,omitempty"`
+	PublicKey   string `json:"publicKey,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+type DockerCertificates struct {
+	CACert     []byte `json:"caCert,omitempty"`
+	CAKey      []byte `json:"caKey,omitempty"`
+	ServerCert []byte `json:"serverCert,omitempty"`
+	ServerKey  []byte `json:"serverKey,omitempty"`
+	ClientCert []byte `json:"clientCert,omitempty"`
+	ClientKey  []byte `json:"clientKey,omitempty"`
+}
+
+type dockerPrivateConfig struct {
+	CA         string `json:"ca"`
+	ServerCert string `json:"server-cert"`
+	ServerKey  string `json:"server-key"`
 }
 
 type dockerPublicConfig struct {
 	DockerPort int `json:"dockerport"`
 	Version    int `json:"version"`
 }
+
+type ResourceExtensionList struct {
+	XMLName            xml.Name            `xml:"ResourceExtensions" json:"-"`
+	Xmlns              string              `xml:"xmlns,attr" json:"-"`
+	ResourceExtensions []ResourceExtension `xml:"ResourceExtension" json:"resourceExtensions,omitempty"`
+}
+
+type ResourceExtension struct {
+	Publisher                   string `json:"publisher,omitempty"`
+	Name                        string `json:"name,omitempty"`
+	Version                     string `json:"version,omitempty"`
+	Label                       string `json:"label,omitempty"`
+	Description                 string `json:"description,omitempty"`
+	PublicConfigurationSchema   string `json:"publicConfigurationSchema,omitempty"`
+	PrivateConfigurationSchema  string `json:"privateConfigurationSchema,omitempty"`
+	SampleConfig                string `json:"sampleConfig,omitempty"`
+	ReplicationCompleted        bool   `json:"replicationCompleted,omitempty"`
+	Eula                        string `json:"eula,omitempty"`
+	PrivacyUri                  string `json:"privacyUri,omitempty"`
+	HomepageUri                 string `json:"homepageUri,omitempty"`
+	IsJsonExtension             bool   `json:"isJsonExtension,omitempty"`
+	IsInternalExtension         bool   `json:"isInternalExtension,omitempty"`
+	DisallowMajorVersionUpgrade bool   `json:"disallowMajorVersionUpgrade,omitempty"`
+	CompanyName                 string `json:"companyName,omitempty"`
+	SupportedOS                 string `json:"supportedOS,omitempty"`
+	PublishedDate               string `json:"publishedDate,omitempty"`
+}
+
+type LinuxDiagnosticsPerfCounter struct {
+	Query     string `json:"query"`
+	Table     string `json:"table"`
+	Frequency int    `json:"frequency,omitempty"`
+}
+
+type linuxDiagnosticsPublicConfig struct {
+	PerfCfg      []LinuxDiagnosticsPerfCounter `json:"perfCfg,omitempty"`
+	EnableSyslog string                        `json:"enableSyslog,omitempty"`
+}
+
+type windowsDiagnosticsPublicConfig struct {
+	XmlCfg         string `json:"xmlCfg"`
+	StorageAccount string `json:"StorageAccount"`
+}
+
+type diagnosticsPrivateConfig struct {
+	StorageAccountName     string `json:"storageAccountName"`
+	StorageAccountKey      string `json:"storageAccountKey"`
+	StorageAccountEndPoint string `json:"storageAccountEndPoint"`
+}
+
+type puppetPublicConfig struct {
+	PuppetMasterServer string `json:"PUPPET_MASTER_SERVER"`
+}
@@ -0,0 +1,564 @@
+package vmClient
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	azure "github.com/MSOpenTech/azure-sdk-for-go"
+	"github.com/MSOpenTech/azure-sdk-for-go/clients/hostedServiceClient"
+	"github.com/MSOpenTech/azure-sdk-for-go/clients/storageServiceClient"
+)
+
+// ProvisioningError is returned by ProvisionVMs when any step failed. It
+// holds the errors of all failed steps and, if the plan asked for a
+// rollback, of the deletions that failed while rolling back.
+type ProvisioningError struct {
+	Errors         []error
+	RollbackErrors []error
+}
+
+func (e *ProvisioningError) Error() string {
+	message := fmt.Sprintf(provisioningFailedError, len(e.Errors), joinErrors(e.Errors))
+	if len(e.RollbackErrors) > 0 {
+		message += provisioningErrorSeparator + fmt.Sprintf(rollbackFailedError, len(e.RollbackErrors), joinErrors(e.RollbackErrors))
+	}
+
+	return message
+}
+
+type deploymentRef struct {
+	cloudserviceName string
+	deploymentName   string
+}
+
+type roleRef struct {
+	deploymentRef
+	roleName string
+}
+
+// provisioningOperations are the Azure calls made by ProvisionVMs.
+type provisioningOperations struct {
+	verifyCoreQuota      func(roles []*Role) error
+	listStorageServices  func() ([]storageServiceClient.StorageService, error)
+	createStorageService func(storageService StorageServicePlan) error
+	verifyCloudService   func(cloudserviceName, location string) (bool, error)
+	createCloudService   func(cloudService CloudServicePlan) error
+	uploadCertificates   func(cloudserviceName string, role *Role) error
+	resolveOSDisk        func(role *Role, location, storageAccount string) (*Role, error)
+	getDeployment        func(cloudserviceName string) (*VMDeployment, error)
+	createDeployment     func(cloudserviceName string, vMDeployment VMDeployment) error
+	addRole              func(cloudserviceName, deploymentName string, role *Role) error
+	deleteRole           func(cloudserviceName, deploymentName, roleName string) error
+	deleteDeployment     func(cloudserviceName, deploymentName string) error
+	deleteCloudService   func(cloudserviceName string) error
+	deleteStorageService func(storageServiceName string) error
+}
+
+var azureProvisioningOperations = provisioningOperations{
+	verifyCoreQuota:     VerifyCoreQuota,
+	listStorageServices: storageServiceClient.ListStorageServices,
+	createStorageService: func(storageService StorageServicePlan) error {
+		_, err := storageServiceClient.CreateStorageServiceWithOptions(storageService.Name, storageService.Options)
+		return err
+	},
+	verifyCloudService: verifyCloudServiceName,
+	createCloudService: func(cloudService CloudServicePlan) error {
+		requestId, err := hostedServiceClient.CreateHostedService(cloudService.Name, cloudService.Location, cloudService.ReverseDnsFqdn)
+		if err != nil {
+			return err
+		}
+
+		return azure.WaitAsyncOperation(requestId)
+	},
+	uploadCertificates: uploadRoleCertificates,
+	resolveOSDisk:      resolveRoleOSDisk,
+	getDeployment: func(cloudserviceName string) (*VMDeployment, error) {
+		return getExistingDeployment(cloudserviceName, "")
+	},
+	createDeployment: sendVMDeploymentRequest,
+	addRole:          addAzureVMRole,
+	deleteRole: func(cloudserviceName, deploymentName, roleName string) error {
+		return DeleteRole(cloudserviceName, deploymentName, roleName, true)
+	},
+	deleteDeployment: func(cloudserviceName, deploymentName string) error {
+		return DeleteVMDeployment(cloudserviceName, deploymentName, true)
+	},
+	deleteCloudService:   hostedServiceClient.DeleteHostedServiceComplete,
+	deleteStorageService: storageServiceClient.DeleteStorageService,
+}
+
+// provisioner runs the steps of a ProvisioningPlan and keeps track of what
+// they created.
+type provisioner struct {
+	operations provisioningOperations
+	semaphore  chan struct{}
+
+	mu                 sync.Mutex
+	errors             []error
+	storageServices    []string
+	cloudServices      []string
+	deployments        []deploymentRef
+	roles              []roleRef
+	storageServiceFail bool
+}
+
+//Region public methods starts
+
+// ProvisionVMs creates the storage accounts, cloud services, service
+// certificates and deployments of the plan. The core quota is checked with
+// VerifyCoreQuota first, so a plan which does not fit fails before anything
+// is created. Storage accounts and cloud services are created in parallel.
+// The certificates of the roles of a cloud service are uploaded as soon as
+// the service exists, and its deployment is created, or its roles are added
+// to the existing deployment, once the certificates and all storage accounts
+// are in place. No more than MaxParallelism requests are in progress at any
+// time.
+//
+// Failed steps do not stop independent ones, a *ProvisioningError with all
+// errors is returned at the end. With RollbackOnFailure the roles,
+// deployments, cloud services and storage accounts created by this call,
+// along with their disks, are then deleted again; existing resources are
+// left alone.
+func ProvisionVMs(plan ProvisioningPlan) error {
+	return provisionVMs(plan, azureProvisioningOperations)
+}
+
+//Region public methods ends
+
+//Region private methods starts
+
+func provisionVMs(plan ProvisioningPlan, operations provisioningOperations) error {
+	if len(plan.CloudServices) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "plan.CloudServices")
+	}
+
+	var roles []*Role
+	cloudServiceNames := map[string]bool{}
+	for _, cloudService := range plan.CloudServices {
+		err := verifyCloudServicePlan(cloudService)
+		if err != nil {
+			return err
+		}
+
+		if cloudServiceNames[cloudService.Name] {
+			return fmt.Errorf(duplicateCloudServiceError, cloudService.Name)
+		}
+		cloudServiceNames[cloudService.Name] = true
+
+		roles = append(roles, cloudService.Roles...)
+	}
+	for _, storageService := range plan.StorageServices {
+		if len(storageService.Name) == 0 {
+			return fmt.Errorf(paramNotSpecifiedError, "plan.StorageServices.Name")
+		}
+	}
+
+	err := operations.verifyCoreQuota(roles)
+	if err != nil {
+		return err
+	}
+
+	parallelism := plan.MaxParallelism
+	if parallelism <= 0 {
+		parallelism = defaultProvisioningParallelism
+	}
+	p := &provisioner{operations: operations, semaphore: make(chan struct{}, parallelism)}
+
+	var storageWG sync.WaitGroup
+	if len(plan.StorageServices) > 0 {
+		existingStorageServices, err := p.listStorageServiceNames()
+		if err != nil {
+			return err
+		}
+
+		for _, storageService := range plan.StorageServices {
+			if existingStorageServices[strings.ToLower(storageService.Name)] {
+				continue
+			}
+
+			storageWG.Add(1)
+			go func(storageService StorageServicePlan) {
+				defer storageWG.Done()
+				p.createStorageService(storageService)
+			}(storageService)
+		}
+	}
+
+	var cloudServiceWG sync.WaitGroup
+	for _, cloudService := range plan.CloudServices {
+		cloudServiceWG.Add(1)
+		go func(cloudService CloudServicePlan) {
+			defer cloudServiceWG.Done()
+			p.provisionCloudService(cloudService, &storageWG)
+		}(cloudService)
+	}
+
+	storageWG.Wait()
+	cloudServiceWG.Wait()
+
+	if len(p.errors) == 0 {
+		return nil
+	}
+
+	provisioningErr := &ProvisioningError{Errors: p.errors}
+	if plan.RollbackOnFailure {
+		provisioningErr.RollbackErrors = p.rollback()
+	}
+
+	return provisioningErr
+}
+
+func verifyCloudServicePlan(cloudService CloudServicePlan) error {
+	if len(cloudService.Name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "plan.CloudServices.Name")
+	}
+	if len(cloudService.Location) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "plan.CloudServices.Location")
+	}
+	if len(cloudService.Options.DeploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "plan.CloudServices.Options.DeploymentName")
+	}
+	if len(cloudService.Roles) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "plan.CloudServices.Roles")
+	}
+
+	err := hostedServiceClient.VerifyDNSName(cloudService.Name)
+	if err != nil {
+		return err
+	}
+
+	roleNames := map[string]bool{}
+	for _, role := range cloudService.Roles {
+		if role == nil {
+			return fmt.Errorf(paramNotSpecifiedError, "plan.CloudServices.Roles")
+		}
+
+		roleName := strings.ToLower(role.RoleName)
+		if roleNames[roleName] {
+			return fmt.Errorf(duplicateRoleNameError, role.RoleName)
+		}
+		roleNames[roleName] = true
+	}
+
+	return nil
+}
+
+// do runs step once fewer than MaxParallelism steps are in progress.
+func (p *provisioner) do(step func() error) error {
+	p.semaphore <- struct{}{}
+	defer func() { <-p.semaphore }()
+
+	return step()
+}
+
+func (p *provisioner) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.errors = append(p.errors, err)
+}
+
+func (p *provisioner) listStorageServiceNames() (map[string]bool, error) {
+	var storageServices []storageServiceClient.StorageService
+	err := p.do(func() error {
+		var err error
+		storageServices, err = p.operations.listStorageServices()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, storageService := range storageServices {
+		names[strings.ToLower(storageService.ServiceName)] = true
+	}
+
+	return names, nil
+}
+
+func (p *provisioner) createStorageService(storageService StorageServicePlan) {
+	err := p.do(func() error {
+		return p.operations.createStorageService(storageService)
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.errors = append(p.errors, err)
+		p.storageServiceFail = true
+		return
+	}
+	p.storageServices = append(p.storageServices, storageService.Name)
+}
+
+// provisionCloudService creates the cloud service unless it exists, uploads
+// the certificates of its roles and, once storageWG is done and no storage
+// account failed, deploys its roles.
+func (p *provisioner) provisionCloudService(cloudService CloudServicePlan, storageWG *sync.WaitGroup) {
+	serviceExisted, err := p.ensureCloudService(cloudService)
+	if err != nil {
+		p.fail(err)
+		return
+	}
+
+	var certificateWG sync.WaitGroup
+	certificatesFailed := false
+	for _, role := range cloudService.Roles {
+		if !role.UseCertAuth {
+			continue
+		}
+
+		certificateWG.Add(1)
+		go func(role *Role) {
+			defer certificateWG.Done()
+
+			err := p.do(func() error {
+				return p.operations.uploadCertificates(cloudService.Name, role)
+			})
+			if err != nil {
+				p.fail(err)
+				p.mu.Lock()
+				certificatesFailed = true
+				p.mu.Unlock()
+			}
+		}(role)
+	}
+	certificateWG.Wait()
+	storageWG.Wait()
+
+	p.mu.Lock()
+	skip := certificatesFailed || p.storageServiceFail
+	p.mu.Unlock()
+	if skip {
+		return
+	}
+
+	roles, err := p.resolveOSDisks(cloudService)
+	if err != nil {
+		p.fail(err)
+		return
+	}
+
+	var existingDeployment *VMDeployment
+	if serviceExisted {
+		err = p.do(func() error {
+			var err error
+			existingDeployment, err = p.operations.getDeployment(cloudService.Name)
+			return err
+		})
+		if err != nil {
+			p.fail(err)
+			return
+		}
+	}
+
+	deploymentName := cloudService.Options.DeploymentName
+	if existingDeployment != nil {
+		if !strings.EqualFold(existingDeployment.Name, deploymentName) {
+			p.fail(fmt.Errorf(deploymentNameMismatchError, cloudService.Name, existingDeployment.Name, deploymentName))
+			return
+		}
+
+		p.addRoles(cloudService.Name, existingDeployment.Name, roles)
+		return
+	}
+
+	vMDeployment, err := createVMDeploymentConfig(deploymentName, roles, cloudService.Options)
+	if err == nil {
+		err = p.do(func() error {
+			return p.operations.createDeployment(cloudService.Name, vMDeployment)
+		})
+	}
+	if err != nil {
+		p.fail(err)
+		return
+	}
+
+	p.mu.Lock()
+	p.deployments = append(p.deployments, deploymentRef{cloudserviceName: cloudService.Name, deploymentName: deploymentName})
+	p.mu.Unlock()
+}
+
+// ensureCloudService creates the cloud service unless it exists and reports
+// whether it existed.
+func (p *provisioner) ensureCloudService(cloudService CloudServicePlan) (bool, error) {
+	var serviceExists bool
+	err := p.do(func() error {
+		var err error
+		serviceExists, err = p.operations.verifyCloudService(cloudService.Name, cloudService.Location)
+		return err
+	})
+	if err != nil || serviceExists {
+		return serviceExists, err
+	}
+
+	err = p.do(func() error {
+		return p.operations.createCloudService(cloudService)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	p.mu.Lock()
+	p.cloudServices = append(p.cloudServices, cloudService.Name)
+	p.mu.Unlock()
+
+	return false, nil
+}
+
+// resolveOSDisks returns the roles of the cloud service with the OS disks of
+// roles created from an image without a media link placed in the storage
+// account of the cloud service. The roles of the plan are not changed.
+func (p *provisioner) resolveOSDisks(cloudService CloudServicePlan) ([]*Role, error) {
+	roles := make([]*Role, len(cloudService.Roles))
+	for i, role := range cloudService.Roles {
+		roles[i] = role
+
+		osDisk := role.OSVirtualHardDisk
+		if len(osDisk.DiskName) > 0 || len(osDisk.MediaLink) > 0 || len(osDisk.SourceImageName) == 0 {
+			continue
+		}
+
+		err := p.do(func() error {
+			var err error
+			roles[i], err = p.operations.resolveOSDisk(role, cloudService.Location, cloudService.StorageAccount)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return roles, nil
+}
+
+// addRoles adds the roles to the existing deployment one at a time, as
+// Azure only runs one operation on a deployment at once, and stops at the
+// first failure.
+func (p *provisioner) addRoles(cloudserviceName, deploymentName string, roles []*Role) {
+	for _, role := range roles {
+		err := p.do(func() error {
+			return p.operations.addRole(cloudserviceName, deploymentName, role)
+		})
+		if err != nil {
+			p.fail(err)
+			return
+		}
+
+		p.mu.Lock()
+		p.roles = append(p.roles, roleRef{deploymentRef{cloudserviceName, deploymentName}, role.RoleName})
+		p.mu.Unlock()
+	}
+}
+
+// rollback deletes the roles, deployments, cloud services and storage
+// accounts the provisioner created and returns the errors of the deletions
+// that failed. Cloud services are deleted along with their deployments and
+// disks, so only deployments in cloud services which existed before are
+// deleted separately; roles are only ever added to existing deployments.
+// Storage accounts go last, as they hold the VHDs of the disks.
+func (p *provisioner) rollback() []error {
+	var rollbackErrors []error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	run := func(step func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := p.do(step)
+			if err != nil {
+				mu.Lock()
+				rollbackErrors = append(rollbackErrors, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	createdCloudServices := map[string]bool{}
+	for _, cloudserviceName := range p.cloudServices {
+		createdCloudServices[cloudserviceName] = true
+	}
+
+	rolesByDeployment := map[deploymentRef][]string{}
+	for _, role := range p.roles {
+		rolesByDeployment[role.deploymentRef] = append(rolesByDeployment[role.deploymentRef], role.roleName)
+	}
+	for deployment, roleNames := range rolesByDeployment {
+		deployment, roleNames := deployment, roleNames
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for _, roleName := range roleNames {
+				err := p.do(func() error {
+					return p.operations.deleteRole(deployment.cloudserviceName, deployment.deploymentName, roleName)
+				})
+				if err != nil {
+					mu.Lock()
+					rollbackErrors = append(rollbackErrors, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, deployment := range p.deployments {
+		if createdCloudServices[deployment.cloudserviceName] {
+			continue
+		}
+
+		deployment := deployment
+		run(func() error {
+			return p.operations.deleteDeployment(deployment.cloudserviceName, deployment.deploymentName)
+		})
+	}
+	for _, cloudserviceName := range p.cloudServices {
+		cloudserviceName := cloudserviceName
+		run(func() error {
+			return p.operations.deleteCloudService(cloudserviceName)
+		})
+	}
+	wg.Wait()
+
+	for _, storageServiceName := range p.storageServices {
+		storageServiceName := storageServiceName
+		run(func() error {
+			return p.operations.deleteStorageService(storageServiceName)
+		})
+	}
+	wg.Wait()
+
+	return rollbackErrors
+}
+
+// resolveRoleOSDisk returns a copy of the role with the image of its OS disk
+// resolved and the disk placed in the storage account, or in an existing
+// account in the location if storageAccount is empty. No storage account is
+// created.
+func resolveRoleOSDisk(role *Role, location, storageAccount string) (*Role, error) {
+	osDisk, err := createOSVirtualHardDisk(role.RoleName, role.OSVirtualHardDisk.SourceImageName, location, OSDiskMediaOptions{StorageAccount: storageAccount})
+	if err != nil {
+		return nil, err
+	}
+
+	role = role.Clone()
+	role.OSVirtualHardDisk.SourceImageName = osDisk.SourceImageName
+	role.OSVirtualHardDisk.MediaLink = osDisk.MediaLink
+
+	return role, nil
+}
+
+func joinErrors(errs []error) string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, provisioningErrorSeparator)
+}
+
+//Region private methods ends
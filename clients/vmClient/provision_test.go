@@ -0,0 +1,343 @@
+package vmClient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/MSOpenTech/azure-sdk-for-go/clients/storageServiceClient"
+)
+
+// fakeProvisioning records the operations ProvisionVMs runs, in the order
+// they complete, and fails those listed in failures.
+type fakeProvisioning struct {
+	mu       sync.Mutex
+	events   []string
+	failures map[string]bool
+
+	existingStorageServices []string
+	existingCloudServices   []string
+	existingDeployments     map[string]string
+}
+
+func (f *fakeProvisioning) record(event string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.events = append(f.events, event)
+	if f.failures[event] {
+		return errors.New(event + " failed")
+	}
+
+	return nil
+}
+
+func (f *fakeProvisioning) operations() provisioningOperations {
+	return provisioningOperations{
+		verifyCoreQuota: func(roles []*Role) error {
+			return nil
+		},
+		listStorageServices: func() ([]storageServiceClient.StorageService, error) {
+			storageServices := []storageServiceClient.StorageService{}
+			for _, name := range f.existingStorageServices {
+				storageServices = append(storageServices, storageServiceClient.StorageService{ServiceName: name})
+			}
+			return storageServices, nil
+		},
+		createStorageService: func(storageService StorageServicePlan) error {
+			return f.record("createStorageService " + storageService.Name)
+		},
+		verifyCloudService: func(cloudserviceName, location string) (bool, error) {
+			for _, name := range f.existingCloudServices {
+				if name == cloudserviceName {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+		createCloudService: func(cloudService CloudServicePlan) error {
+			return f.record("createCloudService " + cloudService.Name)
+		},
+		uploadCertificates: func(cloudserviceName string, role *Role) error {
+			return f.record("uploadCertificates " + cloudserviceName + "/" + role.RoleName)
+		},
+		resolveOSDisk: func(role *Role, location, storageAccount string) (*Role, error) {
+			err := f.record("resolveOSDisk " + role.RoleName + " " + storageAccount)
+			if err != nil {
+				return nil, err
+			}
+
+			role = role.Clone()
+			role.OSVirtualHardDisk.MediaLink = "https://" + storageAccount + ".blob.core.windows.net/vhds/" + role.RoleName + ".vhd"
+			return role, nil
+		},
+		getDeployment: func(cloudserviceName string) (*VMDeployment, error) {
+			deploymentName, ok := f.existingDeployments[cloudserviceName]
+			if !ok {
+				return nil, nil
+			}
+			return &VMDeployment{Name: deploymentName}, nil
+		},
+		createDeployment: func(cloudserviceName string, vMDeployment VMDeployment) error {
+			for _, role := range vMDeployment.RoleList.Role {
+				if len(role.OSVirtualHardDisk.MediaLink) == 0 {
+					return fmt.Errorf("role %s has no media link", role.RoleName)
+				}
+			}
+			return f.record("createDeployment " + cloudserviceName + "/" + vMDeployment.Name)
+		},
+		addRole: func(cloudserviceName, deploymentName string, role *Role) error {
+			return f.record("addRole " + cloudserviceName + "/" + deploymentName + "/" + role.RoleName)
+		},
+		deleteRole: func(cloudserviceName, deploymentName, roleName string) error {
+			return f.record("deleteRole " + cloudserviceName + "/" + deploymentName + "/" + roleName)
+		},
+		deleteDeployment: func(cloudserviceName, deploymentName string) error {
+			return f.record("deleteDeployment " + cloudserviceName + "/" + deploymentName)
+		},
+		deleteCloudService: func(cloudserviceName string) error {
+			return f.record("deleteCloudService " + cloudserviceName)
+		},
+		deleteStorageService: func(storageServiceName string) error {
+			return f.record("deleteStorageService " + storageServiceName)
+		},
+	}
+}
+
+func (f *fakeProvisioning) index(event string) int {
+	for i, e := range f.events {
+		if e == event {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func newTestProvisioningRole(roleName string, useCertAuth bool) *Role {
+	role := newRole(roleName, "Small")
+	role.OSVirtualHardDisk.SourceImageName = "image"
+	role.UseCertAuth = useCertAuth
+
+	return role
+}
+
+func newTestProvisioningPlan() ProvisioningPlan {
+	return ProvisioningPlan{
+		StorageServices: []StorageServicePlan{{Name: "newstorage"}, {Name: "oldstorage"}},
+		CloudServices: []CloudServicePlan{
+			{
+				Name:           "newservice",
+				Location:       "West US",
+				StorageAccount: "newstorage",
+				Roles:          []*Role{newTestProvisioningRole("web1", true), newTestProvisioningRole("web2", false)},
+				Options:        VMDeploymentOptions{DeploymentName: "web"},
+			},
+			{
+				Name:           "oldservice",
+				Location:       "West US",
+				StorageAccount: "oldstorage",
+				Roles:          []*Role{newTestProvisioningRole("db1", false)},
+				Options:        VMDeploymentOptions{DeploymentName: "db"},
+			},
+		},
+		MaxParallelism:    2,
+		RollbackOnFailure: true,
+	}
+}
+
+func Test_provisionVMs_Ordering(t *testing.T) {
+	fake := &fakeProvisioning{
+		existingStorageServices: []string{"OldStorage"},
+		existingCloudServices:   []string{"oldservice"},
+	}
+	plan := newTestProvisioningPlan()
+
+	err := provisionVMs(plan, fake.operations())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type test struct {
+		before, after string
+	}
+
+	tests := []test{
+		{"createStorageService newstorage", "createDeployment newservice/web"},
+		{"createStorageService newstorage", "createDeployment oldservice/db"},
+		{"createCloudService newservice", "uploadCertificates newservice/web1"},
+		{"uploadCertificates newservice/web1", "createDeployment newservice/web"},
+		{"resolveOSDisk web1 newstorage", "createDeployment newservice/web"},
+		{"resolveOSDisk web2 newstorage", "createDeployment newservice/web"},
+		{"resolveOSDisk db1 oldstorage", "createDeployment oldservice/db"},
+	}
+
+	for _, i := range tests {
+		before, after := fake.index(i.before), fake.index(i.after)
+		if before < 0 || after < 0 || before > after {
+			t.Fatalf("Wrong order. Expected: '%s' before '%s', got: '%v'", i.before, i.after, fake.events)
+		}
+	}
+
+	for _, event := range []string{"createStorageService oldstorage", "createCloudService oldservice", "uploadCertificates newservice/web2"} {
+		if fake.index(event) >= 0 {
+			t.Fatalf("Wrong events. Expected no '%s', got: '%v'", event, fake.events)
+		}
+	}
+
+	// The roles of the plan keep their OS disk without a media link
+	if mediaLink := plan.CloudServices[0].Roles[0].OSVirtualHardDisk.MediaLink; len(mediaLink) > 0 {
+		t.Fatalf("Wrong media link of the planned role. Expected: '', got: '%s'", mediaLink)
+	}
+}
+
+func Test_provisionVMs_ExistingDeployment(t *testing.T) {
+	type test struct {
+		name               string
+		existingDeployment string
+		expectedEvents     []string
+		expectedError      string
+	}
+
+	tests := []test{
+		{"same name", "db", []string{"addRole oldservice/db/db1"}, ""},
+		{"other name", "other", nil, fmt.Sprintf(deploymentNameMismatchError, "oldservice", "other", "db")},
+	}
+
+	for _, i := range tests {
+		fake := &fakeProvisioning{
+			existingCloudServices: []string{"oldservice"},
+			existingDeployments:   map[string]string{"oldservice": i.existingDeployment},
+		}
+		plan := newTestProvisioningPlan()
+		plan.StorageServices = nil
+		plan.CloudServices = plan.CloudServices[1:]
+		plan.RollbackOnFailure = false
+
+		err := provisionVMs(plan, fake.operations())
+		if len(i.expectedError) > 0 {
+			if err == nil || !strings.Contains(err.Error(), i.expectedError) {
+				t.Fatalf("Wrong error for %s. Expected: '%s', got: '%v'", i.name, i.expectedError, err)
+			}
+		} else if err != nil {
+			t.Fatalf("Wrong result for %s. Expected no error, got: '%s'", i.name, err)
+		}
+
+		for _, event := range i.expectedEvents {
+			if fake.index(event) < 0 {
+				t.Fatalf("Wrong events for %s. Expected: '%s', got: '%v'", i.name, event, fake.events)
+			}
+		}
+		for _, event := range fake.events {
+			if strings.HasPrefix(event, "createDeployment") {
+				t.Fatalf("Wrong events for %s. Expected no deployment to be created, got: '%v'", i.name, fake.events)
+			}
+		}
+	}
+}
+
+func Test_provisionVMs_Rollback(t *testing.T) {
+	fake := &fakeProvisioning{
+		existingCloudServices: []string{"oldservice"},
+		existingDeployments:   map[string]string{},
+		failures:              map[string]bool{"createDeployment newservice/web": true},
+	}
+	plan := newTestProvisioningPlan()
+
+	err := provisionVMs(plan, fake.operations())
+	provisioningErr, ok := err.(*ProvisioningError)
+	if !ok {
+		t.Fatalf("Wrong error. Expected: '*ProvisioningError', got: '%v'", err)
+	}
+	if len(provisioningErr.Errors) != 1 || len(provisioningErr.RollbackErrors) != 0 {
+		t.Fatalf("Wrong errors. Expected: 1 error and no rollback errors, got: '%s'", provisioningErr)
+	}
+
+	type test struct {
+		before, after string
+	}
+
+	tests := []test{
+		{"createDeployment oldservice/db", "deleteDeployment oldservice/db"},
+		{"createCloudService newservice", "deleteCloudService newservice"},
+		{"deleteCloudService newservice", "deleteStorageService newstorage"},
+		{"deleteDeployment oldservice/db", "deleteStorageService newstorage"},
+		{"deleteDeployment oldservice/db", "deleteStorageService oldstorage"},
+	}
+
+	for _, i := range tests {
+		before, after := fake.index(i.before), fake.index(i.after)
+		if before < 0 || after < 0 || before > after {
+			t.Fatalf("Wrong order. Expected: '%s' before '%s', got: '%v'", i.before, i.after, fake.events)
+		}
+	}
+
+	for _, event := range []string{"deleteDeployment newservice/web", "deleteCloudService oldservice"} {
+		if fake.index(event) >= 0 {
+			t.Fatalf("Wrong events. Expected no '%s', got: '%v'", event, fake.events)
+		}
+	}
+}
+
+func Test_provisionVMs_RollbackAddedRoles(t *testing.T) {
+	fake := &fakeProvisioning{
+		existingCloudServices: []string{"oldservice"},
+		existingDeployments:   map[string]string{"oldservice": "db"},
+		failures:              map[string]bool{"addRole oldservice/db/db2": true},
+	}
+	plan := newTestProvisioningPlan()
+	plan.StorageServices = nil
+	plan.CloudServices = plan.CloudServices[1:]
+	plan.CloudServices[0].Roles = append(plan.CloudServices[0].Roles, newTestProvisioningRole("db2", false), newTestProvisioningRole("db3", false))
+
+	err := provisionVMs(plan, fake.operations())
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	for _, event := range []string{"deleteRole oldservice/db/db1"} {
+		if fake.index(event) < 0 {
+			t.Fatalf("Wrong events. Expected: '%s', got: '%v'", event, fake.events)
+		}
+	}
+	for _, event := range []string{"addRole oldservice/db/db3", "deleteRole oldservice/db/db2", "deleteDeployment oldservice/db"} {
+		if fake.index(event) >= 0 {
+			t.Fatalf("Wrong events. Expected no '%s', got: '%v'", event, fake.events)
+		}
+	}
+}
+
+func Test_verifyCloudServicePlan(t *testing.T) {
+	type test struct {
+		name          string
+		change        func(*CloudServicePlan)
+		expectedError string
+	}
+
+	tests := []test{
+		{"valid", func(c *CloudServicePlan) {}, ""},
+		{"no deployment name", func(c *CloudServicePlan) { c.Options.DeploymentName = "" }, fmt.Sprintf(paramNotSpecifiedError, "plan.CloudServices.Options.DeploymentName")},
+		{"no location", func(c *CloudServicePlan) { c.Location = "" }, fmt.Sprintf(paramNotSpecifiedError, "plan.CloudServices.Location")},
+		{"duplicate role", func(c *CloudServicePlan) {
+			c.Roles = append(c.Roles, newTestProvisioningRole("WEB1", false))
+		}, fmt.Sprintf(duplicateRoleNameError, "WEB1")},
+	}
+
+	for _, i := range tests {
+		cloudService := newTestProvisioningPlan().CloudServices[0]
+		i.change(&cloudService)
+
+		err := verifyCloudServicePlan(cloudService)
+		if len(i.expectedError) == 0 {
+			if err != nil {
+				t.Fatalf("Wrong result for %s. Expected no error, got: '%s'", i.name, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != i.expectedError {
+			t.Fatalf("Wrong error for %s. Expected: '%s', got: '%v'", i.name, i.expectedError, err)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package vmClient
+
+import (
+	"fmt"
+)
+
+const (
+	roleSourceConflictError         = "Only one of image name and disk name can be specified."
+	provisioningConfAlreadySetError = "Provisioning config has already been specified for this role."
+)
+
+// RoleOption configures a RoleBuilder. Options are checked by the functions
+// they wrap when the role is built.
+type RoleOption func(builder *RoleBuilder) error
+
+// RoleBuilder assembles a Role from functional options as an alternative to
+// the CreateAzureVMConfiguration / Add* / Set* chain. Build returns a new Role
+// value on every call, so the result is never shared with the builder or with
+// previously built roles.
+type RoleBuilder struct {
+	dnsName      string
+	location     string
+	instanceSize string
+	imageName    string
+	diskName     string
+	mediaOptions OSDiskMediaOptions
+
+	disableGuestAgent bool
+
+	provisioning []func(role *Role) (*Role, error)
+	steps        []func(role *Role) (*Role, error)
+	err          error
+}
+
+//Region public methods starts
+
+func NewRoleBuilder(dnsName, location string, options ...RoleOption) *RoleBuilder {
+	builder := &RoleBuilder{
+		dnsName:  dnsName,
+		location: location,
+	}
+
+	return builder.With(options...)
+}
+
+func (builder *RoleBuilder) With(options ...RoleOption) *RoleBuilder {
+	for _, option := range options {
+		if builder.err != nil {
+			break
+		}
+
+		builder.err = option(builder)
+	}
+
+	return builder
+}
+
+// Build creates the role from the options without calling Azure. The image
+// of the OS disk is not resolved and the disk has no media link yet, use
+// Resolve to get a role which can be deployed.
+func (builder *RoleBuilder) Build() (Role, error) {
+	if builder.err != nil {
+		return Role{}, builder.err
+	}
+	if len(builder.imageName) > 0 && len(builder.diskName) > 0 {
+		return Role{}, fmt.Errorf(roleSourceConflictError)
+	}
+	if len(builder.imageName) == 0 && len(builder.diskName) == 0 {
+		return Role{}, fmt.Errorf(paramNotSpecifiedError, "imageName")
+	}
+
+	err := verifyRoleParams(builder.dnsName, builder.instanceSize, builder.location)
+	if err != nil {
+		return Role{}, err
+	}
+
+	role := newRole(builder.dnsName, builder.instanceSize)
+	role.ProvisionGuestAgent = !builder.disableGuestAgent
+	if len(builder.diskName) > 0 {
+		role.OSVirtualHardDisk.DiskName = builder.diskName
+	} else {
+		role.OSVirtualHardDisk.SourceImageName = builder.imageName
+	}
+
+	// Provisioning replaces the role configuration sets, so it has to run
+	// before anything that adds endpoints or subnets to the network config.
+	for _, step := range builder.provisioning {
+		role, err = step(role)
+		if err != nil {
+			return Role{}, err
+		}
+	}
+
+	for _, step := range builder.steps {
+		role, err = step(role)
+		if err != nil {
+			return Role{}, err
+		}
+	}
+
+	return *role, nil
+}
+
+// Resolve builds the role and checks it against Azure: the role size has to
+// be available in the location and the image is resolved to the OS image
+// the disk is created from. The media link of the OS disk is set from the
+// media options, which creates a storage account if they ask for it.
+func (builder *RoleBuilder) Resolve() (Role, error) {
+	role, err := builder.Build()
+	if err != nil {
+		return Role{}, err
+	}
+
+	err = verifyInstanceSizeInLocation(role.RoleSize, builder.location)
+	if err != nil {
+		return Role{}, err
+	}
+
+	if len(builder.imageName) == 0 {
+		return role, nil
+	}
+
+	osVirtualHardDisk, err := createOSVirtualHardDisk(role.RoleName, builder.imageName, builder.location, builder.mediaOptions)
+	if err != nil {
+		return Role{}, err
+	}
+
+	role.OSVirtualHardDisk.SourceImageName = osVirtualHardDisk.SourceImageName
+	role.OSVirtualHardDisk.MediaLink = osVirtualHardDisk.MediaLink
+
+	return role, nil
+}
+
+func WithSize(instanceSize string) RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.instanceSize = instanceSize
+		return nil
+	}
+}
+
+func WithImage(imageName string) RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.imageName = imageName
+		return nil
+	}
+}
+
+func WithOSDisk(diskName string) RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.diskName = diskName
+		return nil
+	}
+}
+
+func WithMediaOptions(mediaOptions OSDiskMediaOptions) RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.mediaOptions = mediaOptions
+		return nil
+	}
+}
+
+// WithoutGuestAgent creates the role without the VM agent, for images that do
+// not ship it. Extensions cannot be combined with this option.
+func WithoutGuestAgent() RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.disableGuestAgent = true
+		return nil
+	}
+}
+
+func WithLinuxProvisioning(userName, password, certPath string, sshPort int) RoleOption {
+	return func(builder *RoleBuilder) error {
+		return builder.addProvisioning(func(role *Role) (*Role, error) {
+			return AddAzureLinuxProvisioningConfig(role, userName, password, certPath, sshPort)
+		})
+	}
+}
+
+func WithLinuxSSHPublicKey(userName, password, publicKey string, sshPort int) RoleOption {
+	return func(builder *RoleBuilder) error {
+		return builder.addProvisioning(func(role *Role) (*Role, error) {
+			return AddAzureLinuxProvisioningConfigWithSSHPublicKey(role, userName, password, publicKey, sshPort)
+		})
+	}
+}
+
+func WithWindowsProvisioning(adminUserName, adminPassword string, rdpPort int) RoleOption {
+	return func(builder *RoleBuilder) error {
+		return builder.addProvisioning(func(role *Role) (*Role, error) {
+			return AddAzureWindowsProvisioningConfig(role, adminUserName, adminPassword, rdpPort)
+		})
+	}
+}
+
+func WithEndpoint(name, protocol string, externalPort, internalPort int) RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.addStep(func(role *Role) (*Role, error) {
+			return AddInputEndpoint(role, name, protocol, externalPort, internalPort)
+		})
+		return nil
+	}
+}
+
+func WithVNet(virtualNetworkName, subnetName string) RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.addStep(func(role *Role) (*Role, error) {
+			return SetAzureVMSubnet(role, virtualNetworkName, subnetName)
+		})
+		return nil
+	}
+}
+
+func WithDNSServer(name, address string) RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.addStep(func(role *Role) (*Role, error) {
+			return AddAzureVMDNSServer(role, name, address)
+		})
+		return nil
+	}
+}
+
+func WithAvailabilitySet(availabilitySetName string) RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.addStep(func(role *Role) (*Role, error) {
+			return SetAzureVMAvailabilitySet(role, availabilitySetName)
+		})
+		return nil
+	}
+}
+
+func WithBootDiagnostics() RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.addStep(func(role *Role) (*Role, error) {
+			return SetAzureVMBootDiagnostics(role, true)
+		})
+		return nil
+	}
+}
+
+func WithOSDiskHostCaching(hostCaching HostCachingType) RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.addStep(func(role *Role) (*Role, error) {
+			return SetAzureVMOSDiskHostCaching(role, hostCaching)
+		})
+		return nil
+	}
+}
+
+func WithDataDisk(diskLabel string, lun, sizeInGB int, hostCaching HostCachingType, mediaLink string) RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.addStep(func(role *Role) (*Role, error) {
+			return AddAzureVMDataDisk(role, diskLabel, lun, sizeInGB, hostCaching, mediaLink)
+		})
+		return nil
+	}
+}
+
+func WithExtension(name, publisher, version, referenceName, state string, publicConfiguration, privateConfiguration interface{}) RoleOption {
+	return func(builder *RoleBuilder) error {
+		builder.addStep(func(role *Role) (*Role, error) {
+			return SetAzureVMExtensionWithConfig(role, name, publisher, version, referenceName, state, publicConfiguration, privateConfiguration)
+		})
+		return nil
+	}
+}
+
+//Region public methods ends
+
+//Region private methods starts
+
+func (builder *RoleBuilder) addProvisioning(step func(role *Role) (*Role, error)) error {
+	if len(builder.provisioning) > 0 {
+		return fmt.Errorf(provisioningConfAlreadySetError)
+	}
+
+	builder.provisioning = append(builder.provisioning, step)
+	return nil
+}
+
+func (builder *RoleBuilder) addStep(step func(role *Role) (*Role, error)) {
+	builder.steps = append(builder.steps, step)
+}
+
+//Region private methods ends
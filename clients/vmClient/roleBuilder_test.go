@@ -0,0 +1,84 @@
+package vmClient
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRoleBuilder_Build(t *testing.T) {
+	type test struct {
+		name          string
+		options       []RoleOption
+		expectedError string
+	}
+
+	tests := []test{
+		{"image", []RoleOption{WithSize("Small"), WithImage("image")}, ""},
+		{"disk", []RoleOption{WithSize("Small"), WithOSDisk("disk")}, ""},
+		{"no source", []RoleOption{WithSize("Small")}, fmt.Sprintf(paramNotSpecifiedError, "imageName")},
+		{"image and disk", []RoleOption{WithSize("Small"), WithImage("image"), WithOSDisk("disk")}, roleSourceConflictError},
+		{"no size", []RoleOption{WithImage("image")}, fmt.Sprintf(paramNotSpecifiedError, "instanceSize")},
+		{"invalid endpoint", []RoleOption{WithSize("Small"), WithImage("image"), WithEndpoint("web", "http", 80, 80)}, fmt.Sprintf(invalidEndpointProtocolError, "http")},
+		{"invalid data disk", []RoleOption{WithSize("Small"), WithImage("image"), WithDataDisk("data", maxDataDiskLun+1, 10, HostCachingTypeReadOnly, "")}, fmt.Sprintf(invalidDataDiskLunError, maxDataDiskLun+1, maxDataDiskLun)},
+		{"provisioning twice", []RoleOption{WithSize("Small"), WithImage("image"), WithWindowsProvisioning("admin", "P@ssw0rd!", 0), WithWindowsProvisioning("admin", "P@ssw0rd!", 0)}, provisioningConfAlreadySetError},
+	}
+
+	for _, i := range tests {
+		_, err := NewRoleBuilder("myvm", "West US", i.options...).Build()
+		if len(i.expectedError) == 0 {
+			if err != nil {
+				t.Fatalf("Wrong result for %s. Expected no error, got: '%s'", i.name, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != i.expectedError {
+			t.Fatalf("Wrong error for %s. Expected: '%s', got: '%v'", i.name, i.expectedError, err)
+		}
+	}
+}
+
+func TestRoleBuilder_BuildAppliesOptions(t *testing.T) {
+	builder := NewRoleBuilder("myvm", "West US",
+		WithSize("Small"),
+		WithImage("image"),
+		WithEndpoint("web", "tcp", 80, 8080),
+		WithAvailabilitySet("web-set"),
+		WithOSDiskHostCaching(HostCachingTypeReadOnly),
+		WithoutGuestAgent())
+
+	role, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "image"; role.OSVirtualHardDisk.SourceImageName != expected {
+		t.Fatalf("Wrong source image. Expected: '%s', got: '%s'", expected, role.OSVirtualHardDisk.SourceImageName)
+	}
+	if len(role.OSVirtualHardDisk.MediaLink) > 0 {
+		t.Fatalf("Wrong media link. Expected: '', got: '%s'", role.OSVirtualHardDisk.MediaLink)
+	}
+	if expected := HostCachingTypeReadOnly; role.OSVirtualHardDisk.HostCaching != expected {
+		t.Fatalf("Wrong host caching. Expected: '%s', got: '%s'", expected, role.OSVirtualHardDisk.HostCaching)
+	}
+	if expected := "web-set"; role.AvailabilitySetName != expected {
+		t.Fatalf("Wrong availability set. Expected: '%s', got: '%s'", expected, role.AvailabilitySetName)
+	}
+	if role.ProvisionGuestAgent {
+		t.Fatalf("Wrong ProvisionGuestAgent. Expected: 'false', got: 'true'")
+	}
+
+	networkConfig := getNetworkConfig(&role)
+	if networkConfig == nil || len(networkConfig.InputEndpoints.InputEndpoint) != 1 {
+		t.Fatalf("Wrong input endpoints. Expected: 'web', got: '%v'", role.ConfigurationSets)
+	}
+
+	// Every build returns a separate role
+	other, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	getNetworkConfig(&other).InputEndpoints.InputEndpoint[0].Port = 443
+	if port := networkConfig.InputEndpoints.InputEndpoint[0].Port; port != 80 {
+		t.Fatalf("Wrong port. Expected: '80', got: '%d'", port)
+	}
+}
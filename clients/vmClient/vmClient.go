@@ -2,15 +2,25 @@ package vmClient
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"encoding/pem"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
@@ -19,33 +29,146 @@ import (
 	"github.com/MSOpenTech/azure-sdk-for-go/clients/imageClient"
 	"github.com/MSOpenTech/azure-sdk-for-go/clients/locationClient"
 	"github.com/MSOpenTech/azure-sdk-for-go/clients/storageServiceClient"
+	"github.com/MSOpenTech/azure-sdk-for-go/clients/subscriptionClient"
 )
 
 const (
-	azureXmlns               = "http://schemas.microsoft.com/windowsazure"
-	azureDeploymentListURL   = "services/hostedservices/%s/deployments"
-	azureDeploymentURL       = "services/hostedservices/%s/deployments/%s"
-	deleteAzureDeploymentURL = "services/hostedservices/%s/deployments/%s?comp=media"
-	azureRoleURL             = "services/hostedservices/%s/deployments/%s/roles/%s"
-	azureOperationsURL       = "services/hostedservices/%s/deployments/%s/roleinstances/%s/Operations"
-	azureCertificatListURL   = "services/hostedservices/%s/certificates"
-	azureRoleSizeListURL     = "rolesizes"
-
-	osLinux                   = "Linux"
-	osWindows                 = "Windows"
-	dockerPublicConfigVersion = 2
-
-	provisioningConfDoesNotExistsError = "You should set azure VM provisioning config first"
-	invalidCertExtensionError          = "Certificate %s is invalid. Please specify %s certificate."
-	invalidOSError                     = "You must specify correct OS param. Valid values are 'Linux' and 'Windows'"
-	invalidDnsLengthError              = "The DNS name must be between 3 and 25 characters."
-	invalidPasswordLengthError         = "Password must be between 4 and 30 characters."
-	invalidPasswordError               = "Password must have at least one upper case, lower case and numeric character."
-	invalidRoleSizeError               = "Invalid role size: %s. Available role sizes: %s"
-	invalidRoleSizeInLocationError     = "Role size: %s not available in location: %s."
-	paramNotSpecifiedError             = "Parameter %s is not specified."
+	azureXmlns                            = "http://schemas.microsoft.com/windowsazure"
+	azureDeploymentListURL                = "services/hostedservices/%s/deployments"
+	azureDeploymentURL                    = "services/hostedservices/%s/deployments/%s"
+	azureDeploymentSlotURL                = "services/hostedservices/%s/deploymentslots/%s"
+	deleteMediaQuery                      = "?comp=media"
+	azureRoleURL                          = "services/hostedservices/%s/deployments/%s/roles/%s"
+	azureRoleListURL                      = "services/hostedservices/%s/deployments/%s/roles"
+	azureOperationsURL                    = "services/hostedservices/%s/deployments/%s/roleinstances/%s/Operations"
+	azureRolesOperationsURL               = "services/hostedservices/%s/deployments/%s/Roles/Operations"
+	azureCertificatListURL                = "services/hostedservices/%s/certificates"
+	azureRoleSizeListURL                  = "rolesizes"
+	azureLoadBalancerListURL              = "services/hostedservices/%s/deployments/%s/loadbalancers"
+	azureLoadBalancerURL                  = "services/hostedservices/%s/deployments/%s/loadbalancers/%s"
+	azureResourceExtensionListURL         = "services/resourceextensions"
+	azureResourceExtensionVersionsListURL = "services/resourceextensions/%s/%s"
+
+	// DebugSettings is only accepted and returned from this version of the
+	// API on, requests with boot diagnostics settings are sent with it.
+	bootDiagnosticsApiVersion = "2015-04-01"
+
+	defaultRoleReadyTimeout            = 30 * time.Minute
+	roleInstancePollInterval           = 5 * time.Second
+	extensionHandlerStatusNotReady     = "NotReady"
+	extensionHandlerStatusUnresponsive = "Unresponsive"
+	extensionSettingStatusError        = "error"
+	defaultRoleSizeCacheTTL            = 10 * time.Minute
+	defaultDeploymentWatchInterval     = 30 * time.Second
+	defaultProvisioningParallelism     = 4
+	deploymentEventBufferSize          = 16
+	sshPort                            = 22
+	sshDialTimeout                     = 10 * time.Second
+	resourceNotFoundErrorCode          = "ResourceNotFound"
+
+	osLinux            = "Linux"
+	osWindows          = "Windows"
+	winRMProtocolHttp  = "Http"
+	winRMProtocolHttps = "Https"
+
+	loadBalancerTypePrivate = "Private"
+
+	maxWindowsComputerNameLength = 15
+	minWindowsPasswordLength     = 8
+	maxWindowsPasswordLength     = 123
+	minWindowsPasswordClasses    = 3
+	defaultVHDContainer          = "vhds"
+	maxDataDiskLun               = 31
+	maxDataDiskSizeInGB          = 1023
+	minIdleTimeoutInMinutes      = 4
+	maxIdleTimeoutInMinutes      = 30
+	dockerPublicConfigVersion    = 2
+	sshRsaKeyType                = "ssh-rsa"
+	certificateFormatPfx         = "pfx"
+	certificateFormatCer         = "cer"
+	certPasswordEnv              = "AZURE_SDK_CERT_PASSWORD"
+	certificateKeySize           = 2048
+	certificateValidity          = 3 * 365 * 24 * time.Hour
+	minSSHKeySize                = 1024
+	sshCertificateName           = "Azure SSH Certificate"
+	cloudServiceDomainSuffix     = ".cloudapp.net"
+	storageAccountEndPoint       = "https://core.windows.net"
+
+	provisioningConfDoesNotExistsError     = "You should set azure VM provisioning config first"
+	invalidCertExtensionError              = "Certificate %s is invalid. Please specify %s certificate."
+	invalidSSHPublicKeyError               = "SSH public key is invalid. Please specify %s public key."
+	invalidSSHKeySizeError                 = "SSH key size must be at least %d bits."
+	storageAccountNotFoundError            = "No storage account was found in location %s. Specify a storage account or enable CreateStorageAccount."
+	storageAccountLocationMismatchError    = "Storage account %s is in location %s, but the VM is being created in %s."
+	vmImageNotSupportedError               = "Image %s is a VM image. Only OS images can be used as the source of an OS disk."
+	imageLocationMismatchError             = "Image %s is not available in location %s. Available locations: %s"
+	duplicateRoleNameError                 = "Role name %s is used more than once in the deployment."
+	duplicateCloudServiceError             = "Cloud service %s is used more than once in the plan."
+	deploymentNameMismatchError            = "Cloud service %s already has deployment %s, the plan asks for deployment %s."
+	virtualNetworkMismatchError            = "All roles in a deployment must use the same virtual network, found %s and %s."
+	invalidOSDiskHostCachingError          = "Invalid OS disk host caching: %s. Valid values are 'ReadOnly' and 'ReadWrite'."
+	invalidDataDiskHostCachingError        = "Invalid data disk host caching: %s. Valid values are 'None', 'ReadOnly' and 'ReadWrite'."
+	invalidDataDiskLunError                = "Invalid data disk LUN: %d. LUN must be between 0 and %d."
+	invalidDataDiskSizeError               = "Invalid data disk size: %d GB. Size must be between 1 and %d GB."
+	dataDiskLunInUseError                  = "Data disk LUN %d is already in use."
+	dataDiskNotFoundError                  = "Data disk with LUN %d was not found."
+	invalidWinRMProtocolError              = "Invalid WinRM listener protocol: %s. Valid values are 'Http' and 'Https'."
+	invalidEndpointProtocolError           = "Invalid endpoint protocol: %s. Valid values are 'tcp' and 'udp'."
+	invalidEndpointPortError               = "Invalid endpoint port: %d. Port must be between 1 and 65535."
+	endpointAlreadyExistsError             = "Input endpoint %s already exists."
+	endpointPortInUseError                 = "Public port %d is already used by input endpoint %s."
+	endpointNotFoundError                  = "Input endpoint %s was not found."
+	invalidIdleTimeoutError                = "Invalid idle timeout: %d minutes. Idle timeout must be between %d and %d minutes."
+	loadBalancerWithoutVirtualNetworkError = "Internal load balancer %s requires the deployment to be in a virtual network."
+	invalidLoadBalancerAddressError        = "Invalid static address %s of load balancer %s."
+	invalidDnsServerAddressError           = "Invalid DNS server address: %s."
+	dnsServerAlreadyExistsError            = "DNS server %s already exists."
+	invalidCertFormatError                 = "Certificate format is not recognized. Supported formats are PEM, DER (.cer) and PFX."
+	pfxConversionError                     = "Failed to convert the PEM certificate and private key to PFX: %s"
+	invalidOSError                         = "You must specify correct OS param. Valid values are 'Linux' and 'Windows'"
+	invalidPasswordLengthError             = "Password must be between 4 and 30 characters."
+	invalidPasswordError                   = "Password must have at least one upper case, lower case and numeric character."
+	invalidWindowsPasswordLengthError      = "Windows password must be between %d and %d characters."
+	invalidWindowsPasswordError            = "Windows password must contain characters from at least %d of the following: upper case, lower case, numeric and special characters."
+	invalidRoleSizeError                   = "Invalid role size: %s. Available role sizes: %s"
+	invalidRoleSizeInLocationError         = "Role size: %s not available in location: %s."
+	invalidRoleSizeDataDiskCountError      = "Role size: %s supports at most %d data disks, role %s has %d attached."
+	coreQuotaExceededError                 = "The roles need %d cores, but only %d of the %d cores of the subscription are available."
+	provisioningFailedError                = "Provisioning failed with %d error(s): %s"
+	rollbackFailedError                    = "Rolling back failed with %d error(s): %s"
+	provisioningErrorSeparator             = "; "
+	noMatchingRoleSizeError                = "No role size supporting virtual machines has at least %d cores, %d MB of memory and %d data disks."
+	roleInstanceNotFoundError              = "Role instance for role %s was not found in deployment %s."
+	roleInstanceByNameNotFoundError        = "Role instance %s was not found in deployment %s."
+	rolePreconditionFailedError            = "Role %s was modified since it was retrieved (ETag %s). Get the role again and reapply the changes."
+	roleInstanceFailedError                = "Role instance for role %s reached status %s while waiting for %s."
+	roleInstanceTimeoutError               = "Timed out after %s waiting for role %s to reach %s. Last status: %s."
+	sshEndpointNotFoundError               = "Role %s has no endpoint for SSH port 22."
+	sshTimeoutError                        = "Timed out after %s waiting for SSH on %s: %s"
+	resourceExtensionNotFoundError         = "Resource extension %s from publisher %s was not found."
+	guestAgentRequiredError                = "Resource extension %s requires the VM agent, which is disabled for role %s."
+	guestAgentExtensionsConfiguredError    = "Cannot disable the VM agent for role %s, it has %d resource extensions configured."
+	paramNotSpecifiedError                 = "Parameter %s is not specified."
 )
 
+var passwordValidationEnabled = true
+
+var roleSizeCache = struct {
+	sync.Mutex
+	ttl       time.Duration
+	fetchedAt time.Time
+	roleSizes *RoleSizeList
+	fetch     *roleSizeListFetch
+}{ttl: defaultRoleSizeCacheTTL}
+
+// roleSizeListFetch is a request for the role size list in progress, which
+// concurrent callers wait for instead of sending their own.
+type roleSizeListFetch struct {
+	done      chan struct{}
+	roleSizes RoleSizeList
+	err       error
+}
+
 //Region public methods starts
 
 func CreateAzureVM(azureVMConfiguration *Role, dnsName, location string) error {
@@ -59,11 +182,20 @@ func CreateAzureVM(azureVMConfiguration *Role, dnsName, location string) error {
 		return fmt.Errorf(paramNotSpecifiedError, "location")
 	}
 
-	err := verifyDNSname(dnsName)
+	err := hostedServiceClient.VerifyDNSName(dnsName)
+	if err != nil {
+		return err
+	}
+
+	serviceExists, err := verifyCloudServiceName(dnsName, location)
 	if err != nil {
 		return err
 	}
 
+	if serviceExists {
+		return deployAzureVM(dnsName, azureVMConfiguration, VMDeploymentOptions{})
+	}
+
 	requestId, err := hostedServiceClient.CreateHostedService(dnsName, location, "")
 	if err != nil {
 		return err
@@ -71,71 +203,172 @@ func CreateAzureVM(azureVMConfiguration *Role, dnsName, location string) error {
 
 	azure.WaitAsyncOperation(requestId)
 
-	if azureVMConfiguration.UseCertAuth {
-		err = uploadServiceCert(dnsName, azureVMConfiguration.CertPath)
-		if err != nil {
-			hostedServiceClient.DeleteHostedService(dnsName)
-			return err
+	err = deployAzureVM(dnsName, azureVMConfiguration, VMDeploymentOptions{})
+	if err != nil {
+		hostedServiceClient.DeleteHostedServiceComplete(dnsName)
+		return err
+	}
+
+	return nil
+}
+
+func CreateVMDeployment(cloudserviceName, deploymentName string, roles []*Role, options VMDeploymentOptions) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roles) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roles")
+	}
+
+	roleNames := map[string]bool{}
+	for _, role := range roles {
+		if role == nil {
+			return fmt.Errorf(paramNotSpecifiedError, "roles")
+		}
+
+		roleName := strings.ToLower(role.RoleName)
+		if roleNames[roleName] {
+			return fmt.Errorf(duplicateRoleNameError, role.RoleName)
 		}
+		roleNames[roleName] = true
 	}
 
-	vMDeployment := createVMDeploymentConfig(azureVMConfiguration)
-	vMDeploymentBytes, err := xml.Marshal(vMDeployment)
+	vMDeployment, err := createVMDeploymentConfig(deploymentName, roles, options)
 	if err != nil {
-		hostedServiceClient.DeleteHostedService(dnsName)
 		return err
 	}
 
-	requestURL := fmt.Sprintf(azureDeploymentListURL, azureVMConfiguration.RoleName)
-	requestId, err = azure.SendAzurePostRequest(requestURL, vMDeploymentBytes)
+	for _, role := range roles {
+		err = uploadRoleCertificates(cloudserviceName, role)
+		if err != nil {
+			return err
+		}
+	}
+
+	return sendVMDeploymentRequest(cloudserviceName, vMDeployment)
+}
+
+func CreateAzureVMInService(cloudserviceName string, azureVMConfiguration *Role, options VMDeploymentOptions) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if azureVMConfiguration == nil {
+		return fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+
+	_, err := hostedServiceClient.GetHostedService(cloudserviceName, false)
 	if err != nil {
-		hostedServiceClient.DeleteHostedService(dnsName)
 		return err
 	}
 
-	azure.WaitAsyncOperation(requestId)
-
-	return nil
+	return deployAzureVM(cloudserviceName, azureVMConfiguration, options)
 }
 
-func CreateAzureVMConfiguration(dnsName, instanceSize, imageName, location string) (*Role, error) {
+// EnsureVM creates the virtual machine described by azureVMConfiguration in
+// the cloud service dnsName, creating the cloud service in location first if
+// it does not exist. If the virtual machine exists its role size,
+// availability set and input endpoints are changed to match
+// azureVMConfiguration; its disks and provisioning configuration are left as
+// they are. The returned bool reports whether anything was created or changed.
+func EnsureVM(azureVMConfiguration *Role, dnsName, location string) (bool, error) {
+	if azureVMConfiguration == nil {
+		return false, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
 	if len(dnsName) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "dnsName")
+		return false, fmt.Errorf(paramNotSpecifiedError, "dnsName")
 	}
-	if len(instanceSize) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "instanceSize")
+	if len(location) == 0 {
+		return false, fmt.Errorf(paramNotSpecifiedError, "location")
+	}
+
+	serviceExists, err := verifyCloudServiceName(dnsName, location)
+	if err != nil {
+		return false, err
+	}
+
+	var deployment *VMDeployment
+	if serviceExists {
+		deployment, err = getExistingDeployment(dnsName, "")
+		if err != nil {
+			return false, err
+		}
+	}
+	if deployment == nil || !hasRole(deployment, azureVMConfiguration.RoleName) {
+		err = CreateAzureVM(azureVMConfiguration, dnsName, location)
+		return err == nil, err
+	}
+
+	role, err := GetRole(dnsName, deployment.Name, azureVMConfiguration.RoleName)
+	if err != nil {
+		return false, err
+	}
+	if !convergeRole(role, azureVMConfiguration) {
+		return false, nil
 	}
+
+	err = UpdateRole(dnsName, deployment.Name, role.RoleName, role)
+	return err == nil, err
+}
+
+// SetPasswordValidation enables or disables the local password policy checks
+// performed when adding Linux or Windows provisioning configuration. When
+// disabled, passwords are sent to Azure as is and validated there.
+func SetPasswordValidation(enabled bool) {
+	passwordValidationEnabled = enabled
+}
+
+// CreateAzureVMConfiguration creates the configuration of a VM whose OS disk
+// is stored in an existing storage account in location. Use
+// CreateAzureVMConfigurationWithMediaOptions with CreateStorageAccount to have
+// a storage account created if there is none.
+func CreateAzureVMConfiguration(dnsName, instanceSize, imageName, location string) (*Role, error) {
+	return CreateAzureVMConfigurationWithMediaOptions(dnsName, instanceSize, imageName, location, OSDiskMediaOptions{})
+}
+
+func CreateAzureVMConfigurationWithMediaOptions(dnsName, instanceSize, imageName, location string, mediaOptions OSDiskMediaOptions) (*Role, error) {
 	if len(imageName) == 0 {
 		return nil, fmt.Errorf(paramNotSpecifiedError, "imageName")
 	}
-	if len(location) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "location")
-	}
 
-	err := verifyDNSname(dnsName)
+	err := verifyRoleParams(dnsName, instanceSize, location)
 	if err != nil {
 		return nil, err
 	}
 
-	locationInfo, err := locationClient.GetLocation(location)
+	err = verifyInstanceSizeInLocation(instanceSize, location)
 	if err != nil {
 		return nil, err
 	}
 
-	sizeAvailable, err := isInstanceSizeAvailableInLocation(locationInfo, instanceSize)
+	role, err := createAzureVMRole(dnsName, instanceSize, imageName, location, mediaOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	if sizeAvailable == false {
-		return nil, fmt.Errorf(invalidRoleSizeInLocationError, instanceSize, location)
+	return role, nil
+}
+
+func CreateAzureVMConfigurationFromDisk(dnsName, instanceSize, diskName, location string) (*Role, error) {
+	if len(diskName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "diskName")
+	}
+
+	err := verifyRoleParams(dnsName, instanceSize, location)
+	if err != nil {
+		return nil, err
 	}
 
-	role, err := createAzureVMRole(dnsName, instanceSize, imageName, location)
+	err = verifyInstanceSizeInLocation(instanceSize, location)
 	if err != nil {
 		return nil, err
 	}
 
+	role := newRole(dnsName, instanceSize)
+	role.OSVirtualHardDisk.DiskName = diskName
+
 	return role, nil
 }
 
@@ -147,394 +380,2406 @@ func AddAzureLinuxProvisioningConfig(azureVMConfiguration *Role, userName, passw
 		return nil, fmt.Errorf(paramNotSpecifiedError, "userName")
 	}
 
-	configurationSets := ConfigurationSets{}
-	provisioningConfig, err := createLinuxProvisioningConfig(azureVMConfiguration.RoleName, userName, password, certPath)
-	if err != nil {
-		return nil, err
-	}
+	azureVMConfiguration = azureVMConfiguration.Clone()
 
-	configurationSets.ConfigurationSet = append(configurationSets.ConfigurationSet, provisioningConfig)
+	var certData []byte
+	if len(certPath) > 0 {
+		err := checkServiceCertExtension(certPath)
+		if err != nil {
+			return nil, err
+		}
 
-	networkConfig, networkErr := createNetworkConfig(osLinux, sshPort)
-	if networkErr != nil {
-		return nil, err
+		certData, err = ioutil.ReadFile(certPath)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	configurationSets.ConfigurationSet = append(configurationSets.ConfigurationSet, networkConfig)
-
-	azureVMConfiguration.ConfigurationSets = configurationSets
+	azureVMConfiguration, err := addAzureLinuxProvisioningConfig(azureVMConfiguration, userName, password, certData, "", sshPort)
+	if err != nil {
+		return nil, err
+	}
 
 	if len(certPath) > 0 {
-		azureVMConfiguration.UseCertAuth = true
 		azureVMConfiguration.CertPath = certPath
 	}
 
 	return azureVMConfiguration, nil
 }
 
-func SetAzureVMExtension(azureVMConfiguration *Role, name string, publisher string, version string, referenceName string, state string, publicConfigurationValue string, privateConfigurationValue string) (*Role, error) {
+func AddAzureLinuxProvisioningConfigWithSSHCert(azureVMConfiguration *Role, userName, password string, certData []byte, certPassword string, sshPort int) (*Role, error) {
 	if azureVMConfiguration == nil {
 		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
 	}
-	if len(name) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
-	}
-	if len(publisher) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "publisher")
-	}
-	if len(version) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "version")
+	if len(userName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "userName")
 	}
-	if len(referenceName) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "referenceName")
+	if len(certData) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "certData")
 	}
 
-	extension := ResourceExtensionReference{}
-	extension.Name = name
-	extension.Publisher = publisher
-	extension.Version = version
-	extension.ReferenceName = referenceName
-	extension.State = state
+	azureVMConfiguration = azureVMConfiguration.Clone()
 
-	if len(privateConfigurationValue) > 0 {
-		privateConfig := ResourceExtensionParameter{}
-		privateConfig.Key = "ignored"
-		privateConfig.Value = base64.StdEncoding.EncodeToString([]byte(privateConfigurationValue))
-		privateConfig.Type = "Private"
+	return addAzureLinuxProvisioningConfig(azureVMConfiguration, userName, password, certData, certPassword, sshPort)
+}
 
-		extension.ResourceExtensionParameterValues.ResourceExtensionParameterValue = append(extension.ResourceExtensionParameterValues.ResourceExtensionParameterValue, privateConfig)
+func AddAzureLinuxProvisioningConfigWithSSHPublicKey(azureVMConfiguration *Role, userName, password, publicKey string, sshPort int) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(userName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "userName")
+	}
+	if len(publicKey) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "publicKey")
 	}
 
-	if len(publicConfigurationValue) > 0 {
-		publicConfig := ResourceExtensionParameter{}
-		publicConfig.Key = "ignored"
-		publicConfig.Value = base64.StdEncoding.EncodeToString([]byte(publicConfigurationValue))
-		publicConfig.Type = "Public"
+	azureVMConfiguration = azureVMConfiguration.Clone()
 
-		extension.ResourceExtensionParameterValues.ResourceExtensionParameterValue = append(extension.ResourceExtensionParameterValues.ResourceExtensionParameterValue, publicConfig)
+	rsaPublicKey, err := parseSSHPublicKey(publicKey)
+	if err != nil {
+		return nil, err
 	}
 
-	azureVMConfiguration.ResourceExtensionReferences.ResourceExtensionReference = append(azureVMConfiguration.ResourceExtensionReferences.ResourceExtensionReference, extension)
+	certData, err := createCertificateForPublicKey(rsaPublicKey, userName)
+	if err != nil {
+		return nil, err
+	}
 
-	return azureVMConfiguration, nil
+	return addAzureLinuxProvisioningConfig(azureVMConfiguration, userName, password, certData, "", sshPort)
 }
 
-func SetAzureDockerVMExtension(azureVMConfiguration *Role, dockerPort int, version string) (*Role, error) {
+func AddAzureLinuxSSHPublicKey(azureVMConfiguration *Role, certData []byte, certPassword, path string) (*Role, error) {
 	if azureVMConfiguration == nil {
 		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
 	}
-
-	if len(version) == 0 {
-		version = "0.3"
+	if len(certData) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "certData")
 	}
 
-	err := addDockerPort(azureVMConfiguration.ConfigurationSets.ConfigurationSet, dockerPort)
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	provisioningConfig, err := getLinuxProvisioningConfig(azureVMConfiguration)
 	if err != nil {
 		return nil, err
 	}
 
-	publicConfiguration, err := createDockerPublicConfig(dockerPort)
+	fingerprint, err := getServiceCertFingerprint(certData, certPassword)
 	if err != nil {
 		return nil, err
 	}
 
-	privateConfiguration := "{}"
+	if len(path) == 0 {
+		path = "/home/" + provisioningConfig.UserName + "/.ssh/authorized_keys"
+	}
+
+	publicKey := PublicKey{Fingerprint: fingerprint, Path: path}
+	provisioningConfig.SSH.PublicKeys.PublicKey = append(provisioningConfig.SSH.PublicKeys.PublicKey, publicKey)
+
+	addRoleCertificate(azureVMConfiguration, certData, certPassword)
 
-	azureVMConfiguration, err = SetAzureVMExtension(azureVMConfiguration, "DockerExtension", "MSOpenTech.Extensions", version, "DockerExtension", "enable", publicConfiguration, privateConfiguration)
 	return azureVMConfiguration, nil
 }
 
-func GetVMDeployment(cloudserviceName, deploymentName string) (*VMDeployment, error) {
-	if len(cloudserviceName) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+func AddAzureLinuxSSHKeyPair(azureVMConfiguration *Role, certData []byte, certPassword, path string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
 	}
-	if len(deploymentName) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	if len(certData) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "certData")
 	}
 
-	deployment := new(VMDeployment)
+	azureVMConfiguration = azureVMConfiguration.Clone()
 
-	requestURL := fmt.Sprintf(azureDeploymentURL, cloudserviceName, deploymentName)
-	response, azureErr := azure.SendAzureGetRequest(requestURL)
-	if azureErr != nil {
-		return nil, azureErr
+	provisioningConfig, err := getLinuxProvisioningConfig(azureVMConfiguration)
+	if err != nil {
+		return nil, err
 	}
 
-	err := xml.Unmarshal(response, deployment)
+	fingerprint, err := getServiceCertFingerprint(certData, certPassword)
 	if err != nil {
 		return nil, err
 	}
 
-	return deployment, nil
-}
-
-func DeleteVMDeployment(cloudserviceName, deploymentName string) error {
-	if len(cloudserviceName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
-	}
-	if len(deploymentName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	if len(path) == 0 {
+		path = "/home/" + provisioningConfig.UserName + "/.ssh/id_rsa"
 	}
 
-	requestURL := fmt.Sprintf(deleteAzureDeploymentURL, cloudserviceName, deploymentName)
-	requestId, err := azure.SendAzureDeleteRequest(requestURL)
-	if err != nil {
-		return err
-	}
+	keyPair := KeyPair{Fingerprint: fingerprint, Path: path}
+	provisioningConfig.SSH.KeyPairs.KeyPair = append(provisioningConfig.SSH.KeyPairs.KeyPair, keyPair)
 
-	azure.WaitAsyncOperation(requestId)
+	addRoleCertificate(azureVMConfiguration, certData, certPassword)
 
-	return nil
+	return azureVMConfiguration, nil
 }
 
-func GetRole(cloudserviceName, deploymentName, roleName string) (*Role, error) {
-	if len(cloudserviceName) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+func AddInputEndpoint(azureVMConfiguration *Role, name, protocol string, externalPort, internalPort int) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
 	}
-	if len(deploymentName) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
 	}
-	if len(roleName) == 0 {
-		return nil, fmt.Errorf(paramNotSpecifiedError, "roleName")
+	if protocol != "tcp" && protocol != "udp" {
+		return nil, fmt.Errorf(invalidEndpointProtocolError, protocol)
+	}
+	if externalPort < 1 || externalPort > 65535 {
+		return nil, fmt.Errorf(invalidEndpointPortError, externalPort)
+	}
+	if internalPort < 1 || internalPort > 65535 {
+		return nil, fmt.Errorf(invalidEndpointPortError, internalPort)
 	}
 
-	role := new(Role)
+	azureVMConfiguration = azureVMConfiguration.Clone()
 
-	requestURL := fmt.Sprintf(azureRoleURL, cloudserviceName, deploymentName, roleName)
-	response, azureErr := azure.SendAzureGetRequest(requestURL)
-	if azureErr != nil {
-		return nil, azureErr
+	networkConfig := getNetworkConfig(azureVMConfiguration)
+	if networkConfig == nil {
+		azureVMConfiguration.ConfigurationSets.ConfigurationSet = append(azureVMConfiguration.ConfigurationSets.ConfigurationSet, ConfigurationSet{ConfigurationSetType: "NetworkConfiguration"})
+		networkConfig = getNetworkConfig(azureVMConfiguration)
 	}
 
-	err := xml.Unmarshal(response, role)
-	if err != nil {
-		return nil, err
+	for _, existingEndpoint := range networkConfig.InputEndpoints.InputEndpoint {
+		if strings.EqualFold(existingEndpoint.Name, name) {
+			return nil, fmt.Errorf(endpointAlreadyExistsError, name)
+		}
+		if existingEndpoint.Port == externalPort && strings.EqualFold(existingEndpoint.Protocol, protocol) {
+			return nil, fmt.Errorf(endpointPortInUseError, externalPort, existingEndpoint.Name)
+		}
 	}
 
-	return role, nil
+	endpoint := createEndpoint(name, protocol, externalPort, internalPort)
+	networkConfig.InputEndpoints.InputEndpoint = append(networkConfig.InputEndpoints.InputEndpoint, endpoint)
+
+	return azureVMConfiguration, nil
 }
 
-func StartRole(cloudserviceName, deploymentName, roleName string) error {
-	if len(cloudserviceName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
-	}
-	if len(deploymentName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+func RemoveInputEndpoint(azureVMConfiguration *Role, name string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
 	}
-	if len(roleName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
 	}
 
-	startRoleOperation := createStartRoleOperation()
+	azureVMConfiguration = azureVMConfiguration.Clone()
 
-	startRoleOperationBytes, err := xml.Marshal(startRoleOperation)
-	if err != nil {
-		return err
+	networkConfig := getNetworkConfig(azureVMConfiguration)
+	if networkConfig == nil {
+		return nil, fmt.Errorf(endpointNotFoundError, name)
 	}
 
-	requestURL := fmt.Sprintf(azureOperationsURL, cloudserviceName, deploymentName, roleName)
-	requestId, azureErr := azure.SendAzurePostRequest(requestURL, startRoleOperationBytes)
-	if azureErr != nil {
-		return azureErr
+	endpoints := networkConfig.InputEndpoints.InputEndpoint
+	for i, existingEndpoint := range endpoints {
+		if !strings.EqualFold(existingEndpoint.Name, name) {
+			continue
+		}
+
+		networkConfig.InputEndpoints.InputEndpoint = append(endpoints[:i], endpoints[i+1:]...)
+		return azureVMConfiguration, nil
 	}
 
-	azure.WaitAsyncOperation(requestId)
-	return nil
+	return nil, fmt.Errorf(endpointNotFoundError, name)
 }
 
-func ShutdownRole(cloudserviceName, deploymentName, roleName string) error {
-	if len(cloudserviceName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
-	}
-	if len(deploymentName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+func SetInputEndpointIdleTimeout(azureVMConfiguration *Role, name string, idleTimeoutInMinutes int) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
 	}
-	if len(roleName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
 	}
 
-	shutdownRoleOperation := createShutdowRoleOperation()
+	azureVMConfiguration = azureVMConfiguration.Clone()
 
-	shutdownRoleOperationBytes, err := xml.Marshal(shutdownRoleOperation)
+	err := verifyIdleTimeout(idleTimeoutInMinutes)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	requestURL := fmt.Sprintf(azureOperationsURL, cloudserviceName, deploymentName, roleName)
-	requestId, azureErr := azure.SendAzurePostRequest(requestURL, shutdownRoleOperationBytes)
-	if azureErr != nil {
-		return azureErr
+	networkConfig := getNetworkConfig(azureVMConfiguration)
+	if networkConfig == nil {
+		return nil, fmt.Errorf(endpointNotFoundError, name)
 	}
 
-	azure.WaitAsyncOperation(requestId)
-	return nil
+	endpoints := networkConfig.InputEndpoints.InputEndpoint
+	for i := range endpoints {
+		if !strings.EqualFold(endpoints[i].Name, name) {
+			continue
+		}
+
+		endpoints[i].IdleTimeoutInMinutes = idleTimeoutInMinutes
+		return azureVMConfiguration, nil
+	}
+
+	return nil, fmt.Errorf(endpointNotFoundError, name)
+}
+
+// SetInputEndpointLoadBalancer makes the input endpoint use the internal load
+// balancer with the given name instead of the public virtual IP of the cloud
+// service. An empty loadBalancerName makes the endpoint public again.
+func SetInputEndpointLoadBalancer(azureVMConfiguration *Role, name, loadBalancerName string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	networkConfig := getNetworkConfig(azureVMConfiguration)
+	if networkConfig == nil {
+		return nil, fmt.Errorf(endpointNotFoundError, name)
+	}
+
+	endpoints := networkConfig.InputEndpoints.InputEndpoint
+	for i := range endpoints {
+		if !strings.EqualFold(endpoints[i].Name, name) {
+			continue
+		}
+
+		endpoints[i].LoadBalancerName = loadBalancerName
+		return azureVMConfiguration, nil
+	}
+
+	return nil, fmt.Errorf(endpointNotFoundError, name)
+}
+
+func AddAzureVMPublicIP(azureVMConfiguration *Role, name string, idleTimeoutInMinutes int) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	if idleTimeoutInMinutes != 0 {
+		err := verifyIdleTimeout(idleTimeoutInMinutes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	networkConfig := getNetworkConfig(azureVMConfiguration)
+	if networkConfig == nil {
+		azureVMConfiguration.ConfigurationSets.ConfigurationSet = append(azureVMConfiguration.ConfigurationSets.ConfigurationSet, ConfigurationSet{ConfigurationSetType: "NetworkConfiguration"})
+		networkConfig = getNetworkConfig(azureVMConfiguration)
+	}
+
+	// Azure currently supports a single instance level public IP per VM
+	networkConfig.PublicIPs = &PublicIPs{PublicIP: []PublicIP{{Name: name, IdleTimeoutInMinutes: idleTimeoutInMinutes}}}
+
+	return azureVMConfiguration, nil
+}
+
+func AddAzureWindowsProvisioningConfig(azureVMConfiguration *Role, adminUserName, adminPassword string, rdpPort int) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(adminUserName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "adminUserName")
+	}
+	if len(adminPassword) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "adminPassword")
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	configurationSets := ConfigurationSets{}
+	provisioningConfig, err := createWindowsProvisioningConfig(azureVMConfiguration.RoleName, adminUserName, adminPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	configurationSets.ConfigurationSet = append(configurationSets.ConfigurationSet, provisioningConfig)
+
+	networkConfig, err := createNetworkConfig(osWindows, rdpPort)
+	if err != nil {
+		return nil, err
+	}
+
+	configurationSets.ConfigurationSet = append(configurationSets.ConfigurationSet, networkConfig)
+
+	azureVMConfiguration.ConfigurationSets = configurationSets
+
+	return azureVMConfiguration, nil
+}
+
+func SetAzureWindowsTimeZone(azureVMConfiguration *Role, timeZone string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(timeZone) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "timeZone")
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	provisioningConfig, err := getWindowsProvisioningConfig(azureVMConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	provisioningConfig.TimeZone = timeZone
+
+	return azureVMConfiguration, nil
+}
+
+func SetAzureWindowsAutomaticUpdates(azureVMConfiguration *Role, enableAutomaticUpdates bool) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	provisioningConfig, err := getWindowsProvisioningConfig(azureVMConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	provisioningConfig.EnableAutomaticUpdates = &enableAutomaticUpdates
+
+	return azureVMConfiguration, nil
+}
+
+func AddAzureWindowsWinRMListener(azureVMConfiguration *Role, protocol, certificateThumbprint string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if protocol != winRMProtocolHttp && protocol != winRMProtocolHttps {
+		return nil, fmt.Errorf(invalidWinRMProtocolError, protocol)
+	}
+	if protocol == winRMProtocolHttps && len(certificateThumbprint) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "certificateThumbprint")
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	provisioningConfig, err := getWindowsProvisioningConfig(azureVMConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	if provisioningConfig.WinRM == nil {
+		provisioningConfig.WinRM = new(WinRM)
+	}
+
+	listener := WinRMListener{Protocol: protocol, CertificateThumbprint: certificateThumbprint}
+	provisioningConfig.WinRM.Listeners.Listener = append(provisioningConfig.WinRM.Listeners.Listener, listener)
+
+	return azureVMConfiguration, nil
+}
+
+func AddAzureWindowsUnattendContent(azureVMConfiguration *Role, passName, componentName, settingName, content string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(passName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "passName")
+	}
+	if len(componentName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "componentName")
+	}
+	if len(settingName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "settingName")
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	provisioningConfig, err := getWindowsProvisioningConfig(azureVMConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	if provisioningConfig.AdditionalUnattendContent == nil {
+		provisioningConfig.AdditionalUnattendContent = new(AdditionalUnattendContent)
+	}
+
+	passes := provisioningConfig.AdditionalUnattendContent.Passes.UnattendPass
+	passIndex := -1
+	for i := range passes {
+		if passes[i].PassName == passName {
+			passIndex = i
+			break
+		}
+	}
+	if passIndex < 0 {
+		passes = append(passes, UnattendPass{PassName: passName})
+		passIndex = len(passes) - 1
+	}
+
+	components := passes[passIndex].Components.UnattendComponent
+	componentIndex := -1
+	for i := range components {
+		if components[i].ComponentName == componentName {
+			componentIndex = i
+			break
+		}
+	}
+	if componentIndex < 0 {
+		components = append(components, UnattendComponent{ComponentName: componentName})
+		componentIndex = len(components) - 1
+	}
+
+	setting := ComponentSetting{
+		SettingName: settingName,
+		Content:     base64.StdEncoding.EncodeToString([]byte(content)),
+	}
+	components[componentIndex].ComponentSettings.ComponentSetting = append(components[componentIndex].ComponentSettings.ComponentSetting, setting)
+
+	passes[passIndex].Components.UnattendComponent = components
+	provisioningConfig.AdditionalUnattendContent.Passes.UnattendPass = passes
+
+	return azureVMConfiguration, nil
+}
+
+func SetAzureVMAvailabilitySet(azureVMConfiguration *Role, availabilitySetName string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(availabilitySetName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "availabilitySetName")
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	azureVMConfiguration.AvailabilitySetName = availabilitySetName
+
+	return azureVMConfiguration, nil
+}
+
+func SetAzureVMSubnet(azureVMConfiguration *Role, virtualNetworkName, subnetName string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(virtualNetworkName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "virtualNetworkName")
+	}
+	if len(subnetName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "subnetName")
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	networkConfig := getNetworkConfig(azureVMConfiguration)
+	if networkConfig == nil {
+		azureVMConfiguration.ConfigurationSets.ConfigurationSet = append(azureVMConfiguration.ConfigurationSets.ConfigurationSet, ConfigurationSet{ConfigurationSetType: "NetworkConfiguration"})
+		networkConfig = getNetworkConfig(azureVMConfiguration)
+	}
+
+	networkConfig.SubnetNames = &SubnetNames{SubnetName: []string{subnetName}}
+	azureVMConfiguration.VirtualNetworkName = virtualNetworkName
+
+	return azureVMConfiguration, nil
+}
+
+func AddAzureVMDNSServer(azureVMConfiguration *Role, name, address string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if len(address) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "address")
+	}
+	if net.ParseIP(address) == nil {
+		return nil, fmt.Errorf(invalidDnsServerAddressError, address)
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	for _, dnsServer := range azureVMConfiguration.DnsServers {
+		if strings.EqualFold(dnsServer.Name, name) {
+			return nil, fmt.Errorf(dnsServerAlreadyExistsError, name)
+		}
+	}
+
+	azureVMConfiguration.DnsServers = append(azureVMConfiguration.DnsServers, DnsServer{Name: name, Address: address})
+
+	return azureVMConfiguration, nil
+}
+
+func SetAzureVMOSDiskHostCaching(azureVMConfiguration *Role, hostCaching HostCachingType) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if hostCaching != HostCachingTypeReadOnly && hostCaching != HostCachingTypeReadWrite {
+		return nil, fmt.Errorf(invalidOSDiskHostCachingError, hostCaching)
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	azureVMConfiguration.OSVirtualHardDisk.HostCaching = hostCaching
+
+	return azureVMConfiguration, nil
+}
+
+func AddAzureVMDataDisk(azureVMConfiguration *Role, diskLabel string, lun, sizeInGB int, hostCaching HostCachingType, mediaLink string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if lun < 0 || lun > maxDataDiskLun {
+		return nil, fmt.Errorf(invalidDataDiskLunError, lun, maxDataDiskLun)
+	}
+	if sizeInGB <= 0 || sizeInGB > maxDataDiskSizeInGB {
+		return nil, fmt.Errorf(invalidDataDiskSizeError, sizeInGB, maxDataDiskSizeInGB)
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	err := verifyDataDiskHostCaching(hostCaching)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dataDisk := range azureVMConfiguration.DataVirtualHardDisks.DataVirtualHardDisk {
+		if dataDisk.Lun == lun {
+			return nil, fmt.Errorf(dataDiskLunInUseError, lun)
+		}
+	}
+
+	dataDisk := DataVirtualHardDisk{
+		HostCaching:         hostCaching,
+		DiskLabel:           diskLabel,
+		Lun:                 lun,
+		LogicalDiskSizeInGB: sizeInGB,
+		MediaLink:           mediaLink,
+	}
+	azureVMConfiguration.DataVirtualHardDisks.DataVirtualHardDisk = append(azureVMConfiguration.DataVirtualHardDisks.DataVirtualHardDisk, dataDisk)
+
+	return azureVMConfiguration, nil
+}
+
+func SetAzureVMDataDiskHostCaching(azureVMConfiguration *Role, lun int, hostCaching HostCachingType) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	err := verifyDataDiskHostCaching(hostCaching)
+	if err != nil {
+		return nil, err
+	}
+
+	dataDisks := azureVMConfiguration.DataVirtualHardDisks.DataVirtualHardDisk
+	for i := range dataDisks {
+		if dataDisks[i].Lun != lun {
+			continue
+		}
+
+		dataDisks[i].HostCaching = hostCaching
+		return azureVMConfiguration, nil
+	}
+
+	return nil, fmt.Errorf(dataDiskNotFoundError, lun)
+}
+
+// SetAzureVMBootDiagnostics enables capturing the serial console output and a
+// screenshot of the VM to the storage account holding its OS disk. Once the
+// VM is running, the blob locations are reported in the DebugSettings of the
+// role returned by GetRole.
+func SetAzureVMBootDiagnostics(azureVMConfiguration *Role, enabled bool) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	if !enabled {
+		azureVMConfiguration.DebugSettings = nil
+		return azureVMConfiguration, nil
+	}
+
+	azureVMConfiguration.DebugSettings = &DebugSettings{BootDiagnosticsEnabled: true}
+
+	return azureVMConfiguration, nil
+}
+
+// SetAzureVMGuestAgent controls whether the VM agent is provisioned on the
+// role. Images that do not ship the agent must disable it, which in turn
+// rules out resource extensions.
+func SetAzureVMGuestAgent(azureVMConfiguration *Role, provisionGuestAgent bool) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	extensionCount := len(azureVMConfiguration.ResourceExtensionReferences.ResourceExtensionReference)
+	if !provisionGuestAgent && extensionCount > 0 {
+		return nil, fmt.Errorf(guestAgentExtensionsConfiguredError, azureVMConfiguration.RoleName, extensionCount)
+	}
+
+	azureVMConfiguration.ProvisionGuestAgent = provisionGuestAgent
+
+	return azureVMConfiguration, nil
+}
+
+func SetAzureVMExtension(azureVMConfiguration *Role, name string, publisher string, version string, referenceName string, state string, publicConfigurationValue string, privateConfigurationValue string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if len(publisher) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "publisher")
+	}
+	if len(version) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "version")
+	}
+	if len(referenceName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "referenceName")
+	}
+	if !azureVMConfiguration.ProvisionGuestAgent {
+		return nil, fmt.Errorf(guestAgentRequiredError, name, azureVMConfiguration.RoleName)
+	}
+
+	azureVMConfiguration = azureVMConfiguration.Clone()
+
+	extension := ResourceExtensionReference{}
+	extension.Name = name
+	extension.Publisher = publisher
+	extension.Version = version
+	extension.ReferenceName = referenceName
+	extension.State = state
+
+	if len(privateConfigurationValue) > 0 {
+		privateConfig := ResourceExtensionParameter{}
+		privateConfig.Key = "ignored"
+		privateConfig.Value = base64.StdEncoding.EncodeToString([]byte(privateConfigurationValue))
+		privateConfig.Type = "Private"
+
+		extension.ResourceExtensionParameterValues.ResourceExtensionParameterValue = append(extension.ResourceExtensionParameterValues.ResourceExtensionParameterValue, privateConfig)
+	}
+
+	if len(publicConfigurationValue) > 0 {
+		publicConfig := ResourceExtensionParameter{}
+		publicConfig.Key = "ignored"
+		publicConfig.Value = base64.StdEncoding.EncodeToString([]byte(publicConfigurationValue))
+		publicConfig.Type = "Public"
+
+		extension.ResourceExtensionParameterValues.ResourceExtensionParameterValue = append(extension.ResourceExtensionParameterValues.ResourceExtensionParameterValue, publicConfig)
+	}
+
+	azureVMConfiguration.ResourceExtensionReferences.ResourceExtensionReference = append(azureVMConfiguration.ResourceExtensionReferences.ResourceExtensionReference, extension)
+
+	return azureVMConfiguration, nil
+}
+
+func SetAzureVMExtensionWithConfig(azureVMConfiguration *Role, name string, publisher string, version string, referenceName string, state string, publicConfiguration interface{}, privateConfiguration interface{}) (*Role, error) {
+	publicConfigurationValue := ""
+	if publicConfiguration != nil {
+		var err error
+		publicConfigurationValue, err = createJsonConfig(publicConfiguration)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	privateConfigurationValue := ""
+	if privateConfiguration != nil {
+		var err error
+		privateConfigurationValue, err = createJsonConfig(privateConfiguration)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return SetAzureVMExtension(azureVMConfiguration, name, publisher, version, referenceName, state, publicConfigurationValue, privateConfigurationValue)
+}
+
+func SetAzureDockerVMExtension(azureVMConfiguration *Role, dockerPort int, version string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+
+	return setAzureDockerVMExtension(azureVMConfiguration, dockerPort, version, "{}")
+}
+
+// SetAzureDockerVMExtensionWithTLS configures the Docker extension to only
+// accept clients with a certificate signed by the CA in certificates. If
+// certificates is nil, new ones are generated for the host name of the cloud
+// service the VM is created in. The certificates are returned so the client
+// certificate can be handed to Docker clients.
+func SetAzureDockerVMExtensionWithTLS(azureVMConfiguration *Role, cloudserviceName string, dockerPort int, version string, certificates *DockerCertificates) (*Role, *DockerCertificates, error) {
+	if azureVMConfiguration == nil {
+		return nil, nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+
+	if certificates == nil {
+		if len(cloudserviceName) == 0 {
+			return nil, nil, fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+		}
+
+		var err error
+		certificates, err = GenerateDockerCertificates(cloudserviceName + cloudServiceDomainSuffix)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	privateConfiguration, err := createDockerPrivateConfig(certificates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	azureVMConfiguration, err = setAzureDockerVMExtension(azureVMConfiguration, dockerPort, version, privateConfiguration)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return azureVMConfiguration, certificates, nil
+}
+
+// GenerateSSHCertificate creates an RSA key pair of the given size, 2048 bits
+// if keySize is 0, along with a self-signed certificate for its public key
+// which can be used for Linux SSH configuration.
+func GenerateSSHCertificate(keySize int) (*SSHCertificate, error) {
+	if keySize == 0 {
+		keySize = certificateKeySize
+	}
+	if keySize < minSSHKeySize {
+		return nil, fmt.Errorf(invalidSSHKeySizeError, minSSHKeySize)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := createCertificateTemplate(sshCertificateName)
+	if err != nil {
+		return nil, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	sshCertificate := new(SSHCertificate)
+	sshCertificate.Certificate = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	sshCertificate.PrivateKey = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	sshCertificate.PublicKey = marshalSSHPublicKey(&key.PublicKey)
+	sshCertificate.Fingerprint, err = getServiceCertFingerprint(sshCertificate.Certificate, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return sshCertificate, nil
+}
+
+func GenerateDockerCertificates(hostName string) (*DockerCertificates, error) {
+	if len(hostName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "hostName")
+	}
+
+	caTemplate, err := createCertificateTemplate(hostName + " CA")
+	if err != nil {
+		return nil, err
+	}
+	caTemplate.IsCA = true
+	caTemplate.BasicConstraintsValid = true
+	caTemplate.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	caKey, err := rsa.GenerateKey(rand.Reader, certificateKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	caCertDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	caCert, err := x509.ParseCertificate(caCertDER)
+	if err != nil {
+		return nil, err
+	}
+
+	serverTemplate, err := createCertificateTemplate(hostName)
+	if err != nil {
+		return nil, err
+	}
+	serverTemplate.DNSNames = []string{hostName}
+	serverTemplate.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	serverTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+
+	serverCert, serverKey, err := createSignedCertificate(serverTemplate, caCert, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientTemplate, err := createCertificateTemplate("client")
+	if err != nil {
+		return nil, err
+	}
+	clientTemplate.KeyUsage = x509.KeyUsageDigitalSignature
+	clientTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+
+	clientCert, clientKey, err := createSignedCertificate(clientTemplate, caCert, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates := new(DockerCertificates)
+	certificates.CACert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCertDER})
+	certificates.CAKey = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(caKey)})
+	certificates.ServerCert = serverCert
+	certificates.ServerKey = serverKey
+	certificates.ClientCert = clientCert
+	certificates.ClientKey = clientKey
+
+	return certificates, nil
+}
+
+func SetAzurePuppetExtension(azureVMConfiguration *Role, puppetMasterServer string, version string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(puppetMasterServer) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "puppetMasterServer")
+	}
+
+	if len(version) == 0 {
+		version = "3.*"
+	}
+
+	publicConfiguration := puppetPublicConfig{PuppetMasterServer: puppetMasterServer}
+
+	return SetAzureVMExtensionWithConfig(azureVMConfiguration, "PuppetEnterpriseAgent", "PuppetLabs", version, "PuppetEnterpriseAgent", "enable", publicConfiguration, nil)
+}
+
+func SetAzureLinuxDiagnosticsExtension(azureVMConfiguration *Role, storageAccountName, storageAccountKey string, perfCounters []LinuxDiagnosticsPerfCounter, enableSyslog bool, version string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(storageAccountName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "storageAccountName")
+	}
+	if len(storageAccountKey) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "storageAccountKey")
+	}
+
+	if len(version) == 0 {
+		version = "2.*"
+	}
+
+	publicConfig := linuxDiagnosticsPublicConfig{PerfCfg: perfCounters}
+	if enableSyslog {
+		publicConfig.EnableSyslog = "true"
+	}
+
+	privateConfig := createDiagnosticsPrivateConfig(storageAccountName, storageAccountKey)
+
+	return SetAzureVMExtensionWithConfig(azureVMConfiguration, "LinuxDiagnostic", "Microsoft.OSTCExtensions", version, "LinuxDiagnostic", "enable", publicConfig, privateConfig)
+}
+
+func SetAzureWindowsDiagnosticsExtension(azureVMConfiguration *Role, storageAccountName, storageAccountKey, wadConfiguration string, version string) (*Role, error) {
+	if azureVMConfiguration == nil {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+	if len(storageAccountName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "storageAccountName")
+	}
+	if len(storageAccountKey) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "storageAccountKey")
+	}
+	if len(wadConfiguration) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "wadConfiguration")
+	}
+
+	if len(version) == 0 {
+		version = "1.*"
+	}
+
+	publicConfig := windowsDiagnosticsPublicConfig{
+		XmlCfg:         base64.StdEncoding.EncodeToString([]byte(wadConfiguration)),
+		StorageAccount: storageAccountName,
+	}
+
+	privateConfig := createDiagnosticsPrivateConfig(storageAccountName, storageAccountKey)
+
+	return SetAzureVMExtensionWithConfig(azureVMConfiguration, "IaaSDiagnostics", "Microsoft.Azure.Diagnostics", version, "IaaSDiagnostics", "enable", publicConfig, privateConfig)
+}
+
+func GetVMDeployment(cloudserviceName, deploymentName string) (*VMDeployment, error) {
+	if len(cloudserviceName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+
+	deployment := new(VMDeployment)
+
+	requestURL := fmt.Sprintf(azureDeploymentURL, cloudserviceName, deploymentName)
+	response, azureErr := azure.SendAzureGetRequest(requestURL)
+	if azureErr != nil {
+		return nil, azureErr
+	}
+
+	err := xml.Unmarshal(response, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
+
+// CreateInternalLoadBalancer creates an internal load balancer in an existing
+// deployment. The frontend address is allocated from subnetName, or is
+// staticIPAddress if given.
+func CreateInternalLoadBalancer(cloudserviceName, deploymentName, name, subnetName, staticIPAddress string) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+
+	loadBalancer, err := createInternalLoadBalancer(name, subnetName, staticIPAddress)
+	if err != nil {
+		return err
+	}
+
+	loadBalancerBytes, err := xml.Marshal(loadBalancer)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureLoadBalancerListURL, cloudserviceName, deploymentName)
+	requestId, err := azure.SendAzurePostRequest(requestURL, loadBalancerBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// UpdateInternalLoadBalancer changes the subnet or static address of an
+// internal load balancer of the deployment.
+func UpdateInternalLoadBalancer(cloudserviceName, deploymentName, name, subnetName, staticIPAddress string) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+
+	loadBalancer, err := createInternalLoadBalancer(name, subnetName, staticIPAddress)
+	if err != nil {
+		return err
+	}
+
+	loadBalancerBytes, err := xml.Marshal(loadBalancer)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureLoadBalancerURL, cloudserviceName, deploymentName, name)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", loadBalancerBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// DeleteInternalLoadBalancer deletes an internal load balancer of the
+// deployment. No input endpoint may reference it anymore.
+func DeleteInternalLoadBalancer(cloudserviceName, deploymentName, name string) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	requestURL := fmt.Sprintf(azureLoadBalancerURL, cloudserviceName, deploymentName, name)
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func DeleteVMDeployment(cloudserviceName, deploymentName string, deleteMedia bool) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+
+	requestURL := fmt.Sprintf(azureDeploymentURL, cloudserviceName, deploymentName)
+	if deleteMedia {
+		requestURL += deleteMediaQuery
+	}
+
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	azure.WaitAsyncOperation(requestId)
+
+	return nil
+}
+
+func GetRole(cloudserviceName, deploymentName, roleName string) (*Role, error) {
+	if len(cloudserviceName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+
+	role := new(Role)
+
+	requestURL := fmt.Sprintf(azureRoleURL, cloudserviceName, deploymentName, roleName)
+	response, etag, azureErr := azure.SendAzureGetRequestWithETagAndVersion(requestURL, bootDiagnosticsApiVersion)
+	if azureErr != nil {
+		return nil, azureErr
+	}
+
+	err := xml.Unmarshal(response, role)
+	if err != nil {
+		return nil, err
+	}
+
+	role.ETag = etag
+
+	return role, nil
+}
+
+func UpdateRole(cloudserviceName, deploymentName, roleName string, role *Role) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+	if role == nil {
+		return fmt.Errorf(paramNotSpecifiedError, "role")
+	}
+
+	persistentVMRole := PersistentVMRole{Xmlns: azureXmlns, Role: *role}
+	roleBytes, err := xml.Marshal(persistentVMRole)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureRoleURL, cloudserviceName, deploymentName, roleName)
+	requestId, azureErr := azure.SendAzurePutRequestWithETagAndVersion(requestURL, "", roleBytes, role.ETag, rolesApiVersion(role))
+	if azureErr != nil {
+		if typedErr, ok := azureErr.(*azure.AzureError); ok && typedErr.StatusCode == http.StatusPreconditionFailed {
+			return &PreconditionFailedError{RoleName: roleName, ETag: role.ETag}
+		}
+		return azureErr
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// ResizeRole changes the size of the role and waits for it to come back, or
+// to be stopped again if it was stopped before, for at most the default role
+// ready timeout of 30 minutes. The new size must be
+// available in the location of the cloud service and support the data disks
+// attached to the role.
+func ResizeRole(cloudserviceName, deploymentName, roleName, newSize string) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+	if len(newSize) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "newSize")
+	}
+
+	role, err := GetRole(cloudserviceName, deploymentName, roleName)
+	if err != nil {
+		return err
+	}
+
+	if role.RoleSize == newSize {
+		return nil
+	}
+
+	hostedService, err := hostedServiceClient.GetHostedService(cloudserviceName, false)
+	if err != nil {
+		return err
+	}
+
+	location := hostedService.HostedServiceProperties.Location
+	if len(location) > 0 {
+		locationInfo, err := locationClient.GetLocation(location)
+		if err != nil {
+			return err
+		}
+
+		sizeAvailable, err := isInstanceSizeAvailableInLocation(locationInfo, newSize)
+		if err != nil {
+			return err
+		}
+
+		if sizeAvailable == false {
+			return fmt.Errorf(invalidRoleSizeInLocationError, newSize, location)
+		}
+	}
+
+	role.RoleSize = newSize
+	err = VerifyRoleSizeDataDiskCount(role)
+	if err != nil {
+		return err
+	}
+
+	deployment, err := GetVMDeployment(cloudserviceName, deploymentName)
+	if err != nil {
+		return err
+	}
+	roleInstance := findRoleInstance(deployment, roleName)
+
+	err = UpdateRole(cloudserviceName, deploymentName, roleName, role)
+	if err != nil {
+		return err
+	}
+
+	// A stopped VM stays stopped when it is resized
+	if roleInstance != nil && roleInstance.IsStopped() {
+		return WaitForRoleStopped(cloudserviceName, deploymentName, roleName, defaultRoleReadyTimeout)
+	}
+
+	if !role.ProvisionGuestAgent {
+		return WaitForRoleRunning(cloudserviceName, deploymentName, roleName, defaultRoleReadyTimeout)
+	}
+
+	return WaitForRoleReady(cloudserviceName, deploymentName, roleName, defaultRoleReadyTimeout)
+}
+
+// WaitForRoleReady waits until the guest agent of the role reports it as
+// ready. A role instance which fails or is stopped while waiting is reported
+// as an error; use WaitForRoleStopped to wait for a role to shut down.
+func WaitForRoleReady(cloudserviceName, deploymentName, roleName string, timeout time.Duration) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+
+	return waitForRoleInstance(cloudserviceName, deploymentName, roleName, timeout, string(RoleInstanceStatusReadyRole), (*RoleInstance).IsReady)
+}
+
+// WaitForRoleRunning waits until the VM behind the role is powered on. Unlike
+// WaitForRoleReady it does not depend on the guest agent reporting status, so
+// it can be used for roles created with ProvisionGuestAgent disabled.
+func WaitForRoleRunning(cloudserviceName, deploymentName, roleName string, timeout time.Duration) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+
+	return waitForRoleInstance(cloudserviceName, deploymentName, roleName, timeout, string(PowerStateStarted), (*RoleInstance).IsRunning)
+}
+
+// WaitForRoleStopped waits until the role is stopped, either still allocated
+// or deallocated, e.g. after ShutdownRole.
+func WaitForRoleStopped(cloudserviceName, deploymentName, roleName string, timeout time.Duration) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+
+	return waitForRoleInstance(cloudserviceName, deploymentName, roleName, timeout, string(RoleInstanceStatusStoppedVM), (*RoleInstance).IsStopped)
+}
+
+func GetRoleInstanceNetworkInfo(cloudserviceName, deploymentName, roleName string) (*RoleInstanceNetworkInfo, error) {
+	if len(cloudserviceName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+
+	deployment, err := GetVMDeployment(cloudserviceName, deploymentName)
+	if err != nil {
+		return nil, err
+	}
+
+	roleInstance := findRoleInstance(deployment, roleName)
+	if roleInstance == nil {
+		return nil, fmt.Errorf(roleInstanceNotFoundError, roleName, deploymentName)
+	}
+
+	networkInfo := new(RoleInstanceNetworkInfo)
+	networkInfo.RoleName = roleInstance.RoleName
+	networkInfo.InstanceName = roleInstance.InstanceName
+	networkInfo.IPAddress = roleInstance.IpAddress
+	networkInfo.Endpoints = roleInstance.InstanceEndpoints.InstanceEndpoint
+
+	if len(deployment.VirtualIPs.VirtualIP) > 0 {
+		networkInfo.VirtualIPAddress = deployment.VirtualIPs.VirtualIP[0].Address
+	}
+
+	for _, endpoint := range networkInfo.Endpoints {
+		if len(networkInfo.VirtualIPAddress) > 0 {
+			break
+		}
+
+		networkInfo.VirtualIPAddress = endpoint.Vip
+	}
+
+	return networkInfo, nil
+}
+
+// WaitForSSH waits until the SSH endpoint of a Linux role accepts TCP
+// connections and returns the address to connect to. The SSH endpoint is the
+// instance endpoint with local port 22.
+func WaitForSSH(cloudserviceName, deploymentName, roleName string, timeout time.Duration) (*SSHConnectionInfo, error) {
+	if len(cloudserviceName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	err := WaitForRoleRunning(cloudserviceName, deploymentName, roleName, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	networkInfo, err := GetRoleInstanceNetworkInfo(cloudserviceName, deploymentName, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	connectionInfo := new(SSHConnectionInfo)
+	connectionInfo.DnsName = cloudserviceName + cloudServiceDomainSuffix
+	connectionInfo.IPAddress = networkInfo.VirtualIPAddress
+	for _, endpoint := range networkInfo.Endpoints {
+		if endpoint.LocalPort != sshPort {
+			continue
+		}
+
+		connectionInfo.Port = endpoint.PublicPort
+		if len(endpoint.Vip) > 0 {
+			connectionInfo.IPAddress = endpoint.Vip
+		}
+		break
+	}
+
+	if connectionInfo.Port == 0 {
+		return nil, fmt.Errorf(sshEndpointNotFoundError, roleName)
+	}
+
+	address := net.JoinHostPort(connectionInfo.IPAddress, strconv.Itoa(connectionInfo.Port))
+	for {
+		conn, err := net.DialTimeout("tcp", address, sshDialTimeout)
+		if err == nil {
+			conn.Close()
+			return connectionInfo, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf(sshTimeoutError, timeout, address, err)
+		}
+
+		time.Sleep(5000 * time.Millisecond)
+	}
+}
+
+func GetRoleInstanceExtensionStatus(cloudserviceName, deploymentName, instanceName string) ([]ResourceExtensionStatus, error) {
+	if len(cloudserviceName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(instanceName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "instanceName")
+	}
+
+	deployment, err := GetVMDeployment(cloudserviceName, deploymentName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, roleInstance := range deployment.RoleInstanceList.RoleInstance {
+		if roleInstance.InstanceName != instanceName {
+			continue
+		}
+
+		return roleInstance.ResourceExtensionStatusList.ResourceExtensionStatus, nil
+	}
+
+	return nil, fmt.Errorf(roleInstanceByNameNotFoundError, instanceName, deploymentName)
+}
+
+func StartRole(cloudserviceName, deploymentName, roleName string) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+
+	startRoleOperation := createStartRoleOperation()
+
+	startRoleOperationBytes, err := xml.Marshal(startRoleOperation)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureOperationsURL, cloudserviceName, deploymentName, roleName)
+	requestId, azureErr := azure.SendAzurePostRequest(requestURL, startRoleOperationBytes)
+	if azureErr != nil {
+		return azureErr
+	}
+
+	azure.WaitAsyncOperation(requestId)
+	return nil
+}
+
+func ShutdownRole(cloudserviceName, deploymentName, roleName string, postShutdownAction PostShutdownAction) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+	if len(postShutdownAction) == 0 {
+		postShutdownAction = PostShutdownActionStopped
+	}
+
+	shutdownRoleOperation := createShutdowRoleOperation(postShutdownAction)
+
+	shutdownRoleOperationBytes, err := xml.Marshal(shutdownRoleOperation)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureOperationsURL, cloudserviceName, deploymentName, roleName)
+	requestId, azureErr := azure.SendAzurePostRequest(requestURL, shutdownRoleOperationBytes)
+	if azureErr != nil {
+		return azureErr
+	}
+
+	azure.WaitAsyncOperation(requestId)
+	return nil
+}
+
+func StartRoles(cloudserviceName, deploymentName string, roleNames []string) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleNames) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roleNames")
+	}
+
+	startRolesOperation := createStartRolesOperation(roleNames)
+
+	startRolesOperationBytes, err := xml.Marshal(startRolesOperation)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureRolesOperationsURL, cloudserviceName, deploymentName)
+	requestId, azureErr := azure.SendAzurePostRequest(requestURL, startRolesOperationBytes)
+	if azureErr != nil {
+		return azureErr
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func ShutdownRoles(cloudserviceName, deploymentName string, roleNames []string, postShutdownAction PostShutdownAction) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleNames) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roleNames")
+	}
+	if len(postShutdownAction) == 0 {
+		postShutdownAction = PostShutdownActionStopped
+	}
+
+	shutdownRolesOperation := createShutdownRolesOperation(roleNames, postShutdownAction)
+
+	shutdownRolesOperationBytes, err := xml.Marshal(shutdownRolesOperation)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureRolesOperationsURL, cloudserviceName, deploymentName)
+	requestId, azureErr := azure.SendAzurePostRequest(requestURL, shutdownRolesOperationBytes)
+	if azureErr != nil {
+		return azureErr
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func RestartRole(cloudserviceName, deploymentName, roleName string) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+
+	restartRoleOperation := createRestartRoleOperation()
+
+	restartRoleOperationBytes, err := xml.Marshal(restartRoleOperation)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureOperationsURL, cloudserviceName, deploymentName, roleName)
+	requestId, azureErr := azure.SendAzurePostRequest(requestURL, restartRoleOperationBytes)
+	if azureErr != nil {
+		return azureErr
+	}
+
+	azure.WaitAsyncOperation(requestId)
+	return nil
+}
+
+func DeleteRole(cloudserviceName, deploymentName, roleName string, deleteMedia bool) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if len(roleName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+	}
+
+	requestURL := fmt.Sprintf(azureRoleURL, cloudserviceName, deploymentName, roleName)
+	if deleteMedia {
+		requestURL += deleteMediaQuery
+	}
+
+	requestId, azureErr := azure.SendAzureDeleteRequest(requestURL)
+	if azureErr != nil {
+		return azureErr
+	}
+
+	azure.WaitAsyncOperation(requestId)
+	return nil
+}
+
+// GetRoleSizeList returns the role sizes available to the subscription. The
+// list is cached for the duration set by SetRoleSizeCacheTTL, use
+// RefreshRoleSizeList to bypass the cache. The returned list is a copy which
+// the caller may change.
+func GetRoleSizeList() (RoleSizeList, error) {
+	roleSizeCache.Lock()
+	if roleSizeCache.roleSizes != nil && time.Since(roleSizeCache.fetchedAt) < roleSizeCache.ttl {
+		roleSizes := roleSizeCache.roleSizes.clone()
+		roleSizeCache.Unlock()
+		return roleSizes, nil
+	}
+	roleSizeCache.Unlock()
+
+	return refreshRoleSizeList()
+}
+
+func RefreshRoleSizeList() (RoleSizeList, error) {
+	return refreshRoleSizeList()
+}
+
+// SetRoleSizeCacheTTL changes how long the role size list is cached. A ttl of
+// zero disables caching.
+func SetRoleSizeCacheTTL(ttl time.Duration) {
+	roleSizeCache.Lock()
+	defer roleSizeCache.Unlock()
+
+	roleSizeCache.ttl = ttl
+}
+
+func ResolveRoleSize(roleSizeName string) error {
+	if len(roleSizeName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "roleSizeName")
+	}
+
+	_, err := GetRoleSize(roleSizeName)
+	return err
+}
+
+func GetRoleSize(roleSizeName string) (*RoleSize, error) {
+	if len(roleSizeName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "roleSizeName")
+	}
+
+	roleSizeList, err := GetRoleSizeList()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, roleSize := range roleSizeList.RoleSizes {
+		if roleSize.Name != roleSizeName {
+			continue
+		}
+
+		return &roleSize, nil
+	}
+
+	var availableSizes bytes.Buffer
+	for _, existingSize := range roleSizeList.RoleSizes {
+		availableSizes.WriteString(existingSize.Name + ", ")
+	}
+
+	return nil, errors.New(fmt.Sprintf(invalidRoleSizeError, roleSizeName, strings.Trim(availableSizes.String(), ", ")))
+}
+
+func SelectRoleSize(minCores, minMemoryInMb, minDataDiskCount int) (*RoleSize, error) {
+	roleSizeList, err := GetRoleSizeList()
+	if err != nil {
+		return nil, err
+	}
+
+	var selectedSize *RoleSize
+	for _, roleSize := range roleSizeList.RoleSizes {
+		if !roleSize.SupportedByVirtualMachines {
+			continue
+		}
+		if roleSize.Cores < minCores || roleSize.MemoryInMb < minMemoryInMb || roleSize.MaxDataDiskCount < minDataDiskCount {
+			continue
+		}
+
+		if selectedSize == nil || isSmallerRoleSize(roleSize, *selectedSize) {
+			candidateSize := roleSize
+			selectedSize = &candidateSize
+		}
+	}
+
+	if selectedSize == nil {
+		return nil, fmt.Errorf(noMatchingRoleSizeError, minCores, minMemoryInMb, minDataDiskCount)
+	}
+
+	return selectedSize, nil
+}
+
+func VerifyRoleSizeDataDiskCount(azureVMConfiguration *Role) error {
+	if azureVMConfiguration == nil {
+		return fmt.Errorf(paramNotSpecifiedError, "azureVMConfiguration")
+	}
+
+	roleSize, err := GetRoleSize(azureVMConfiguration.RoleSize)
+	if err != nil {
+		return err
+	}
+
+	dataDiskCount := len(azureVMConfiguration.DataVirtualHardDisks.DataVirtualHardDisk)
+	if dataDiskCount > roleSize.MaxDataDiskCount {
+		return fmt.Errorf(invalidRoleSizeDataDiskCountError, roleSize.Name, roleSize.MaxDataDiskCount, azureVMConfiguration.RoleName, dataDiskCount)
+	}
+
+	return nil
+}
+
+// CheckCoreQuota adds up the cores of the role sizes of the given roles and
+// compares them with the core quota and current core usage of the
+// subscription, so a batch of virtual machines can be checked before any of
+// them is created. Use Fits on the result to find out whether the batch fits.
+func CheckCoreQuota(roles []*Role) (*CoreQuota, error) {
+	if len(roles) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "roles")
+	}
+
+	quota := &CoreQuota{RequestedCoresByRoleSize: map[string]int{}}
+	for _, role := range roles {
+		if role == nil {
+			return nil, fmt.Errorf(paramNotSpecifiedError, "roles")
+		}
+
+		roleSize, err := GetRoleSize(role.RoleSize)
+		if err != nil {
+			return nil, err
+		}
+
+		quota.RequestedCores += roleSize.Cores
+		quota.RequestedCoresByRoleSize[roleSize.Name] += roleSize.Cores
+	}
+
+	subscription, err := subscriptionClient.GetSubscription()
+	if err != nil {
+		return nil, err
+	}
+
+	quota.MaxCores = subscription.MaxCoreCount
+	quota.CurrentCores = subscription.CurrentCoreCount
+
+	return quota, nil
+}
+
+// VerifyCoreQuota returns an error if the roles need more cores than the
+// subscription has left, see CheckCoreQuota.
+func VerifyCoreQuota(roles []*Role) error {
+	quota, err := CheckCoreQuota(roles)
+	if err != nil {
+		return err
+	}
+
+	if !quota.Fits() {
+		return fmt.Errorf(coreQuotaExceededError, quota.RequestedCores, quota.AvailableCores(), quota.MaxCores)
+	}
+
+	return nil
+}
+
+func ListResourceExtensions() (ResourceExtensionList, error) {
+	resourceExtensionList := ResourceExtensionList{}
+
+	response, err := azure.SendAzureGetRequest(azureResourceExtensionListURL)
+	if err != nil {
+		return resourceExtensionList, err
+	}
+
+	err = xml.Unmarshal(response, &resourceExtensionList)
+	if err != nil {
+		return resourceExtensionList, err
+	}
+
+	return resourceExtensionList, nil
+}
+
+func ListResourceExtensionVersions(publisher, name string) (ResourceExtensionList, error) {
+	resourceExtensionList := ResourceExtensionList{}
+
+	if len(publisher) == 0 {
+		return resourceExtensionList, fmt.Errorf(paramNotSpecifiedError, "publisher")
+	}
+	if len(name) == 0 {
+		return resourceExtensionList, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	requestURL := fmt.Sprintf(azureResourceExtensionVersionsListURL, publisher, name)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return resourceExtensionList, err
+	}
+
+	err = xml.Unmarshal(response, &resourceExtensionList)
+	if err != nil {
+		return resourceExtensionList, err
+	}
+
+	return resourceExtensionList, nil
+}
+
+func ResolveLatestResourceExtensionVersion(publisher, name string) (string, error) {
+	resourceExtensionList, err := ListResourceExtensionVersions(publisher, name)
+	if err != nil {
+		return "", err
+	}
+
+	latestVersion := ""
+	for _, resourceExtension := range resourceExtensionList.ResourceExtensions {
+		if len(latestVersion) == 0 || compareVersions(resourceExtension.Version, latestVersion) > 0 {
+			latestVersion = resourceExtension.Version
+		}
+	}
+
+	if len(latestVersion) == 0 {
+		return "", fmt.Errorf(resourceExtensionNotFoundError, name, publisher)
+	}
+
+	return latestVersion, nil
+}
+
+// UploadServiceCertificate adds a certificate to a cloud service, e.g. for SSL
+// endpoints or WinRM over HTTPS. certData can be a PFX protected with
+// certPassword, or PEM with the certificate and its private key, which is
+// converted to a PFX protected with certPassword before it is uploaded. A
+// PEM or DER certificate without private key is uploaded as is.
+func UploadServiceCertificate(cloudserviceName string, certData []byte, certPassword string) error {
+	if len(cloudserviceName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(certData) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "certData")
+	}
+
+	return uploadServiceCert(cloudserviceName, certData, certPassword)
+}
+
+//Region public methods ends
+
+//Region private methods starts
+
+// verifyCloudServiceName checks that a cloud service with the given name can
+// be created, or already exists in this subscription in the given location.
+// It reports whether the cloud service exists.
+func verifyCloudServiceName(dnsName, location string) (bool, error) {
+	hostedService, err := hostedServiceClient.VerifyHostedServiceName(dnsName, location)
+	if err != nil {
+		return false, err
+	}
+
+	return hostedService != nil, nil
+}
+
+// refreshRoleSizeList fetches the role size list and caches it. The cache is
+// not locked while the request is in progress, callers arriving in the
+// meantime wait for its result.
+func refreshRoleSizeList() (RoleSizeList, error) {
+	roleSizeCache.Lock()
+	fetch := roleSizeCache.fetch
+	if fetch == nil {
+		fetch = &roleSizeListFetch{done: make(chan struct{})}
+		roleSizeCache.fetch = fetch
+		roleSizeCache.Unlock()
+
+		fetch.roleSizes, fetch.err = fetchRoleSizeList()
+
+		roleSizeCache.Lock()
+		if fetch.err == nil {
+			roleSizes := fetch.roleSizes.clone()
+			roleSizeCache.roleSizes = &roleSizes
+			roleSizeCache.fetchedAt = time.Now()
+		}
+		roleSizeCache.fetch = nil
+		close(fetch.done)
+	}
+	roleSizeCache.Unlock()
+
+	<-fetch.done
+	if fetch.err != nil {
+		return RoleSizeList{}, fetch.err
+	}
+
+	return fetch.roleSizes.clone(), nil
+}
+
+func fetchRoleSizeList() (RoleSizeList, error) {
+	roleSizeList := RoleSizeList{}
+
+	response, err := azure.SendAzureGetRequest(azureRoleSizeListURL)
+	if err != nil {
+		return roleSizeList, err
+	}
+
+	err = xml.Unmarshal(response, &roleSizeList)
+	if err != nil {
+		return roleSizeList, err
+	}
+
+	return roleSizeList, nil
 }
 
-func RestartRole(cloudserviceName, deploymentName, roleName string) error {
-	if len(cloudserviceName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+func isSmallerRoleSize(roleSize, otherRoleSize RoleSize) bool {
+	if roleSize.Cores != otherRoleSize.Cores {
+		return roleSize.Cores < otherRoleSize.Cores
 	}
-	if len(deploymentName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	if roleSize.MemoryInMb != otherRoleSize.MemoryInMb {
+		return roleSize.MemoryInMb < otherRoleSize.MemoryInMb
 	}
-	if len(roleName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+
+	return roleSize.MaxDataDiskCount < otherRoleSize.MaxDataDiskCount
+}
+
+// waitForRoleInstance polls the role instance until reached reports true.
+// Failed instances end the wait with an error unless reached accepts them.
+// A stopped instance only does so once it was seen in another status, as a
+// role which is being started may still be reported as stopped at first.
+func waitForRoleInstance(cloudserviceName, deploymentName, roleName string, timeout time.Duration, target string, reached func(*RoleInstance) bool) error {
+	deadline := time.Now().Add(timeout)
+	var lastStatus RoleInstanceStatus
+	seenNotStopped := false
+	for {
+		deployment, err := GetVMDeployment(cloudserviceName, deploymentName)
+		if err != nil {
+			return err
+		}
+
+		roleInstance := findRoleInstance(deployment, roleName)
+		if roleInstance == nil {
+			return fmt.Errorf(roleInstanceNotFoundError, roleName, deploymentName)
+		}
+
+		lastStatus = roleInstance.InstanceStatus
+		if reached(roleInstance) {
+			return nil
+		}
+		if roleInstance.IsFailed() || (roleInstance.IsStopped() && seenNotStopped) {
+			return fmt.Errorf(roleInstanceFailedError, roleName, lastStatus, target)
+		}
+		if !roleInstance.IsStopped() {
+			seenNotStopped = true
+		}
+
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			return fmt.Errorf(roleInstanceTimeoutError, timeout, roleName, target, lastStatus)
+		}
+
+		// Do not sleep past the deadline, so that the wait is bounded by timeout
+		if remaining > roleInstancePollInterval {
+			remaining = roleInstancePollInterval
+		}
+		time.Sleep(remaining)
 	}
+}
 
-	restartRoleOperation := createRestartRoleOperation()
+func findRoleInstance(deployment *VMDeployment, roleName string) *RoleInstance {
+	for _, roleInstance := range deployment.RoleInstanceList.RoleInstance {
+		if roleInstance.RoleName == roleName {
+			return roleInstance
+		}
+	}
 
-	restartRoleOperationBytes, err := xml.Marshal(restartRoleOperation)
+	return nil
+}
+
+func createStartRoleOperation() StartRoleOperation {
+	startRoleOperation := StartRoleOperation{}
+	startRoleOperation.OperationType = "StartRoleOperation"
+	startRoleOperation.Xmlns = azureXmlns
+
+	return startRoleOperation
+}
+
+func createShutdowRoleOperation(postShutdownAction PostShutdownAction) ShutdownRoleOperation {
+	shutdownRoleOperation := ShutdownRoleOperation{}
+	shutdownRoleOperation.OperationType = "ShutdownRoleOperation"
+	shutdownRoleOperation.PostShutdownAction = postShutdownAction
+	shutdownRoleOperation.Xmlns = azureXmlns
+
+	return shutdownRoleOperation
+}
+
+func createStartRolesOperation(roleNames []string) StartRolesOperation {
+	startRolesOperation := StartRolesOperation{}
+	startRolesOperation.Roles = roleNames
+	startRolesOperation.Xmlns = azureXmlns
+
+	return startRolesOperation
+}
+
+func createShutdownRolesOperation(roleNames []string, postShutdownAction PostShutdownAction) ShutdownRolesOperation {
+	shutdownRolesOperation := ShutdownRolesOperation{}
+	shutdownRolesOperation.Roles = roleNames
+	shutdownRolesOperation.PostShutdownAction = postShutdownAction
+	shutdownRolesOperation.Xmlns = azureXmlns
+
+	return shutdownRolesOperation
+}
+
+func createRestartRoleOperation() RestartRoleOperation {
+	startRoleOperation := RestartRoleOperation{}
+	startRoleOperation.OperationType = "RestartRoleOperation"
+	startRoleOperation.Xmlns = azureXmlns
+
+	return startRoleOperation
+}
+
+func setAzureDockerVMExtension(azureVMConfiguration *Role, dockerPort int, version string, privateConfiguration string) (*Role, error) {
+	if len(version) == 0 {
+		version = "0.3"
+	}
+
+	if len(azureVMConfiguration.ConfigurationSets.ConfigurationSet) == 0 {
+		return nil, errors.New(provisioningConfDoesNotExistsError)
+	}
+
+	azureVMConfiguration, err := AddInputEndpoint(azureVMConfiguration, "docker", "tcp", dockerPort, dockerPort)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	requestURL := fmt.Sprintf(azureOperationsURL, cloudserviceName, deploymentName, roleName)
-	requestId, azureErr := azure.SendAzurePostRequest(requestURL, restartRoleOperationBytes)
-	if azureErr != nil {
-		return azureErr
+	publicConfiguration, err := createDockerPublicConfig(dockerPort)
+	if err != nil {
+		return nil, err
 	}
 
-	azure.WaitAsyncOperation(requestId)
-	return nil
+	return SetAzureVMExtension(azureVMConfiguration, "DockerExtension", "MSOpenTech.Extensions", version, "DockerExtension", "enable", publicConfiguration, privateConfiguration)
 }
 
-func DeleteRole(cloudserviceName, deploymentName, roleName string) error {
-	if len(cloudserviceName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+func createDockerPrivateConfig(certificates *DockerCertificates) (string, error) {
+	if len(certificates.CACert) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "certificates.CACert")
 	}
-	if len(deploymentName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	if len(certificates.ServerCert) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "certificates.ServerCert")
 	}
-	if len(roleName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "roleName")
+	if len(certificates.ServerKey) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "certificates.ServerKey")
 	}
 
-	requestURL := fmt.Sprintf(azureRoleURL, cloudserviceName, deploymentName, roleName)
-	requestId, azureErr := azure.SendAzureDeleteRequest(requestURL)
-	if azureErr != nil {
-		return azureErr
+	config := dockerPrivateConfig{
+		CA:         base64.StdEncoding.EncodeToString(certificates.CACert),
+		ServerCert: base64.StdEncoding.EncodeToString(certificates.ServerCert),
+		ServerKey:  base64.StdEncoding.EncodeToString(certificates.ServerKey),
+	}
+
+	return createJsonConfig(config)
+}
+
+func createCertificateTemplate(commonName string) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	notBefore := time.Now().Add(-time.Hour)
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(certificateValidity),
+	}
+
+	return template, nil
+}
+
+func createSignedCertificate(template, parent *x509.Certificate, parentKey *rsa.PrivateKey) ([]byte, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, certificateKeySize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return cert, keyPEM, nil
+}
+
+func createDockerPublicConfig(dockerPort int) (string, error) {
+	config := dockerPublicConfig{DockerPort: dockerPort, Version: dockerPublicConfigVersion}
+	configJson, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	return string(configJson), nil
+}
+
+func createDiagnosticsPrivateConfig(storageAccountName, storageAccountKey string) diagnosticsPrivateConfig {
+	config := diagnosticsPrivateConfig{
+		StorageAccountName:     storageAccountName,
+		StorageAccountKey:      storageAccountKey,
+		StorageAccountEndPoint: storageAccountEndPoint,
+	}
+
+	return config
+}
+
+func createJsonConfig(config interface{}) (string, error) {
+	configJson, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	return string(configJson), nil
+}
+
+func getNetworkConfig(azureVMConfiguration *Role) *ConfigurationSet {
+	return getConfigurationSet(azureVMConfiguration, "NetworkConfiguration")
+}
+
+func getConfigurationSet(azureVMConfiguration *Role, configurationSetType string) *ConfigurationSet {
+	configurationSets := azureVMConfiguration.ConfigurationSets.ConfigurationSet
+	for i := 0; i < len(configurationSets); i++ {
+		if configurationSets[i].ConfigurationSetType == configurationSetType {
+			return &configurationSets[i]
+		}
 	}
 
-	azure.WaitAsyncOperation(requestId)
 	return nil
 }
 
-func GetRoleSizeList() (RoleSizeList, error) {
-	roleSizeList := RoleSizeList{}
+func hasRole(deployment *VMDeployment, roleName string) bool {
+	for _, role := range deployment.RoleList.Role {
+		if strings.EqualFold(role.RoleName, roleName) {
+			return true
+		}
+	}
 
-	response, err := azure.SendAzureGetRequest(azureRoleSizeListURL)
+	return false
+}
+
+// convergeRole changes the role size, availability set and input endpoints
+// of the role to those of the desired role and reports whether any of them
+// differed.
+func convergeRole(role, desired *Role) bool {
+	changed := false
+
+	if len(desired.RoleSize) > 0 && role.RoleSize != desired.RoleSize {
+		role.RoleSize = desired.RoleSize
+		changed = true
+	}
+	if role.AvailabilitySetName != desired.AvailabilitySetName {
+		role.AvailabilitySetName = desired.AvailabilitySetName
+		changed = true
+	}
+
+	var desiredEndpoints []InputEndpoint
+	if desiredNetworkConfig := getNetworkConfig(desired); desiredNetworkConfig != nil {
+		desiredEndpoints = desiredNetworkConfig.InputEndpoints.InputEndpoint
+	}
+
+	networkConfig := getNetworkConfig(role)
+	if networkConfig == nil {
+		if len(desiredEndpoints) == 0 {
+			return changed
+		}
+		role.ConfigurationSets.ConfigurationSet = append(role.ConfigurationSets.ConfigurationSet, ConfigurationSet{ConfigurationSetType: "NetworkConfiguration"})
+		networkConfig = getNetworkConfig(role)
+	}
+
+	if !equalInputEndpoints(networkConfig.InputEndpoints.InputEndpoint, desiredEndpoints) {
+		networkConfig.InputEndpoints.InputEndpoint = append([]InputEndpoint(nil), desiredEndpoints...)
+		changed = true
+	}
+
+	return changed
+}
+
+// equalInputEndpoints compares endpoints by their settings, ignoring the
+// virtual IP address Azure assigns.
+func equalInputEndpoints(endpoints, otherEndpoints []InputEndpoint) bool {
+	if len(endpoints) != len(otherEndpoints) {
+		return false
+	}
+
+	byName := map[string]InputEndpoint{}
+	for _, endpoint := range endpoints {
+		endpoint.Vip = ""
+		byName[strings.ToLower(endpoint.Name)] = endpoint
+	}
+
+	for _, otherEndpoint := range otherEndpoints {
+		otherEndpoint.Vip = ""
+		endpoint, ok := byName[strings.ToLower(otherEndpoint.Name)]
+		if !ok {
+			return false
+		}
+		endpoint.Name = otherEndpoint.Name
+		endpoint.Protocol = strings.ToLower(endpoint.Protocol)
+		otherEndpoint.Protocol = strings.ToLower(otherEndpoint.Protocol)
+		if endpoint != otherEndpoint {
+			return false
+		}
+	}
+
+	return true
+}
+
+func deployAzureVM(cloudserviceName string, azureVMConfiguration *Role, options VMDeploymentOptions) error {
+	err := uploadRoleCertificates(cloudserviceName, azureVMConfiguration)
 	if err != nil {
-		return roleSizeList, err
+		return err
 	}
 
-	err = xml.Unmarshal(response, &roleSizeList)
+	existingDeployment, err := getExistingDeployment(cloudserviceName, options.DeploymentName)
 	if err != nil {
-		return roleSizeList, err
+		return err
+	}
+
+	if existingDeployment != nil {
+		return addAzureVMRole(cloudserviceName, existingDeployment.Name, azureVMConfiguration)
+	}
+
+	deploymentName := options.DeploymentName
+	if len(deploymentName) == 0 {
+		deploymentName = azureVMConfiguration.RoleName
+	}
+
+	vMDeployment, err := createVMDeploymentConfig(deploymentName, []*Role{azureVMConfiguration}, options)
+	if err != nil {
+		return err
 	}
 
-	return roleSizeList, err
+	return sendVMDeploymentRequest(cloudserviceName, vMDeployment)
 }
 
-func ResolveRoleSize(roleSizeName string) error {
-	if len(roleSizeName) == 0 {
-		return fmt.Errorf(paramNotSpecifiedError, "roleSizeName")
+func uploadRoleCertificates(cloudserviceName string, azureVMConfiguration *Role) error {
+	if !azureVMConfiguration.UseCertAuth {
+		return nil
 	}
 
-	roleSizeList, err := GetRoleSizeList()
+	for _, certificate := range azureVMConfiguration.Certificates {
+		err := uploadServiceCert(cloudserviceName, certificate.Data, certificate.Password)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sendVMDeploymentRequest(cloudserviceName string, vMDeployment VMDeployment) error {
+	vMDeploymentBytes, err := xml.Marshal(vMDeployment)
 	if err != nil {
 		return err
 	}
 
-	for _, roleSize := range roleSizeList.RoleSizes {
-		if roleSize.Name != roleSizeName {
-			continue
+	requestURL := fmt.Sprintf(azureDeploymentListURL, cloudserviceName)
+	requestId, err := azure.SendAzurePostRequestWithVersion(requestURL, vMDeploymentBytes, rolesApiVersion(vMDeployment.RoleList.Role...))
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func getExistingDeployment(cloudserviceName, deploymentName string) (*VMDeployment, error) {
+	var deployment *VMDeployment
+	var err error
+	if len(deploymentName) > 0 {
+		deployment, err = GetVMDeployment(cloudserviceName, deploymentName)
+	} else {
+		deployment, err = getProductionDeployment(cloudserviceName)
+	}
+
+	if err != nil {
+		if isResourceNotFoundError(err) {
+			return nil, nil
 		}
+		return nil, err
+	}
 
-		return nil
+	return deployment, nil
+}
+
+func getProductionDeployment(cloudserviceName string) (*VMDeployment, error) {
+	deployment := new(VMDeployment)
+
+	requestURL := fmt.Sprintf(azureDeploymentSlotURL, cloudserviceName, azure.SlotProduction)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
 	}
 
-	var availableSizes bytes.Buffer
-	for _, existingSize := range roleSizeList.RoleSizes {
-		availableSizes.WriteString(existingSize.Name + ", ")
+	err = xml.Unmarshal(response, deployment)
+	if err != nil {
+		return nil, err
 	}
 
-	return errors.New(fmt.Sprintf(invalidRoleSizeError, roleSizeName, strings.Trim(availableSizes.String(), ", ")))
+	return deployment, nil
 }
 
-//Region public methods ends
+func addAzureVMRole(cloudserviceName, deploymentName string, azureVMConfiguration *Role) error {
+	persistentVMRole := PersistentVMRole{Xmlns: azureXmlns, Role: *azureVMConfiguration}
+	roleBytes, err := xml.Marshal(persistentVMRole)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureRoleListURL, cloudserviceName, deploymentName)
+	requestId, err := azure.SendAzurePostRequestWithVersion(requestURL, roleBytes, rolesApiVersion(azureVMConfiguration))
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// rolesApiVersion returns the API version requests with the roles have to be
+// sent with, or an empty string for the default version.
+func rolesApiVersion(roles ...*Role) string {
+	for _, role := range roles {
+		if role.DebugSettings != nil {
+			return bootDiagnosticsApiVersion
+		}
+	}
+
+	return ""
+}
+
+func isResourceNotFoundError(err error) bool {
+	azureErr, ok := err.(*azure.AzureError)
+	return ok && azureErr.Code == resourceNotFoundErrorCode
+}
+
+func createVMDeploymentConfig(deploymentName string, roles []*Role, options VMDeploymentOptions) (VMDeployment, error) {
+	deployment := VMDeployment{}
+	deployment.Name = deploymentName
+	deployment.Xmlns = azureXmlns
+	deployment.DeploymentSlot = azure.SlotProduction
+	deployment.Label = deploymentName
+	if len(options.Label) > 0 {
+		deployment.Label = options.Label
+	}
+
+	var dnsServers []DnsServer
+	for _, role := range roles {
+		if len(role.VirtualNetworkName) > 0 {
+			if len(deployment.VirtualNetworkName) > 0 && deployment.VirtualNetworkName != role.VirtualNetworkName {
+				return deployment, fmt.Errorf(virtualNetworkMismatchError, deployment.VirtualNetworkName, role.VirtualNetworkName)
+			}
+			deployment.VirtualNetworkName = role.VirtualNetworkName
+		}
+
+	nextDnsServer:
+		for _, dnsServer := range role.DnsServers {
+			for _, existingServer := range dnsServers {
+				if strings.EqualFold(existingServer.Name, dnsServer.Name) {
+					continue nextDnsServer
+				}
+			}
+			dnsServers = append(dnsServers, dnsServer)
+		}
+
+		deployment.RoleList.Role = append(deployment.RoleList.Role, role)
+	}
 
-//Region private methods starts
+	if len(dnsServers) > 0 {
+		deployment.Dns = &Dns{DnsServers: DnsServerList{DnsServer: dnsServers}}
+	}
 
-func createStartRoleOperation() StartRoleOperation {
-	startRoleOperation := StartRoleOperation{}
-	startRoleOperation.OperationType = "StartRoleOperation"
-	startRoleOperation.Xmlns = azureXmlns
+	for _, loadBalancer := range options.LoadBalancers {
+		if len(deployment.VirtualNetworkName) == 0 {
+			return deployment, fmt.Errorf(loadBalancerWithoutVirtualNetworkError, loadBalancer.Name)
+		}
 
-	return startRoleOperation
-}
+		frontend := loadBalancer.FrontendIpConfiguration
+		internalLoadBalancer, err := createInternalLoadBalancer(loadBalancer.Name, frontend.SubnetName, frontend.StaticVirtualNetworkIPAddress)
+		if err != nil {
+			return deployment, err
+		}
+		internalLoadBalancer.Xmlns = ""
 
-func createShutdowRoleOperation() ShutdownRoleOperation {
-	shutdownRoleOperation := ShutdownRoleOperation{}
-	shutdownRoleOperation.OperationType = "ShutdownRoleOperation"
-	shutdownRoleOperation.Xmlns = azureXmlns
+		if deployment.LoadBalancers == nil {
+			deployment.LoadBalancers = &LoadBalancerList{}
+		}
+		deployment.LoadBalancers.LoadBalancer = append(deployment.LoadBalancers.LoadBalancer, internalLoadBalancer)
+	}
 
-	return shutdownRoleOperation
+	return deployment, nil
 }
 
-func createRestartRoleOperation() RestartRoleOperation {
-	startRoleOperation := RestartRoleOperation{}
-	startRoleOperation.OperationType = "RestartRoleOperation"
-	startRoleOperation.Xmlns = azureXmlns
+func createInternalLoadBalancer(name, subnetName, staticIPAddress string) (LoadBalancer, error) {
+	loadBalancer := LoadBalancer{}
+	if len(name) == 0 {
+		return loadBalancer, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if len(staticIPAddress) > 0 && net.ParseIP(staticIPAddress) == nil {
+		return loadBalancer, fmt.Errorf(invalidLoadBalancerAddressError, staticIPAddress, name)
+	}
 
-	return startRoleOperation
+	loadBalancer.Xmlns = azureXmlns
+	loadBalancer.Name = name
+	loadBalancer.FrontendIpConfiguration = FrontendIpConfiguration{
+		Type:                          loadBalancerTypePrivate,
+		SubnetName:                    subnetName,
+		StaticVirtualNetworkIPAddress: staticIPAddress,
+	}
+
+	return loadBalancer, nil
 }
 
-func createDockerPublicConfig(dockerPort int) (string, error) {
-	config := dockerPublicConfig{DockerPort: dockerPort, Version: dockerPublicConfigVersion}
-	configJson, err := json.Marshal(config)
-	if err != nil {
-		return "", err
+// verifyRoleParams checks the parameters shared by the role configuration
+// functions which can be checked without calling Azure.
+func verifyRoleParams(dnsName, instanceSize, location string) error {
+	if len(dnsName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "dnsName")
+	}
+	if len(instanceSize) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "instanceSize")
+	}
+	if len(location) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "location")
 	}
 
-	return string(configJson), nil
+	return hostedServiceClient.VerifyDNSName(dnsName)
 }
 
-func addDockerPort(configurationSets []ConfigurationSet, dockerPort int) error {
-	if len(configurationSets) == 0 {
-		return errors.New(provisioningConfDoesNotExistsError)
+func verifyInstanceSizeInLocation(instanceSize, location string) error {
+	locationInfo, err := locationClient.GetLocation(location)
+	if err != nil {
+		return err
 	}
 
-	for i := 0; i < len(configurationSets); i++ {
-		if configurationSets[i].ConfigurationSetType != "NetworkConfiguration" {
-			continue
-		}
+	sizeAvailable, err := isInstanceSizeAvailableInLocation(locationInfo, instanceSize)
+	if err != nil {
+		return err
+	}
 
-		dockerEndpoint := createEndpoint("docker", "tcp", dockerPort, dockerPort)
-		configurationSets[i].InputEndpoints.InputEndpoint = append(configurationSets[i].InputEndpoints.InputEndpoint, dockerEndpoint)
+	if sizeAvailable == false {
+		return fmt.Errorf(invalidRoleSizeInLocationError, instanceSize, location)
 	}
 
 	return nil
 }
 
-func createVMDeploymentConfig(role *Role) VMDeployment {
-	deployment := VMDeployment{}
-	deployment.Name = role.RoleName
-	deployment.Xmlns = azureXmlns
-	deployment.DeploymentSlot = "Production"
-	deployment.Label = role.RoleName
-	deployment.RoleList.Role = append(deployment.RoleList.Role, role)
+func newRole(name, instanceSize string) *Role {
+	role := new(Role)
+	role.RoleName = name
+	role.RoleSize = instanceSize
+	role.RoleType = "PersistentVMRole"
+	role.ProvisionGuestAgent = true
 
-	return deployment
+	return role
 }
 
-func createAzureVMRole(name, instanceSize, imageName, location string) (*Role, error) {
-	config := new(Role)
-	config.RoleName = name
-	config.RoleSize = instanceSize
-	config.RoleType = "PersistentVMRole"
-	config.ProvisionGuestAgent = true
+func createAzureVMRole(name, instanceSize, imageName, location string, mediaOptions OSDiskMediaOptions) (*Role, error) {
+	config := newRole(name, instanceSize)
 	var err error
-	config.OSVirtualHardDisk, err = createOSVirtualHardDisk(name, imageName, location)
+	config.OSVirtualHardDisk, err = createOSVirtualHardDisk(name, imageName, location, mediaOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -542,16 +2787,22 @@ func createAzureVMRole(name, instanceSize, imageName, location string) (*Role, e
 	return config, nil
 }
 
-func createOSVirtualHardDisk(dnsName, imageName, location string) (OSVirtualHardDisk, error) {
+func createOSVirtualHardDisk(dnsName, imageName, location string, mediaOptions OSDiskMediaOptions) (OSVirtualHardDisk, error) {
 	oSVirtualHardDisk := OSVirtualHardDisk{}
 
-	err := imageClient.ResolveImageName(imageName)
+	image, err := imageClient.ResolveImage(imageName)
 	if err != nil {
 		return oSVirtualHardDisk, err
 	}
+	if image.IsVMImage {
+		return oSVirtualHardDisk, fmt.Errorf(vmImageNotSupportedError, imageName)
+	}
+	if !image.IsAvailableIn(location) {
+		return oSVirtualHardDisk, fmt.Errorf(imageLocationMismatchError, imageName, location, strings.Join(image.Locations, ", "))
+	}
 
-	oSVirtualHardDisk.SourceImageName = imageName
-	oSVirtualHardDisk.MediaLink, err = getVHDMediaLink(dnsName, location)
+	oSVirtualHardDisk.SourceImageName = image.Name
+	oSVirtualHardDisk.MediaLink, err = getVHDMediaLink(dnsName, location, mediaOptions)
 	if err != nil {
 		return oSVirtualHardDisk, err
 	}
@@ -559,37 +2810,140 @@ func createOSVirtualHardDisk(dnsName, imageName, location string) (OSVirtualHard
 	return oSVirtualHardDisk, nil
 }
 
-func getVHDMediaLink(dnsName, location string) (string, error) {
+func getVHDMediaLink(dnsName, location string, mediaOptions OSDiskMediaOptions) (string, error) {
+	storageService, err := getVHDStorageService(location, mediaOptions)
+	if err != nil {
+		return "", err
+	}
 
-	storageService, err := storageServiceClient.GetStorageServiceByLocation(location)
+	blobEndpoint, err := storageServiceClient.GetBlobEndpoint(storageService)
 	if err != nil {
 		return "", err
 	}
 
+	container := mediaOptions.Container
+	if len(container) == 0 {
+		container = defaultVHDContainer
+	}
+
+	blobName := mediaOptions.BlobName
+	if len(blobName) == 0 {
+		blobName = dnsName + "-" + time.Now().Local().Format("20060102150405") + ".vhd"
+	}
+
+	vhdMediaLink := blobEndpoint + container + "/" + blobName
+	return vhdMediaLink, nil
+}
+
+func getVHDStorageService(location string, mediaOptions OSDiskMediaOptions) (*storageServiceClient.StorageService, error) {
+	if len(mediaOptions.StorageAccount) > 0 {
+		storageService, err := storageServiceClient.GetStorageServiceByName(mediaOptions.StorageAccount)
+		if err != nil {
+			return nil, err
+		}
+
+		storageLocation := storageService.StorageServiceProperties.Location
+		if len(storageLocation) > 0 && storageLocation != location {
+			return nil, fmt.Errorf(storageAccountLocationMismatchError, mediaOptions.StorageAccount, storageLocation, location)
+		}
+
+		return storageService, nil
+	}
+
+	storageService, err := storageServiceClient.GetStorageServiceByLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
 	if storageService == nil {
+		if !mediaOptions.CreateStorageAccount {
+			return nil, fmt.Errorf(storageAccountNotFoundError, location)
+		}
 
 		uuid, err := azure.NewUUID()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		serviceName := "portalvhds" + uuid
 		storageService, err = storageServiceClient.CreateStorageService(serviceName, location)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 
-	blobEndpoint, err := storageServiceClient.GetBlobEndpoint(storageService)
+	return storageService, nil
+}
+
+func addAzureLinuxProvisioningConfig(azureVMConfiguration *Role, userName, password string, certData []byte, certPassword string, sshPort int) (*Role, error) {
+	configurationSets := ConfigurationSets{}
+	provisioningConfig, err := createLinuxProvisioningConfig(azureVMConfiguration.RoleName, userName, password, certData, certPassword)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	vhdMediaLink := blobEndpoint + "vhds/" + dnsName + "-" + time.Now().Local().Format("20060102150405") + ".vhd"
-	return vhdMediaLink, nil
+	configurationSets.ConfigurationSet = append(configurationSets.ConfigurationSet, provisioningConfig)
+
+	networkConfig, err := createNetworkConfig(osLinux, sshPort)
+	if err != nil {
+		return nil, err
+	}
+
+	configurationSets.ConfigurationSet = append(configurationSets.ConfigurationSet, networkConfig)
+
+	azureVMConfiguration.ConfigurationSets = configurationSets
+
+	if len(certData) > 0 {
+		addRoleCertificate(azureVMConfiguration, certData, certPassword)
+	}
+
+	return azureVMConfiguration, nil
+}
+
+func getLinuxProvisioningConfig(azureVMConfiguration *Role) (*ConfigurationSet, error) {
+	provisioningConfig := getConfigurationSet(azureVMConfiguration, "LinuxProvisioningConfiguration")
+	if provisioningConfig == nil {
+		return nil, errors.New(provisioningConfDoesNotExistsError)
+	}
+
+	return provisioningConfig, nil
+}
+
+func getWindowsProvisioningConfig(azureVMConfiguration *Role) (*ConfigurationSet, error) {
+	provisioningConfig := getConfigurationSet(azureVMConfiguration, "WindowsProvisioningConfiguration")
+	if provisioningConfig == nil {
+		return nil, errors.New(provisioningConfDoesNotExistsError)
+	}
+
+	return provisioningConfig, nil
+}
+
+func createWindowsProvisioningConfig(computerName, adminUserName, adminPassword string) (ConfigurationSet, error) {
+	provisioningConfig := ConfigurationSet{}
+
+	if len(computerName) > maxWindowsComputerNameLength {
+		computerName = computerName[:maxWindowsComputerNameLength]
+	}
+
+	err := verifyPassword(osWindows, adminPassword)
+	if err != nil {
+		return provisioningConfig, err
+	}
+
+	provisioningConfig.ConfigurationSetType = "WindowsProvisioningConfiguration"
+	provisioningConfig.ComputerName = computerName
+	provisioningConfig.AdminUsername = adminUserName
+	provisioningConfig.AdminPassword = adminPassword
+
+	return provisioningConfig, nil
 }
 
-func createLinuxProvisioningConfig(dnsName, userName, userPassword, certPath string) (ConfigurationSet, error) {
+func addRoleCertificate(azureVMConfiguration *Role, certData []byte, certPassword string) {
+	azureVMConfiguration.UseCertAuth = true
+	azureVMConfiguration.Certificates = append(azureVMConfiguration.Certificates, RoleCertificate{Data: certData, Password: certPassword})
+}
+
+func createLinuxProvisioningConfig(dnsName, userName, userPassword string, certData []byte, certPassword string) (ConfigurationSet, error) {
 	provisioningConfig := ConfigurationSet{}
 
 	disableSshPasswordAuthentication := false
@@ -598,7 +2952,7 @@ func createLinuxProvisioningConfig(dnsName, userName, userPassword, certPath str
 		// We need to set dummy password otherwise azure API will throw an error
 		userPassword = "P@ssword1"
 	} else {
-		err := verifyPassword(userPassword)
+		err := verifyPassword(osLinux, userPassword)
 		if err != nil {
 			return provisioningConfig, err
 		}
@@ -610,9 +2964,9 @@ func createLinuxProvisioningConfig(dnsName, userName, userPassword, certPath str
 	provisioningConfig.UserName = userName
 	provisioningConfig.UserPassword = userPassword
 
-	if len(certPath) > 0 {
+	if len(certData) > 0 {
 		var err error
-		provisioningConfig.SSH, err = createSshConfig(certPath, userName)
+		provisioningConfig.SSH, err = createSshConfig(certData, certPassword, userName)
 		if err != nil {
 			return provisioningConfig, err
 		}
@@ -621,8 +2975,8 @@ func createLinuxProvisioningConfig(dnsName, userName, userPassword, certPath str
 	return provisioningConfig, nil
 }
 
-func uploadServiceCert(dnsName, certPath string) error {
-	certificateConfig, err := createServiceCertDeploymentConf(certPath)
+func uploadServiceCert(dnsName string, certData []byte, certPassword string) error {
+	certificateConfig, err := createServiceCertDeploymentConf(certData, certPassword)
 	if err != nil {
 		return err
 	}
@@ -642,31 +2996,40 @@ func uploadServiceCert(dnsName, certPath string) error {
 	return err
 }
 
-func createServiceCertDeploymentConf(certPath string) (ServiceCertificate, error) {
+func createServiceCertDeploymentConf(certData []byte, certPassword string) (ServiceCertificate, error) {
 	certConfig := ServiceCertificate{}
 	certConfig.Xmlns = azureXmlns
-	data, err := ioutil.ReadFile(certPath)
+
+	if pemHasPrivateKey(certData) {
+		// Azure only accepts private keys as part of a PFX
+		pfxData, err := convertPEMToPFX(certData, certPassword)
+		if err != nil {
+			return certConfig, err
+		}
+		certData = pfxData
+	}
+
+	certificate, certFormat, err := parseServiceCert(certData, certPassword)
 	if err != nil {
 		return certConfig, err
 	}
 
-	certData := base64.StdEncoding.EncodeToString(data)
-	certConfig.Data = certData
-	certConfig.CertificateFormat = "pfx"
+	if certFormat == certificateFormatPfx {
+		certConfig.Data = base64.StdEncoding.EncodeToString(certData)
+		certConfig.Password = certPassword
+	} else {
+		certConfig.Data = base64.StdEncoding.EncodeToString(certificate)
+	}
+	certConfig.CertificateFormat = certFormat
 
 	return certConfig, nil
 }
 
-func createSshConfig(certPath, userName string) (SSH, error) {
+func createSshConfig(certData []byte, certPassword, userName string) (SSH, error) {
 	sshConfig := SSH{}
 	publicKey := PublicKey{}
 
-	err := checkServiceCertExtension(certPath)
-	if err != nil {
-		return sshConfig, err
-	}
-
-	fingerprint, err := getServiceCertFingerprint(certPath)
+	fingerprint, err := getServiceCertFingerprint(certData, certPassword)
 	if err != nil {
 		return sshConfig, err
 	}
@@ -678,43 +3041,204 @@ func createSshConfig(certPath, userName string) (SSH, error) {
 	return sshConfig, nil
 }
 
-func getServiceCertFingerprint(certPath string) (string, error) {
-	certData, readErr := ioutil.ReadFile(certPath)
-	if readErr != nil {
-		return "", readErr
+func getServiceCertFingerprint(certData []byte, certPassword string) (string, error) {
+	certificate, _, err := parseServiceCert(certData, certPassword)
+	if err != nil {
+		return "", err
 	}
 
+	sha1sum := sha1.Sum(certificate)
+	fingerprint := fmt.Sprintf("%X", sha1sum)
+	return fingerprint, nil
+}
+
+// parseServiceCert detects whether certData is a PEM, DER or PFX encoded
+// certificate and returns the DER encoded certificate along with the
+// format that should be used when uploading it to Azure.
+func parseServiceCert(certData []byte, certPassword string) ([]byte, string, error) {
 	block, rest := pem.Decode(certData)
-	if block == nil {
-		return "", errors.New(string(rest))
+	if block != nil {
+		// The certificate may follow its private key in the PEM data
+		for ; block != nil; block, rest = pem.Decode(rest) {
+			if block.Type == "CERTIFICATE" {
+				return block.Bytes, certificateFormatCer, nil
+			}
+		}
+
+		return nil, "", errors.New(invalidCertFormatError)
 	}
 
-	sha1sum := sha1.Sum(block.Bytes)
-	fingerprint := fmt.Sprintf("%X", sha1sum)
-	return fingerprint, nil
+	_, err := x509.ParseCertificate(certData)
+	if err == nil {
+		return certData, certificateFormatCer, nil
+	}
+
+	pemData, err := azure.ExecuteCommandWithEnv("openssl pkcs12 -nokeys -passin env:"+certPasswordEnv, certData, []string{certPasswordEnv + "=" + certPassword})
+	if err != nil {
+		return nil, "", errors.New(invalidCertFormatError)
+	}
+
+	for block, pemData = pem.Decode(pemData); block != nil; block, pemData = pem.Decode(pemData) {
+		if block.Type == "CERTIFICATE" {
+			return block.Bytes, certificateFormatPfx, nil
+		}
+	}
+
+	return nil, "", errors.New(invalidCertFormatError)
+}
+
+func pemHasPrivateKey(certData []byte) bool {
+	for block, rest := pem.Decode(certData); block != nil; block, rest = pem.Decode(rest) {
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func convertPEMToPFX(certData []byte, certPassword string) ([]byte, error) {
+	// openssl reads the private key first and cannot rewind its input to
+	// find a key which follows the certificate
+	var keyData, otherData []byte
+	for block, rest := pem.Decode(certData); block != nil; block, rest = pem.Decode(rest) {
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			keyData = append(keyData, pem.EncodeToMemory(block)...)
+		} else {
+			otherData = append(otherData, pem.EncodeToMemory(block)...)
+		}
+	}
+
+	pfxData, err := azure.ExecuteCommandWithEnv("openssl pkcs12 -export -passout env:"+certPasswordEnv, append(keyData, otherData...), []string{certPasswordEnv + "=" + certPassword})
+	if err != nil {
+		return nil, fmt.Errorf(pfxConversionError, err)
+	}
+
+	return pfxData, nil
+}
+
+func parseSSHPublicKey(publicKey string) (*rsa.PublicKey, error) {
+	fields := strings.Fields(publicKey)
+	if len(fields) < 2 || fields[0] != sshRsaKeyType {
+		return nil, fmt.Errorf(invalidSSHPublicKeyError, sshRsaKeyType)
+	}
+
+	keyData, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	keyType, keyData, ok := readSSHWireValue(keyData)
+	if !ok || string(keyType) != sshRsaKeyType {
+		return nil, fmt.Errorf(invalidSSHPublicKeyError, sshRsaKeyType)
+	}
+
+	exponent, keyData, ok := readSSHWireValue(keyData)
+	if !ok {
+		return nil, fmt.Errorf(invalidSSHPublicKeyError, sshRsaKeyType)
+	}
+
+	modulus, _, ok := readSSHWireValue(keyData)
+	if !ok {
+		return nil, fmt.Errorf(invalidSSHPublicKeyError, sshRsaKeyType)
+	}
+
+	e := new(big.Int).SetBytes(exponent)
+	if !e.IsInt64() || e.Int64() > int64(^uint32(0)>>1) {
+		return nil, fmt.Errorf(invalidSSHPublicKeyError, sshRsaKeyType)
+	}
+
+	rsaPublicKey := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(e.Int64()),
+	}
+
+	return rsaPublicKey, nil
+}
+
+func marshalSSHPublicKey(publicKey *rsa.PublicKey) string {
+	var buf bytes.Buffer
+
+	writeSSHWireValue(&buf, []byte(sshRsaKeyType))
+	writeSSHWireValue(&buf, big.NewInt(int64(publicKey.E)).Bytes())
+
+	// The modulus is a positive mpint, which needs a leading zero byte when
+	// its high bit is set
+	modulus := publicKey.N.Bytes()
+	if len(modulus) > 0 && modulus[0]&0x80 != 0 {
+		modulus = append([]byte{0}, modulus...)
+	}
+	writeSSHWireValue(&buf, modulus)
+
+	return sshRsaKeyType + " " + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func writeSSHWireValue(buf *bytes.Buffer, value []byte) {
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(value)))
+	buf.Write(length)
+	buf.Write(value)
+}
+
+func readSSHWireValue(data []byte) ([]byte, []byte, bool) {
+	if len(data) < 4 {
+		return nil, nil, false
+	}
+
+	length := binary.BigEndian.Uint32(data)
+	data = data[4:]
+	if uint32(len(data)) < length {
+		return nil, nil, false
+	}
+
+	return data[:length], data[length:], true
+}
+
+func createCertificateForPublicKey(publicKey *rsa.PublicKey, commonName string) ([]byte, error) {
+	template, err := createCertificateTemplate(commonName)
+	if err != nil {
+		return nil, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+
+	// Azure only uses the public key embedded in the certificate, so it is
+	// signed with a throwaway key as the private half is not available.
+	signingKey, err := rsa.GenerateKey(rand.Reader, certificateKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, publicKey, signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), nil
 }
 
 func checkServiceCertExtension(certPath string) error {
 	certParts := strings.Split(certPath, ".")
 	certExt := certParts[len(certParts)-1]
 
-	acceptedExtension := "pem"
-	if certExt != acceptedExtension {
-		return errors.New(fmt.Sprintf(invalidCertExtensionError, certPath, acceptedExtension))
+	acceptedExtensions := []string{"pem", "cer", "pfx"}
+	for _, acceptedExtension := range acceptedExtensions {
+		if strings.EqualFold(certExt, acceptedExtension) {
+			return nil
+		}
 	}
 
-	return nil
+	return errors.New(fmt.Sprintf(invalidCertExtensionError, certPath, strings.Join(acceptedExtensions, ", ")))
 }
 
-func createNetworkConfig(os string, sshPort int) (ConfigurationSet, error) {
+func createNetworkConfig(os string, port int) (ConfigurationSet, error) {
 	networkConfig := ConfigurationSet{}
 	networkConfig.ConfigurationSetType = "NetworkConfiguration"
 
 	var endpoint InputEndpoint
 	if os == osLinux {
-		endpoint = createEndpoint("ssh", "tcp", sshPort, 22)
+		endpoint = createEndpoint("ssh", "tcp", port, 22)
 	} else if os == osWindows {
-		//!TODO add rdp endpoint
+		endpoint = createEndpoint("rdp", "tcp", port, 3389)
 	} else {
 		return networkConfig, errors.New(fmt.Sprintf(invalidOSError))
 	}
@@ -734,15 +3258,22 @@ func createEndpoint(name string, protocol string, extertalPort int, internalPort
 	return endpoint
 }
 
-func verifyDNSname(dns string) error {
-	if len(dns) < 3 || len(dns) > 25 {
-		return fmt.Errorf(invalidDnsLengthError)
+func verifyPassword(os, password string) error {
+	if !passwordValidationEnabled {
+		return nil
 	}
 
-	return nil
+	switch os {
+	case osLinux:
+		return verifyLinuxPassword(password)
+	case osWindows:
+		return verifyWindowsPassword(password)
+	}
+
+	return fmt.Errorf(invalidOSError)
 }
 
-func verifyPassword(password string) error {
+func verifyLinuxPassword(password string) error {
 	if len(password) < 4 || len(password) > 30 {
 		return fmt.Errorf(invalidPasswordLengthError)
 	}
@@ -763,11 +3294,93 @@ next:
 	return nil
 }
 
+func verifyWindowsPassword(password string) error {
+	length := len([]rune(password))
+	if length < minWindowsPasswordLength || length > maxWindowsPasswordLength {
+		return fmt.Errorf(invalidWindowsPasswordLengthError, minWindowsPasswordLength, maxWindowsPasswordLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r) || unicode.IsTitle(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r) || unicode.IsNumber(r):
+			hasDigit = true
+		default:
+			hasSpecial = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{hasUpper, hasLower, hasDigit, hasSpecial} {
+		if present {
+			classes++
+		}
+	}
+
+	if classes < minWindowsPasswordClasses {
+		return fmt.Errorf(invalidWindowsPasswordError, minWindowsPasswordClasses)
+	}
+
+	return nil
+}
+
+func compareVersions(first, second string) int {
+	firstParts := strings.Split(first, ".")
+	secondParts := strings.Split(second, ".")
+
+	for i := 0; i < len(firstParts) || i < len(secondParts); i++ {
+		firstPart, secondPart := -1, -1
+		if i < len(firstParts) {
+			firstPart, _ = strconv.Atoi(firstParts[i])
+		}
+		if i < len(secondParts) {
+			secondPart, _ = strconv.Atoi(secondParts[i])
+		}
+
+		if firstPart != secondPart {
+			if firstPart > secondPart {
+				return 1
+			}
+			return -1
+		}
+	}
+
+	return 0
+}
+
+func verifyIdleTimeout(idleTimeoutInMinutes int) error {
+	if idleTimeoutInMinutes < minIdleTimeoutInMinutes || idleTimeoutInMinutes > maxIdleTimeoutInMinutes {
+		return fmt.Errorf(invalidIdleTimeoutError, idleTimeoutInMinutes, minIdleTimeoutInMinutes, maxIdleTimeoutInMinutes)
+	}
+
+	return nil
+}
+
+func verifyDataDiskHostCaching(hostCaching HostCachingType) error {
+	switch hostCaching {
+	case "", HostCachingTypeNone, HostCachingTypeReadOnly, HostCachingTypeReadWrite:
+		return nil
+	}
+
+	return fmt.Errorf(invalidDataDiskHostCachingError, hostCaching)
+}
+
 func isInstanceSizeAvailableInLocation(location *locationClient.Location, instanceSize string) (bool, error) {
 	if len(instanceSize) == 0 {
 		return false, fmt.Errorf(paramNotSpecifiedError, "vmSize")
 	}
 
+	// Report sizes unknown to the subscription, e.g. misspelled ones, with
+	// the list of valid sizes rather than as unavailable in the location.
+	err := ResolveRoleSize(instanceSize)
+	if err != nil {
+		return false, err
+	}
+
 	for _, availableRoleSize := range location.VirtualMachineRoleSizes {
 		if availableRoleSize == instanceSize {
 			return true, nil
@@ -0,0 +1,128 @@
+package vmClient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_equalInputEndpoints(t *testing.T) {
+	type test struct {
+		name     string
+		other    []InputEndpoint
+		expected bool
+	}
+
+	endpoints := []InputEndpoint{
+		{Name: "ssh", Protocol: "tcp", Port: 22, LocalPort: 22, Vip: "1.2.3.4"},
+		{Name: "web", Protocol: "tcp", Port: 80, LocalPort: 8080, Vip: "1.2.3.4"},
+	}
+
+	tests := []test{
+		{"same", endpoints, true},
+		{"other order", []InputEndpoint{endpoints[1], endpoints[0]}, true},
+		{"no vip", []InputEndpoint{
+			{Name: "ssh", Protocol: "tcp", Port: 22, LocalPort: 22},
+			{Name: "web", Protocol: "tcp", Port: 80, LocalPort: 8080},
+		}, true},
+		{"other case", []InputEndpoint{
+			{Name: "SSH", Protocol: "TCP", Port: 22, LocalPort: 22},
+			{Name: "Web", Protocol: "Tcp", Port: 80, LocalPort: 8080},
+		}, true},
+		{"missing endpoint", endpoints[:1], false},
+		{"other name", []InputEndpoint{endpoints[0], {Name: "http", Protocol: "tcp", Port: 80, LocalPort: 8080}}, false},
+		{"other port", []InputEndpoint{endpoints[0], {Name: "web", Protocol: "tcp", Port: 443, LocalPort: 8080}}, false},
+		{"other protocol", []InputEndpoint{endpoints[0], {Name: "web", Protocol: "udp", Port: 80, LocalPort: 8080}}, false},
+		{"load balanced", []InputEndpoint{endpoints[0], {Name: "web", Protocol: "tcp", Port: 80, LocalPort: 8080, LoadBalancerName: "lb"}}, false},
+	}
+
+	for _, i := range tests {
+		if out := equalInputEndpoints(endpoints, i.other); out != i.expected {
+			t.Fatalf("Wrong result for %s. Expected: '%t', got: '%t'", i.name, i.expected, out)
+		}
+	}
+}
+
+func Test_convergeRole(t *testing.T) {
+	type test struct {
+		name              string
+		role              *Role
+		desired           *Role
+		expectedChanged   bool
+		expectedSize      string
+		expectedSet       string
+		expectedEndpoints []InputEndpoint
+	}
+
+	ssh := InputEndpoint{Name: "ssh", Protocol: "tcp", Port: 22, LocalPort: 22}
+	web := InputEndpoint{Name: "web", Protocol: "tcp", Port: 80, LocalPort: 80}
+	deployedSSH := ssh
+	deployedSSH.Vip = "1.2.3.4"
+
+	tests := []test{
+		{"unchanged",
+			testConvergeRole("Small", "set", deployedSSH),
+			testConvergeRole("Small", "set", ssh),
+			false, "Small", "set", []InputEndpoint{deployedSSH}},
+		{"size",
+			testConvergeRole("Small", "set", ssh),
+			testConvergeRole("Large", "set", ssh),
+			true, "Large", "set", []InputEndpoint{ssh}},
+		{"no desired size",
+			testConvergeRole("Small", "set", ssh),
+			testConvergeRole("", "set", ssh),
+			false, "Small", "set", []InputEndpoint{ssh}},
+		{"availability set",
+			testConvergeRole("Small", "set", ssh),
+			testConvergeRole("Small", "", ssh),
+			true, "Small", "", []InputEndpoint{ssh}},
+		{"endpoint added",
+			testConvergeRole("Small", "", ssh),
+			testConvergeRole("Small", "", ssh, web),
+			true, "Small", "", []InputEndpoint{ssh, web}},
+		{"endpoints removed",
+			testConvergeRole("Small", "", ssh, web),
+			&Role{RoleSize: "Small"},
+			true, "Small", "", nil},
+		{"no network configuration",
+			&Role{RoleSize: "Small"},
+			testConvergeRole("Small", "", web),
+			true, "Small", "", []InputEndpoint{web}},
+		{"no endpoints",
+			&Role{RoleSize: "Small"},
+			&Role{RoleSize: "Small"},
+			false, "Small", "", nil},
+	}
+
+	for _, i := range tests {
+		changed := convergeRole(i.role, i.desired)
+		if changed != i.expectedChanged {
+			t.Fatalf("Wrong changed for %s. Expected: '%t', got: '%t'", i.name, i.expectedChanged, changed)
+		}
+		if i.role.RoleSize != i.expectedSize {
+			t.Fatalf("Wrong role size for %s. Expected: '%s', got: '%s'", i.name, i.expectedSize, i.role.RoleSize)
+		}
+		if i.role.AvailabilitySetName != i.expectedSet {
+			t.Fatalf("Wrong availability set for %s. Expected: '%s', got: '%s'", i.name, i.expectedSet, i.role.AvailabilitySetName)
+		}
+
+		var endpoints []InputEndpoint
+		if networkConfig := getNetworkConfig(i.role); networkConfig != nil {
+			endpoints = networkConfig.InputEndpoints.InputEndpoint
+		}
+		if len(endpoints) != 0 || len(i.expectedEndpoints) != 0 {
+			if !reflect.DeepEqual(endpoints, i.expectedEndpoints) {
+				t.Fatalf("Wrong input endpoints for %s. Expected: '%v', got: '%v'", i.name, i.expectedEndpoints, endpoints)
+			}
+		}
+	}
+}
+
+func testConvergeRole(roleSize, availabilitySetName string, endpoints ...InputEndpoint) *Role {
+	return &Role{
+		RoleSize:            roleSize,
+		AvailabilitySetName: availabilitySetName,
+		ConfigurationSets: ConfigurationSets{ConfigurationSet: []ConfigurationSet{
+			{ConfigurationSetType: "NetworkConfiguration", InputEndpoints: InputEndpoints{InputEndpoint: endpoints}},
+		}},
+	}
+}
@@ -0,0 +1,256 @@
+package vmClient
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DeploymentWatcher polls a deployment and reports its changes on Events.
+// It is created by WatchDeployment.
+type DeploymentWatcher struct {
+	// Events receives the changes of the deployment. It is closed after
+	// Stop returns.
+	Events <-chan DeploymentEvent
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+//Region public methods starts
+
+// WatchDeployment reads the deployment and then reads it again every
+// interval, sending a DeploymentEvent on the Events channel of the returned
+// watcher for every role instance, instance endpoint and extension status
+// that changed since the previous read. The first read only sets the state
+// changes are compared with, so it does not produce events; it has to
+// succeed for the watch to start. Failed reads later on are reported as
+// DeploymentEventError events and the watch continues. A zero interval
+// defaults to 30 seconds. The caller has to keep receiving events, polling
+// pauses while an event cannot be delivered.
+func WatchDeployment(cloudserviceName, deploymentName string, interval time.Duration) (*DeploymentWatcher, error) {
+	if len(cloudserviceName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "cloudserviceName")
+	}
+	if len(deploymentName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "deploymentName")
+	}
+	if interval <= 0 {
+		interval = defaultDeploymentWatchInterval
+	}
+
+	deployment, err := GetVMDeployment(cloudserviceName, deploymentName)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan DeploymentEvent, deploymentEventBufferSize)
+	watcher := &DeploymentWatcher{
+		Events: events,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go watcher.run(cloudserviceName, deploymentName, interval, deployment, events)
+
+	return watcher, nil
+}
+
+// Stop ends the watch, waiting for a read in progress to finish, and closes
+// the Events channel. It is safe to call Stop more than once.
+func (watcher *DeploymentWatcher) Stop() {
+	watcher.stopOnce.Do(func() {
+		close(watcher.stop)
+	})
+	<-watcher.done
+}
+
+//Region public methods ends
+
+//Region private methods starts
+
+func (watcher *DeploymentWatcher) run(cloudserviceName, deploymentName string, interval time.Duration, previous *VMDeployment, events chan<- DeploymentEvent) {
+	defer close(watcher.done)
+	defer close(events)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-watcher.stop:
+			return
+		case <-ticker.C:
+		}
+
+		deployment, err := GetVMDeployment(cloudserviceName, deploymentName)
+		if err != nil {
+			if !watcher.send(events, DeploymentEvent{Type: DeploymentEventError, Err: err}) {
+				return
+			}
+			continue
+		}
+
+		for _, event := range diffDeployments(previous, deployment) {
+			if !watcher.send(events, event) {
+				return
+			}
+		}
+		previous = deployment
+	}
+}
+
+// send delivers the event unless the watch is stopped first and reports
+// whether it was delivered.
+func (watcher *DeploymentWatcher) send(events chan<- DeploymentEvent, event DeploymentEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-watcher.stop:
+		return false
+	}
+}
+
+// diffDeployments returns the events which lead from the previous to the
+// current state of a deployment, in the order of the role instances of the
+// current state followed by the removed instances.
+func diffDeployments(previous, current *VMDeployment) []DeploymentEvent {
+	var events []DeploymentEvent
+
+	previousInstances := map[string]*RoleInstance{}
+	for _, instance := range previous.RoleInstanceList.RoleInstance {
+		previousInstances[instance.InstanceName] = instance
+	}
+
+	currentInstances := map[string]bool{}
+	for _, instance := range current.RoleInstanceList.RoleInstance {
+		currentInstances[instance.InstanceName] = true
+
+		previousInstance, ok := previousInstances[instance.InstanceName]
+		if !ok {
+			events = append(events, DeploymentEvent{
+				Type:         DeploymentEventInstanceAdded,
+				RoleName:     instance.RoleName,
+				InstanceName: instance.InstanceName,
+				Instance:     instance,
+			})
+			previousInstance = &RoleInstance{RoleName: instance.RoleName, InstanceName: instance.InstanceName}
+		} else if previousInstance.InstanceStatus != instance.InstanceStatus || previousInstance.PowerState != instance.PowerState {
+			events = append(events, DeploymentEvent{
+				Type:             DeploymentEventInstanceStatusChanged,
+				RoleName:         instance.RoleName,
+				InstanceName:     instance.InstanceName,
+				PreviousInstance: previousInstance,
+				Instance:         instance,
+			})
+		}
+
+		events = append(events, diffInstanceEndpoints(previousInstance, instance)...)
+		events = append(events, diffExtensionStatuses(previousInstance, instance)...)
+	}
+
+	for _, instance := range previous.RoleInstanceList.RoleInstance {
+		if currentInstances[instance.InstanceName] {
+			continue
+		}
+
+		events = append(events, DeploymentEvent{
+			Type:             DeploymentEventInstanceRemoved,
+			RoleName:         instance.RoleName,
+			InstanceName:     instance.InstanceName,
+			PreviousInstance: instance,
+		})
+	}
+
+	return events
+}
+
+func diffInstanceEndpoints(previous, current *RoleInstance) []DeploymentEvent {
+	var events []DeploymentEvent
+
+	previousEndpoints := map[string]InstanceEndpoint{}
+	for _, endpoint := range previous.InstanceEndpoints.InstanceEndpoint {
+		previousEndpoints[endpoint.Name] = endpoint
+	}
+
+	currentEndpoints := map[string]bool{}
+	for _, endpoint := range current.InstanceEndpoints.InstanceEndpoint {
+		endpoint := endpoint
+		currentEndpoints[endpoint.Name] = true
+
+		previousEndpoint, ok := previousEndpoints[endpoint.Name]
+		switch {
+		case !ok:
+			events = append(events, newEndpointEvent(DeploymentEventEndpointAdded, current, nil, &endpoint))
+		case previousEndpoint != endpoint:
+			events = append(events, newEndpointEvent(DeploymentEventEndpointChanged, current, &previousEndpoint, &endpoint))
+		}
+	}
+
+	for _, endpoint := range previous.InstanceEndpoints.InstanceEndpoint {
+		endpoint := endpoint
+		if !currentEndpoints[endpoint.Name] {
+			events = append(events, newEndpointEvent(DeploymentEventEndpointRemoved, current, &endpoint, nil))
+		}
+	}
+
+	return events
+}
+
+func newEndpointEvent(eventType DeploymentEventType, instance *RoleInstance, previous, current *InstanceEndpoint) DeploymentEvent {
+	return DeploymentEvent{
+		Type:             eventType,
+		RoleName:         instance.RoleName,
+		InstanceName:     instance.InstanceName,
+		PreviousEndpoint: previous,
+		Endpoint:         current,
+	}
+}
+
+// diffExtensionStatuses reports the extension handlers whose status or code,
+// or the status or code of whose last run, changed. Handlers which are
+// removed from an instance are not reported.
+func diffExtensionStatuses(previous, current *RoleInstance) []DeploymentEvent {
+	var events []DeploymentEvent
+
+	previousStatuses := map[string]*ResourceExtensionStatus{}
+	for i := range previous.ResourceExtensionStatusList.ResourceExtensionStatus {
+		status := &previous.ResourceExtensionStatusList.ResourceExtensionStatus[i]
+		previousStatuses[status.HandlerName] = status
+	}
+
+	for i := range current.ResourceExtensionStatusList.ResourceExtensionStatus {
+		status := &current.ResourceExtensionStatusList.ResourceExtensionStatus[i]
+
+		previousStatus := previousStatuses[status.HandlerName]
+		if previousStatus != nil && equalExtensionStatus(previousStatus, status) {
+			continue
+		}
+
+		events = append(events, DeploymentEvent{
+			Type:                    DeploymentEventExtensionStatusChanged,
+			RoleName:                current.RoleName,
+			InstanceName:            current.InstanceName,
+			PreviousExtensionStatus: previousStatus,
+			ExtensionStatus:         status,
+		})
+	}
+
+	return events
+}
+
+func equalExtensionStatus(status, other *ResourceExtensionStatus) bool {
+	if status.Status != other.Status || status.Code != other.Code {
+		return false
+	}
+
+	settingStatus, otherSettingStatus := status.ExtensionSettingStatus, other.ExtensionSettingStatus
+	if settingStatus == nil || otherSettingStatus == nil {
+		return settingStatus == otherSettingStatus
+	}
+
+	return settingStatus.Status == otherSettingStatus.Status && settingStatus.Code == otherSettingStatus.Code
+}
+
+//Region private methods ends
@@ -0,0 +1,126 @@
+package vmClient
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func Test_diffDeployments(t *testing.T) {
+	type test struct {
+		name     string
+		previous []*RoleInstance
+		current  []*RoleInstance
+		expected []string
+	}
+
+	endpoint := InstanceEndpoint{Name: "ssh", Vip: "1.2.3.4", PublicPort: 22, LocalPort: 22, Protocol: "tcp"}
+	movedEndpoint := endpoint
+	movedEndpoint.PublicPort = 2222
+
+	extension := ResourceExtensionStatus{HandlerName: "Docker", Status: "Ready"}
+	failedExtension := ResourceExtensionStatus{HandlerName: "Docker", Status: "Ready", ExtensionSettingStatus: &ExtensionSettingStatus{Status: "error", Code: 1}}
+
+	tests := []test{
+		{"no changes",
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole, endpoint)},
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole, endpoint)},
+			nil},
+		{"instance added",
+			nil,
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole, endpoint)},
+			[]string{"InstanceAdded vm1", "EndpointAdded vm1 ssh"}},
+		{"instance removed",
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole, endpoint)},
+			nil,
+			[]string{"InstanceRemoved vm1"}},
+		{"status changed",
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusStartingVM)},
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole)},
+			[]string{"InstanceStatusChanged vm1"}},
+		{"endpoint changed",
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole, endpoint)},
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole, movedEndpoint)},
+			[]string{"EndpointChanged vm1 ssh"}},
+		{"endpoint removed",
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole, endpoint)},
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole)},
+			[]string{"EndpointRemoved vm1 ssh"}},
+		{"extension added",
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole)},
+			[]*RoleInstance{withExtensionStatus(testRoleInstance("vm1", RoleInstanceStatusReadyRole), extension)},
+			[]string{"ExtensionStatusChanged vm1 Docker"}},
+		{"extension unchanged",
+			[]*RoleInstance{withExtensionStatus(testRoleInstance("vm1", RoleInstanceStatusReadyRole), extension)},
+			[]*RoleInstance{withExtensionStatus(testRoleInstance("vm1", RoleInstanceStatusReadyRole), extension)},
+			nil},
+		{"extension run failed",
+			[]*RoleInstance{withExtensionStatus(testRoleInstance("vm1", RoleInstanceStatusReadyRole), extension)},
+			[]*RoleInstance{withExtensionStatus(testRoleInstance("vm1", RoleInstanceStatusReadyRole), failedExtension)},
+			[]string{"ExtensionStatusChanged vm1 Docker"}},
+		{"extension removed",
+			[]*RoleInstance{withExtensionStatus(testRoleInstance("vm1", RoleInstanceStatusReadyRole), extension)},
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole)},
+			nil},
+		{"order",
+			[]*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole), testRoleInstance("vm2", RoleInstanceStatusReadyRole)},
+			[]*RoleInstance{testRoleInstance("vm3", RoleInstanceStatusReadyRole), testRoleInstance("vm2", RoleInstanceStatusStoppedVM)},
+			[]string{"InstanceAdded vm3", "InstanceStatusChanged vm2", "InstanceRemoved vm1"}},
+	}
+
+	for _, i := range tests {
+		previous := &VMDeployment{RoleInstanceList: RoleInstanceList{RoleInstance: i.previous}}
+		current := &VMDeployment{RoleInstanceList: RoleInstanceList{RoleInstance: i.current}}
+
+		var out []string
+		for _, event := range diffDeployments(previous, current) {
+			out = append(out, describeDeploymentEvent(event))
+		}
+		if !reflect.DeepEqual(out, i.expected) {
+			t.Fatalf("Wrong events for %s. Expected: '%v', got: '%v'", i.name, i.expected, out)
+		}
+	}
+}
+
+func Test_diffDeploymentsSetsStates(t *testing.T) {
+	previous := &VMDeployment{RoleInstanceList: RoleInstanceList{RoleInstance: []*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusStartingVM)}}}
+	current := &VMDeployment{RoleInstanceList: RoleInstanceList{RoleInstance: []*RoleInstance{testRoleInstance("vm1", RoleInstanceStatusReadyRole)}}}
+
+	events := diffDeployments(previous, current)
+	if len(events) != 1 {
+		t.Fatalf("Wrong number of events. Expected: '1', got: '%d'", len(events))
+	}
+	if events[0].PreviousInstance != previous.RoleInstanceList.RoleInstance[0] {
+		t.Fatalf("Wrong previous instance. Expected: '%v', got: '%v'", previous.RoleInstanceList.RoleInstance[0], events[0].PreviousInstance)
+	}
+	if events[0].Instance != current.RoleInstanceList.RoleInstance[0] {
+		t.Fatalf("Wrong instance. Expected: '%v', got: '%v'", current.RoleInstanceList.RoleInstance[0], events[0].Instance)
+	}
+}
+
+func testRoleInstance(name string, status RoleInstanceStatus, endpoints ...InstanceEndpoint) *RoleInstance {
+	return &RoleInstance{
+		RoleName:          name,
+		InstanceName:      name,
+		InstanceStatus:    status,
+		InstanceEndpoints: InstanceEndpoints{InstanceEndpoint: endpoints},
+	}
+}
+
+func withExtensionStatus(instance *RoleInstance, statuses ...ResourceExtensionStatus) *RoleInstance {
+	instance.ResourceExtensionStatusList.ResourceExtensionStatus = statuses
+	return instance
+}
+
+func describeDeploymentEvent(event DeploymentEvent) string {
+	description := fmt.Sprintf("%s %s", event.Type, event.InstanceName)
+	switch {
+	case event.Endpoint != nil:
+		description += " " + event.Endpoint.Name
+	case event.PreviousEndpoint != nil:
+		description += " " + event.PreviousEndpoint.Name
+	case event.ExtensionStatus != nil:
+		description += " " + event.ExtensionStatus.HandlerName
+	}
+	return description
+}
@@ -0,0 +1,49 @@
+package vmDiskClient
+
+import (
+	"encoding/xml"
+)
+
+type DiskList struct {
+	XMLName xml.Name `xml:"Disks"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Disks   []Disk   `xml:"Disk"`
+}
+
+type Disk struct {
+	AffinityGroup       string
+	AttachedTo          *AttachedTo
+	OS                  string
+	IOType              string
+	Location            string
+	LogicalDiskSizeInGB float64
+	MediaLink           string
+	Name                string
+	Label               string
+	SourceImageName     string
+	CreatedTime         string
+	IsPremium           bool
+}
+
+type AttachedTo struct {
+	HostedServiceName string
+	DeploymentName    string
+	RoleName          string
+}
+
+type AddDiskConfig struct {
+	XMLName   xml.Name `xml:"Disk"`
+	Xmlns     string   `xml:"xmlns,attr"`
+	OS        string   `xml:",omitempty"`
+	Label     string
+	MediaLink string
+	Name      string
+}
+
+type UpdateDiskConfig struct {
+	XMLName         xml.Name `xml:"Disk"`
+	Xmlns           string   `xml:"xmlns,attr"`
+	Label           string
+	Name            string
+	ResizedSizeInGB int `xml:",omitempty"`
+}
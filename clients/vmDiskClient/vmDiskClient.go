@@ -1,30 +1,198 @@
 package vmDiskClient
 
 import (
+	"encoding/xml"
 	"fmt"
+	"time"
+
 	azure "github.com/MSOpenTech/azure-sdk-for-go"
 )
 
 const (
+	azureXmlns             = "http://schemas.microsoft.com/windowsazure"
+	azureVMDiskListURL     = "services/disks"
 	azureVMDiskURL         = "services/disks/%s"
+	deleteMediaQuery       = "?comp=media"
+	osLinux                = "Linux"
+	osWindows              = "Windows"
+	invalidOSError         = "Invalid OS: %s. Valid values are 'Linux', 'Windows' or empty for a data disk."
+	invalidDiskSizeError   = "Invalid disk size: %d GB. Disk %s is %v GB and its size can only be increased."
+	diskDetachTimeoutError = "Timed out after %s waiting for disk %s to be detached from role %s."
 	paramNotSpecifiedError = "Parameter %s is not specified."
 )
 
 //Region public methods starts
 
+func GetDiskList() (DiskList, error) {
+	diskList := DiskList{}
+
+	response, err := azure.SendAzureGetRequest(azureVMDiskListURL)
+	if err != nil {
+		return diskList, err
+	}
+
+	err = xml.Unmarshal(response, &diskList)
+	if err != nil {
+		return diskList, err
+	}
+
+	return diskList, nil
+}
+
+func GetDisk(diskName string) (*Disk, error) {
+	if len(diskName) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "diskName")
+	}
+
+	disk := new(Disk)
+
+	requestURL := fmt.Sprintf(azureVMDiskURL, diskName)
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = xml.Unmarshal(response, disk)
+	if err != nil {
+		return nil, err
+	}
+
+	return disk, nil
+}
+
+// AddDisk registers a VHD in blob storage as a disk. An empty os registers a
+// data disk, "Linux" or "Windows" registers an OS disk.
+func AddDisk(diskName, label, mediaLink, os string) error {
+	if len(diskName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "diskName")
+	}
+	if len(label) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "label")
+	}
+	if len(mediaLink) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "mediaLink")
+	}
+	if len(os) > 0 && os != osLinux && os != osWindows {
+		return fmt.Errorf(invalidOSError, os)
+	}
+
+	diskConfig := AddDiskConfig{Xmlns: azureXmlns, OS: os, Label: label, MediaLink: mediaLink, Name: diskName}
+	diskBytes, err := xml.Marshal(diskConfig)
+	if err != nil {
+		return err
+	}
+
+	requestId, err := azure.SendAzurePostRequest(azureVMDiskListURL, diskBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// UpdateDisk changes the label of a disk and, when resizedSizeInGB is not
+// zero, grows the disk to that size. The new size cannot be smaller than the
+// current size of the disk.
+func UpdateDisk(diskName, label string, resizedSizeInGB int) error {
+	if len(diskName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "diskName")
+	}
+	if len(label) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "label")
+	}
+	if resizedSizeInGB != 0 {
+		disk, err := GetDisk(diskName)
+		if err != nil {
+			return err
+		}
+
+		if float64(resizedSizeInGB) < disk.LogicalDiskSizeInGB {
+			return fmt.Errorf(invalidDiskSizeError, resizedSizeInGB, diskName, disk.LogicalDiskSizeInGB)
+		}
+	}
+
+	diskConfig := UpdateDiskConfig{Xmlns: azureXmlns, Label: label, Name: diskName, ResizedSizeInGB: resizedSizeInGB}
+	diskBytes, err := xml.Marshal(diskConfig)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureVMDiskURL, diskName)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", diskBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// DeleteDisk deletes the disk and keeps its VHD in blob storage.
 func DeleteDisk(diskName string) error {
+	return deleteDisk(diskName, false)
+}
+
+// DeleteDiskWithVHD deletes the disk together with its VHD.
+func DeleteDiskWithVHD(diskName string) error {
+	return deleteDisk(diskName, true)
+}
+
+// WaitForDiskDetach waits until the disk is no longer attached to a role.
+// Azure keeps a disk attached for a while after its VM has been deleted.
+func WaitForDiskDetach(diskName string, timeout time.Duration) error {
+	if len(diskName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "diskName")
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		disk, err := GetDisk(diskName)
+		if err != nil {
+			return err
+		}
+
+		if disk.AttachedTo == nil || len(disk.AttachedTo.RoleName) == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf(diskDetachTimeoutError, timeout, diskName, disk.AttachedTo.RoleName)
+		}
+
+		time.Sleep(5000 * time.Millisecond)
+	}
+}
+
+// DeleteDiskWhenDetached waits for the disk to be detached and then deletes
+// it, optionally together with its VHD.
+func DeleteDiskWhenDetached(diskName string, deleteVHD bool, timeout time.Duration) error {
+	err := WaitForDiskDetach(diskName, timeout)
+	if err != nil {
+		return err
+	}
+
+	return deleteDisk(diskName, deleteVHD)
+}
+
+//Region public methods ends
+
+//Region private methods starts
+
+func deleteDisk(diskName string, deleteVHD bool) error {
 	if len(diskName) == 0 {
 		return fmt.Errorf(paramNotSpecifiedError, "diskName")
 	}
 
 	requestURL := fmt.Sprintf(azureVMDiskURL, diskName)
+	if deleteVHD {
+		requestURL += deleteMediaQuery
+	}
+
 	requestId, err := azure.SendAzureDeleteRequest(requestURL)
 	if err != nil {
 		return err
 	}
 
-	azure.WaitAsyncOperation(requestId)
-	return nil
+	return azure.WaitAsyncOperation(requestId)
 }
 
-//Region public methods ends
+//Region private methods ends
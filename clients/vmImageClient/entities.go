@@ -0,0 +1,148 @@
+package vmImageClient
+
+import (
+	"encoding/xml"
+)
+
+type VMImageList struct {
+	XMLName  xml.Name  `xml:"VMImages"`
+	Xmlns    string    `xml:"xmlns,attr"`
+	VMImages []VMImage `xml:"VMImage"`
+}
+
+type VMImage struct {
+	Name                   string
+	Label                  string
+	Category               string
+	Description            string
+	OSDiskConfiguration    OSDiskConfiguration
+	DataDiskConfigurations DataDiskConfigurations
+	ServiceName            string
+	DeploymentName         string
+	RoleName               string
+	AffinityGroup          string
+	Location               string
+	CreatedTime            string
+	ModifiedTime           string
+	Language               string
+	ImageFamily            string
+	RecommendedVMSize      string
+	IsPremium              bool
+	Eula                   string
+	IconUri                string
+	SmallIconUri           string
+	PrivacyUri             string
+	PublisherName          string
+	PublishedDate          string
+	ShowInGui              bool
+	PricingDetailLink      string
+}
+
+type OSDiskConfiguration struct {
+	Name                string  `xml:",omitempty"`
+	HostCaching         string  `xml:",omitempty"`
+	OSState             OSState `xml:",omitempty"`
+	OS                  string  `xml:",omitempty"`
+	MediaLink           string  `xml:",omitempty"`
+	LogicalDiskSizeInGB int     `xml:",omitempty"`
+}
+
+type DataDiskConfigurations struct {
+	DataDiskConfiguration []DataDiskConfiguration
+}
+
+type DataDiskConfiguration struct {
+	Name                string `xml:",omitempty"`
+	HostCaching         string `xml:",omitempty"`
+	Lun                 int
+	MediaLink           string `xml:",omitempty"`
+	LogicalDiskSizeInGB int    `xml:",omitempty"`
+}
+
+type OSState string
+
+const (
+	OSStateGeneralized OSState = "Generalized"
+	OSStateSpecialized OSState = "Specialized"
+)
+
+type ReplicationInput struct {
+	XMLName                xml.Name `xml:"ReplicationInput"`
+	Xmlns                  string   `xml:"xmlns,attr"`
+	TargetLocations        TargetLocations
+	ComputeImageAttributes ComputeImageAttributes
+}
+
+type TargetLocations struct {
+	Region []string
+}
+
+type ComputeImageAttributes struct {
+	Offer   string
+	Sku     string
+	Version string
+}
+
+type SharePermission string
+
+const (
+	SharePermissionPublic  SharePermission = "public"
+	SharePermissionMSDN    SharePermission = "msdn"
+	SharePermissionPrivate SharePermission = "private"
+)
+
+type VMImageFilter struct {
+	Location  string
+	Publisher string
+	Category  string
+}
+
+type VMImageOptions struct {
+	Description       string
+	Language          string
+	ImageFamily       string
+	RecommendedVMSize string
+	Eula              string
+	IconUri           string
+	SmallIconUri      string
+	PrivacyUri        string
+	PublishedDate     string
+	ShowInGui         bool
+}
+
+type CreateVMImageConfig struct {
+	XMLName                xml.Name `xml:"VMImage"`
+	Xmlns                  string   `xml:"xmlns,attr"`
+	Name                   string
+	Label                  string
+	Description            string `xml:",omitempty"`
+	OSDiskConfiguration    OSDiskConfiguration
+	DataDiskConfigurations *DataDiskConfigurations `xml:",omitempty"`
+	Language               string                  `xml:",omitempty"`
+	ImageFamily            string                  `xml:",omitempty"`
+	RecommendedVMSize      string                  `xml:",omitempty"`
+	Eula                   string                  `xml:",omitempty"`
+	IconUri                string                  `xml:",omitempty"`
+	SmallIconUri           string                  `xml:",omitempty"`
+	PrivacyUri             string                  `xml:",omitempty"`
+	PublishedDate          string                  `xml:",omitempty"`
+	ShowInGui              bool
+}
+
+type UpdateVMImageConfig struct {
+	XMLName                xml.Name `xml:"VMImage"`
+	Xmlns                  string   `xml:"xmlns,attr"`
+	Label                  string
+	OSDiskConfiguration    *OSDiskConfiguration    `xml:",omitempty"`
+	DataDiskConfigurations *DataDiskConfigurations `xml:",omitempty"`
+	Description            string                  `xml:",omitempty"`
+	Language               string                  `xml:",omitempty"`
+	ImageFamily            string                  `xml:",omitempty"`
+	RecommendedVMSize      string                  `xml:",omitempty"`
+	Eula                   string                  `xml:",omitempty"`
+	IconUri                string                  `xml:",omitempty"`
+	SmallIconUri           string                  `xml:",omitempty"`
+	PrivacyUri             string                  `xml:",omitempty"`
+	PublishedDate          string                  `xml:",omitempty"`
+	ShowInGui              bool
+}
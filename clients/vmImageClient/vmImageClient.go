@@ -0,0 +1,286 @@
+package vmImageClient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+
+	azure "github.com/MSOpenTech/azure-sdk-for-go"
+)
+
+const (
+	azureXmlns          = "http://schemas.microsoft.com/windowsazure"
+	azureVMImageListURL = "services/vmimages"
+	azureVMImageURL     = "services/vmimages/%s"
+	deleteMediaQuery    = "?comp=media"
+
+	azureVMImageReplicateURL   = "services/vmimages/%s/replicate"
+	azureVMImageUnreplicateURL = "services/vmimages/%s/unreplicate"
+	azureVMImageShareURL       = "services/vmimages/%s/share?permission=%s"
+
+	osLinux   = "Linux"
+	osWindows = "Windows"
+
+	invalidOSError         = "Invalid OS: %s. Valid values are 'Linux' and 'Windows'."
+	invalidOSStateError    = "Invalid OS state: %s. Valid values are 'Generalized' and 'Specialized'."
+	invalidPermissionError = "Invalid share permission: %s. Valid values are 'public', 'msdn' and 'private'."
+	paramNotSpecifiedError = "Parameter %s is not specified."
+)
+
+//Region public methods starts
+
+func ListVMImages(filter VMImageFilter) (VMImageList, error) {
+	vmImageList := VMImageList{}
+
+	requestURL := azureVMImageListURL
+	query := createFilterQuery(filter)
+	if len(query) > 0 {
+		requestURL += "?" + query
+	}
+
+	response, err := azure.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return vmImageList, err
+	}
+
+	err = xml.Unmarshal(response, &vmImageList)
+	if err != nil {
+		return vmImageList, err
+	}
+
+	return vmImageList, nil
+}
+
+// CreateVMImage registers a VM image from an OS disk VHD and optional data
+// disk VHDs. The OS state of the OS disk configuration tells Azure whether
+// the VHD was generalized or should be deployed as is.
+func CreateVMImage(name, label string, osDisk OSDiskConfiguration, dataDisks []DataDiskConfiguration, options VMImageOptions) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if len(label) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "label")
+	}
+	if len(osDisk.MediaLink) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "osDisk.MediaLink")
+	}
+	if osDisk.OS != osLinux && osDisk.OS != osWindows {
+		return fmt.Errorf(invalidOSError, osDisk.OS)
+	}
+	if osDisk.OSState != OSStateGeneralized && osDisk.OSState != OSStateSpecialized {
+		return fmt.Errorf(invalidOSStateError, osDisk.OSState)
+	}
+	for _, dataDisk := range dataDisks {
+		if len(dataDisk.MediaLink) == 0 {
+			return fmt.Errorf(paramNotSpecifiedError, "dataDisk.MediaLink")
+		}
+	}
+
+	vmImageConfig := createVMImageConfig(name, label, osDisk, dataDisks, options)
+	vmImageBytes, err := xml.Marshal(vmImageConfig)
+	if err != nil {
+		return err
+	}
+
+	requestId, err := azure.SendAzurePostRequest(azureVMImageListURL, vmImageBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// UpdateVMImage replaces the label and descriptive metadata of a VM image.
+func UpdateVMImage(name, label string, options VMImageOptions) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if len(label) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "label")
+	}
+
+	vmImageConfig := updateVMImageConfig(label, options)
+	vmImageBytes, err := xml.Marshal(vmImageConfig)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureVMImageURL, name)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", vmImageBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// DeleteVMImage removes a VM image, and when deleteVHDs is set also deletes
+// the VHDs backing its disks.
+func DeleteVMImage(name string, deleteVHDs bool) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	requestURL := fmt.Sprintf(azureVMImageURL, name)
+	if deleteVHDs {
+		requestURL += deleteMediaQuery
+	}
+
+	requestId, err := azure.SendAzureDeleteRequest(requestURL)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// ReplicateVMImage replicates a VM image owned by a publisher subscription to
+// the target locations, publishing it under the given offer, sku and
+// version.
+func ReplicateVMImage(name string, targetLocations []string, offer, sku, version string) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if len(targetLocations) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "targetLocations")
+	}
+	if len(offer) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "offer")
+	}
+	if len(sku) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "sku")
+	}
+	if len(version) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "version")
+	}
+
+	replicationInput := ReplicationInput{}
+	replicationInput.Xmlns = azureXmlns
+	replicationInput.TargetLocations.Region = targetLocations
+	replicationInput.ComputeImageAttributes = ComputeImageAttributes{Offer: offer, Sku: sku, Version: version}
+
+	replicationBytes, err := xml.Marshal(replicationInput)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureVMImageReplicateURL, name)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", replicationBytes)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+// UnreplicateVMImage removes the replicas of a VM image created by
+// ReplicateVMImage. The image itself is kept.
+func UnreplicateVMImage(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	requestURL := fmt.Sprintf(azureVMImageUnreplicateURL, name)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", nil)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+func ShareVMImage(name string, permission SharePermission) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	switch permission {
+	case SharePermissionPublic, SharePermissionMSDN, SharePermissionPrivate:
+	default:
+		return fmt.Errorf(invalidPermissionError, permission)
+	}
+
+	requestURL := fmt.Sprintf(azureVMImageShareURL, name, permission)
+	requestId, err := azure.SendAzurePutRequest(requestURL, "", nil)
+	if err != nil {
+		return err
+	}
+
+	return azure.WaitAsyncOperation(requestId)
+}
+
+//Region public methods ends
+
+//Region private methods starts
+
+func createFilterQuery(filter VMImageFilter) string {
+	query := url.Values{}
+	if len(filter.Location) > 0 {
+		query.Set("location", filter.Location)
+	}
+	if len(filter.Publisher) > 0 {
+		query.Set("publisher", filter.Publisher)
+	}
+	if len(filter.Category) > 0 {
+		query.Set("category", filter.Category)
+	}
+
+	return query.Encode()
+}
+
+func createVMImageConfig(name, label string, osDisk OSDiskConfiguration, dataDisks []DataDiskConfiguration, options VMImageOptions) CreateVMImageConfig {
+	vmImageConfig := CreateVMImageConfig{}
+	vmImageConfig.Xmlns = azureXmlns
+	vmImageConfig.Name = name
+	vmImageConfig.Label = label
+	vmImageConfig.Description = options.Description
+	vmImageConfig.OSDiskConfiguration = OSDiskConfiguration{
+		HostCaching: osDisk.HostCaching,
+		OSState:     osDisk.OSState,
+		OS:          osDisk.OS,
+		MediaLink:   osDisk.MediaLink,
+	}
+
+	if len(dataDisks) > 0 {
+		vmImageConfig.DataDiskConfigurations = &DataDiskConfigurations{}
+		for _, dataDisk := range dataDisks {
+			vmImageConfig.DataDiskConfigurations.DataDiskConfiguration = append(vmImageConfig.DataDiskConfigurations.DataDiskConfiguration, DataDiskConfiguration{
+				HostCaching: dataDisk.HostCaching,
+				Lun:         dataDisk.Lun,
+				MediaLink:   dataDisk.MediaLink,
+			})
+		}
+	}
+
+	vmImageConfig.Language = options.Language
+	vmImageConfig.ImageFamily = options.ImageFamily
+	vmImageConfig.RecommendedVMSize = options.RecommendedVMSize
+	vmImageConfig.Eula = options.Eula
+	vmImageConfig.IconUri = options.IconUri
+	vmImageConfig.SmallIconUri = options.SmallIconUri
+	vmImageConfig.PrivacyUri = options.PrivacyUri
+	vmImageConfig.PublishedDate = options.PublishedDate
+	vmImageConfig.ShowInGui = options.ShowInGui
+
+	return vmImageConfig
+}
+
+func updateVMImageConfig(label string, options VMImageOptions) UpdateVMImageConfig {
+	vmImageConfig := UpdateVMImageConfig{}
+	vmImageConfig.Xmlns = azureXmlns
+	vmImageConfig.Label = label
+	vmImageConfig.Description = options.Description
+	vmImageConfig.Language = options.Language
+	vmImageConfig.ImageFamily = options.ImageFamily
+	vmImageConfig.RecommendedVMSize = options.RecommendedVMSize
+	vmImageConfig.Eula = options.Eula
+	vmImageConfig.IconUri = options.IconUri
+	vmImageConfig.SmallIconUri = options.SmallIconUri
+	vmImageConfig.PrivacyUri = options.PrivacyUri
+	vmImageConfig.PublishedDate = options.PublishedDate
+	vmImageConfig.ShowInGui = options.ShowInGui
+
+	return vmImageConfig
+}
+
+//Region private methods ends
@@ -0,0 +1,122 @@
+package vnetClient
+
+import (
+	"encoding/xml"
+)
+
+//Clone returns a deep copy of the network configuration, so that the copy can
+//be changed without affecting the original.
+func (self NetworkConfiguration) Clone() NetworkConfiguration {
+	clone := self
+	configuration := &clone.Configuration
+
+	configuration.Dns.UnknownAttrs = cloneAttrs(self.Configuration.Dns.UnknownAttrs)
+	configuration.Dns.UnknownElements = cloneElements(self.Configuration.Dns.UnknownElements)
+	if self.Configuration.Dns.DnsServers != nil {
+		configuration.Dns.DnsServers = make([]DnsServer, len(self.Configuration.Dns.DnsServers))
+		for i, dnsServer := range self.Configuration.Dns.DnsServers {
+			dnsServer.UnknownAttrs = cloneAttrs(dnsServer.UnknownAttrs)
+			configuration.Dns.DnsServers[i] = dnsServer
+		}
+	}
+
+	if self.Configuration.LocalNetworkSites != nil {
+		configuration.LocalNetworkSites = make([]LocalNetworkSite, len(self.Configuration.LocalNetworkSites))
+		for i, site := range self.Configuration.LocalNetworkSites {
+			site.AddressSpace = site.AddressSpace.clone()
+			site.UnknownAttrs = cloneAttrs(site.UnknownAttrs)
+			site.UnknownElements = cloneElements(site.UnknownElements)
+			configuration.LocalNetworkSites[i] = site
+		}
+	}
+
+	if self.Configuration.VirtualNetworkSites != nil {
+		configuration.VirtualNetworkSites = make([]VirtualNetworkSite, len(self.Configuration.VirtualNetworkSites))
+		for i, site := range self.Configuration.VirtualNetworkSites {
+			configuration.VirtualNetworkSites[i] = site.clone()
+		}
+	}
+
+	configuration.UnknownElements = cloneElements(self.Configuration.UnknownElements)
+
+	return clone
+}
+
+//Region private methods starts
+
+func (self VirtualNetworkSite) clone() VirtualNetworkSite {
+	clone := self
+	clone.AddressSpace = self.AddressSpace.clone()
+
+	if self.Subnets != nil {
+		clone.Subnets = make([]Subnet, len(self.Subnets))
+		for i, subnet := range self.Subnets {
+			subnet.UnknownAttrs = cloneAttrs(subnet.UnknownAttrs)
+			subnet.UnknownElements = cloneElements(subnet.UnknownElements)
+			clone.Subnets[i] = subnet
+		}
+	}
+
+	if self.DnsServersRef != nil {
+		clone.DnsServersRef = make([]DnsServerRef, len(self.DnsServersRef))
+		for i, ref := range self.DnsServersRef {
+			ref.UnknownAttrs = cloneAttrs(ref.UnknownAttrs)
+			ref.UnknownElements = cloneElements(ref.UnknownElements)
+			clone.DnsServersRef[i] = ref
+		}
+	}
+
+	if self.Gateway != nil {
+		gateway := *self.Gateway
+		if gateway.VPNClientAddressPool != nil {
+			pool := gateway.VPNClientAddressPool.clone()
+			gateway.VPNClientAddressPool = &pool
+		}
+		if gateway.ConnectionsToLocalNetwork != nil {
+			gateway.ConnectionsToLocalNetwork = make([]LocalNetworkSiteRef, len(self.Gateway.ConnectionsToLocalNetwork))
+			for i, siteRef := range self.Gateway.ConnectionsToLocalNetwork {
+				if siteRef.Connection != nil {
+					connection := *siteRef.Connection
+					connection.UnknownAttrs = cloneAttrs(connection.UnknownAttrs)
+					connection.UnknownElements = cloneElements(connection.UnknownElements)
+					siteRef.Connection = &connection
+				}
+				siteRef.UnknownAttrs = cloneAttrs(siteRef.UnknownAttrs)
+				siteRef.UnknownElements = cloneElements(siteRef.UnknownElements)
+				gateway.ConnectionsToLocalNetwork[i] = siteRef
+			}
+		}
+		gateway.UnknownAttrs = cloneAttrs(gateway.UnknownAttrs)
+		gateway.UnknownElements = cloneElements(gateway.UnknownElements)
+		clone.Gateway = &gateway
+	}
+
+	clone.UnknownAttrs = cloneAttrs(self.UnknownAttrs)
+	clone.UnknownElements = cloneElements(self.UnknownElements)
+
+	return clone
+}
+
+func (self AddressSpace) clone() AddressSpace {
+	return AddressSpace{AddressPrefix: append([]string(nil), self.AddressPrefix...)}
+}
+
+func cloneAttrs(attrs []xml.Attr) []xml.Attr {
+	return append([]xml.Attr(nil), attrs...)
+}
+
+func cloneElements(elements []UnknownElement) []UnknownElement {
+	if elements == nil {
+		return nil
+	}
+
+	clone := make([]UnknownElement, len(elements))
+	for i, element := range elements {
+		element.Attrs = cloneAttrs(element.Attrs)
+		clone[i] = element
+	}
+
+	return clone
+}
+
+//Region private methods ends
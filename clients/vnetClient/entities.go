@@ -1,6 +1,7 @@
 package vnetClient
 
 import (
+	"encoding/json"
 	"encoding/xml"
 )
 
@@ -12,11 +13,11 @@ const xmlNamespaceXsi = "http://www.w3.org/2001/XMLSchema-instance"
 //subscription. TODO: Nicer builder methods for these that abstract away the
 //underlying structure
 type NetworkConfiguration struct {
-	XMLName         xml.Name                    `xml:"NetworkConfiguration"`
-	XmlNamespaceXsd string                      `xml:"xmlns:xsd,attr"`
-	XmlNamespaceXsi string                      `xml:"xmlns:xsi,attr"`
-	Xmlns           string                      `xml:"xmlns,attr"`
-	Configuration   VirtualNetworkConfiguration `xml:"VirtualNetworkConfiguration"`
+	XMLName         xml.Name                    `xml:"NetworkConfiguration" json:"-"`
+	XmlNamespaceXsd string                      `xml:"xmlns:xsd,attr" json:"-"`
+	XmlNamespaceXsi string                      `xml:"xmlns:xsi,attr" json:"-"`
+	Xmlns           string                      `xml:"xmlns,attr" json:"-"`
+	Configuration   VirtualNetworkConfiguration `xml:"VirtualNetworkConfiguration" json:"configuration,omitempty"`
 }
 
 //NewNetworkConfiguration creates a new empty NetworkConfiguration structure for
@@ -38,44 +39,203 @@ func (self *NetworkConfiguration) setXmlNamespaces() {
 }
 
 type VirtualNetworkConfiguration struct {
-	Dns                 Dns                  `xml:"Dns,omitempty"`
-	LocalNetworkSites   []LocalNetworkSite   `xml:"LocalNetworkSites>LocalNetworkSite"`
-	VirtualNetworkSites []VirtualNetworkSite `xml:"VirtualNetworkSites>VirtualNetworkSite"`
+	Dns                 Dns                  `xml:"Dns,omitempty" json:"dns,omitempty"`
+	LocalNetworkSites   []LocalNetworkSite   `xml:"LocalNetworkSites>LocalNetworkSite" json:"localNetworkSites,omitempty"`
+	VirtualNetworkSites []VirtualNetworkSite `xml:"VirtualNetworkSites>VirtualNetworkSite" json:"virtualNetworkSites,omitempty"`
+	UnknownElements     []UnknownElement     `xml:",any" json:"unknownElements,omitempty"`
 }
 
 type Dns struct {
-	DnsServers []DnsServer `xml:"DnsServers,omitempty>DnsServer,omitempty"`
+	DnsServers      []DnsServer      `xml:"DnsServers>DnsServer" json:"dnsServers,omitempty"`
+	UnknownAttrs    []xml.Attr       `xml:",any,attr" json:"unknownAttrs,omitempty"`
+	UnknownElements []UnknownElement `xml:",any" json:"unknownElements,omitempty"`
 }
 
 type DnsServer struct {
-	XMLName   xml.Name `xml:"DnsServer"`
-	Name      string   `xml:"name,attr"`
-	IPAddress string   `xml:"IPAddress,attr"`
+	XMLName      xml.Name   `xml:"DnsServer" json:"-"`
+	Name         string     `xml:"name,attr" json:"name,omitempty"`
+	IPAddress    string     `xml:"IPAddress,attr" json:"ipAddress,omitempty"`
+	UnknownAttrs []xml.Attr `xml:",any,attr" json:"unknownAttrs,omitempty"`
 }
 
 type DnsServerRef struct {
-	Name string `xml:"name,attr"`
+	Name            string           `xml:"name,attr" json:"name,omitempty"`
+	UnknownAttrs    []xml.Attr       `xml:",any,attr" json:"unknownAttrs,omitempty"`
+	UnknownElements []UnknownElement `xml:",any" json:"unknownElements,omitempty"`
 }
 
 type VirtualNetworkSite struct {
-	Name          string         `xml:"name,attr"`
-	Location      string         `xml:"Location,attr"`
-	AddressSpace  AddressSpace   `xml:"AddressSpace"`
-	Subnets       []Subnet       `xml:"Subnets>Subnet"`
-	DnsServersRef []DnsServerRef `xml:"DnsServersRef,omitempty>DnsServerRef"`
+	Name            string           `xml:"name,attr" json:"name,omitempty"`
+	Location        string           `xml:"Location,attr,omitempty" json:"location,omitempty"`
+	AffinityGroup   string           `xml:"AffinityGroup,attr,omitempty" json:"affinityGroup,omitempty"`
+	AddressSpace    AddressSpace     `xml:"AddressSpace" json:"addressSpace,omitempty"`
+	Subnets         []Subnet         `xml:"Subnets>Subnet" json:"subnets,omitempty"`
+	DnsServersRef   []DnsServerRef   `xml:"DnsServersRef>DnsServerRef" json:"dnsServersRef,omitempty"`
+	Gateway         *Gateway         `xml:"Gateway,omitempty" json:"gateway,omitempty"`
+	UnknownAttrs    []xml.Attr       `xml:",any,attr" json:"unknownAttrs,omitempty"`
+	UnknownElements []UnknownElement `xml:",any" json:"unknownElements,omitempty"`
 }
 
+//Gateway is the VPN gateway configuration of a virtual network site.
+type Gateway struct {
+	Profile                   string                `xml:"profile,attr,omitempty" json:"profile,omitempty"`
+	VPNClientAddressPool      *AddressSpace         `xml:"VPNClientAddressPool,omitempty" json:"vpnClientAddressPool,omitempty"`
+	ConnectionsToLocalNetwork []LocalNetworkSiteRef `xml:"ConnectionsToLocalNetwork>LocalNetworkSiteRef" json:"connectionsToLocalNetwork,omitempty"`
+	UnknownAttrs              []xml.Attr            `xml:",any,attr" json:"unknownAttrs,omitempty"`
+	UnknownElements           []UnknownElement      `xml:",any" json:"unknownElements,omitempty"`
+}
+
+type LocalNetworkSiteRef struct {
+	Name            string           `xml:"name,attr" json:"name,omitempty"`
+	Connection      *Connection      `xml:"Connection,omitempty" json:"connection,omitempty"`
+	UnknownAttrs    []xml.Attr       `xml:",any,attr" json:"unknownAttrs,omitempty"`
+	UnknownElements []UnknownElement `xml:",any" json:"unknownElements,omitempty"`
+}
+
+type Connection struct {
+	Type            string           `xml:"type,attr" json:"type,omitempty"`
+	UnknownAttrs    []xml.Attr       `xml:",any,attr" json:"unknownAttrs,omitempty"`
+	UnknownElements []UnknownElement `xml:",any" json:"unknownElements,omitempty"`
+}
+
+const (
+	ConnectionTypeIPsec     = "IPsec"
+	ConnectionTypeDedicated = "Dedicated"
+)
+
 type LocalNetworkSite struct {
-	Name              string `xml:"name,attr"`
-	VPNGatewayAddress string
-	AddressSpace      AddressSpace
+	Name              string           `xml:"name,attr" json:"name,omitempty"`
+	AddressSpace      AddressSpace     `json:"addressSpace,omitempty"`
+	VPNGatewayAddress string           `json:"vpnGatewayAddress,omitempty"`
+	UnknownAttrs      []xml.Attr       `xml:",any,attr" json:"unknownAttrs,omitempty"`
+	UnknownElements   []UnknownElement `xml:",any" json:"unknownElements,omitempty"`
 }
 
 type AddressSpace struct {
-	AddressPrefix []string
+	AddressPrefix []string `json:"addressPrefix,omitempty"`
 }
 
 type Subnet struct {
-	Name          string `xml:"name,attr"`
-	AddressPrefix string
+	Name            string           `xml:"name,attr" json:"name,omitempty"`
+	AddressPrefix   string           `json:"addressPrefix,omitempty"`
+	UnknownAttrs    []xml.Attr       `xml:",any,attr" json:"unknownAttrs,omitempty"`
+	UnknownElements []UnknownElement `xml:",any" json:"unknownElements,omitempty"`
+}
+
+//UnknownElement keeps an element of the network configuration which the types
+//of this package do not model, so that reading, changing and writing back the
+//configuration does not drop it. Unknown elements are written back before the
+//known element of their parent they were read before, as the schema of the
+//network configuration fixes the order of the elements.
+//
+//In JSON an unknown element is kept as a string holding its XML. Its position
+//is not kept, elements read from JSON are written after the known elements of
+//their parent.
+type UnknownElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	InnerXML string     `xml:",innerxml"`
+	//Before is the name of the known element the unknown element was read
+	//before, empty if it came after all of them.
+	Before string `xml:"-"`
+}
+
+type VirtualNetworkSiteInfoList struct {
+	XMLName             xml.Name                 `xml:"VirtualNetworkSites" json:"-"`
+	Xmlns               string                   `xml:"xmlns,attr" json:"-"`
+	VirtualNetworkSites []VirtualNetworkSiteInfo `xml:"VirtualNetworkSite" json:"virtualNetworkSites,omitempty"`
+}
+
+//VirtualNetworkSiteInfo is a virtual network site as listed by Azure. Unlike
+//VirtualNetworkSite it is read-only and includes the runtime state of the
+//site, like its ID, provisioning state and whether it is in use.
+type VirtualNetworkSiteInfo struct {
+	Name          string                  `json:"name,omitempty"`
+	Label         string                  `json:"label,omitempty"`
+	Id            string                  `json:"id,omitempty"`
+	AffinityGroup string                  `json:"affinityGroup,omitempty"`
+	Location      string                  `json:"location,omitempty"`
+	State         VirtualNetworkSiteState `json:"state,omitempty"`
+	InUse         bool                    `json:"inUse,omitempty"`
+	AddressSpace  []string                `xml:"AddressSpace>AddressPrefixes>AddressPrefix" json:"addressSpace,omitempty"`
+	Subnets       []SubnetInfo            `xml:"Subnets>Subnet" json:"subnets,omitempty"`
+	DnsServers    []DnsServerInfo         `xml:"DnsServers>DnsServer" json:"dnsServers,omitempty"`
+	Gateway       *GatewayInfo            `xml:"Gateway" json:"gateway,omitempty"`
+}
+
+type VirtualNetworkSiteState string
+
+const (
+	VirtualNetworkSiteStateCreated     VirtualNetworkSiteState = "Created"
+	VirtualNetworkSiteStateCreating    VirtualNetworkSiteState = "Creating"
+	VirtualNetworkSiteStateUpdating    VirtualNetworkSiteState = "Updating"
+	VirtualNetworkSiteStateDeleting    VirtualNetworkSiteState = "Deleting"
+	VirtualNetworkSiteStateUnavailable VirtualNetworkSiteState = "Unavailable"
+)
+
+type SubnetInfo struct {
+	Name                 string `json:"name,omitempty"`
+	AddressPrefix        string `json:"addressPrefix,omitempty"`
+	NetworkSecurityGroup string `json:"networkSecurityGroup,omitempty"`
+}
+
+type DnsServerInfo struct {
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+type GatewayInfo struct {
+	Profile              string                 `json:"profile,omitempty"`
+	Sites                []LocalNetworkSiteInfo `xml:"Sites>LocalNetworkSite" json:"sites,omitempty"`
+	VPNClientAddressPool []string               `xml:"VPNClientAddressPool>AddressPrefixes>AddressPrefix" json:"vpnClientAddressPool,omitempty"`
+}
+
+type LocalNetworkSiteInfo struct {
+	Name              string   `json:"name,omitempty"`
+	AddressSpace      []string `xml:"AddressSpace>AddressPrefixes>AddressPrefix" json:"addressSpace,omitempty"`
+	VpnGatewayAddress string   `json:"vpnGatewayAddress,omitempty"`
+	Connections       []string `xml:"Connections>Connection>Type" json:"connections,omitempty"`
+}
+
+//MarshalXML writes the element back in the namespace of the network
+//configuration without repeating its declaration, which would otherwise be
+//written once for the element name and once more for the captured attribute.
+func (self UnknownElement) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	start.Name = self.XMLName
+	if start.Name.Space == xmlNamespace {
+		start.Name.Space = ""
+	}
+
+	start.Attr = nil
+	for _, attr := range self.Attrs {
+		if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			continue
+		}
+		start.Attr = append(start.Attr, attr)
+	}
+
+	return encoder.EncodeElement(struct {
+		InnerXML string `xml:",innerxml"`
+	}{self.InnerXML}, start)
+}
+
+//MarshalJSON writes the element as a JSON string holding its XML.
+func (self UnknownElement) MarshalJSON() ([]byte, error) {
+	elementBytes, err := xml.Marshal(self)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(string(elementBytes))
+}
+
+//UnmarshalJSON reads an element written by MarshalJSON.
+func (self *UnknownElement) UnmarshalJSON(data []byte) error {
+	var element string
+	err := json.Unmarshal(data, &element)
+	if err != nil {
+		return err
+	}
+
+	return xml.Unmarshal([]byte(element), self)
 }
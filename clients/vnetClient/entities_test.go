@@ -0,0 +1,160 @@
+package vnetClient
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+)
+
+//networkConfigurationSample is a network configuration as exported by Azure,
+//with elements and attributes the package does not model added at different
+//positions.
+const networkConfigurationSample = `<NetworkConfiguration xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns="http://schemas.microsoft.com/ServiceHosting/2011/07/NetworkConfiguration">
+  <VirtualNetworkConfiguration>
+    <Dns ttl="300">
+      <DnsServers>
+        <DnsServer name="dns1" IPAddress="10.0.0.4" />
+      </DnsServers>
+      <Suffix>contoso.local</Suffix>
+    </Dns>
+    <LocalNetworkSites>
+      <LocalNetworkSite name="onprem">
+        <AddressSpace>
+          <AddressPrefix>192.168.0.0/16</AddressPrefix>
+        </AddressSpace>
+        <DeviceType>RRAS</DeviceType>
+        <VPNGatewayAddress>203.0.113.10</VPNGatewayAddress>
+      </LocalNetworkSite>
+    </LocalNetworkSites>
+    <LogicalSites>
+      <LogicalSite name="site1" />
+    </LogicalSites>
+    <VirtualNetworkSites>
+      <VirtualNetworkSite name="vnet1" Location="West Europe">
+        <Label>Production</Label>
+        <AddressSpace>
+          <AddressPrefix>10.0.0.0/16</AddressPrefix>
+        </AddressSpace>
+        <Subnets>
+          <Subnet name="Subnet-1">
+            <AddressPrefix>10.0.0.0/24</AddressPrefix>
+            <NetworkSecurityGroup>nsg1</NetworkSecurityGroup>
+          </Subnet>
+          <Subnet name="GatewaySubnet">
+            <AddressPrefix>10.0.1.0/29</AddressPrefix>
+          </Subnet>
+        </Subnets>
+        <RouteTableRef name="routes1" />
+        <DnsServersRef>
+          <DnsServerRef name="dns1" priority="1" />
+        </DnsServersRef>
+        <Gateway profile="Small">
+          <VPNClientAddressPool>
+            <AddressPrefix>172.16.0.0/24</AddressPrefix>
+          </VPNClientAddressPool>
+          <DefaultSite>onprem</DefaultSite>
+          <ConnectionsToLocalNetwork>
+            <LocalNetworkSiteRef name="onprem">
+              <Connection type="IPsec">
+                <SharedKeyRef name="key1" />
+              </Connection>
+            </LocalNetworkSiteRef>
+          </ConnectionsToLocalNetwork>
+        </Gateway>
+      </VirtualNetworkSite>
+    </VirtualNetworkSites>
+  </VirtualNetworkConfiguration>
+</NetworkConfiguration>`
+
+//xmlOutline lists the elements, attributes and text of an XML document in
+//document order, ignoring namespaces and whitespace.
+func xmlOutline(t *testing.T, data []byte) []string {
+	outline := []string{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return outline
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			attrs := []string{}
+			for _, attr := range token.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				attrs = append(attrs, attr.Name.Local+"="+attr.Value)
+			}
+			sort.Strings(attrs)
+			outline = append(outline, "<"+token.Name.Local+" "+strings.Join(attrs, " ")+">")
+		case xml.EndElement:
+			outline = append(outline, "</"+token.Name.Local+">")
+		case xml.CharData:
+			if text := strings.TrimSpace(string(token)); len(text) > 0 {
+				outline = append(outline, text)
+			}
+		}
+	}
+}
+
+func TestNetworkConfiguration_RoundTrip(t *testing.T) {
+	networkConfiguration := NetworkConfiguration{}
+	err := xml.Unmarshal([]byte(networkConfigurationSample), &networkConfiguration)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	site := networkConfiguration.Configuration.VirtualNetworkSites[0]
+	if expected := "10.0.0.0/16"; site.AddressSpace.AddressPrefix[0] != expected {
+		t.Fatalf("Wrong address prefix. Expected: '%s', got: '%s'", expected, site.AddressSpace.AddressPrefix[0])
+	}
+	if expected := ConnectionTypeIPsec; site.Gateway.ConnectionsToLocalNetwork[0].Connection.Type != expected {
+		t.Fatalf("Wrong connection type. Expected: '%s', got: '%s'", expected, site.Gateway.ConnectionsToLocalNetwork[0].Connection.Type)
+	}
+
+	output, err := xml.Marshal(networkConfiguration.Clone())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := xmlOutline(t, []byte(networkConfigurationSample))
+	got := xmlOutline(t, output)
+	if len(got) != len(expected) {
+		t.Fatalf("Wrong XML. Expected: '%s', got: '%s'", networkConfigurationSample, output)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("Wrong XML at '%s'. Expected: '%s', got: '%s'", expected[i], networkConfigurationSample, output)
+		}
+	}
+}
+
+func TestNetworkConfiguration_UnknownElementsAfterRemovedElement(t *testing.T) {
+	networkConfiguration := NetworkConfiguration{}
+	err := xml.Unmarshal([]byte(networkConfigurationSample), &networkConfiguration)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//The unknown element stays in front of the next known element when the one
+	//it was read before is removed
+	networkConfiguration.Configuration.VirtualNetworkSites[0].DnsServersRef = nil
+
+	output, err := xml.Marshal(networkConfiguration.Configuration.VirtualNetworkSites[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(output)
+	routeTable, gateway := strings.Index(got, "<RouteTableRef"), strings.Index(got, "<Gateway")
+	if routeTable < 0 || gateway < 0 || routeTable > gateway {
+		t.Fatalf("Wrong position of RouteTableRef. Expected before Gateway, got: '%s'", got)
+	}
+}
@@ -0,0 +1,263 @@
+package vnetClient
+
+import (
+	"encoding/xml"
+)
+
+//The known child elements of the types keeping unknown elements, in the order
+//of the schema of the network configuration.
+var (
+	virtualNetworkConfigurationElements = []string{"Dns", "LocalNetworkSites", "VirtualNetworkSites"}
+	dnsElements                         = []string{"DnsServers"}
+	localNetworkSiteElements            = []string{"AddressSpace", "VPNGatewayAddress"}
+	virtualNetworkSiteElements          = []string{"AddressSpace", "Subnets", "DnsServersRef", "Gateway"}
+	subnetElements                      = []string{"AddressPrefix"}
+	gatewayElements                     = []string{"VPNClientAddressPool", "ConnectionsToLocalNetwork"}
+	localNetworkSiteRefElements         = []string{"Connection"}
+)
+
+//rawElement is an element with its children kept as XML, in the order they
+//were read.
+type rawElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr       `xml:",any,attr"`
+	Children []UnknownElement `xml:",any"`
+}
+
+//UnmarshalXML reads the configuration and remembers the position of its
+//unknown elements.
+func (self *VirtualNetworkConfiguration) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	type virtualNetworkConfiguration VirtualNetworkConfiguration
+	return decodeOrdered(decoder, start, (*virtualNetworkConfiguration)(self), &self.UnknownElements, virtualNetworkConfigurationElements)
+}
+
+//MarshalXML writes the configuration with its unknown elements back at their
+//position.
+func (self VirtualNetworkConfiguration) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	type virtualNetworkConfiguration VirtualNetworkConfiguration
+	unknownElements := self.UnknownElements
+	self.UnknownElements = nil
+	return encodeOrdered(encoder, start, virtualNetworkConfiguration(self), unknownElements, virtualNetworkConfigurationElements)
+}
+
+//UnmarshalXML reads the DNS configuration and remembers the position of its
+//unknown elements.
+func (self *Dns) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	type dns Dns
+	return decodeOrdered(decoder, start, (*dns)(self), &self.UnknownElements, dnsElements)
+}
+
+//MarshalXML writes the DNS configuration with its unknown elements back at
+//their position.
+func (self Dns) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	type dns Dns
+	unknownElements := self.UnknownElements
+	self.UnknownElements = nil
+	return encodeOrdered(encoder, start, dns(self), unknownElements, dnsElements)
+}
+
+//UnmarshalXML reads the site and remembers the position of its unknown
+//elements.
+func (self *LocalNetworkSite) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	type localNetworkSite LocalNetworkSite
+	return decodeOrdered(decoder, start, (*localNetworkSite)(self), &self.UnknownElements, localNetworkSiteElements)
+}
+
+//MarshalXML writes the site with its unknown elements back at their position.
+func (self LocalNetworkSite) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	type localNetworkSite LocalNetworkSite
+	unknownElements := self.UnknownElements
+	self.UnknownElements = nil
+	return encodeOrdered(encoder, start, localNetworkSite(self), unknownElements, localNetworkSiteElements)
+}
+
+//UnmarshalXML reads the site and remembers the position of its unknown
+//elements.
+func (self *VirtualNetworkSite) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	type virtualNetworkSite VirtualNetworkSite
+	return decodeOrdered(decoder, start, (*virtualNetworkSite)(self), &self.UnknownElements, virtualNetworkSiteElements)
+}
+
+//MarshalXML writes the site with its unknown elements back at their position.
+func (self VirtualNetworkSite) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	type virtualNetworkSite VirtualNetworkSite
+	unknownElements := self.UnknownElements
+	self.UnknownElements = nil
+	return encodeOrdered(encoder, start, virtualNetworkSite(self), unknownElements, virtualNetworkSiteElements)
+}
+
+//UnmarshalXML reads the subnet and remembers the position of its unknown
+//elements.
+func (self *Subnet) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	type subnet Subnet
+	return decodeOrdered(decoder, start, (*subnet)(self), &self.UnknownElements, subnetElements)
+}
+
+//MarshalXML writes the subnet with its unknown elements back at their
+//position.
+func (self Subnet) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	type subnet Subnet
+	unknownElements := self.UnknownElements
+	self.UnknownElements = nil
+	return encodeOrdered(encoder, start, subnet(self), unknownElements, subnetElements)
+}
+
+//UnmarshalXML reads the gateway and remembers the position of its unknown
+//elements.
+func (self *Gateway) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	type gateway Gateway
+	return decodeOrdered(decoder, start, (*gateway)(self), &self.UnknownElements, gatewayElements)
+}
+
+//MarshalXML writes the gateway with its unknown elements back at their
+//position.
+func (self Gateway) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	type gateway Gateway
+	unknownElements := self.UnknownElements
+	self.UnknownElements = nil
+	return encodeOrdered(encoder, start, gateway(self), unknownElements, gatewayElements)
+}
+
+//UnmarshalXML reads the reference and remembers the position of its unknown
+//elements.
+func (self *LocalNetworkSiteRef) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	type localNetworkSiteRef LocalNetworkSiteRef
+	return decodeOrdered(decoder, start, (*localNetworkSiteRef)(self), &self.UnknownElements, localNetworkSiteRefElements)
+}
+
+//MarshalXML writes the reference with its unknown elements back at their
+//position.
+func (self LocalNetworkSiteRef) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	type localNetworkSiteRef LocalNetworkSiteRef
+	unknownElements := self.UnknownElements
+	self.UnknownElements = nil
+	return encodeOrdered(encoder, start, localNetworkSiteRef(self), unknownElements, localNetworkSiteRefElements)
+}
+
+//Region private methods starts
+
+//decodeOrdered decodes the element into value, a pointer to a type without
+//an UnmarshalXML method, and sets the Before field of the unknown elements
+//decoded into unknownElements. knownElements are the names of the child
+//elements the type models.
+func decodeOrdered(decoder *xml.Decoder, start xml.StartElement, value interface{}, unknownElements *[]UnknownElement, knownElements []string) error {
+	var raw rawElement
+	err := decoder.DecodeElement(&raw, &start)
+	if err != nil {
+		return err
+	}
+
+	rawBytes, err := xml.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	err = xml.Unmarshal(rawBytes, value)
+	if err != nil {
+		return err
+	}
+
+	//The unknown elements are decoded in the order of the children, so walk
+	//both backwards to find the known element following each of them
+	i := len(*unknownElements) - 1
+	before := ""
+	for j := len(raw.Children) - 1; j >= 0 && i >= 0; j-- {
+		name := raw.Children[j].XMLName.Local
+		if indexOfElement(knownElements, name) >= 0 {
+			before = name
+			continue
+		}
+
+		(*unknownElements)[i].Before = before
+		i--
+	}
+
+	return nil
+}
+
+//encodeOrdered encodes value, which must not have a MarshalXML method or
+//unknown elements, as the element start and inserts the unknown elements
+//before the known elements they were read before.
+func encodeOrdered(encoder *xml.Encoder, start xml.StartElement, value interface{}, unknownElements []UnknownElement, knownElements []string) error {
+	valueBytes, err := xml.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	var raw rawElement
+	err = xml.Unmarshal(valueBytes, &raw)
+	if err != nil {
+		return err
+	}
+
+	//The encoder declares the namespaces it needs itself
+	start.Attr = nil
+	for _, attr := range raw.Attrs {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		start.Attr = append(start.Attr, attr)
+	}
+
+	err = encoder.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	positions := make([]int, len(unknownElements))
+	for i, element := range unknownElements {
+		positions[i] = insertPosition(raw.Children, knownElements, element.Before)
+	}
+
+	for j := 0; j <= len(raw.Children); j++ {
+		for i, element := range unknownElements {
+			if positions[i] != j {
+				continue
+			}
+
+			err = encoder.Encode(element)
+			if err != nil {
+				return err
+			}
+		}
+
+		if j < len(raw.Children) {
+			err = encoder.Encode(raw.Children[j])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+//insertPosition returns the index of the first child which comes at or after
+//the known element before in the schema, or the number of children if there
+//is none or before is empty.
+func insertPosition(children []UnknownElement, knownElements []string, before string) int {
+	rank := indexOfElement(knownElements, before)
+	if rank < 0 {
+		return len(children)
+	}
+
+	for j, child := range children {
+		if indexOfElement(knownElements, child.XMLName.Local) >= rank {
+			return j
+		}
+	}
+
+	return len(children)
+}
+
+func indexOfElement(elements []string, name string) int {
+	for i, element := range elements {
+		if element == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+//Region private methods ends
@@ -0,0 +1,299 @@
+package vnetClient
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	addressSpacesOverlapError            = "Address prefixes %s and %s of network site %s overlap."
+	virtualNetworkSitesOverlapWarning    = "Address prefix %s of virtual network site %s overlaps address prefix %s of virtual network site %s, the sites cannot be connected."
+	localNetworkSiteOverlapError         = "Address prefix %s of virtual network site %s overlaps address prefix %s of its connected local network site %s."
+	invalidNetworkConfigurationError     = "The network configuration is invalid: %s"
+	invalidNetworkConfigurationSeparator = " "
+)
+
+type FindingSeverity string
+
+const (
+	FindingSeverityError   FindingSeverity = "Error"
+	FindingSeverityWarning FindingSeverity = "Warning"
+)
+
+//ValidationFinding is a problem found in a network configuration. Site is the
+//name of the virtual network site, local network site or DNS server the
+//finding is about.
+type ValidationFinding struct {
+	Severity FindingSeverity
+	Site     string
+	Message  string
+}
+
+//ValidationError is returned by SetVirtualNetworkConfiguration and the
+//helpers building on it when the change to the network configuration causes
+//findings with FindingSeverityError. Warnings, including the errors the
+//configuration had before the change, are included for completeness.
+type ValidationError struct {
+	Findings []ValidationFinding
+}
+
+func (e *ValidationError) Error() string {
+	messages := []string{}
+	for _, finding := range e.Findings {
+		if finding.Severity == FindingSeverityError {
+			messages = append(messages, finding.Message)
+		}
+	}
+
+	return fmt.Sprintf(invalidNetworkConfigurationError, strings.Join(messages, invalidNetworkConfigurationSeparator))
+}
+
+//ValidateNetworkConfiguration checks the network configuration for mistakes
+//Azure reports with hard to understand errors, or not at all: invalid CIDRs
+//and addresses, duplicate names, references to undefined DNS servers and local
+//network sites, subnets outside the address space of their site and
+//overlapping address spaces. Overlapping virtual network sites are only a
+//warning, since Azure accepts them as long as the sites are not connected.
+func ValidateNetworkConfiguration(networkConfiguration NetworkConfiguration) []ValidationFinding {
+	v := &validator{}
+	v.validate(networkConfiguration.Configuration)
+	return v.findings
+}
+
+//Region private methods starts
+
+//validateNetworkConfigurationChange validates the changed configuration. Errors
+//which the current configuration has as well are not caused by the change and
+//are turned into warnings.
+func validateNetworkConfigurationChange(currentConfiguration, changedConfiguration NetworkConfiguration) error {
+	existingErrors := map[ValidationFinding]bool{}
+	for _, finding := range ValidateNetworkConfiguration(currentConfiguration) {
+		if finding.Severity == FindingSeverityError {
+			existingErrors[finding] = true
+		}
+	}
+
+	findings := ValidateNetworkConfiguration(changedConfiguration)
+	hasErrors := false
+	for i, finding := range findings {
+		if finding.Severity != FindingSeverityError {
+			continue
+		}
+
+		if existingErrors[finding] {
+			findings[i].Severity = FindingSeverityWarning
+		} else {
+			hasErrors = true
+		}
+	}
+
+	if hasErrors {
+		return &ValidationError{Findings: findings}
+	}
+
+	return nil
+}
+
+type validator struct {
+	findings []ValidationFinding
+}
+
+func (v *validator) errorf(site, format string, args ...interface{}) {
+	v.findings = append(v.findings, ValidationFinding{Severity: FindingSeverityError, Site: site, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warningf(site, format string, args ...interface{}) {
+	v.findings = append(v.findings, ValidationFinding{Severity: FindingSeverityWarning, Site: site, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(configuration VirtualNetworkConfiguration) {
+	dnsServers := map[string]bool{}
+	for _, dnsServer := range configuration.Dns.DnsServers {
+		if len(dnsServer.Name) == 0 {
+			v.errorf("", paramNotSpecifiedError, "DnsServer.Name")
+			continue
+		}
+		if dnsServers[dnsServer.Name] {
+			v.errorf(dnsServer.Name, duplicateDnsServerError, dnsServer.Name)
+		}
+		if net.ParseIP(dnsServer.IPAddress) == nil {
+			v.errorf(dnsServer.Name, invalidDnsServerAddressError, dnsServer.IPAddress, dnsServer.Name)
+		}
+		dnsServers[dnsServer.Name] = true
+	}
+
+	localAddressSpaces := map[string][]*net.IPNet{}
+	for _, site := range configuration.LocalNetworkSites {
+		if len(site.Name) == 0 {
+			v.errorf("", paramNotSpecifiedError, "LocalNetworkSite.Name")
+			continue
+		}
+		if _, ok := localAddressSpaces[site.Name]; ok {
+			v.errorf(site.Name, duplicateLocalNetworkSiteError, site.Name)
+		}
+		if net.ParseIP(site.VPNGatewayAddress) == nil {
+			v.errorf(site.Name, invalidVPNGatewayAddressError, site.VPNGatewayAddress, site.Name)
+		}
+		localAddressSpaces[site.Name] = v.addressSpace(site.Name, site.AddressSpace)
+	}
+
+	addressSpaces := make([][]*net.IPNet, len(configuration.VirtualNetworkSites))
+	names := map[string]bool{}
+	for i, site := range configuration.VirtualNetworkSites {
+		if len(site.Name) == 0 {
+			v.errorf("", paramNotSpecifiedError, "VirtualNetworkSite.Name")
+			continue
+		}
+		if names[site.Name] {
+			v.errorf(site.Name, duplicateVirtualNetworkSiteError, site.Name)
+		}
+		names[site.Name] = true
+
+		if (len(site.Location) == 0) == (len(site.AffinityGroup) == 0) {
+			v.errorf(site.Name, locationAffinityGroupError, site.Name)
+		}
+
+		addressSpaces[i] = v.addressSpace(site.Name, site.AddressSpace)
+		v.validateSubnets(site, addressSpaces[i])
+
+		for _, ref := range site.DnsServersRef {
+			if !dnsServers[ref.Name] {
+				v.errorf(site.Name, unknownDnsServerRefError, site.Name, ref.Name)
+			}
+		}
+
+		if site.Gateway != nil {
+			for _, ref := range site.Gateway.ConnectionsToLocalNetwork {
+				localAddressSpace, ok := localAddressSpaces[ref.Name]
+				if !ok {
+					v.errorf(site.Name, unknownLocalNetworkSiteRefError, site.Name, ref.Name)
+					continue
+				}
+
+				for _, ipNet := range addressSpaces[i] {
+					for _, localNet := range localAddressSpace {
+						if overlapsNet(ipNet, localNet) {
+							v.errorf(site.Name, localNetworkSiteOverlapError, ipNet, site.Name, localNet, ref.Name)
+						}
+					}
+				}
+			}
+
+			v.validateVPNClientAddressPool(site, addressSpaces[i])
+		}
+
+		for j, other := range configuration.VirtualNetworkSites[:i] {
+			for _, ipNet := range addressSpaces[i] {
+				for _, otherNet := range addressSpaces[j] {
+					if overlapsNet(ipNet, otherNet) {
+						v.warningf(site.Name, virtualNetworkSitesOverlapWarning, ipNet, site.Name, otherNet, other.Name)
+					}
+				}
+			}
+		}
+	}
+}
+
+//addressSpace parses the address prefixes of a site and reports the ones
+//which are invalid or overlap each other. Only the valid prefixes are
+//returned.
+func (v *validator) addressSpace(siteName string, addressSpace AddressSpace) []*net.IPNet {
+	if len(addressSpace.AddressPrefix) == 0 {
+		v.errorf(siteName, addressSpaceNotSpecifiedError, siteName)
+	}
+
+	ipNets := []*net.IPNet{}
+	for _, prefix := range addressSpace.AddressPrefix {
+		_, ipNet, err := net.ParseCIDR(prefix)
+		if err != nil {
+			v.errorf(siteName, invalidAddressPrefixError, prefix, siteName, err)
+			continue
+		}
+
+		for _, other := range ipNets {
+			if overlapsNet(other, ipNet) {
+				v.errorf(siteName, addressSpacesOverlapError, other, ipNet, siteName)
+			}
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+
+	return ipNets
+}
+
+//validateSubnets checks that every subnet of the site has a valid address
+//prefix within the address space of the site and that no two subnets overlap.
+func (v *validator) validateSubnets(site VirtualNetworkSite, addressSpace []*net.IPNet) {
+	subnetNets := make([]*net.IPNet, len(site.Subnets))
+	for i, subnet := range site.Subnets {
+		if len(subnet.Name) == 0 {
+			v.errorf(site.Name, paramNotSpecifiedError, "Subnet.Name")
+		}
+		for _, other := range site.Subnets[:i] {
+			if len(subnet.Name) > 0 && other.Name == subnet.Name {
+				v.errorf(site.Name, duplicateSubnetError, subnet.Name, site.Name)
+			}
+		}
+
+		_, subnetNet, err := net.ParseCIDR(subnet.AddressPrefix)
+		if err != nil {
+			v.errorf(site.Name, invalidAddressPrefixError, subnet.AddressPrefix, site.Name, err)
+			continue
+		}
+		subnetNets[i] = subnetNet
+
+		withinAddressSpace := false
+		for _, ipNet := range addressSpace {
+			if containsNet(ipNet, subnetNet) {
+				withinAddressSpace = true
+				break
+			}
+		}
+		if !withinAddressSpace {
+			v.errorf(site.Name, subnetOutsideAddressSpaceError, subnet.Name, subnet.AddressPrefix, site.Name)
+		}
+
+		for j, other := range site.Subnets[:i] {
+			if subnetNets[j] != nil && overlapsNet(subnetNets[j], subnetNet) {
+				v.errorf(site.Name, subnetsOverlapError, other.Name, other.AddressPrefix, subnet.Name, subnet.AddressPrefix, site.Name)
+			}
+		}
+	}
+}
+
+func (v *validator) validateVPNClientAddressPool(site VirtualNetworkSite, addressSpace []*net.IPNet) {
+	if site.Gateway.VPNClientAddressPool == nil {
+		return
+	}
+
+	for _, prefix := range site.Gateway.VPNClientAddressPool.AddressPrefix {
+		_, poolNet, err := net.ParseCIDR(prefix)
+		if err != nil {
+			v.errorf(site.Name, invalidAddressPrefixError, prefix, site.Name, err)
+			continue
+		}
+
+		for _, ipNet := range addressSpace {
+			if overlapsNet(ipNet, poolNet) {
+				v.errorf(site.Name, vpnClientAddressPoolOverlapError, prefix, site.Name)
+			}
+		}
+	}
+}
+
+//containsNet reports whether inner lies completely within outer.
+func containsNet(outer, inner *net.IPNet) bool {
+	outerOnes, outerBits := outer.Mask.Size()
+	innerOnes, innerBits := inner.Mask.Size()
+	return outerBits == innerBits && outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
+
+//overlapsNet reports whether the two networks share any address. Since CIDR
+//blocks are either nested or disjoint, it is enough to check the first
+//address of each.
+func overlapsNet(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+//Region private methods ends
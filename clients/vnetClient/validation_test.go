@@ -0,0 +1,180 @@
+package vnetClient
+
+import (
+	"net"
+	"testing"
+)
+
+func parseNet(t *testing.T, cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ipNet
+}
+
+func Test_containsNet(t *testing.T) {
+	type test struct {
+		outer, inner string
+		expected     bool
+	}
+
+	tests := []test{
+		{"10.0.0.0/16", "10.0.1.0/24", true},
+		{"10.0.0.0/16", "10.0.0.0/16", true},
+		{"10.0.1.0/24", "10.0.0.0/16", false},
+		{"10.0.0.0/16", "10.1.0.0/24", false},
+		{"10.0.0.0/16", "::/64", false},
+	}
+
+	for _, i := range tests {
+		if out := containsNet(parseNet(t, i.outer), parseNet(t, i.inner)); out != i.expected {
+			t.Fatalf("Wrong result for containsNet(%s, %s). Expected: '%v', got: '%v'", i.outer, i.inner, i.expected, out)
+		}
+	}
+}
+
+func Test_overlapsNet(t *testing.T) {
+	type test struct {
+		a, b     string
+		expected bool
+	}
+
+	tests := []test{
+		{"10.0.0.0/16", "10.0.1.0/24", true},
+		{"10.0.1.0/24", "10.0.0.0/16", true},
+		{"10.0.0.0/24", "10.0.1.0/24", false},
+		{"10.0.0.0/8", "192.168.0.0/16", false},
+	}
+
+	for _, i := range tests {
+		if out := overlapsNet(parseNet(t, i.a), parseNet(t, i.b)); out != i.expected {
+			t.Fatalf("Wrong result for overlapsNet(%s, %s). Expected: '%v', got: '%v'", i.a, i.b, i.expected, out)
+		}
+	}
+}
+
+func newTestVirtualNetworkSite(name string, addressPrefixes ...string) VirtualNetworkSite {
+	return VirtualNetworkSite{
+		Name:         name,
+		Location:     "West US",
+		AddressSpace: AddressSpace{AddressPrefix: addressPrefixes},
+	}
+}
+
+func TestValidateNetworkConfiguration(t *testing.T) {
+	type test struct {
+		name     string
+		change   func(*VirtualNetworkConfiguration)
+		expected []ValidationFinding
+	}
+
+	tests := []test{
+		{"valid", func(c *VirtualNetworkConfiguration) {}, nil},
+		{"invalid address prefix", func(c *VirtualNetworkConfiguration) {
+			c.VirtualNetworkSites[0].AddressSpace.AddressPrefix = []string{"10.0.0.0/33"}
+			c.VirtualNetworkSites[0].Subnets = nil
+		}, []ValidationFinding{
+			{FindingSeverityError, "vnet1", "Address prefix 10.0.0.0/33 of network site vnet1 is not a valid CIDR: invalid CIDR address: 10.0.0.0/33"},
+		}},
+		{"duplicate site", func(c *VirtualNetworkConfiguration) {
+			c.VirtualNetworkSites = append(c.VirtualNetworkSites, newTestVirtualNetworkSite("vnet1", "10.2.0.0/16"))
+		}, []ValidationFinding{
+			{FindingSeverityError, "vnet1", "Virtual network site vnet1 is defined more than once."},
+		}},
+		{"subnet outside address space", func(c *VirtualNetworkConfiguration) {
+			c.VirtualNetworkSites[0].Subnets[0].AddressPrefix = "10.5.0.0/24"
+		}, []ValidationFinding{
+			{FindingSeverityError, "vnet1", "Subnet Subnet-1 (10.5.0.0/24) is not within the address space of virtual network site vnet1."},
+		}},
+		{"overlapping sites", func(c *VirtualNetworkConfiguration) {
+			c.VirtualNetworkSites[1].AddressSpace.AddressPrefix = []string{"10.0.0.0/8"}
+		}, []ValidationFinding{
+			{FindingSeverityWarning, "vnet2", "Address prefix 10.0.0.0/8 of virtual network site vnet2 overlaps address prefix 10.0.0.0/16 of virtual network site vnet1, the sites cannot be connected."},
+		}},
+		{"invalid VPN gateway address", func(c *VirtualNetworkConfiguration) {
+			c.LocalNetworkSites[0].VPNGatewayAddress = "gateway"
+		}, []ValidationFinding{
+			{FindingSeverityError, "local1", "VPN gateway address gateway of local network site local1 is not a valid IP address."},
+		}},
+	}
+
+	for _, i := range tests {
+		networkConfiguration := newTestNetworkConfiguration()
+		i.change(&networkConfiguration.Configuration)
+
+		findings := ValidateNetworkConfiguration(networkConfiguration)
+		if len(findings) != len(i.expected) {
+			t.Fatalf("Wrong findings for %s. Expected: '%v', got: '%v'", i.name, i.expected, findings)
+		}
+		for j := range findings {
+			if findings[j] != i.expected[j] {
+				t.Fatalf("Wrong finding for %s. Expected: '%v', got: '%v'", i.name, i.expected[j], findings[j])
+			}
+		}
+	}
+}
+
+func Test_validateNetworkConfigurationChange(t *testing.T) {
+	broken := newTestNetworkConfiguration()
+	broken.Configuration.LocalNetworkSites[0].VPNGatewayAddress = "gateway"
+
+	type test struct {
+		name          string
+		current       NetworkConfiguration
+		change        func(*VirtualNetworkConfiguration)
+		expectedError bool
+	}
+
+	tests := []test{
+		{"valid change", newTestNetworkConfiguration(), func(c *VirtualNetworkConfiguration) {
+			c.VirtualNetworkSites = append(c.VirtualNetworkSites, newTestVirtualNetworkSite("vnet3", "10.3.0.0/16"))
+		}, false},
+		{"new error", newTestNetworkConfiguration(), func(c *VirtualNetworkConfiguration) {
+			c.VirtualNetworkSites = append(c.VirtualNetworkSites, newTestVirtualNetworkSite("vnet3", "10.3.0.0/33"))
+		}, true},
+		{"existing error in untouched site", broken, func(c *VirtualNetworkConfiguration) {
+			c.VirtualNetworkSites = append(c.VirtualNetworkSites, newTestVirtualNetworkSite("vnet3", "10.3.0.0/16"))
+		}, false},
+		{"existing and new error", broken, func(c *VirtualNetworkConfiguration) {
+			c.VirtualNetworkSites = append(c.VirtualNetworkSites, newTestVirtualNetworkSite("vnet3"))
+		}, true},
+	}
+
+	for _, i := range tests {
+		changed := i.current.Clone()
+		i.change(&changed.Configuration)
+
+		err := validateNetworkConfigurationChange(i.current, changed)
+		if (err != nil) != i.expectedError {
+			t.Fatalf("Wrong result for %s. Expected error: '%v', got: '%v'", i.name, i.expectedError, err)
+		}
+		if err == nil {
+			continue
+		}
+
+		for _, finding := range err.(*ValidationError).Findings {
+			if finding.Site == "local1" && finding.Severity != FindingSeverityWarning {
+				t.Fatalf("Wrong severity for %s. Expected: '%s', got: '%s'", finding.Message, FindingSeverityWarning, finding.Severity)
+			}
+		}
+	}
+}
+
+func newTestNetworkConfiguration() NetworkConfiguration {
+	vnet1 := newTestVirtualNetworkSite("vnet1", "10.0.0.0/16")
+	vnet1.Subnets = []Subnet{{Name: "Subnet-1", AddressPrefix: "10.0.1.0/24"}}
+
+	networkConfiguration := NewNetworkConfiguration()
+	networkConfiguration.Configuration = VirtualNetworkConfiguration{
+		LocalNetworkSites: []LocalNetworkSite{{
+			Name:              "local1",
+			AddressSpace:      AddressSpace{AddressPrefix: []string{"192.168.0.0/16"}},
+			VPNGatewayAddress: "192.0.2.1",
+		}},
+		VirtualNetworkSites: []VirtualNetworkSite{vnet1, newTestVirtualNetworkSite("vnet2", "10.1.0.0/16")},
+	}
+
+	return networkConfiguration
+}
@@ -1,14 +1,58 @@
 package vnetClient
 
 import (
+	"bytes"
 	"encoding/xml"
+	"errors"
+	"fmt"
+
 	azure "github.com/MSOpenTech/azure-sdk-for-go"
 )
 
 const (
 	azureNetworkConfigurationURL = "services/networking/media"
+	azureVirtualNetworkSitesURL  = "services/networking/virtualnetwork"
+
+	notFoundStatusCode = 404
+
+	paramNotSpecifiedError            = "Parameter %s is not specified."
+	networkConfigurationChangedError  = "The network configuration was changed by another operation, retry the update."
+	virtualNetworkSiteExistsError     = "Virtual network site %s already exists."
+	virtualNetworkSiteNotFoundError   = "Virtual network site %s does not exist."
+	duplicateVirtualNetworkSiteError  = "Virtual network site %s is defined more than once."
+	locationAffinityGroupError        = "Exactly one of location and affinity group has to be specified for virtual network site %s."
+	addressSpaceNotSpecifiedError     = "Network site %s has no address space."
+	invalidAddressPrefixError         = "Address prefix %s of network site %s is not a valid CIDR: %s"
+	subnetExistsError                 = "Subnet %s already exists in virtual network site %s."
+	subnetNotFoundError               = "Subnet %s does not exist in virtual network site %s."
+	duplicateSubnetError              = "Subnet %s is defined more than once in virtual network site %s."
+	subnetOutsideAddressSpaceError    = "Subnet %s (%s) is not within the address space of virtual network site %s."
+	subnetsOverlapError               = "Subnets %s (%s) and %s (%s) of virtual network site %s overlap."
+	dnsServerExistsError              = "DNS server %s already exists."
+	dnsServerNotFoundError            = "DNS server %s does not exist."
+	duplicateDnsServerError           = "DNS server %s is defined more than once."
+	invalidDnsServerAddressError      = "IP address %s of DNS server %s is not valid."
+	dnsServerInUseError               = "DNS server %s is still referenced by virtual network site %s."
+	dnsServerRefExistsError           = "Virtual network site %s already references DNS server %s."
+	dnsServerRefNotFoundError         = "Virtual network site %s does not reference DNS server %s."
+	unknownDnsServerRefError          = "Virtual network site %s references DNS server %s which does not exist."
+	localNetworkSiteExistsError       = "Local network site %s already exists."
+	localNetworkSiteNotFoundError     = "Local network site %s does not exist."
+	duplicateLocalNetworkSiteError    = "Local network site %s is defined more than once."
+	invalidVPNGatewayAddressError     = "VPN gateway address %s of local network site %s is not a valid IP address."
+	localNetworkSiteInUseError        = "Local network site %s is still connected to virtual network site %s."
+	localNetworkSiteConnectedError    = "Virtual network site %s is already connected to local network site %s."
+	localNetworkSiteNotConnectedError = "Virtual network site %s is not connected to local network site %s."
+	unknownLocalNetworkSiteRefError   = "Virtual network site %s is connected to local network site %s which does not exist."
+	vpnClientAddressPoolOverlapError  = "VPN client address pool %s of virtual network site %s overlaps its address space."
 )
 
+//ErrNetworkConfigurationChanged is returned by the virtual network site
+//helpers when the network configuration of the subscription was changed
+//between reading it and writing back the merged configuration. Nothing is
+//written in that case, so the operation can simply be retried.
+var ErrNetworkConfigurationChanged = errors.New(networkConfigurationChangedError)
+
 //GetVirtualNetworkConfiguration retreives the current virtual network
 //configuration for the currently active subscription. Note that the
 //underlying Azure API means that network related operations are not safe
@@ -30,9 +74,502 @@ func GetVirtualNetworkConfiguration() (NetworkConfiguration, error) {
 
 //SetVirtualNetworkConfiguration configures the virtual networks for the
 //currently active subscription according to the NetworkConfiguration given.
+//The configuration is checked with ValidateNetworkConfiguration first and a
+//*ValidationError is returned if it has errors. Errors the current
+//configuration of the subscription already has are reported as warnings
+//only, so that a configuration with a broken site can still be changed,
+//including to fix the site.
 //Note that the underlying Azure API means that network related operations
 //are not safe for running concurrently.
 func SetVirtualNetworkConfiguration(networkConfiguration NetworkConfiguration) error {
+	currentConfiguration, _, err := getNetworkConfigurationForUpdate()
+	if err != nil {
+		return err
+	}
+
+	return setNetworkConfiguration(networkConfiguration, currentConfiguration)
+}
+
+//GetVirtualNetworkSites lists the virtual network sites of the currently
+//active subscription along with their runtime state, which is not part of the
+//network configuration returned by GetVirtualNetworkConfiguration.
+func GetVirtualNetworkSites() ([]VirtualNetworkSiteInfo, error) {
+	response, err := azure.SendAzureGetRequest(azureVirtualNetworkSitesURL)
+	if err != nil {
+		return nil, err
+	}
+
+	siteList := new(VirtualNetworkSiteInfoList)
+	err = xml.Unmarshal(response, siteList)
+	if err != nil {
+		return nil, err
+	}
+
+	return siteList.VirtualNetworkSites, nil
+}
+
+//GetVirtualNetworkSite returns the virtual network site with the given name
+//from the network configuration of the currently active subscription.
+func GetVirtualNetworkSite(name string) (*VirtualNetworkSite, error) {
+	if len(name) == 0 {
+		return nil, fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	networkConfiguration, _, err := getNetworkConfigurationForUpdate()
+	if err != nil {
+		return nil, err
+	}
+
+	index := findVirtualNetworkSite(networkConfiguration, name)
+	if index < 0 {
+		return nil, fmt.Errorf(virtualNetworkSiteNotFoundError, name)
+	}
+
+	return &networkConfiguration.Configuration.VirtualNetworkSites[index], nil
+}
+
+//AddVirtualNetworkSite adds a virtual network site to the network
+//configuration of the currently active subscription, leaving the rest of the
+//configuration untouched. Unlike SetVirtualNetworkConfiguration it reads the
+//current configuration first and fails with ErrNetworkConfigurationChanged if
+//the configuration changes before the merged one is written back.
+func AddVirtualNetworkSite(site VirtualNetworkSite) error {
+	if len(site.Name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "site.Name")
+	}
+
+	return updateNetworkConfiguration(func(networkConfiguration *NetworkConfiguration) error {
+		if findVirtualNetworkSite(*networkConfiguration, site.Name) >= 0 {
+			return fmt.Errorf(virtualNetworkSiteExistsError, site.Name)
+		}
+
+		sites := &networkConfiguration.Configuration.VirtualNetworkSites
+		*sites = append(*sites, site)
+		return nil
+	})
+}
+
+//UpdateVirtualNetworkSite replaces the virtual network site with the same
+//name in the network configuration of the currently active subscription.
+//See AddVirtualNetworkSite for how concurrent changes are detected.
+func UpdateVirtualNetworkSite(site VirtualNetworkSite) error {
+	if len(site.Name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "site.Name")
+	}
+
+	return updateNetworkConfiguration(func(networkConfiguration *NetworkConfiguration) error {
+		index := findVirtualNetworkSite(*networkConfiguration, site.Name)
+		if index < 0 {
+			return fmt.Errorf(virtualNetworkSiteNotFoundError, site.Name)
+		}
+
+		networkConfiguration.Configuration.VirtualNetworkSites[index] = site
+		return nil
+	})
+}
+
+//EnsureVirtualNetworkSite adds the virtual network site to the network
+//configuration of the currently active subscription, or replaces the site
+//with the same name if it differs from the given one. Unknown attributes and
+//elements of an existing site are kept unless the given site has its own.
+//The returned bool reports whether the configuration was changed. See
+//AddVirtualNetworkSite for how concurrent changes are detected.
+func EnsureVirtualNetworkSite(site VirtualNetworkSite) (bool, error) {
+	if len(site.Name) == 0 {
+		return false, fmt.Errorf(paramNotSpecifiedError, "site.Name")
+	}
+
+	networkConfiguration, _, err := getNetworkConfigurationForUpdate()
+	if err != nil {
+		return false, err
+	}
+
+	index := findVirtualNetworkSite(networkConfiguration, site.Name)
+	if index >= 0 {
+		existing := networkConfiguration.Configuration.VirtualNetworkSites[index]
+		site = mergeUnknownSiteContent(site, existing)
+
+		equal, err := equalVirtualNetworkSites(site, existing)
+		if err != nil || equal {
+			return false, err
+		}
+	}
+
+	err = updateNetworkConfiguration(func(networkConfiguration *NetworkConfiguration) error {
+		sites := &networkConfiguration.Configuration.VirtualNetworkSites
+
+		index := findVirtualNetworkSite(*networkConfiguration, site.Name)
+		if index < 0 {
+			*sites = append(*sites, site)
+		} else {
+			(*sites)[index] = site
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+//RemoveVirtualNetworkSite removes the virtual network site with the given
+//name from the network configuration of the currently active subscription.
+//See AddVirtualNetworkSite for how concurrent changes are detected.
+func RemoveVirtualNetworkSite(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	return updateNetworkConfiguration(func(networkConfiguration *NetworkConfiguration) error {
+		index := findVirtualNetworkSite(*networkConfiguration, name)
+		if index < 0 {
+			return fmt.Errorf(virtualNetworkSiteNotFoundError, name)
+		}
+
+		sites := &networkConfiguration.Configuration.VirtualNetworkSites
+		*sites = append((*sites)[:index], (*sites)[index+1:]...)
+		return nil
+	})
+}
+
+//AddSubnet adds a subnet with the given address prefix to an existing virtual
+//network site. The prefix has to be within the address space of the site and
+//must not overlap any of its other subnets. See AddVirtualNetworkSite for how
+//concurrent changes are detected.
+func AddSubnet(siteName, subnetName, addressPrefix string) error {
+	if len(siteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "siteName")
+	}
+	if len(subnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "subnetName")
+	}
+	if len(addressPrefix) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "addressPrefix")
+	}
+
+	return updateVirtualNetworkSite(siteName, func(site *VirtualNetworkSite) error {
+		if findSubnet(*site, subnetName) >= 0 {
+			return fmt.Errorf(subnetExistsError, subnetName, siteName)
+		}
+
+		site.Subnets = append(site.Subnets, Subnet{Name: subnetName, AddressPrefix: addressPrefix})
+		return nil
+	})
+}
+
+//ResizeSubnet changes the address prefix of a subnet of an existing virtual
+//network site. The same restrictions as for AddSubnet apply.
+func ResizeSubnet(siteName, subnetName, addressPrefix string) error {
+	if len(siteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "siteName")
+	}
+	if len(subnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "subnetName")
+	}
+	if len(addressPrefix) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "addressPrefix")
+	}
+
+	return updateVirtualNetworkSite(siteName, func(site *VirtualNetworkSite) error {
+		index := findSubnet(*site, subnetName)
+		if index < 0 {
+			return fmt.Errorf(subnetNotFoundError, subnetName, siteName)
+		}
+
+		site.Subnets[index].AddressPrefix = addressPrefix
+		return nil
+	})
+}
+
+//RemoveSubnet removes a subnet from an existing virtual network site. Azure
+//refuses to remove subnets which are still in use by virtual machines.
+func RemoveSubnet(siteName, subnetName string) error {
+	if len(siteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "siteName")
+	}
+	if len(subnetName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "subnetName")
+	}
+
+	return updateVirtualNetworkSite(siteName, func(site *VirtualNetworkSite) error {
+		index := findSubnet(*site, subnetName)
+		if index < 0 {
+			return fmt.Errorf(subnetNotFoundError, subnetName, siteName)
+		}
+
+		site.Subnets = append(site.Subnets[:index], site.Subnets[index+1:]...)
+		return nil
+	})
+}
+
+//AddDnsServer registers a DNS server in the network configuration of the
+//currently active subscription so that virtual network sites can reference it
+//with AddDnsServerReference. See AddVirtualNetworkSite for how concurrent
+//changes are detected.
+func AddDnsServer(name, ipAddress string) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+	if len(ipAddress) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "ipAddress")
+	}
+
+	return updateNetworkConfiguration(func(networkConfiguration *NetworkConfiguration) error {
+		if findDnsServer(*networkConfiguration, name) >= 0 {
+			return fmt.Errorf(dnsServerExistsError, name)
+		}
+
+		dns := &networkConfiguration.Configuration.Dns
+		dns.DnsServers = append(dns.DnsServers, DnsServer{Name: name, IPAddress: ipAddress})
+		return nil
+	})
+}
+
+//RemoveDnsServer removes a DNS server from the network configuration of the
+//currently active subscription. DNS servers which are still referenced by a
+//virtual network site cannot be removed.
+func RemoveDnsServer(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	return updateNetworkConfiguration(func(networkConfiguration *NetworkConfiguration) error {
+		index := findDnsServer(*networkConfiguration, name)
+		if index < 0 {
+			return fmt.Errorf(dnsServerNotFoundError, name)
+		}
+
+		for _, site := range networkConfiguration.Configuration.VirtualNetworkSites {
+			if findDnsServerRef(site, name) >= 0 {
+				return fmt.Errorf(dnsServerInUseError, name, site.Name)
+			}
+		}
+
+		dns := &networkConfiguration.Configuration.Dns
+		dns.DnsServers = append(dns.DnsServers[:index], dns.DnsServers[index+1:]...)
+		return nil
+	})
+}
+
+//AddDnsServerReference makes the virtual network site use a DNS server
+//registered with AddDnsServer.
+func AddDnsServerReference(siteName, dnsServerName string) error {
+	if len(siteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "siteName")
+	}
+	if len(dnsServerName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "dnsServerName")
+	}
+
+	return updateVirtualNetworkSite(siteName, func(site *VirtualNetworkSite) error {
+		if findDnsServerRef(*site, dnsServerName) >= 0 {
+			return fmt.Errorf(dnsServerRefExistsError, siteName, dnsServerName)
+		}
+
+		site.DnsServersRef = append(site.DnsServersRef, DnsServerRef{Name: dnsServerName})
+		return nil
+	})
+}
+
+//RemoveDnsServerReference stops the virtual network site from using the DNS
+//server. The DNS server itself stays registered.
+func RemoveDnsServerReference(siteName, dnsServerName string) error {
+	if len(siteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "siteName")
+	}
+	if len(dnsServerName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "dnsServerName")
+	}
+
+	return updateVirtualNetworkSite(siteName, func(site *VirtualNetworkSite) error {
+		index := findDnsServerRef(*site, dnsServerName)
+		if index < 0 {
+			return fmt.Errorf(dnsServerRefNotFoundError, siteName, dnsServerName)
+		}
+
+		site.DnsServersRef = append(site.DnsServersRef[:index], site.DnsServersRef[index+1:]...)
+		return nil
+	})
+}
+
+//AddLocalNetworkSite adds a local (on-premises) network site, described by the
+//public address of its VPN device and its address prefixes, to the network
+//configuration of the currently active subscription. See
+//AddVirtualNetworkSite for how concurrent changes are detected.
+func AddLocalNetworkSite(site LocalNetworkSite) error {
+	if len(site.Name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "site.Name")
+	}
+
+	return updateNetworkConfiguration(func(networkConfiguration *NetworkConfiguration) error {
+		if findLocalNetworkSite(*networkConfiguration, site.Name) >= 0 {
+			return fmt.Errorf(localNetworkSiteExistsError, site.Name)
+		}
+
+		sites := &networkConfiguration.Configuration.LocalNetworkSites
+		*sites = append(*sites, site)
+		return nil
+	})
+}
+
+//UpdateLocalNetworkSite replaces the local network site with the same name,
+//e.g. after the address of the VPN device or the on-premises address prefixes
+//changed.
+func UpdateLocalNetworkSite(site LocalNetworkSite) error {
+	if len(site.Name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "site.Name")
+	}
+
+	return updateNetworkConfiguration(func(networkConfiguration *NetworkConfiguration) error {
+		index := findLocalNetworkSite(*networkConfiguration, site.Name)
+		if index < 0 {
+			return fmt.Errorf(localNetworkSiteNotFoundError, site.Name)
+		}
+
+		networkConfiguration.Configuration.LocalNetworkSites[index] = site
+		return nil
+	})
+}
+
+//RemoveLocalNetworkSite removes a local network site. Local network sites which
+//are still connected to a virtual network site cannot be removed.
+func RemoveLocalNetworkSite(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "name")
+	}
+
+	return updateNetworkConfiguration(func(networkConfiguration *NetworkConfiguration) error {
+		index := findLocalNetworkSite(*networkConfiguration, name)
+		if index < 0 {
+			return fmt.Errorf(localNetworkSiteNotFoundError, name)
+		}
+
+		for _, site := range networkConfiguration.Configuration.VirtualNetworkSites {
+			if findLocalNetworkSiteRef(site, name) >= 0 {
+				return fmt.Errorf(localNetworkSiteInUseError, name, site.Name)
+			}
+		}
+
+		sites := &networkConfiguration.Configuration.LocalNetworkSites
+		*sites = append((*sites)[:index], (*sites)[index+1:]...)
+		return nil
+	})
+}
+
+//ConnectLocalNetworkSite adds the local network site to the connections of the
+//gateway of the virtual network site, using an IPsec connection. This only
+//changes the network configuration, the gateway itself has to be created
+//separately for the site-to-site VPN to be established.
+func ConnectLocalNetworkSite(siteName, localNetworkSiteName string) error {
+	if len(siteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "siteName")
+	}
+	if len(localNetworkSiteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "localNetworkSiteName")
+	}
+
+	return updateVirtualNetworkSite(siteName, func(site *VirtualNetworkSite) error {
+		if findLocalNetworkSiteRef(*site, localNetworkSiteName) >= 0 {
+			return fmt.Errorf(localNetworkSiteConnectedError, siteName, localNetworkSiteName)
+		}
+
+		if site.Gateway == nil {
+			site.Gateway = &Gateway{}
+		}
+		site.Gateway.ConnectionsToLocalNetwork = append(site.Gateway.ConnectionsToLocalNetwork, LocalNetworkSiteRef{
+			Name:       localNetworkSiteName,
+			Connection: &Connection{Type: ConnectionTypeIPsec},
+		})
+		return nil
+	})
+}
+
+//DisconnectLocalNetworkSite removes the local network site from the
+//connections of the gateway of the virtual network site.
+func DisconnectLocalNetworkSite(siteName, localNetworkSiteName string) error {
+	if len(siteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "siteName")
+	}
+	if len(localNetworkSiteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "localNetworkSiteName")
+	}
+
+	return updateVirtualNetworkSite(siteName, func(site *VirtualNetworkSite) error {
+		index := findLocalNetworkSiteRef(*site, localNetworkSiteName)
+		if index < 0 {
+			return fmt.Errorf(localNetworkSiteNotConnectedError, siteName, localNetworkSiteName)
+		}
+
+		connections := &site.Gateway.ConnectionsToLocalNetwork
+		*connections = append((*connections)[:index], (*connections)[index+1:]...)
+		return nil
+	})
+}
+
+//SetVPNClientAddressPool sets the address prefixes point-to-site VPN clients
+//of the virtual network site get their addresses from. The prefixes must not
+//overlap the address space of the site. Passing no prefixes removes the pool
+//and disables point-to-site connectivity.
+func SetVPNClientAddressPool(siteName string, addressPrefixes []string) error {
+	if len(siteName) == 0 {
+		return fmt.Errorf(paramNotSpecifiedError, "siteName")
+	}
+
+	return updateVirtualNetworkSite(siteName, func(site *VirtualNetworkSite) error {
+		if len(addressPrefixes) == 0 {
+			if site.Gateway != nil {
+				site.Gateway.VPNClientAddressPool = nil
+			}
+			return nil
+		}
+
+		if site.Gateway == nil {
+			site.Gateway = &Gateway{}
+		}
+		site.Gateway.VPNClientAddressPool = &AddressSpace{AddressPrefix: addressPrefixes}
+		return nil
+	})
+}
+
+//Region private methods starts
+
+//updateNetworkConfiguration applies change to the current network
+//configuration, validates the result and writes it back, unless the
+//configuration was changed by someone else in the meantime.
+func updateNetworkConfiguration(change func(*NetworkConfiguration) error) error {
+	networkConfiguration, original, err := getNetworkConfigurationForUpdate()
+	if err != nil {
+		return err
+	}
+	currentConfiguration := networkConfiguration.Clone()
+
+	err = change(&networkConfiguration)
+	if err != nil {
+		return err
+	}
+
+	// The network configuration does not support conditional updates, so
+	// compare it with what the change was based on right before writing.
+	_, current, err := getNetworkConfigurationForUpdate()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(original, current) {
+		return ErrNetworkConfigurationChanged
+	}
+
+	return setNetworkConfiguration(networkConfiguration, currentConfiguration)
+}
+
+//setNetworkConfiguration validates the change from currentConfiguration to
+//networkConfiguration and writes networkConfiguration.
+func setNetworkConfiguration(networkConfiguration, currentConfiguration NetworkConfiguration) error {
+	err := validateNetworkConfigurationChange(currentConfiguration, networkConfiguration)
+	if err != nil {
+		return err
+	}
+
 	networkConfiguration.setXmlNamespaces()
 	networkConfigurationBytes, err := xml.Marshal(networkConfiguration)
 	if err != nil {
@@ -44,6 +581,134 @@ func SetVirtualNetworkConfiguration(networkConfiguration NetworkConfiguration) e
 		return err
 	}
 
-	err = azure.WaitAsyncOperation(requestId)
-	return err
+	return azure.WaitAsyncOperation(requestId)
+}
+
+//updateVirtualNetworkSite applies change to the virtual network site with the
+//given name, see updateNetworkConfiguration.
+func updateVirtualNetworkSite(siteName string, change func(*VirtualNetworkSite) error) error {
+	return updateNetworkConfiguration(func(networkConfiguration *NetworkConfiguration) error {
+		index := findVirtualNetworkSite(*networkConfiguration, siteName)
+		if index < 0 {
+			return fmt.Errorf(virtualNetworkSiteNotFoundError, siteName)
+		}
+
+		return change(&networkConfiguration.Configuration.VirtualNetworkSites[index])
+	})
 }
+
+//mergeUnknownSiteContent copies the unknown attributes and elements of the
+//existing site to site where site has none of its own.
+func mergeUnknownSiteContent(site, existing VirtualNetworkSite) VirtualNetworkSite {
+	if len(site.UnknownAttrs) == 0 {
+		site.UnknownAttrs = existing.UnknownAttrs
+	}
+	if len(site.UnknownElements) == 0 {
+		site.UnknownElements = existing.UnknownElements
+	}
+
+	return site
+}
+
+//equalVirtualNetworkSites compares two sites by the XML they are written to
+//the network configuration as.
+func equalVirtualNetworkSites(site, other VirtualNetworkSite) (bool, error) {
+	siteBytes, err := xml.Marshal(site)
+	if err != nil {
+		return false, err
+	}
+
+	otherBytes, err := xml.Marshal(other)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(siteBytes, otherBytes), nil
+}
+
+//getNetworkConfigurationForUpdate returns the current network configuration
+//along with the raw response it was read from. A subscription without network
+//configuration yields an empty configuration.
+func getNetworkConfigurationForUpdate() (NetworkConfiguration, []byte, error) {
+	networkConfiguration := NewNetworkConfiguration()
+	response, err := azure.SendAzureGetRequest(azureNetworkConfigurationURL)
+	if err != nil {
+		if azureErr, ok := err.(*azure.AzureError); ok && azureErr.StatusCode == notFoundStatusCode {
+			return networkConfiguration, nil, nil
+		}
+		return networkConfiguration, nil, err
+	}
+
+	err = xml.Unmarshal(response, &networkConfiguration)
+	if err != nil {
+		return networkConfiguration, nil, err
+	}
+
+	return networkConfiguration, response, nil
+}
+
+func findVirtualNetworkSite(networkConfiguration NetworkConfiguration, name string) int {
+	for i, site := range networkConfiguration.Configuration.VirtualNetworkSites {
+		if site.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func findSubnet(site VirtualNetworkSite, name string) int {
+	for i, subnet := range site.Subnets {
+		if subnet.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func findDnsServer(networkConfiguration NetworkConfiguration, name string) int {
+	for i, dnsServer := range networkConfiguration.Configuration.Dns.DnsServers {
+		if dnsServer.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func findDnsServerRef(site VirtualNetworkSite, name string) int {
+	for i, ref := range site.DnsServersRef {
+		if ref.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func findLocalNetworkSite(networkConfiguration NetworkConfiguration, name string) int {
+	for i, site := range networkConfiguration.Configuration.LocalNetworkSites {
+		if site.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func findLocalNetworkSiteRef(site VirtualNetworkSite, name string) int {
+	if site.Gateway == nil {
+		return -1
+	}
+
+	for i, ref := range site.Gateway.ConnectionsToLocalNetwork {
+		if ref.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+//Region private methods ends
@@ -9,13 +9,16 @@ import (
 	"github.com/MSOpenTech/azure-sdk-for-go/core/http"
 	"github.com/MSOpenTech/azure-sdk-for-go/core/tls"
 	"io"
+	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"time"
 )
 
 const (
 	paramNotSpecifiedError = "Parameter %s is not specified."
+	operationTimeoutError  = "Operation %s did not complete within %s."
 
 	azureManagementDnsName    = "https://management.core.windows.net"
 	msVersionHeader           = "x-ms-version"
@@ -23,6 +26,10 @@ const (
 	contentHeader             = "Content-Type"
 	defaultContentHeaderValue = "application/xml"
 	requestIdHeader           = "X-Ms-Request-Id"
+	eTagHeader                = "ETag"
+	ifMatchHeader             = "If-Match"
+
+	defaultOperationPollInterval = 2000 * time.Millisecond
 )
 
 //Region public methods starts
@@ -69,6 +76,87 @@ func SendAzurePutRequest(url string, contentType string, data []byte) (string, e
 	return requestId[0], nil
 }
 
+// SendAzurePostRequestWithVersion behaves like SendAzurePostRequest but sends
+// the request with the given x-ms-version, for payloads with elements the
+// default version of the API does not accept. An empty version sends the
+// default one.
+func SendAzurePostRequestWithVersion(url string, data []byte, version string) (string, error) {
+	if len(url) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "url")
+	}
+
+	client := createHttpClient()
+
+	response, err := sendRequest(client, url, "POST", "", data, versionHeaders(version), 7)
+	if err != nil {
+		return "", err
+	}
+
+	requestId := response.Header[requestIdHeader]
+	return requestId[0], nil
+}
+
+// SendAzureGetRequestWithETag behaves like SendAzureGetRequest and also
+// returns the ETag of the resource, for use with SendAzurePutRequestWithETag.
+func SendAzureGetRequestWithETag(url string) ([]byte, string, error) {
+	return SendAzureGetRequestWithETagAndVersion(url, "")
+}
+
+// SendAzureGetRequestWithETagAndVersion behaves like
+// SendAzureGetRequestWithETag but sends the request with the given
+// x-ms-version, so that the response includes the elements added in that
+// version. An empty version sends the default one.
+func SendAzureGetRequestWithETagAndVersion(url string, version string) ([]byte, string, error) {
+	if len(url) == 0 {
+		return nil, "", fmt.Errorf(paramNotSpecifiedError, "url")
+	}
+
+	client := createHttpClient()
+
+	response, err := sendRequest(client, url, "GET", "", nil, versionHeaders(version), 7)
+	if err != nil {
+		return nil, "", err
+	}
+
+	responseContent := getResponseBody(response)
+	return responseContent, response.Header.Get(eTagHeader), nil
+}
+
+// SendAzurePutRequestWithETag behaves like SendAzurePutRequest but only
+// applies the update when the resource still matches etag. If the resource
+// was modified in the meantime an *AzureError with StatusCode 412 is
+// returned. An empty etag sends an unconditional request.
+func SendAzurePutRequestWithETag(url string, contentType string, data []byte, etag string) (string, error) {
+	return SendAzurePutRequestWithETagAndVersion(url, contentType, data, etag, "")
+}
+
+// SendAzurePutRequestWithETagAndVersion behaves like
+// SendAzurePutRequestWithETag but sends the request with the given
+// x-ms-version. An empty version sends the default one.
+func SendAzurePutRequestWithETagAndVersion(url string, contentType string, data []byte, etag string, version string) (string, error) {
+	if len(url) == 0 {
+		return "", fmt.Errorf(paramNotSpecifiedError, "url")
+	}
+
+	headers := versionHeaders(version)
+	if len(etag) > 0 {
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		headers[ifMatchHeader] = etag
+	}
+
+	client := createHttpClient()
+
+	response, err := sendRequest(client, url, "PUT", contentType, data, headers, 7)
+	if err != nil {
+		return "", err
+	}
+
+	requestId := response.Header[requestIdHeader]
+	return requestId[0], nil
+}
+
 func SendAzureDeleteRequest(url string) (string, error) {
 	if len(url) == 0 {
 		return "", fmt.Errorf(paramNotSpecifiedError, "url")
@@ -83,6 +171,21 @@ func SendAzureDeleteRequest(url string) (string, error) {
 	return requestId[0], nil
 }
 
+// SendAzureGetSubscriptionRequest returns the properties of the subscription
+// itself, which is the only resource addressed without a path below the
+// subscription ID.
+func SendAzureGetSubscriptionRequest() ([]byte, error) {
+	client := createHttpClient()
+
+	response, err := sendRequest(client, "", "GET", "", nil, nil, 7)
+	if err != nil {
+		return nil, err
+	}
+
+	responseContent := getResponseBody(response)
+	return responseContent, nil
+}
+
 func SendAzureRequest(url string, requestType string, contentType string, data []byte) (*http.Response, error) {
 	if len(url) == 0 {
 		return nil, fmt.Errorf(paramNotSpecifiedError, "url")
@@ -93,7 +196,7 @@ func SendAzureRequest(url string, requestType string, contentType string, data [
 
 	client := createHttpClient()
 
-	response, err := sendRequest(client, url, requestType, contentType, data, 7)
+	response, err := sendRequest(client, url, requestType, contentType, data, nil, 7)
 	if err != nil {
 		return nil, err
 	}
@@ -102,6 +205,14 @@ func SendAzureRequest(url string, requestType string, contentType string, data [
 }
 
 func ExecuteCommand(command string, input []byte) ([]byte, error) {
+	return ExecuteCommandWithEnv(command, input, nil)
+}
+
+// ExecuteCommandWithEnv behaves like ExecuteCommand and adds env, a list of
+// "key=value" pairs, to the environment of the command. Secrets such as
+// passwords should be passed this way rather than as arguments, which other
+// users of the machine can read.
+func ExecuteCommandWithEnv(command string, input []byte, env []string) ([]byte, error) {
 	if len(command) == 0 {
 		return nil, fmt.Errorf(paramNotSpecifiedError, "command")
 	}
@@ -111,6 +222,9 @@ func ExecuteCommand(command string, input []byte) ([]byte, error) {
 	parts = parts[1:len(parts)]
 
 	cmd := exec.Command(head, parts...)
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	if input != nil {
 		cmd.Stdin = bytes.NewReader(input)
 	}
@@ -144,15 +258,28 @@ func GetOperationStatus(operationId string) (*Operation, error) {
 }
 
 func WaitAsyncOperation(operationId string) error {
+	return WaitAsyncOperationWithTimeout(operationId, defaultOperationPollInterval, 0)
+}
+
+// WaitAsyncOperationWithTimeout waits for the asynchronous operation like
+// WaitAsyncOperation, but polls its status every pollInterval and gives up
+// once the operation did not complete within timeout. A zero timeout waits
+// indefinitely. Giving up does not cancel the operation in Azure.
+func WaitAsyncOperationWithTimeout(operationId string, pollInterval, timeout time.Duration) error {
 	if len(operationId) == 0 {
 		return fmt.Errorf(paramNotSpecifiedError, "operationId")
 	}
 
+	deadline := time.Now().Add(timeout)
 	status := "InProgress"
 	operation := new(Operation)
 	err := errors.New("")
 	for status == "InProgress" {
-		time.Sleep(2000 * time.Millisecond)
+		if timeout > 0 && time.Now().After(deadline) {
+			return fmt.Errorf(operationTimeoutError, operationId, timeout)
+		}
+
+		time.Sleep(pollInterval)
 		operation, err = GetOperationStatus(operationId)
 		if err != nil {
 			return err
@@ -182,6 +309,18 @@ func CheckStringParams(url string) ([]byte, error) {
 	return responseContent, nil
 }
 
+// SortedKeys returns the keys of m in sorted order, so that request bodies
+// built from a map do not depend on the iteration order of the map.
+func SortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
 // NewUUID generates a random UUID according to RFC 4122
 func NewUUID() (string, error) {
 	uuid := make([]byte, 16)
@@ -202,8 +341,8 @@ func NewUUID() (string, error) {
 
 //Region private methods starts
 
-func sendRequest(client *http.Client, url string, requestType string, contentType string, data []byte, numberOfRetries int) (*http.Response, error) {
-	request, reqErr := createAzureRequest(url, requestType, contentType, data)
+func sendRequest(client *http.Client, url string, requestType string, contentType string, data []byte, headers map[string]string, numberOfRetries int) (*http.Response, error) {
+	request, reqErr := createAzureRequest(url, requestType, contentType, data, headers)
 	if reqErr != nil {
 		return nil, reqErr
 	}
@@ -214,18 +353,23 @@ func sendRequest(client *http.Client, url string, requestType string, contentTyp
 			return nil, err
 		}
 
-		return sendRequest(client, url, requestType, contentType, data, numberOfRetries-1)
+		return sendRequest(client, url, requestType, contentType, data, headers, numberOfRetries-1)
 	}
 
 	if response.StatusCode > 299 {
 		responseContent := getResponseBody(response)
 		azureErr := getAzureError(responseContent)
 		if azureErr != nil {
-			if numberOfRetries == 0 {
+			if typedErr, ok := azureErr.(*AzureError); ok {
+				typedErr.StatusCode = response.StatusCode
+			}
+
+			// A failed precondition will not succeed on retry
+			if numberOfRetries == 0 || response.StatusCode == http.StatusPreconditionFailed {
 				return nil, azureErr
 			}
 
-			return sendRequest(client, url, requestType, contentType, data, numberOfRetries-1)
+			return sendRequest(client, url, requestType, contentType, data, headers, numberOfRetries-1)
 		}
 	}
 
@@ -242,11 +386,15 @@ func getAzureError(responseBody []byte) error {
 	return error
 }
 
-func createAzureRequest(url string, requestType string, contentType string, data []byte) (*http.Request, error) {
+func createAzureRequest(url string, requestType string, contentType string, data []byte, headers map[string]string) (*http.Request, error) {
 	var request *http.Request
 	var err error
 
-	url = fmt.Sprintf("%s/%s/%s", azureManagementDnsName, GetPublishSettings().SubscriptionID, url)
+	if len(url) > 0 {
+		url = fmt.Sprintf("%s/%s/%s", azureManagementDnsName, GetPublishSettings().SubscriptionID, url)
+	} else {
+		url = fmt.Sprintf("%s/%s", azureManagementDnsName, GetPublishSettings().SubscriptionID)
+	}
 	if data != nil {
 		body := bytes.NewBuffer(data)
 		request, err = http.NewRequest(requestType, url, body)
@@ -265,9 +413,23 @@ func createAzureRequest(url string, requestType string, contentType string, data
 		request.Header.Add(contentHeader, defaultContentHeaderValue)
 	}
 
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
 	return request, nil
 }
 
+// versionHeaders returns the headers overriding the default x-ms-version with
+// version, or nil if version is empty.
+func versionHeaders(version string) map[string]string {
+	if len(version) == 0 {
+		return nil
+	}
+
+	return map[string]string{msVersionHeader: version}
+}
+
 func createHttpClient() *http.Client {
 	cert, _ := tls.X509KeyPair(GetPublishSettings().SubscriptionCert, GetPublishSettings().SubscriptionKey)
 
@@ -293,9 +455,10 @@ func getResponseBody(response *http.Response) []byte {
 //Region private methods ends
 
 type AzureError struct {
-	XMLName xml.Name `xml:"Error"`
-	Code    string
-	Message string
+	XMLName    xml.Name `xml:"Error"`
+	Code       string
+	Message    string
+	StatusCode int `xml:"-"`
 }
 
 func (e *AzureError) Error() string {
@@ -0,0 +1,56 @@
+package azureSdkForGo
+
+// Names of the role sizes of virtual machines and web and worker roles. Which
+// sizes are available depends on the subscription and location; vmClient
+// verifies sizes against the role size list of the subscription.
+const (
+	RoleSizeExtraSmall = "ExtraSmall"
+	RoleSizeSmall      = "Small"
+	RoleSizeMedium     = "Medium"
+	RoleSizeLarge      = "Large"
+	RoleSizeExtraLarge = "ExtraLarge"
+	RoleSizeA5         = "A5"
+	RoleSizeA6         = "A6"
+	RoleSizeA7         = "A7"
+	RoleSizeA8         = "A8"
+	RoleSizeA9         = "A9"
+	RoleSizeA10        = "A10"
+	RoleSizeA11        = "A11"
+
+	RoleSizeBasicA0 = "Basic_A0"
+	RoleSizeBasicA1 = "Basic_A1"
+	RoleSizeBasicA2 = "Basic_A2"
+	RoleSizeBasicA3 = "Basic_A3"
+	RoleSizeBasicA4 = "Basic_A4"
+
+	RoleSizeStandardD1  = "Standard_D1"
+	RoleSizeStandardD2  = "Standard_D2"
+	RoleSizeStandardD3  = "Standard_D3"
+	RoleSizeStandardD4  = "Standard_D4"
+	RoleSizeStandardD11 = "Standard_D11"
+	RoleSizeStandardD12 = "Standard_D12"
+	RoleSizeStandardD13 = "Standard_D13"
+	RoleSizeStandardD14 = "Standard_D14"
+
+	RoleSizeStandardDS1  = "Standard_DS1"
+	RoleSizeStandardDS2  = "Standard_DS2"
+	RoleSizeStandardDS3  = "Standard_DS3"
+	RoleSizeStandardDS4  = "Standard_DS4"
+	RoleSizeStandardDS11 = "Standard_DS11"
+	RoleSizeStandardDS12 = "Standard_DS12"
+	RoleSizeStandardDS13 = "Standard_DS13"
+	RoleSizeStandardDS14 = "Standard_DS14"
+
+	RoleSizeStandardG1 = "Standard_G1"
+	RoleSizeStandardG2 = "Standard_G2"
+	RoleSizeStandardG3 = "Standard_G3"
+	RoleSizeStandardG4 = "Standard_G4"
+	RoleSizeStandardG5 = "Standard_G5"
+)
+
+// Deployment slots of a cloud service. Virtual machines are always deployed
+// to the production slot.
+const (
+	SlotProduction = "Production"
+	SlotStaging    = "Staging"
+)